@@ -0,0 +1,214 @@
+// Package achievements unlocks badges for children when reading events are recorded.
+package achievements
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"library/internal/labels"
+	"library/internal/models"
+)
+
+// Badge keys. Label badges use LabelPrefix followed by the label.
+const (
+	KeyTenBooks    = "books_10"
+	KeyWeekStreak  = "streak_7"
+	KeyFirstReader = "first_reader"
+	LabelPrefix    = "label:"
+)
+
+// distinctBooksGoal and streakGoal are the thresholds of the books and streak badges
+const (
+	distinctBooksGoal = 10
+	streakGoal        = 7
+)
+
+// Definition describes a badge for display
+type Definition struct {
+	Key         string `json:"key"`
+	Emoji       string `json:"emoji"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// Describe returns the display definition of a badge key
+func Describe(key string) Definition {
+	switch {
+	case key == KeyTenBooks:
+		return Definition{Key: key, Emoji: "📚", Name: "Bookworm", Description: fmt.Sprintf("Read %d different books", distinctBooksGoal)}
+	case key == KeyWeekStreak:
+		return Definition{Key: key, Emoji: "🔥", Name: "Week on fire", Description: fmt.Sprintf("Read every day for %d days", streakGoal)}
+	case key == KeyFirstReader:
+		return Definition{Key: key, Emoji: "🆕", Name: "Explorer", Description: "First to read a new book"}
+	case strings.HasPrefix(key, LabelPrefix):
+		label := strings.TrimPrefix(key, LabelPrefix)
+		return Definition{Key: key, Emoji: "🏷", Name: fmt.Sprintf("All of %q", label), Description: fmt.Sprintf("Read every book labelled %q", label)}
+	default:
+		return Definition{Key: key, Emoji: "🏅", Name: key}
+	}
+}
+
+// Store is the part of storage the engine needs
+type Store interface {
+	GetEvent(ctx context.Context, id string) (models.Event, error)
+	GetBookByName(ctx context.Context, name string) (models.Book, error)
+	ListParticipants(ctx context.Context) ([]models.Participant, error)
+	GetDetailedBookStats(ctx context.Context, startDate, endDate time.Time, bookName, participantName string) ([]models.DetailedBookStat, error)
+	GetBooksByLabel(ctx context.Context, filter labels.Expr) ([]models.Book, error)
+	GetStreaks(ctx context.Context, today time.Time) ([]models.Streak, error)
+	AddBadge(ctx context.Context, badge models.Badge) error
+	ListBadges(ctx context.Context, participantID string) ([]models.Badge, error)
+}
+
+// Engine checks the badge rules for newly recorded events
+type Engine struct {
+	store Store
+	loc   *time.Location
+}
+
+// New creates an engine; loc defines calendar days for streaks
+func New(store Store, loc *time.Location) *Engine {
+	if loc == nil {
+		loc = time.UTC
+	}
+	return &Engine{store: store, loc: loc}
+}
+
+// Check evaluates the rules for the children who attended the event, stores badges
+// they have not unlocked before and returns them with participant names set
+func (e *Engine) Check(ctx context.Context, eventID string) ([]models.Badge, error) {
+	event, err := e.store.GetEvent(ctx, eventID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get event: %w", err)
+	}
+	participants, err := e.store.ListParticipants(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list participants: %w", err)
+	}
+
+	// Everyone's reads of this book tell whether the event was its first reading
+	bookReads, err := e.store.GetDetailedBookStats(ctx, time.Time{}, time.Time{}, event.BookName, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get book stats: %w", err)
+	}
+	firstReading := isFirstReading(event, bookReads)
+
+	book, err := e.store.GetBookByName(ctx, event.BookName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get book: %w", err)
+	}
+
+	var streaks []models.Streak
+	var unlocked []models.Badge
+	for _, p := range participants {
+		if p.IsParent || !slices.Contains(event.ParticipantIDs, p.ID) {
+			continue
+		}
+
+		owned, err := e.store.ListBadges(ctx, p.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list badges: %w", err)
+		}
+		has := make(map[string]bool)
+		for _, badge := range owned {
+			has[badge.Key] = true
+		}
+
+		reads, err := e.store.GetDetailedBookStats(ctx, time.Time{}, time.Time{}, "", p.Name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get reading stats: %w", err)
+		}
+		booksRead := make(map[string]bool)
+		for _, stat := range reads {
+			if stat.ReadCount > 0 {
+				booksRead[stat.BookName] = true
+			}
+		}
+
+		var earned []string
+		if len(booksRead) >= distinctBooksGoal {
+			earned = append(earned, KeyTenBooks)
+		}
+		if firstReading {
+			earned = append(earned, KeyFirstReader)
+		}
+		if !has[KeyWeekStreak] {
+			if streaks == nil {
+				if streaks, err = e.store.GetStreaks(ctx, time.Now().In(e.loc)); err != nil {
+					return nil, fmt.Errorf("failed to get streaks: %w", err)
+				}
+			}
+			for _, streak := range streaks {
+				if streak.ParticipantID == p.ID && streak.Longest >= streakGoal {
+					earned = append(earned, KeyWeekStreak)
+				}
+			}
+		}
+		for _, label := range book.Labels {
+			if has[LabelPrefix+label] {
+				continue
+			}
+			complete, err := e.labelComplete(ctx, label, booksRead)
+			if err != nil {
+				return nil, err
+			}
+			if complete {
+				earned = append(earned, LabelPrefix+label)
+			}
+		}
+
+		for _, key := range earned {
+			if has[key] {
+				continue
+			}
+			badge := models.Badge{
+				ParticipantID:   p.ID,
+				ParticipantName: p.Name,
+				Key:             key,
+				EventID:         event.ID,
+				UnlockedAt:      time.Now(),
+			}
+			if err := e.store.AddBadge(ctx, badge); err != nil {
+				return nil, fmt.Errorf("failed to add badge: %w", err)
+			}
+			has[key] = true
+			unlocked = append(unlocked, badge)
+		}
+	}
+
+	return unlocked, nil
+}
+
+// labelComplete reports whether every readable book with the label (at least two) was read
+func (e *Engine) labelComplete(ctx context.Context, label string, booksRead map[string]bool) (bool, error) {
+	books, err := e.store.GetBooksByLabel(ctx, labels.Label(label))
+	if err != nil {
+		return false, fmt.Errorf("failed to get books by label: %w", err)
+	}
+	if len(books) < 2 {
+		return false, nil
+	}
+	for _, book := range books {
+		if !booksRead[book.Name] {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// isFirstReading reports whether all reads of the book come from this single event:
+// only its attendees have read the book, each exactly once
+func isFirstReading(event models.Event, bookReads []models.DetailedBookStat) bool {
+	for _, stat := range bookReads {
+		if stat.ReadCount == 0 {
+			continue
+		}
+		if stat.ReadCount > 1 || !slices.Contains(event.ParticipantNames, stat.ParticipantName) {
+			return false
+		}
+	}
+	return true
+}
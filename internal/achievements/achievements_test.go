@@ -0,0 +1,138 @@
+package achievements
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"library/internal/models"
+	"library/internal/storage/stubs"
+)
+
+func setup(t *testing.T) (*stubs.MockDB, map[string]string) {
+	t.Helper()
+	ctx := context.Background()
+	db := stubs.NewMockDB()
+	if err := db.Initialize(ctx); err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	participants, _ := db.ListParticipants(ctx)
+	ids := make(map[string]string)
+	for _, p := range participants {
+		ids[p.Name] = p.ID
+	}
+	return db, ids
+}
+
+func record(t *testing.T, db *stubs.MockDB, date time.Time, bookName string, participantIDs ...string) string {
+	t.Helper()
+	book, err := db.GetBookByName(context.Background(), bookName)
+	if err != nil {
+		t.Fatalf("Failed to find book %q: %v", bookName, err)
+	}
+	id, err := db.CreateEvent(context.Background(), models.Event{Date: date, BookID: book.ID, ParticipantIDs: participantIDs})
+	if err != nil {
+		t.Fatalf("Failed to create event: %v", err)
+	}
+	return id
+}
+
+func keys(badges []models.Badge, participant string) []string {
+	var result []string
+	for _, badge := range badges {
+		if badge.ParticipantName == participant {
+			result = append(result, badge.Key)
+		}
+	}
+	return result
+}
+
+func TestEngine_FirstReaderAndParents(t *testing.T) {
+	ctx := context.Background()
+	db, ids := setup(t)
+	engine := New(db, time.UTC)
+	now := time.Now()
+
+	badges, err := engine.Check(ctx, record(t, db, now, "Matilda", ids["Alice"], ids["Mom"]))
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if len(badges) != 1 || badges[0].ParticipantName != "Alice" || badges[0].Key != KeyFirstReader {
+		t.Fatalf("Expected only Alice to become first reader, got %+v", badges)
+	}
+
+	// Bob reads the same book later: not a first reading, and Alice keeps a single badge
+	badges, _ = engine.Check(ctx, record(t, db, now, "Matilda", ids["Alice"], ids["Bob"]))
+	if len(badges) != 0 {
+		t.Errorf("Expected no badges for a book already read, got %+v", badges)
+	}
+	stored, _ := db.ListBadges(ctx, ids["Alice"])
+	if len(stored) != 1 {
+		t.Errorf("Expected one stored badge for Alice, got %+v", stored)
+	}
+}
+
+func TestEngine_TenBooksAndStreak(t *testing.T) {
+	ctx := context.Background()
+	db, ids := setup(t)
+	engine := New(db, time.UTC)
+
+	books, _ := db.ListReadableBooks(ctx)
+	if len(books) < distinctBooksGoal {
+		t.Fatalf("Need %d books, have %d", distinctBooksGoal, len(books))
+	}
+	// Bob read every book once before; Alice reads them on consecutive days
+	for _, book := range books {
+		record(t, db, time.Now().AddDate(0, 0, -30), book.Name, ids["Bob"])
+	}
+
+	var unlocked []models.Badge
+	for i, book := range books[:distinctBooksGoal] {
+		eventID := record(t, db, time.Now().AddDate(0, 0, i-distinctBooksGoal+1), book.Name, ids["Alice"])
+		badges, err := engine.Check(ctx, eventID)
+		if err != nil {
+			t.Fatalf("Check failed: %v", err)
+		}
+		if i < streakGoal-1 && len(badges) > 0 {
+			t.Errorf("Unexpected badges after %d days: %+v", i+1, badges)
+		}
+		unlocked = append(unlocked, badges...)
+	}
+
+	got := keys(unlocked, "Alice")
+	if len(got) != 2 || got[0] != KeyWeekStreak || got[1] != KeyTenBooks {
+		t.Errorf("Expected the streak and then the ten books badge, got %v", got)
+	}
+}
+
+func TestEngine_LabelComplete(t *testing.T) {
+	ctx := context.Background()
+	db, ids := setup(t)
+	engine := New(db, time.UTC)
+
+	for _, name := range []string{"The Hobbit", "Matilda"} {
+		book, _ := db.GetBookByName(ctx, name)
+		_ = db.AddLabelToBook(ctx, book.ID, "classics")
+	}
+	record(t, db, time.Now().AddDate(0, 0, -3), "The Hobbit", ids["Bob"])
+
+	badges, _ := engine.Check(ctx, record(t, db, time.Now().AddDate(0, 0, -2), "The Hobbit", ids["Alice"]))
+	if len(keys(badges, "Alice")) != 0 {
+		t.Errorf("Expected no label badge with one of two books read, got %+v", badges)
+	}
+
+	badges, _ = engine.Check(ctx, record(t, db, time.Now().AddDate(0, 0, -1), "Matilda", ids["Alice"]))
+	got := keys(badges, "Alice")
+	if len(got) != 2 || got[0] != KeyFirstReader || got[1] != LabelPrefix+"classics" {
+		t.Errorf("Expected first reader and classics badges, got %v", got)
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	if def := Describe(LabelPrefix + "dinosaurs"); def.Description != `Read every book labelled "dinosaurs"` {
+		t.Errorf("Unexpected label badge definition: %+v", def)
+	}
+	if def := Describe("unknown"); def.Name != "unknown" || def.Emoji == "" {
+		t.Errorf("Unexpected fallback definition: %+v", def)
+	}
+}
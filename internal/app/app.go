@@ -2,11 +2,13 @@ package app
 
 import (
 	"context"
+	"crypto/subtle"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
@@ -17,6 +19,7 @@ import (
 	"library/internal/bot"
 	"library/internal/config"
 	"library/internal/llm"
+	"library/internal/scheduler"
 	"library/internal/storage"
 	"library/internal/storage/ch"
 	"library/internal/storage/stubs"
@@ -29,6 +32,9 @@ type App struct {
 	bot    *bot.Bot
 	server *http.Server
 	logger *zap.Logger
+
+	scheduler     *scheduler.Scheduler
+	stopScheduler context.CancelFunc
 }
 
 // New creates and initializes a new application instance
@@ -70,6 +76,12 @@ func New() (*App, error) {
 		return nil, err
 	}
 
+	// Initialize scheduled jobs
+	if err := app.initScheduler(); err != nil {
+		logger.Error("Failed to initialize scheduler", zap.Error(err))
+		return nil, err
+	}
+
 	// Initialize HTTP server
 	app.initHTTPServer()
 
@@ -117,7 +129,7 @@ func (a *App) initDatabase() error {
 	}
 	a.logger.Info("Database initialized successfully")
 
-	a.db = db
+	a.db = storage.NewAudited(db, a.logger)
 	return nil
 }
 
@@ -138,19 +150,79 @@ func (a *App) initBot() error {
 		a.logger.Info("LLM client not configured (LLM_API_KEY not set)")
 	}
 
-	telegramBot, err := bot.NewBot(a.config.TelegramToken, a.db, a.config.AllowedUserIDs, a.config.NotificationChatID, a.config.NotificationThreadID, llmClient, a.logger)
+	telegramBot, err := bot.NewBot(a.config.TelegramToken, a.db, a.config.AllowedUserIDs, a.config.NotificationChatID, a.config.NotificationThreadID, a.config.Timezone, llmClient, a.logger)
 	if err != nil {
 		a.logger.Error("Failed to create Telegram bot", zap.Error(err))
 		return fmt.Errorf("failed to create Telegram bot: %w", err)
 	}
+	if len(a.config.NotificationRoutes) > 0 {
+		routes := make(map[string]bot.NotificationRoute, len(a.config.NotificationRoutes))
+		for kind, route := range a.config.NotificationRoutes {
+			routes[kind] = bot.NotificationRoute{ChatID: route.ChatID, ThreadID: route.ThreadID}
+		}
+		telegramBot.SetNotificationRoutes(routes)
+	}
 	a.logger.Info("Bot created successfully",
 		zap.Int64s("allowed_users", a.config.AllowedUserIDs),
+		zap.String("timezone", a.config.Timezone.String()),
 	)
 
 	a.bot = telegramBot
 	return nil
 }
 
+// initScheduler registers scheduled jobs: the daily reading reminder and the digests
+func (a *App) initScheduler() error {
+	a.scheduler = scheduler.New(a.db, a.config.Timezone, a.logger)
+
+	if a.config.ReminderTime == "" {
+		a.logger.Info("Daily reminder not configured (REMINDER_TIME not set)")
+	} else {
+		hour, minute, err := scheduler.ParseTimeOfDay(a.config.ReminderTime)
+		if err != nil {
+			return fmt.Errorf("failed to parse REMINDER_TIME: %w", err)
+		}
+		a.bot.ConfigureReminder(a.config.ReminderTime, a.config.ReminderSkipIfRead)
+		a.scheduler.Add(scheduler.Job{
+			Name:   "reading_reminder",
+			Hour:   hour,
+			Minute: minute,
+			Run:    a.bot.SendReadingReminder,
+		})
+		a.logger.Info("Daily reminder scheduled",
+			zap.String("time", a.config.ReminderTime),
+			zap.Bool("skip_if_read", a.config.ReminderSkipIfRead),
+		)
+	}
+
+	if a.config.DigestTime == "" {
+		a.logger.Info("Digests not configured (DIGEST_TIME not set)")
+	} else {
+		hour, minute, err := scheduler.ParseTimeOfDay(a.config.DigestTime)
+		if err != nil {
+			return fmt.Errorf("failed to parse DIGEST_TIME: %w", err)
+		}
+		a.bot.ConfigureDigest(a.config.DigestTime)
+		a.scheduler.Add(scheduler.Job{
+			Name:   "weekly_digest",
+			Hour:   hour,
+			Minute: minute,
+			On:     scheduler.Weekly(time.Monday),
+			Run:    a.bot.SendWeeklyDigest,
+		})
+		a.scheduler.Add(scheduler.Job{
+			Name:   "monthly_digest",
+			Hour:   hour,
+			Minute: minute,
+			On:     scheduler.Monthly(1),
+			Run:    a.bot.SendMonthlyDigest,
+		})
+		a.logger.Info("Digests scheduled", zap.String("time", a.config.DigestTime))
+	}
+
+	return nil
+}
+
 // initHTTPServer initializes the HTTP server for health checks, webhook, and Mini App
 func (a *App) initHTTPServer() {
 	port := os.Getenv("PORT")
@@ -206,14 +278,37 @@ func (a *App) initHTTPServer() {
 		w.WriteHeader(http.StatusOK)
 	})
 
+	// External trigger for scheduled jobs, for instances that are not kept running
+	if a.config.SchedulerToken != "" {
+		mux.HandleFunc("/scheduler/run-due", func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(a.config.SchedulerToken)) != 1 {
+				a.logger.Warn("Unauthorized scheduler trigger", zap.String("remote_addr", r.RemoteAddr))
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			// Run before responding: the platform may stop the instance once the request is done
+			a.scheduler.RunDue(r.Context())
+			w.WriteHeader(http.StatusOK)
+			fmt.Fprintf(w, "OK")
+		})
+	}
+
 	// Register Mini App routes (web-app and API endpoints)
 	// Pass webhook mode to enable/disable authentication
 	httpServer := bot.NewHTTPServer(a.bot, a.config.WebhookMode)
+	httpServer.ConfigureInitDataMaxAge(a.config.InitDataMaxAge)
 	httpServer.RegisterRoutes(mux)
 
 	a.logger.Info("HTTP routes registered",
 		zap.Bool("webhook_mode", a.config.WebhookMode),
 		zap.String("auth_required", fmt.Sprintf("%v", a.config.WebhookMode)),
+		zap.Duration("init_data_max_age", a.config.InitDataMaxAge),
 	)
 
 	a.server = &http.Server{
@@ -259,6 +354,11 @@ func (a *App) Run() error {
 		}()
 	}
 
+	// Run scheduled jobs in both modes
+	schedulerCtx, stopScheduler := context.WithCancel(ctx)
+	a.stopScheduler = stopScheduler
+	go a.scheduler.Start(schedulerCtx)
+
 	// Wait for interrupt signal
 	<-sigChan
 
@@ -271,6 +371,11 @@ func (a *App) Shutdown() error {
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
+	// Stop scheduled jobs
+	if a.stopScheduler != nil {
+		a.stopScheduler()
+	}
+
 	// Shutdown bot (including its HTTP server)
 	if a.bot != nil {
 		if err := a.bot.Shutdown(shutdownCtx); err != nil {
@@ -10,7 +10,9 @@ import (
 	tgbot "github.com/go-telegram/bot"
 	"github.com/go-telegram/bot/models"
 	"go.uber.org/zap"
+	"library/internal/labels"
 	"library/internal/llm"
+	"library/internal/storage"
 )
 
 const askSystemPrompt = `Ты — помощник семейной библиотеки. Отвечай на русском языке. Будь кратким и точным.
@@ -79,7 +81,7 @@ var askTools = []llm.Tool{
 			Name:        "get_rarely_read_books",
 			Description: "Получить книги, которые давно не читали, отсортированные по дате последнего прочтения",
 			Parameters: json.RawMessage(`{"type":"object","properties":{
-				"label":{"type":"string","description":"Фильтр по метке (пусто = все книги)"},
+				"label":{"type":"string","description":"Фильтр по меткам: метка или выражение с AND/OR/NOT, например genre=fairy-tale AND NOT lang=en (пусто = все книги)"},
 				"limit":{"type":"integer","description":"Сколько книг вернуть (по умолчанию 10)"}
 			}}`),
 		},
@@ -193,6 +195,8 @@ func (b *Bot) handleAskConversation(ctx context.Context, message *models.Message
 
 // runAskWithTools executes the tool-calling loop: LLM requests tools, bot executes them, repeats.
 func (b *Bot) runAskWithTools(ctx context.Context, history []llm.Message) (string, []llm.Message, error) {
+	// Anything the tools change is attributed to /ask in the audit log
+	ctx = storage.WithSource(ctx, storage.SourceAsk)
 	for i := 0; i < maxToolIterations; i++ {
 		resp, err := b.llmClient.ChatWithTools(ctx, history, askTools)
 		if err != nil {
@@ -332,7 +336,7 @@ func (b *Bot) toolGetLastEvents(ctx context.Context, limit int, since, until, pa
 
 	var sb strings.Builder
 	for i, e := range events {
-		sb.WriteString(fmt.Sprintf("%d. %s | %s | %s\n", i+1, e.Date.Format("2006-01-02"), e.ParticipantName, e.BookName))
+		sb.WriteString(fmt.Sprintf("%d. %s | %s | %s\n", i+1, e.Date.Format("2006-01-02"), strings.Join(e.ParticipantNames, ", "), e.BookName))
 	}
 	if len(events) == 0 {
 		sb.WriteString("(нет событий за указанный период)\n")
@@ -358,7 +362,11 @@ func (b *Bot) toolGetTopBooks(ctx context.Context, days int, participant string,
 }
 
 func (b *Bot) toolGetRarelyReadBooks(ctx context.Context, label string, limit int) string {
-	stats, err := b.db.GetRarelyReadBooks(ctx, limit, true, label, nil)
+	filter, err := labels.Parse(label)
+	if err != nil {
+		return fmt.Sprintf("error: invalid label filter: %v", err)
+	}
+	stats, err := b.db.GetRarelyReadBooks(ctx, limit, true, filter)
 	if err != nil {
 		return fmt.Sprintf("error: %v", err)
 	}
@@ -0,0 +1,68 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"library/internal/storage"
+
+	"github.com/go-telegram/bot/models"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultAuditLimit = 10
+	maxAuditLimit     = 20
+	// maxAuditPayload is how much of an entry's payload is shown, keeping the message within Telegram limits
+	maxAuditPayload = 150
+)
+
+// handleAudit lists recent data changes: /audit [count]
+func (b *Bot) handleAudit(ctx context.Context, message *models.Message) {
+	limit := defaultAuditLimit
+	if args := commandArgs(message.Text); args != "" {
+		n, err := strconv.Atoi(args)
+		if err != nil || n < 1 {
+			b.sendMessageInThread(ctx, message.Chat.ID, fmt.Sprintf("Usage: /audit [count], up to %d", maxAuditLimit), message.MessageThreadID)
+			return
+		}
+		limit = min(n, maxAuditLimit)
+	}
+
+	entries, err := b.db.ListAuditEntries(ctx, limit, 0)
+	if err != nil {
+		b.logger.Error("Failed to list audit entries",
+			zap.Error(err),
+			zap.Int64("user_id", message.From.ID),
+		)
+		b.sendMessageInThread(ctx, message.Chat.ID, fmt.Sprintf("Error: %v", err), message.MessageThreadID)
+		return
+	}
+	if len(entries) == 0 {
+		b.sendMessageInThread(ctx, message.Chat.ID, "No changes recorded yet.", message.MessageThreadID)
+		return
+	}
+
+	var text strings.Builder
+	text.WriteString("🕵️ Recent changes (newest first):\n")
+	for _, entry := range entries {
+		actor := "unknown user"
+		switch {
+		case entry.ActorID != 0:
+			actor = fmt.Sprintf("%s (%d)", entry.ActorName, entry.ActorID)
+		case entry.Source == storage.SourceSystem:
+			actor = "the bot"
+		}
+
+		payload := entry.Payload
+		if len([]rune(payload)) > maxAuditPayload {
+			payload = string([]rune(payload)[:maxAuditPayload]) + "…"
+		}
+
+		text.WriteString(fmt.Sprintf("\n%s %s by %s via %s\n%s\n",
+			b.formatEventTime(entry.CreatedAt), entry.Action, actor, entry.Source, payload))
+	}
+	b.sendMessageInThread(ctx, message.Chat.ID, text.String(), message.MessageThreadID)
+}
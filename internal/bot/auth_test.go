@@ -70,14 +70,14 @@ func TestValidateInitData_Valid(t *testing.T) {
 	allowed := map[int64]bool{42: true}
 	initData := generateTestInitData(t, testBotToken, 42, time.Now())
 
-	userID, err := validateTelegramInitData(initData, testBotToken, allowed)
+	userID, err := validateTelegramInitData(initData, testBotToken, allowed, defaultInitDataMaxAge)
 	require.NoError(t, err)
 	assert.Equal(t, int64(42), userID)
 }
 
 func TestValidateInitData_EmptyString(t *testing.T) {
 	allowed := map[int64]bool{42: true}
-	_, err := validateTelegramInitData("", testBotToken, allowed)
+	_, err := validateTelegramInitData("", testBotToken, allowed, defaultInitDataMaxAge)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "missing initData")
 }
@@ -88,7 +88,7 @@ func TestValidateInitData_TamperedHash(t *testing.T) {
 	// Replace last char of hash to tamper it
 	initData = strings.Replace(initData, "hash=", "hash=00", 1)
 
-	_, err := validateTelegramInitData(initData, testBotToken, allowed)
+	_, err := validateTelegramInitData(initData, testBotToken, allowed, defaultInitDataMaxAge)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "invalid hash")
 }
@@ -97,25 +97,42 @@ func TestValidateInitData_ExpiredAuthDate(t *testing.T) {
 	allowed := map[int64]bool{42: true}
 	initData := generateTestInitData(t, testBotToken, 42, time.Now().Add(-25*time.Hour))
 
-	_, err := validateTelegramInitData(initData, testBotToken, allowed)
+	_, err := validateTelegramInitData(initData, testBotToken, allowed, defaultInitDataMaxAge)
+	assert.ErrorIs(t, err, errInitDataExpired)
+}
+
+func TestValidateInitData_ConfiguredMaxAge(t *testing.T) {
+	allowed := map[int64]bool{42: true}
+	initData := generateTestInitData(t, testBotToken, 42, time.Now().Add(-2*time.Hour))
+
+	_, err := validateTelegramInitData(initData, testBotToken, allowed, defaultInitDataMaxAge)
+	assert.NoError(t, err)
+	_, err = validateTelegramInitData(initData, testBotToken, allowed, time.Hour)
+	assert.ErrorIs(t, err, errInitDataExpired)
+}
+
+func TestValidateInitData_FutureAuthDate(t *testing.T) {
+	allowed := map[int64]bool{42: true}
+	initData := generateTestInitData(t, testBotToken, 42, time.Now().Add(time.Hour))
+
+	_, err := validateTelegramInitData(initData, testBotToken, allowed, defaultInitDataMaxAge)
 	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "too old")
+	assert.Contains(t, err.Error(), "in the future")
 }
 
 func TestValidateInitData_UserNotAllowed(t *testing.T) {
 	allowed := map[int64]bool{99: true} // userID 42 is not allowed
 	initData := generateTestInitData(t, testBotToken, 42, time.Now())
 
-	_, err := validateTelegramInitData(initData, testBotToken, allowed)
-	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "not allowed")
+	_, err := validateTelegramInitData(initData, testBotToken, allowed, defaultInitDataMaxAge)
+	assert.ErrorIs(t, err, errUserNotAllowed)
 }
 
 func TestValidateInitData_WrongToken(t *testing.T) {
 	allowed := map[int64]bool{42: true}
 	initData := generateTestInitData(t, testBotToken, 42, time.Now())
 
-	_, err := validateTelegramInitData(initData, "wrong-token", allowed)
+	_, err := validateTelegramInitData(initData, "wrong-token", allowed, defaultInitDataMaxAge)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "invalid hash")
 }
@@ -145,7 +162,20 @@ func TestValidateInitData_MissingUserField(t *testing.T) {
 	params.Set("hash", hex.EncodeToString(h.Sum(nil)))
 
 	allowed := map[int64]bool{42: true}
-	_, err := validateTelegramInitData(params.Encode(), testBotToken, allowed)
+	_, err := validateTelegramInitData(params.Encode(), testBotToken, allowed, defaultInitDataMaxAge)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "missing user")
 }
+
+func TestInitDataUser(t *testing.T) {
+	initData := generateTestInitData(t, testBotToken, 42, time.Now())
+
+	user, err := initDataUser(initData)
+	require.NoError(t, err)
+	assert.Equal(t, int64(42), user.ID)
+	assert.Equal(t, "Test", user.FirstName)
+	assert.Equal(t, "testuser", user.Username)
+
+	_, err = initDataUser("auth_date=1")
+	assert.Error(t, err)
+}
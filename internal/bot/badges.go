@@ -0,0 +1,73 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"library/internal/achievements"
+
+	"github.com/go-telegram/bot/models"
+	"go.uber.org/zap"
+)
+
+// awardBadges runs the achievement rules for a new event and announces unlocked badges
+// in the given chat (chatID 0 stores them silently)
+func (b *Bot) awardBadges(ctx context.Context, eventID string, chatID int64, messageThreadID int) {
+	badges, err := achievements.New(b.db, b.loc()).Check(ctx, eventID)
+	if err != nil {
+		b.logger.Warn("Failed to check achievements", zap.Error(err), zap.String("event_id", eventID))
+		return
+	}
+
+	for _, badge := range badges {
+		def := achievements.Describe(badge.Key)
+		b.logger.Info("Badge unlocked",
+			zap.String("participant", badge.ParticipantName),
+			zap.String("badge", badge.Key),
+		)
+		if chatID != 0 {
+			b.sendMessageInThread(ctx, chatID,
+				fmt.Sprintf("🏅 New badge for %s: %s %s\n%s", badge.ParticipantName, def.Emoji, def.Name, def.Description),
+				messageThreadID)
+		}
+	}
+}
+
+// handleBadges lists the badges of a participant: /badges <name>
+func (b *Bot) handleBadges(ctx context.Context, message *models.Message) {
+	name := strings.TrimSpace(commandArgs(message.Text))
+	if name == "" {
+		b.sendMessageInThread(ctx, message.Chat.ID, "Usage: /badges <name>", message.MessageThreadID)
+		return
+	}
+
+	participant, err := b.findActiveParticipant(ctx, name)
+	if err != nil {
+		b.sendParticipantLookupError(ctx, message, name, err)
+		return
+	}
+
+	badges, err := b.db.ListBadges(ctx, participant.ID)
+	if err != nil {
+		b.logger.Error("Failed to list badges",
+			zap.Error(err),
+			zap.Int64("user_id", message.From.ID),
+			zap.String("participant", participant.Name),
+		)
+		b.sendMessageInThread(ctx, message.Chat.ID, fmt.Sprintf("Error: %v", err), message.MessageThreadID)
+		return
+	}
+	if len(badges) == 0 {
+		b.sendMessageInThread(ctx, message.Chat.ID, fmt.Sprintf("%s has no badges yet. Keep reading! 📖", participant.Name), message.MessageThreadID)
+		return
+	}
+
+	var text strings.Builder
+	text.WriteString(fmt.Sprintf("🏅 Badges of %s:\n\n", participant.Name))
+	for _, badge := range badges {
+		def := achievements.Describe(badge.Key)
+		text.WriteString(fmt.Sprintf("%s %s - %s (%s)\n", def.Emoji, def.Name, def.Description, badge.UnlockedAt.In(b.loc()).Format("2006-01-02")))
+	}
+	b.sendMessageInThread(ctx, message.Chat.ID, text.String(), message.MessageThreadID)
+}
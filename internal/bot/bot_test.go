@@ -2,7 +2,14 @@ package bot
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"library/internal/labels"
+	libmodels "library/internal/models"
+	"library/internal/storage"
 	"library/internal/storage/stubs"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
@@ -54,7 +61,7 @@ func TestBot_NewBookConversation(t *testing.T) {
 		t.Errorf("Expected step 1, got %d", state.Step)
 	}
 
-	// Step 2: Provide book name (conversation completes immediately)
+	// Step 2: Provide book name (the book is created, optional details follow)
 	message2 := &models.Message{
 		From: &models.User{ID: userID},
 		Chat: models.Chat{ID: chatID},
@@ -63,9 +70,44 @@ func TestBot_NewBookConversation(t *testing.T) {
 
 	bot.handleNewBookConversation(ctx, message2, state)
 
+	if state.Step != 2 {
+		t.Errorf("Expected step 2 (author), got %d", state.Step)
+	}
+
+	// Author, skip the ISBN, series with volume, an invalid age is asked again, then stop
+	text := func(text string) *models.Message {
+		return &models.Message{From: &models.User{ID: userID}, Chat: models.Chat{ID: chatID}, Text: text}
+	}
+	query := func(data string) *models.CallbackQuery {
+		return &models.CallbackQuery{
+			From:    models.User{ID: userID},
+			Data:    data,
+			Message: models.MaybeInaccessibleMessage{Message: &models.Message{Chat: models.Chat{ID: chatID}}},
+		}
+	}
+	bot.handleNewBookConversation(ctx, text(" Jane Doe "), state)
+	bot.handleNewBookCallback(ctx, query("newbook:skip"), state)
+	bot.handleNewBookConversation(ctx, text("Test Series #3"), state)
+	bot.handleNewBookConversation(ctx, &models.Message{From: &models.User{ID: userID}, Chat: models.Chat{ID: chatID},
+		Photo: []models.PhotoSize{{FileID: "small"}, {FileID: "large"}}}, state)
+	bot.handleNewBookConversation(ctx, text("teen"), state)
+	if state.Step != 6 {
+		t.Errorf("Expected step 6 (age) after an invalid age, got %d", state.Step)
+	}
+	bot.handleNewBookConversation(ctx, text("6+"), state)
+	bot.handleNewBookCallback(ctx, query("newbook:done"), state)
+
 	if state.Step != -1 {
 		t.Errorf("Expected step -1 (completed), got %d", state.Step)
 	}
+	book, err := db.GetBookByName(ctx, "Test Book")
+	if err != nil {
+		t.Fatalf("Failed to get book: %v", err)
+	}
+	want := libmodels.BookMetadata{Author: "Jane Doe", Series: "Test Series", SeriesVolume: 3, Cover: "large", Age: "6+"}
+	if book.BookMetadata != want {
+		t.Errorf("Expected metadata %+v, got %+v", want, book.BookMetadata)
+	}
 
 	// Verify book was created by checking it appears in readable books list
 	books, err := db.ListReadableBooks(ctx)
@@ -675,3 +717,1363 @@ func TestBot_MultipleAuthorizedUsers(t *testing.T) {
 		t.Error("Expected unauthorized user to NOT have conversation state")
 	}
 }
+
+func TestBot_ParticipantsAddFlow(t *testing.T) {
+	db := stubs.NewMockDB()
+	ctx := context.Background()
+	if err := db.Initialize(ctx); err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+
+	bot := &Bot{
+		api:          nil,
+		db:           db,
+		allowedUsers: map[int64]bool{123: true},
+		states:       make(map[int64]*ConversationState),
+		logger:       zap.NewNop(),
+	}
+
+	userID := int64(123)
+	chatID := int64(456)
+
+	bot.handleParticipantsStart(ctx, &models.Message{
+		From: &models.User{ID: userID},
+		Chat: models.Chat{ID: chatID},
+		Text: "/participants",
+	})
+
+	state, ok := bot.states[userID]
+	if !ok {
+		t.Fatal("Expected conversation state to be created")
+	}
+
+	query := func(data string) *models.CallbackQuery {
+		return &models.CallbackQuery{
+			From: models.User{ID: userID},
+			Data: data,
+			Message: models.MaybeInaccessibleMessage{
+				Message: &models.Message{Chat: models.Chat{ID: chatID}},
+			},
+		}
+	}
+
+	bot.handleParticipantsAddCallback(ctx, query("participants_add:"), state)
+	if state.Step != 2 {
+		t.Fatalf("Expected step 2, got %d", state.Step)
+	}
+
+	bot.handleParticipantsConversation(ctx, &models.Message{
+		From: &models.User{ID: userID},
+		Chat: models.Chat{ID: chatID},
+		Text: "Charlie",
+	}, state)
+	if state.Step != 3 {
+		t.Fatalf("Expected step 3, got %d", state.Step)
+	}
+
+	bot.handleParticipantsRoleCallback(ctx, query("participants_role:child"), state)
+	if state.Step != -1 {
+		t.Errorf("Expected step -1 (completed), got %d", state.Step)
+	}
+
+	participants, _ := db.ListParticipants(ctx)
+	found := false
+	for _, p := range participants {
+		if p.Name == "Charlie" && !p.IsParent {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("Expected 'Charlie' to be created as a child")
+	}
+}
+
+func TestBot_ParticipantsArchiveAction(t *testing.T) {
+	db := stubs.NewMockDB()
+	ctx := context.Background()
+	if err := db.Initialize(ctx); err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+
+	bot := &Bot{
+		api:          nil,
+		db:           db,
+		allowedUsers: map[int64]bool{123: true},
+		states:       make(map[int64]*ConversationState),
+		logger:       zap.NewNop(),
+	}
+
+	participants, _ := db.ListParticipants(ctx)
+	state := &ConversationState{
+		Command: "participants",
+		Step:    1,
+		Data:    map[string]interface{}{"participants": participants},
+	}
+
+	query := &models.CallbackQuery{
+		From: models.User{ID: 123},
+		Data: "participants_select:0",
+		Message: models.MaybeInaccessibleMessage{
+			Message: &models.Message{Chat: models.Chat{ID: 456}},
+		},
+	}
+	bot.handleParticipantsSelectCallback(ctx, query, state)
+
+	query.Data = "participants_action:archive"
+	bot.handleParticipantsActionCallback(ctx, query, state)
+
+	if state.Step != -1 {
+		t.Errorf("Expected step -1 (completed), got %d", state.Step)
+	}
+
+	remaining, _ := db.ListParticipants(ctx)
+	if len(remaining) != len(participants)-1 {
+		t.Errorf("Expected %d participants after archive, got %d", len(participants)-1, len(remaining))
+	}
+	for _, p := range remaining {
+		if p.Name == participants[0].Name {
+			t.Errorf("Expected %s to be archived", p.Name)
+		}
+	}
+}
+
+func TestBot_AddLabelToSeveralBooks(t *testing.T) {
+	db := stubs.NewMockDB()
+	ctx := context.Background()
+	if err := db.Initialize(ctx); err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+
+	bot := &Bot{
+		api:          nil,
+		db:           db,
+		allowedUsers: map[int64]bool{123: true},
+		states:       make(map[int64]*ConversationState),
+		logger:       zap.NewNop(),
+	}
+
+	userID := int64(123)
+	chatID := int64(456)
+	query := func(data string) *models.CallbackQuery {
+		return &models.CallbackQuery{
+			From: models.User{ID: userID},
+			Data: data,
+			Message: models.MaybeInaccessibleMessage{
+				Message: &models.Message{Chat: models.Chat{ID: chatID}},
+			},
+		}
+	}
+
+	bot.handleAddLabelStart(ctx, &models.Message{From: &models.User{ID: userID}, Chat: models.Chat{ID: chatID}, Text: "/add_label"})
+	state := bot.states[userID]
+	bot.handleAddLabelConversation(ctx, &models.Message{From: &models.User{ID: userID}, Chat: models.Chat{ID: chatID}, Text: "winter"}, state)
+	if state.Step != 2 {
+		t.Fatalf("Expected step 2, got %d", state.Step)
+	}
+
+	// Done without a selection keeps the conversation open
+	bot.handleAddLabelBookCallback(ctx, query("addlabel_book:done"), state)
+	if state.Step != 2 {
+		t.Fatalf("Expected step 2 after empty Done, got %d", state.Step)
+	}
+
+	// Select all, deselect one, toggle it back on and off again
+	bot.handleAddLabelBookCallback(ctx, query("addlabel_book:all"), state)
+	bot.handleAddLabelBookCallback(ctx, query("addlabel_book:0"), state)
+	bot.handleAddLabelBookCallback(ctx, query("addlabel_book:0"), state)
+	bot.handleAddLabelBookCallback(ctx, query("addlabel_book:0"), state)
+	if selected := state.Data["selected_books"].([]string); len(selected) != 9 {
+		t.Fatalf("Expected 9 selected books, got %d", len(selected))
+	}
+
+	bot.handleAddLabelBookCallback(ctx, query("addlabel_book:done"), state)
+	if state.Step != -1 {
+		t.Errorf("Expected step -1 (completed), got %d", state.Step)
+	}
+
+	books, _ := db.GetBooksByLabel(ctx, labels.Label("winter"))
+	if len(books) != 9 {
+		t.Errorf("Expected 9 books labeled 'winter', got %d", len(books))
+	}
+}
+
+func TestBookToggleKeyboardPages(t *testing.T) {
+	var books []libmodels.Book
+	for i := 0; i < bookTogglePageSize+5; i++ {
+		books = append(books, libmodels.Book{ID: fmt.Sprintf("id-%d", i), Name: fmt.Sprintf("Book %d", i)})
+	}
+
+	first := bookToggleKeyboard("p:", books, []string{"id-1"}, 0)
+	if got := first.InlineKeyboard[0][1].Text; got != "✅ Book 1" {
+		t.Errorf("Expected selected book to be marked, got %q", got)
+	}
+	nav := first.InlineKeyboard[len(first.InlineKeyboard)-2]
+	if len(nav) != 1 || nav[0].CallbackData != "p:page:1" {
+		t.Errorf("Expected only a Next button on the first page, got %+v", nav)
+	}
+
+	second := bookToggleKeyboard("p:", books, nil, 1)
+	if got := second.InlineKeyboard[0][0].CallbackData; got != fmt.Sprintf("p:%d", bookTogglePageSize) {
+		t.Errorf("Expected second page to start at book %d, got %q", bookTogglePageSize, got)
+	}
+	last := second.InlineKeyboard[len(second.InlineKeyboard)-1]
+	if last[1].CallbackData != "p:done" || last[1].Text != "✔️ Done (0)" {
+		t.Errorf("Unexpected last row %+v", last)
+	}
+}
+
+func TestNormalizeISBN(t *testing.T) {
+	valid := map[string]string{
+		"978-0-261-10221-7": "9780261102217",
+		"0 261 10221 4":     "0261102214",
+		"0-8044-2957-x":     "080442957X",
+	}
+	for input, want := range valid {
+		if got, err := normalizeISBN(input); err != nil || got != want {
+			t.Errorf("normalizeISBN(%q) = %q, %v; want %q", input, got, err, want)
+		}
+	}
+	for _, input := range []string{"978-0-261-10221-8", "12345", "026110221A", "X261102214"} {
+		if got, err := normalizeISBN(input); err == nil {
+			t.Errorf("normalizeISBN(%q) = %q, expected an error", input, got)
+		}
+	}
+
+	if series, volume, err := parseSeries(" Harry Potter # 2 "); err != nil || series != "Harry Potter" || volume != 2 {
+		t.Errorf("parseSeries = %q, %d, %v", series, volume, err)
+	}
+	if _, _, err := parseSeries("Harry Potter #two"); err == nil {
+		t.Error("Expected an error for a volume that is not a number")
+	}
+}
+
+func TestBot_RareFilterKeyboard(t *testing.T) {
+	db := stubs.NewMockDB()
+	ctx := context.Background()
+	if err := db.Initialize(ctx); err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	hobbit, _ := db.GetBookByName(ctx, "The Hobbit")
+	cat, _ := db.GetBookByName(ctx, "The Cat in the Hat")
+	_ = db.AddLabelToBook(ctx, hobbit.ID, "genre:fantasy")
+	_ = db.AddLabelToBook(ctx, hobbit.ID, "lang:en")
+	_ = db.AddLabelToBook(ctx, cat.ID, "lang:en")
+
+	bot := &Bot{
+		api:          nil,
+		db:           db,
+		allowedUsers: map[int64]bool{123: true},
+		states:       make(map[int64]*ConversationState),
+		logger:       zap.NewNop(),
+	}
+
+	userID := int64(123)
+	message := &models.Message{From: &models.User{ID: userID}, Chat: models.Chat{ID: 456}, Text: "/rare"}
+	query := func(data string) *models.CallbackQuery {
+		return &models.CallbackQuery{
+			From: models.User{ID: userID},
+			Data: data,
+			Message: models.MaybeInaccessibleMessage{
+				Message: &models.Message{Chat: models.Chat{ID: 456}},
+			},
+		}
+	}
+
+	// Namespaces in use get a "key: any" button before the labels
+	keyboard := labelFilterKeyboard("rare_label:", []string{"bedtime", "genre:fantasy", "lang:en"})
+	if got := keyboard.InlineKeyboard[0]; len(got) != 2 || got[0].CallbackData != "rare_label:genre:*" || got[1].CallbackData != "rare_label:lang:*" {
+		t.Errorf("Expected genre and lang namespace buttons first, got %+v", got)
+	}
+
+	// lang:en AND NOT genre:*
+	bot.handleRareStart(ctx, message)
+	state := bot.states[userID]
+	if state == nil {
+		t.Fatal("Expected conversation state to be created")
+	}
+	bot.handleRareLabelCallback(ctx, query("rare_label:lang:en"), state)
+	bot.handleRareOpCallback(ctx, query("rare_op:not"), state)
+	bot.handleRareLabelCallback(ctx, query("rare_label:genre:*"), state)
+	filter, _ := state.Data["filter"].(labels.Expr)
+	if filter == nil || filter.String() != "lang:en AND NOT genre:*" {
+		t.Fatalf("Expected filter 'lang:en AND NOT genre:*', got %v", filter)
+	}
+	if filter.Match([]string{"genre:fantasy", "lang:en"}) || !filter.Match([]string{"lang:en"}) {
+		t.Errorf("Unexpected matches for %s", filter)
+	}
+
+	bot.handleRareShowCallback(ctx, query("rare_show"), state)
+	if state.Step != -1 {
+		t.Errorf("Expected step -1 (completed), got %d", state.Step)
+	}
+
+	// OR extends the filter
+	if got := combineFilters(labels.Or{labels.Label("a"), labels.Label("b")}, "or", labels.Label("c")).String(); got != "a OR b OR c" {
+		t.Errorf("Expected 'a OR b OR c', got %q", got)
+	}
+	if got := combineFilters(labels.Or{labels.Label("a"), labels.Label("b")}, "and", labels.Label("c")).String(); got != "(a OR b) AND c" {
+		t.Errorf("Expected '(a OR b) AND c', got %q", got)
+	}
+}
+
+func TestBot_AddLabelValidation(t *testing.T) {
+	db := stubs.NewMockDB()
+	ctx := context.Background()
+	if err := db.Initialize(ctx); err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+
+	bot := &Bot{
+		api:          nil,
+		db:           db,
+		allowedUsers: map[int64]bool{123: true},
+		states:       make(map[int64]*ConversationState),
+		logger:       zap.NewNop(),
+	}
+	message := func(text string) *models.Message {
+		return &models.Message{From: &models.User{ID: 123}, Chat: models.Chat{ID: 456}, Text: text}
+	}
+
+	bot.handleAddLabelStart(ctx, message("/add_label"))
+	state := bot.states[123]
+
+	// Malformed typed labels are rejected and the label is asked again
+	bot.handleAddLabelConversation(ctx, message("age:teen"), state)
+	if state.Step != 1 {
+		t.Fatalf("Expected step 1 after an invalid label, got %d", state.Step)
+	}
+
+	bot.handleAddLabelConversation(ctx, message("Lang:EN"), state)
+	if state.Step != 2 || state.Data["label"] != "lang:en" {
+		t.Errorf("Expected normalized label lang:en at step 2, got %v at step %d", state.Data["label"], state.Step)
+	}
+}
+
+func TestBot_LabelManagementFlow(t *testing.T) {
+	db := stubs.NewMockDB()
+	ctx := context.Background()
+	if err := db.Initialize(ctx); err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	hobbit, _ := db.GetBookByName(ctx, "The Hobbit")
+	cat, _ := db.GetBookByName(ctx, "The Cat in the Hat")
+	_ = db.AddLabelToBook(ctx, hobbit.ID, "bedtime")
+	_ = db.AddLabelToBook(ctx, hobbit.ID, "fantasy")
+	_ = db.AddLabelToBook(ctx, cat.ID, "Bedtime")
+
+	bot := &Bot{
+		api:          nil,
+		db:           db,
+		allowedUsers: map[int64]bool{123: true},
+		states:       make(map[int64]*ConversationState),
+		logger:       zap.NewNop(),
+	}
+
+	userID := int64(123)
+	chatID := int64(456)
+	message := func(text string) *models.Message {
+		return &models.Message{From: &models.User{ID: userID}, Chat: models.Chat{ID: chatID}, Text: text}
+	}
+	query := func(data string) *models.CallbackQuery {
+		return &models.CallbackQuery{
+			From: models.User{ID: userID},
+			Data: data,
+			Message: models.MaybeInaccessibleMessage{
+				Message: &models.Message{Chat: models.Chat{ID: chatID}},
+			},
+		}
+	}
+
+	// Merge "Bedtime" into "bedtime" (labels are sorted: Bedtime, bedtime, fantasy)
+	bot.handleLabelsStart(ctx, message("/labels"))
+	state := bot.states[userID]
+	if state == nil {
+		t.Fatal("Expected conversation state to be created")
+	}
+	bot.handleLabelsSelectCallback(ctx, query("labels_select:0"), state)
+	bot.handleLabelsActionCallback(ctx, query("labels_action:merge"), state)
+	bot.handleLabelsMergeCallback(ctx, query("labels_merge:0"), state)
+	if state.Step != -1 {
+		t.Errorf("Expected step -1 (completed), got %d", state.Step)
+	}
+	if labels, _ := db.GetAllLabels(ctx); !slices.Equal(labels, []string{"bedtime", "fantasy"}) {
+		t.Errorf("Expected labels [bedtime fantasy] after merge, got %v", labels)
+	}
+
+	// Rename "fantasy" to "magic"
+	bot.handleLabelsStart(ctx, message("/labels"))
+	state = bot.states[userID]
+	bot.handleLabelsSelectCallback(ctx, query("labels_select:1"), state)
+	bot.handleLabelsActionCallback(ctx, query("labels_action:rename"), state)
+	if state.Step != 2 {
+		t.Fatalf("Expected step 2, got %d", state.Step)
+	}
+	bot.handleLabelsConversation(ctx, message("magic"), state)
+	if state.Step != -1 {
+		t.Errorf("Expected step -1 (completed), got %d", state.Step)
+	}
+	if labels, _ := db.GetAllLabels(ctx); !slices.Equal(labels, []string{"bedtime", "magic"}) {
+		t.Errorf("Expected labels [bedtime magic] after rename, got %v", labels)
+	}
+
+	// Remove "bedtime" from The Hobbit
+	bot.handleRemoveLabelStart(ctx, message("/remove_label"))
+	state = bot.states[userID]
+	books := state.Data["books"].([]libmodels.Book)
+	idx := slices.IndexFunc(books, func(b libmodels.Book) bool { return b.ID == hobbit.ID })
+	bot.handleRemoveLabelBookCallback(ctx, query(fmt.Sprintf("rmlabel_book:%d", idx)), state)
+	bot.handleRemoveLabelLabelCallback(ctx, query("rmlabel_label:0"), state)
+	if state.Step != -1 {
+		t.Errorf("Expected step -1 (completed), got %d", state.Step)
+	}
+	hobbit, _ = db.GetBookByName(ctx, "The Hobbit")
+	if !slices.Equal(hobbit.Labels, []string{"magic"}) {
+		t.Errorf("Expected The Hobbit to keep only [magic], got %v", hobbit.Labels)
+	}
+}
+
+func TestBot_RetireBookFlow(t *testing.T) {
+	db := stubs.NewMockDB()
+	ctx := context.Background()
+	if err := db.Initialize(ctx); err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+
+	bot := &Bot{
+		api:          nil,
+		db:           db,
+		allowedUsers: map[int64]bool{123: true},
+		states:       make(map[int64]*ConversationState),
+		logger:       zap.NewNop(),
+	}
+
+	userID := int64(123)
+	chatID := int64(456)
+
+	bot.handleRetireBookStart(ctx, &models.Message{
+		From: &models.User{ID: userID},
+		Chat: models.Chat{ID: chatID},
+		Text: "/retire_book",
+	})
+
+	state, ok := bot.states[userID]
+	if !ok {
+		t.Fatal("Expected conversation state to be created")
+	}
+	books := state.Data["books"].([]libmodels.Book)
+	retiredName := books[0].Name
+
+	bot.handleBookReadableCallback(ctx, &models.CallbackQuery{
+		From: models.User{ID: userID},
+		Data: "retire_book:0",
+		Message: models.MaybeInaccessibleMessage{
+			Message: &models.Message{Chat: models.Chat{ID: chatID}},
+		},
+	}, state)
+	if state.Step != -1 {
+		t.Errorf("Expected step -1 (completed), got %d", state.Step)
+	}
+
+	readable, _ := db.ListReadableBooks(ctx)
+	for _, book := range readable {
+		if book.Name == retiredName {
+			t.Errorf("Expected '%s' to be retired", retiredName)
+		}
+	}
+}
+
+func TestBot_UndoEvent(t *testing.T) {
+	db := stubs.NewMockDB()
+	ctx := context.Background()
+	if err := db.Initialize(ctx); err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+
+	bot := &Bot{
+		api:          nil,
+		db:           db,
+		allowedUsers: map[int64]bool{123: true},
+		states:       make(map[int64]*ConversationState),
+		logger:       zap.NewNop(),
+	}
+
+	books, _ := db.ListReadableBooks(ctx)
+	participants, _ := db.ListParticipants(ctx)
+	eventID, err := db.CreateEvent(ctx, libmodels.Event{Date: time.Now(), BookID: books[0].ID, ParticipantIDs: []string{participants[0].ID}})
+	if err != nil {
+		t.Fatalf("Failed to create event: %v", err)
+	}
+
+	query := &models.CallbackQuery{
+		From: models.User{ID: 123},
+		Data: "undo_event:" + eventID,
+		Message: models.MaybeInaccessibleMessage{
+			Message: &models.Message{Chat: models.Chat{ID: 456}},
+		},
+	}
+	bot.handleUndoEventCallback(ctx, query)
+
+	events, _ := db.GetLastEvents(ctx, 10)
+	if len(events) != 0 {
+		t.Errorf("Expected event to be removed, got %d events", len(events))
+	}
+
+	// Pressing the button again must not fail
+	bot.handleUndoEventCallback(ctx, query)
+}
+
+func TestBot_EditLastFlow(t *testing.T) {
+	db := stubs.NewMockDB()
+	ctx := context.Background()
+	if err := db.Initialize(ctx); err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+
+	bot := &Bot{
+		api:          nil,
+		db:           db,
+		allowedUsers: map[int64]bool{123: true},
+		states:       make(map[int64]*ConversationState),
+		logger:       zap.NewNop(),
+	}
+
+	userID := int64(123)
+	chatID := int64(456)
+
+	books, _ := db.ListReadableBooks(ctx)
+	participants, _ := db.ListParticipants(ctx)
+	eventDate := time.Date(2024, 3, 1, 20, 0, 0, 0, time.UTC)
+	eventID, err := db.CreateEvent(ctx, libmodels.Event{Date: eventDate, BookID: books[0].ID, ParticipantIDs: []string{participants[0].ID}})
+	if err != nil {
+		t.Fatalf("Failed to create event: %v", err)
+	}
+
+	bot.handleEditLastStart(ctx, &models.Message{
+		From: &models.User{ID: userID},
+		Chat: models.Chat{ID: chatID},
+		Text: "/edit_last",
+	})
+
+	state, ok := bot.states[userID]
+	if !ok {
+		t.Fatal("Expected conversation state to be created")
+	}
+
+	callback := func(data string) {
+		bot.handleCallbackQuery(ctx, &models.CallbackQuery{
+			From: models.User{ID: userID},
+			Data: data,
+			Message: models.MaybeInaccessibleMessage{
+				Message: &models.Message{Chat: models.Chat{ID: chatID}},
+			},
+		})
+	}
+	_ = callback
+
+	query := func(data string) *models.CallbackQuery {
+		return &models.CallbackQuery{
+			From: models.User{ID: userID},
+			Data: data,
+			Message: models.MaybeInaccessibleMessage{
+				Message: &models.Message{Chat: models.Chat{ID: chatID}},
+			},
+		}
+	}
+
+	bot.handleEditLastEventCallback(ctx, query("editlast_event:0"), state)
+	bot.handleEditLastActionCallback(ctx, query("editlast_action:date"), state)
+	bot.handleConversation(ctx, &models.Message{
+		From: &models.User{ID: userID},
+		Chat: models.Chat{ID: chatID},
+		Text: "2024-02-28",
+	}, state)
+
+	if state.Step != -1 {
+		t.Errorf("Expected step -1 (completed), got %d", state.Step)
+	}
+
+	event, err := db.GetEvent(ctx, eventID)
+	if err != nil {
+		t.Fatalf("Failed to get event: %v", err)
+	}
+	expected := time.Date(2024, 2, 28, 20, 0, 0, 0, time.UTC)
+	if !event.Date.Equal(expected) {
+		t.Errorf("Expected date %v, got %v", expected, event.Date)
+	}
+	if event.BookID != books[0].ID {
+		t.Errorf("Expected book to stay unchanged")
+	}
+}
+
+func TestBot_ReadDuplicateConfirmation(t *testing.T) {
+	db := stubs.NewMockDB()
+	ctx := context.Background()
+	if err := db.Initialize(ctx); err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+
+	bot := &Bot{
+		api:          nil,
+		db:           db,
+		allowedUsers: map[int64]bool{123: true},
+		states:       make(map[int64]*ConversationState),
+		logger:       zap.NewNop(),
+	}
+
+	books, _ := db.ListReadableBooks(ctx)
+	participants, _ := db.ListParticipants(ctx)
+	date := time.Date(2024, 5, 10, 0, 0, 0, 0, time.UTC)
+	if _, err := db.CreateEvent(ctx, libmodels.Event{Date: date, BookID: books[0].ID, ParticipantIDs: []string{participants[0].ID}}); err != nil {
+		t.Fatalf("Failed to create event: %v", err)
+	}
+
+	newState := func() *ConversationState {
+		return &ConversationState{
+			Command: "read",
+			Step:    3,
+			Data:    map[string]interface{}{"date": date, "book": books[0], "participant_list": participants},
+		}
+	}
+	query := func(data string) *models.CallbackQuery {
+		return &models.CallbackQuery{
+			From: models.User{ID: 123},
+			Data: data,
+			Message: models.MaybeInaccessibleMessage{
+				Message: &models.Message{Chat: models.Chat{ID: 456}},
+			},
+		}
+	}
+
+	// Duplicate is held back and cancelled
+	state := newState()
+	bot.handleParticipantCallback(ctx, query("participant:"+participants[0].ID), state)
+	bot.handleParticipantCallback(ctx, query("participant:done"), state)
+	if state.Step != 4 {
+		t.Fatalf("Expected step 4 (awaiting session details), got %d", state.Step)
+	}
+	bot.handleDetailsCallback(ctx, query("details:skip"), state)
+	if state.Step != 5 {
+		t.Fatalf("Expected step 5 (awaiting confirmation), got %d", state.Step)
+	}
+	bot.handleDuplicateConfirmCallback(ctx, query("dup_confirm:no"), state)
+	if state.Step != -1 {
+		t.Errorf("Expected step -1 (completed), got %d", state.Step)
+	}
+	if events, _ := db.GetLastEvents(ctx, 10); len(events) != 1 {
+		t.Errorf("Expected duplicate to be dropped, got %d events", len(events))
+	}
+
+	// Duplicate is recorded when confirmed
+	state = newState()
+	bot.handleParticipantCallback(ctx, query("participant:"+participants[0].ID), state)
+	bot.handleParticipantCallback(ctx, query("participant:done"), state)
+	bot.handleDetailsCallback(ctx, query("details:skip"), state)
+	bot.handleDuplicateConfirmCallback(ctx, query("dup_confirm:yes"), state)
+	if state.Step != -1 {
+		t.Errorf("Expected step -1 (completed), got %d", state.Step)
+	}
+	if events, _ := db.GetLastEvents(ctx, 10); len(events) != 2 {
+		t.Errorf("Expected duplicate to be recorded, got %d events", len(events))
+	}
+}
+
+func TestBot_ReadMultipleParticipants(t *testing.T) {
+	db := stubs.NewMockDB()
+	ctx := context.Background()
+	if err := db.Initialize(ctx); err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+
+	bot := &Bot{
+		api:          nil,
+		db:           db,
+		allowedUsers: map[int64]bool{123: true},
+		states:       make(map[int64]*ConversationState),
+		logger:       zap.NewNop(),
+	}
+
+	query := func(data string) *models.CallbackQuery {
+		return &models.CallbackQuery{
+			From: models.User{ID: 123},
+			Data: data,
+			Message: models.MaybeInaccessibleMessage{
+				Message: &models.Message{Chat: models.Chat{ID: 456}},
+			},
+		}
+	}
+
+	state := &ConversationState{
+		Command: "read",
+		Step:    2,
+		Data:    map[string]interface{}{"date": time.Now()},
+	}
+	bot.handleBookCallback(ctx, query("book:0"), state)
+
+	participants := state.Data["participant_list"].([]libmodels.Participant)
+	alice := participantIDByName(t, participants, "Alice")
+	bob := participantIDByName(t, participants, "Bob")
+	mom := participantIDByName(t, participants, "Mom")
+
+	// Toggling Mom twice leaves her unselected
+	bot.handleParticipantCallback(ctx, query("participant:"+alice), state)
+	bot.handleParticipantCallback(ctx, query("participant:"+mom), state)
+	bot.handleParticipantCallback(ctx, query("participant:"+bob), state)
+	bot.handleParticipantCallback(ctx, query("participant:"+mom), state)
+	bot.handleParticipantCallback(ctx, query("participant:done"), state)
+	bot.handleDetailsCallback(ctx, query("details:skip"), state)
+
+	if state.Step != -1 {
+		t.Fatalf("Expected step -1 (completed), got %d", state.Step)
+	}
+
+	events, _ := db.GetLastEvents(ctx, 10)
+	if len(events) != 1 {
+		t.Fatalf("Expected one shared event, got %d", len(events))
+	}
+	if len(events[0].ParticipantNames) != 2 || events[0].ParticipantNames[0] != "Alice" || events[0].ParticipantNames[1] != "Bob" {
+		t.Errorf("Expected readers Alice and Bob, got %v", events[0].ParticipantNames)
+	}
+}
+
+func TestBot_ReadSessionDetails(t *testing.T) {
+	db := stubs.NewMockDB()
+	ctx := context.Background()
+	if err := db.Initialize(ctx); err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+
+	bot := &Bot{
+		api:          nil,
+		db:           db,
+		allowedUsers: map[int64]bool{123: true},
+		states:       make(map[int64]*ConversationState),
+		logger:       zap.NewNop(),
+	}
+
+	books, _ := db.ListReadableBooks(ctx)
+	participants, _ := db.ListParticipants(ctx)
+	state := &ConversationState{
+		Command: "read",
+		Step:    3,
+		Data:    map[string]interface{}{"date": time.Now(), "book": books[0]},
+	}
+	message := func(text string) *models.Message {
+		return &models.Message{
+			From: &models.User{ID: 123},
+			Chat: models.Chat{ID: 456},
+			Text: text,
+		}
+	}
+
+	bot.handleReadConversation(ctx, message("1"), state)
+	if state.Step != 4 {
+		t.Fatalf("Expected step 4 (awaiting session details), got %d", state.Step)
+	}
+
+	// Invalid input keeps the conversation on the same step
+	bot.handleReadConversation(ctx, message("a while"), state)
+	if state.Step != 4 {
+		t.Fatalf("Expected step 4 after invalid input, got %d", state.Step)
+	}
+
+	bot.handleReadConversation(ctx, message("25m 12p"), state)
+	if state.Step != -1 {
+		t.Fatalf("Expected step -1 (completed), got %d", state.Step)
+	}
+
+	events, _ := db.GetLastEvents(ctx, 10)
+	if len(events) != 1 {
+		t.Fatalf("Expected one event, got %d", len(events))
+	}
+	event := events[0]
+	if event.ParticipantIDs[0] != participants[0].ID || event.DurationMinutes != 25 ||
+		event.Progress != 12 || event.ProgressUnit != libmodels.ProgressPages {
+		t.Errorf("Unexpected event: %+v", event)
+	}
+}
+
+func TestParseSessionDetails(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    sessionDetails
+		wantErr bool
+	}{
+		{input: "20m", want: sessionDetails{DurationMinutes: 20}},
+		{input: "20m 15p", want: sessionDetails{DurationMinutes: 20, Progress: 15, ProgressUnit: libmodels.ProgressPages}},
+		{input: "30 min, 2 chapters", want: sessionDetails{DurationMinutes: 30, Progress: 2, ProgressUnit: libmodels.ProgressChapters}},
+		{input: "3CH", want: sessionDetails{Progress: 3, ProgressUnit: libmodels.ProgressChapters}},
+		{input: "", wantErr: true},
+		{input: "twenty minutes", wantErr: true},
+		{input: "20x", wantErr: true},
+		{input: "0m", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseSessionDetails(tt.input)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseSessionDetails(%q) expected error, got %+v", tt.input, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseSessionDetails(%q) unexpected error: %v", tt.input, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseSessionDetails(%q) = %+v, want %+v", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestBot_ReadCustomDateWithTime(t *testing.T) {
+	db := stubs.NewMockDB()
+	ctx := context.Background()
+	if err := db.Initialize(ctx); err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+
+	loc := time.FixedZone("UTC+3", 3*60*60)
+	bot := &Bot{
+		api:          nil,
+		db:           db,
+		allowedUsers: map[int64]bool{123: true},
+		states:       make(map[int64]*ConversationState),
+		logger:       zap.NewNop(),
+		location:     loc,
+	}
+
+	state := &ConversationState{
+		Command: "read",
+		Step:    1,
+		Data:    map[string]interface{}{"awaiting_custom_date": true},
+	}
+	message := &models.Message{
+		From: &models.User{ID: 123},
+		Chat: models.Chat{ID: 456},
+		Text: "2024-01-15 19:30",
+	}
+	bot.handleReadConversation(ctx, message, state)
+
+	if state.Step != 2 {
+		t.Fatalf("Expected step 2 (book selection), got %d", state.Step)
+	}
+	date := state.Data["date"].(time.Time)
+	if !date.Equal(time.Date(2024, 1, 15, 19, 30, 0, 0, loc)) {
+		t.Errorf("Expected 2024-01-15 19:30 +03:00, got %v", date)
+	}
+	if got := bot.formatEventTime(date.UTC()); got != "2024-01-15 19:30" {
+		t.Errorf("Expected time shown in the bot timezone, got %q", got)
+	}
+}
+
+func TestParseEventDateTime(t *testing.T) {
+	tests := []struct {
+		input    string
+		want     time.Time
+		wantTime bool
+		wantErr  bool
+	}{
+		{input: "2024-01-15", want: time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)},
+		{input: "2024-01-15 07:05", want: time.Date(2024, 1, 15, 7, 5, 0, 0, time.UTC), wantTime: true},
+		{input: "  2024-01-15   21:40 ", want: time.Date(2024, 1, 15, 21, 40, 0, 0, time.UTC), wantTime: true},
+		{input: "2024-01-15 24:00", wantErr: true},
+		{input: "15.01.2024", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, hasTime, err := parseEventDateTime(tt.input, time.UTC)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseEventDateTime(%q) expected error, got %v", tt.input, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseEventDateTime(%q) unexpected error: %v", tt.input, err)
+			continue
+		}
+		if !got.Equal(tt.want) || hasTime != tt.wantTime {
+			t.Errorf("parseEventDateTime(%q) = %v, %v; want %v, %v", tt.input, got, hasTime, tt.want, tt.wantTime)
+		}
+	}
+}
+
+func TestFormatTimeOfDay(t *testing.T) {
+	hours := []libmodels.HourStat{
+		{Hour: 2, ReadCount: 1},
+		{Hour: 8, ReadCount: 2},
+		{Hour: 19, ReadCount: 3},
+		{Hour: 20, ReadCount: 1},
+		{Hour: 22, ReadCount: 4},
+	}
+
+	text := formatTimeOfDay(hours)
+	for _, want := range []string{"Morning (5–12): 2", "Evening (17–21): 4", "Night (21–5): 5"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Expected %q in:\n%s", want, text)
+		}
+	}
+	if strings.Contains(text, "Afternoon") {
+		t.Errorf("Expected empty parts of the day to be omitted:\n%s", text)
+	}
+	if formatTimeOfDay(nil) != "" {
+		t.Error("Expected no breakdown without sessions")
+	}
+}
+
+func TestBot_RotationSettings(t *testing.T) {
+	db := stubs.NewMockDB()
+	ctx := context.Background()
+	if err := db.Initialize(ctx); err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+
+	bot := &Bot{
+		api:          nil,
+		db:           db,
+		allowedUsers: map[int64]bool{123: true},
+		states:       make(map[int64]*ConversationState),
+		logger:       zap.NewNop(),
+	}
+
+	query := func(data string) *models.CallbackQuery {
+		return &models.CallbackQuery{
+			From: models.User{ID: 123},
+			Data: data,
+			Message: models.MaybeInaccessibleMessage{
+				Message: &models.Message{Chat: models.Chat{ID: 456}},
+			},
+		}
+	}
+
+	participants, _ := db.ListParticipants(ctx)
+	bob := participantIDByName(t, participants, "Bob")
+	mom := participantIDByName(t, participants, "Mom")
+
+	bot.handleRotationCallback(ctx, query("rotation_strategy:least_recent"))
+	bot.handleRotationCallback(ctx, query("rotation_skip:"+bob))
+	bot.handleRotationCallback(ctx, query("rotation_strategy:nonsense"))
+	bot.handleRotationCallback(ctx, query("rotation_skip:"+mom)) // parents are not skippable
+
+	settings, _ := db.GetChatSettings(ctx, 456)
+	if settings.RotationStrategy != "least_recent" {
+		t.Errorf("Expected least_recent strategy, got %q", settings.RotationStrategy)
+	}
+	if len(settings.RotationSkipIDs) != 1 || settings.RotationSkipIDs[0] != bob {
+		t.Errorf("Expected only Bob to be skipped, got %v", settings.RotationSkipIDs)
+	}
+
+	// Nobody has read yet and Bob is skipped, so Alice is next
+	input, err := bot.rotationInput(ctx, participants, settings)
+	if err != nil {
+		t.Fatalf("Failed to build rotation input: %v", err)
+	}
+	if next := rotationStrategyByName(settings.RotationStrategy).Next(input); next != "Alice" {
+		t.Errorf("Expected Alice, got %q", next)
+	}
+
+	// Toggling again brings Bob back
+	bot.handleRotationCallback(ctx, query("rotation_skip:"+bob))
+	settings, _ = db.GetChatSettings(ctx, 456)
+	if len(settings.RotationSkipIDs) != 0 {
+		t.Errorf("Expected no skipped children, got %v", settings.RotationSkipIDs)
+	}
+
+	// Settings are per chat
+	if other, _ := db.GetChatSettings(ctx, 789); other.RotationStrategy != "" {
+		t.Errorf("Expected default settings for another chat, got %+v", other)
+	}
+}
+
+func TestBot_AwayAndBack(t *testing.T) {
+	db := stubs.NewMockDB()
+	ctx := context.Background()
+	if err := db.Initialize(ctx); err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+
+	bot := &Bot{
+		api:          nil,
+		db:           db,
+		allowedUsers: map[int64]bool{123: true},
+		states:       make(map[int64]*ConversationState),
+		logger:       zap.NewNop(),
+	}
+
+	message := func(text string) *models.Message {
+		return &models.Message{
+			From: &models.User{ID: 123},
+			Chat: models.Chat{ID: 456},
+			Text: text,
+		}
+	}
+
+	bot.handleAway(ctx, message("/away alice 3d"))
+	bot.handleAway(ctx, message("/away Nobody 3d")) // unknown participants are ignored
+	bot.handleAway(ctx, message("/away Bob"))       // missing date is rejected
+
+	absences, _ := db.ListAbsences(ctx, bot.now())
+	if len(absences) != 1 || absences[0].ParticipantName != "Alice" {
+		t.Fatalf("Expected only Alice to be away, got %+v", absences)
+	}
+
+	participants, _ := db.ListParticipants(ctx)
+	settings, _ := db.GetChatSettings(ctx, 456)
+	input, err := bot.rotationInput(ctx, participants, settings)
+	if err != nil {
+		t.Fatalf("Failed to build rotation input: %v", err)
+	}
+	if next := rotationStrategyByName("").Next(input); next != "Bob" {
+		t.Errorf("Expected Bob while Alice is away, got %q", next)
+	}
+	expected := "away until " + bot.now().AddDate(0, 0, 2).Format("2006-01-02")
+	if input.Skip["Alice"] != expected {
+		t.Errorf("Expected skip reason %q, got %q", expected, input.Skip["Alice"])
+	}
+	if text := formatSkipped(participants, input.Skip); !strings.Contains(text, "Alice: "+expected) {
+		t.Errorf("Expected Alice in the skipped list, got %q", text)
+	}
+
+	bot.handleBack(ctx, message("/back Alice"))
+	if absences, _ := db.ListAbsences(ctx, bot.now()); len(absences) != 0 {
+		t.Errorf("Expected nobody away after /back, got %+v", absences)
+	}
+}
+
+func TestBot_Remind(t *testing.T) {
+	db := stubs.NewMockDB()
+	ctx := context.Background()
+	if err := db.Initialize(ctx); err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+
+	bot := &Bot{
+		api:          nil,
+		db:           db,
+		allowedUsers: map[int64]bool{123: true},
+		states:       make(map[int64]*ConversationState),
+		logger:       zap.NewNop(),
+	}
+	bot.ConfigureReminder("19:30", true)
+
+	message := func(text string, threadID int) *models.Message {
+		return &models.Message{
+			From:            &models.User{ID: 123},
+			Chat:            models.Chat{ID: 456},
+			Text:            text,
+			MessageThreadID: threadID,
+		}
+	}
+
+	bot.handleRemind(ctx, message("/remind on", 7))
+	bot.handleRemind(ctx, message("/remind maybe", 0)) // unknown arguments change nothing
+
+	chats, _ := db.ListReminderChats(ctx)
+	if len(chats) != 1 || chats[0].ChatID != 456 || chats[0].ReminderThreadID != 7 {
+		t.Fatalf("Expected chat 456 registered in thread 7, got %+v", chats)
+	}
+	if status := bot.reminderStatus(chats[0]); !strings.Contains(status, "19:30") {
+		t.Errorf("Expected the reminder time in the status, got %q", status)
+	}
+
+	text, err := bot.whoIsNextText(ctx, chats[0])
+	if err != nil {
+		t.Fatalf("Failed to compute next reader: %v", err)
+	}
+	if !strings.HasPrefix(text, "Next to read: ") {
+		t.Errorf("Unexpected reminder text %q", text)
+	}
+	if err := bot.SendReadingReminder(ctx); err != nil {
+		t.Errorf("Failed to send reminder: %v", err)
+	}
+
+	bot.handleRemind(ctx, message("/remind off", 7))
+	if chats, _ := db.ListReminderChats(ctx); len(chats) != 0 {
+		t.Errorf("Expected no registered chats after /remind off, got %+v", chats)
+	}
+}
+
+func TestBot_BuildDigest(t *testing.T) {
+	db := stubs.NewMockDB()
+	ctx := context.Background()
+	if err := db.Initialize(ctx); err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+
+	bot := &Bot{
+		api:          nil,
+		db:           db,
+		allowedUsers: map[int64]bool{123: true},
+		states:       make(map[int64]*ConversationState),
+		logger:       zap.NewNop(),
+	}
+
+	participants, _ := db.ListParticipants(ctx)
+	books, _ := db.ListReadableBooks(ctx)
+	hobbit, _ := db.GetBookByName(ctx, "The Hobbit")
+	alice := participantIDByName(t, participants, "Alice")
+	mom := participantIDByName(t, participants, "Mom")
+
+	now := bot.now()
+	for _, event := range []libmodels.Event{
+		{Date: now.Add(-2 * time.Hour), BookID: hobbit.ID, ParticipantIDs: []string{alice, mom}},
+		{Date: now.Add(-time.Hour), BookID: hobbit.ID, ParticipantIDs: []string{alice}},
+		{Date: now.AddDate(0, 0, -30), BookID: books[0].ID, ParticipantIDs: []string{alice}},
+	} {
+		if _, err := db.CreateEvent(ctx, event); err != nil {
+			t.Fatalf("Failed to create event: %v", err)
+		}
+	}
+	if _, err := db.CreateBook(ctx, "Momo"); err != nil {
+		t.Fatalf("Failed to create book: %v", err)
+	}
+
+	text, err := bot.buildDigest(ctx, "📰 Weekly digest", now.AddDate(0, 0, -7), bot.now())
+	if err != nil {
+		t.Fatalf("Failed to build digest: %v", err)
+	}
+	for _, want := range []string{
+		"1. The Hobbit - 2 reads",
+		"Alice - 2 reads",
+		"Bob - 0 reads",
+		"🆕 New books:\n• Momo",
+		"💤 Forgotten books:",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Expected %q in digest:\n%s", want, text)
+		}
+	}
+	if strings.Contains(text, "Mom -") {
+		t.Errorf("Parents should not be listed per child:\n%s", text)
+	}
+
+	// Without a notification chat scheduled digests are skipped
+	if err := bot.SendWeeklyDigest(ctx); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestBot_GoalsAndCelebrations(t *testing.T) {
+	db := stubs.NewMockDB()
+	ctx := context.Background()
+	if err := db.Initialize(ctx); err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+
+	bot := &Bot{
+		api:          nil,
+		db:           db,
+		allowedUsers: map[int64]bool{123: true},
+		states:       make(map[int64]*ConversationState),
+		logger:       zap.NewNop(),
+	}
+
+	message := func(text string) *models.Message {
+		return &models.Message{
+			From: &models.User{ID: 123},
+			Chat: models.Chat{ID: 456},
+			Text: text,
+		}
+	}
+
+	bot.handleGoals(ctx, message("/goals set alice 2"))
+	bot.handleGoals(ctx, message("/goals set Bob 10 month"))
+	bot.handleGoals(ctx, message("/goals set Bob lots")) // invalid target keeps the previous goal
+
+	goals, _ := db.ListGoals(ctx)
+	if len(goals) != 2 || goals[0].Target != 2 || goals[0].Period != libmodels.GoalPeriodWeek || goals[1].Target != 10 {
+		t.Fatalf("Unexpected goals: %+v", goals)
+	}
+
+	participants, _ := db.ListParticipants(ctx)
+	hobbit, _ := db.GetBookByName(ctx, "The Hobbit")
+	alice := participantIDByName(t, participants, "Alice")
+
+	// Three days in a row for Alice
+	now := bot.now()
+	for _, date := range []time.Time{now.AddDate(0, 0, -2), now.AddDate(0, 0, -1)} {
+		if _, err := db.CreateEvent(ctx, libmodels.Event{Date: date, BookID: hobbit.ID, ParticipantIDs: []string{alice}}); err != nil {
+			t.Fatalf("Failed to create event: %v", err)
+		}
+	}
+	if _, err := db.CreateEvent(ctx, libmodels.Event{Date: now, BookID: hobbit.ID, ParticipantIDs: []string{alice}}); err != nil {
+		t.Fatalf("Failed to create event: %v", err)
+	}
+
+	// Make the latest reading the one that meets the goal, whatever day of the week it is
+	reads, _ := bot.readsSince(ctx, alice, goalPeriodStart(libmodels.GoalPeriodWeek, now))
+	_ = db.SetGoal(ctx, alice, reads, libmodels.GoalPeriodWeek)
+
+	messages, err := bot.celebrations(ctx, []string{alice}, now)
+	if err != nil {
+		t.Fatalf("Failed to check celebrations: %v", err)
+	}
+	if !slices.Contains(messages, "🔥 Alice has read 3 days in a row!") {
+		t.Errorf("Expected a streak milestone, got %v", messages)
+	}
+	if !slices.Contains(messages, fmt.Sprintf("🎯 Alice reached the goal of %d reads this week!", reads)) {
+		t.Errorf("Expected the goal to be met, got %v", messages)
+	}
+
+	// A second reading the same day celebrates nothing new
+	if _, err := db.CreateEvent(ctx, libmodels.Event{Date: now, BookID: hobbit.ID, ParticipantIDs: []string{alice}}); err != nil {
+		t.Fatalf("Failed to create event: %v", err)
+	}
+	if messages, _ = bot.celebrations(ctx, []string{alice}, now); len(messages) != 0 {
+		t.Errorf("Unexpected repeated celebrations %v", messages)
+	}
+
+	bot.handleGoals(ctx, message("/goals clear Bob"))
+	if goals, _ := db.ListGoals(ctx); len(goals) != 1 {
+		t.Errorf("Expected one goal after clearing Bob's, got %+v", goals)
+	}
+}
+
+func TestBot_Tokens(t *testing.T) {
+	db := stubs.NewMockDB()
+	ctx := context.Background()
+	if err := db.Initialize(ctx); err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+
+	bot := &Bot{
+		api:          nil,
+		db:           db,
+		allowedUsers: map[int64]bool{123: true},
+		states:       make(map[int64]*ConversationState),
+		logger:       zap.NewNop(),
+	}
+
+	message := func(text string, chatType models.ChatType) *models.Message {
+		return &models.Message{
+			From: &models.User{ID: 123},
+			Chat: models.Chat{ID: 456, Type: chatType},
+			Text: text,
+		}
+	}
+
+	bot.handleToken(ctx, message("/token create wall tablet", models.ChatTypePrivate))
+	bot.handleToken(ctx, message("/token create backup script write", models.ChatTypePrivate))
+	bot.handleToken(ctx, message("/token create wall tablet", models.ChatTypePrivate)) // duplicate name
+	bot.handleToken(ctx, message("/token create leaked", models.ChatTypeSupergroup))   // secrets are never sent to groups
+
+	tokens, _ := db.ListAPITokens(ctx, 123)
+	if len(tokens) != 2 {
+		t.Fatalf("Expected 2 tokens, got %+v", tokens)
+	}
+	if tokens[0].Name != "backup script" || tokens[0].Scope != libmodels.TokenScopeReadWrite {
+		t.Errorf("Unexpected token %+v", tokens[0])
+	}
+	if tokens[1].Name != "wall tablet" || tokens[1].Scope != libmodels.TokenScopeRead || len(tokens[1].Hash) != 64 {
+		t.Errorf("Unexpected token %+v", tokens[1])
+	}
+
+	bot.handleToken(ctx, message("/token revoke Wall Tablet", models.ChatTypeGroup))
+	if tokens, _ := db.ListAPITokens(ctx, 123); len(tokens) != 1 {
+		t.Errorf("Expected 1 token after revoke, got %+v", tokens)
+	}
+	if err := bot.revokeAPIToken(ctx, 123, "wall tablet"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestAPITokenSecrets(t *testing.T) {
+	secret, hash, err := generateAPIToken()
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+	other, _, _ := generateAPIToken()
+	if !strings.HasPrefix(secret, apiTokenPrefix) || secret == other {
+		t.Errorf("Unexpected secrets %q and %q", secret, other)
+	}
+	if hash != hashAPIToken(secret) || strings.Contains(hash, secret) {
+		t.Errorf("Unexpected hash %q of %q", hash, secret)
+	}
+}
+
+func TestParseAwayUntil(t *testing.T) {
+	now := time.Date(2024, 5, 10, 20, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		input   string
+		want    time.Time
+		wantErr bool
+	}{
+		{input: "2024-05-12", want: time.Date(2024, 5, 13, 0, 0, 0, 0, time.UTC)},
+		{input: "2024-05-10", want: time.Date(2024, 5, 11, 0, 0, 0, 0, time.UTC)},
+		{input: "1d", want: time.Date(2024, 5, 11, 0, 0, 0, 0, time.UTC)},
+		{input: "7D", want: time.Date(2024, 5, 17, 0, 0, 0, 0, time.UTC)},
+		{input: "2024-05-09", wantErr: true},
+		{input: "0d", wantErr: true},
+		{input: "soon", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseAwayUntil(tt.input, now)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseAwayUntil(%q) expected error, got %v", tt.input, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseAwayUntil(%q) unexpected error: %v", tt.input, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("parseAwayUntil(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestNotificationRoute(t *testing.T) {
+	b := &Bot{notificationChatID: -100, notificationThreadID: 3, logger: zap.NewNop()}
+
+	if got := b.notificationRoute(NotificationNewBook); got != (NotificationRoute{ChatID: -100, ThreadID: 3}) {
+		t.Errorf("Expected default route, got %+v", got)
+	}
+
+	b.SetNotificationRoutes(map[string]NotificationRoute{
+		NotificationNewBook:    {ChatID: -200, ThreadID: 5},
+		NotificationLabelAdded: {},
+	})
+	if got := b.notificationRoute(NotificationNewBook); got != (NotificationRoute{ChatID: -200, ThreadID: 5}) {
+		t.Errorf("Expected new_book override, got %+v", got)
+	}
+	if got := b.notificationRoute(NotificationLabelAdded); got.ChatID != 0 {
+		t.Errorf("Expected label_added to be disabled, got %+v", got)
+	}
+	if got := b.notificationRoute(NotificationNewEvent); got != (NotificationRoute{ChatID: -100, ThreadID: 3}) {
+		t.Errorf("Expected new_event to fall back to the default route, got %+v", got)
+	}
+}
+
+func TestActorLink(t *testing.T) {
+	ctx := context.Background()
+	if got := actorLink(ctx); got != "someone" {
+		t.Errorf("Expected 'someone' without an actor, got %q", got)
+	}
+
+	ctx = withActor(ctx, &models.User{ID: 7, FirstName: "Ann", LastName: "<Lee>"}, storage.SourceBot)
+	want := `<a href="tg://user?id=7">Ann &lt;Lee&gt;</a>`
+	if got := actorLink(ctx); got != want {
+		t.Errorf("actorLink() = %q, want %q", got, want)
+	}
+
+	ctx = withActor(context.Background(), &models.User{ID: 8, Username: "bob"}, storage.SourceBot)
+	if got := actorLink(ctx); !strings.Contains(got, ">bob</a>") {
+		t.Errorf("Expected username fallback, got %q", got)
+	}
+}
+
+func participantIDByName(t *testing.T, participants []libmodels.Participant, name string) string {
+	t.Helper()
+	for _, p := range participants {
+		if p.Name == name {
+			return p.ID
+		}
+	}
+	t.Fatalf("participant %q not found", name)
+	return ""
+}
@@ -2,12 +2,16 @@ package bot
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
+	"library/internal/labels"
 	libmodels "library/internal/models"
+	"library/internal/storage"
 
 	"github.com/go-telegram/bot/models"
 	"go.uber.org/zap"
@@ -21,6 +25,14 @@ func getChatIDFromQuery(query *models.CallbackQuery) int64 {
 	return 0
 }
 
+// getThreadIDFromQuery extracts the message thread (topic) ID from callback query message
+func getThreadIDFromQuery(query *models.CallbackQuery) int {
+	if query.Message.Message != nil {
+		return query.Message.Message.MessageThreadID
+	}
+	return 0
+}
+
 // handleDateCallback processes date selection from inline keyboard
 func (b *Bot) handleDateCallback(ctx context.Context, query *models.CallbackQuery, state *ConversationState) {
 	data := strings.TrimPrefix(query.Data, "date:")
@@ -28,20 +40,20 @@ func (b *Bot) handleDateCallback(ctx context.Context, query *models.CallbackQuer
 	// Handle custom date option
 	if data == "custom" {
 		state.Data["awaiting_custom_date"] = true
-		b.sendMessageInThread(ctx, getChatIDFromQuery(query), "📝 Please enter the date in format YYYY-MM-DD\n\nExample: 2024-01-15", state.MessageThreadID)
+		b.sendMessageInThread(ctx, getChatIDFromQuery(query), "📝 Please enter the date in format YYYY-MM-DD, optionally with the time (HH:MM)\n\nExample: 2024-01-15 or 2024-01-15 19:30", state.MessageThreadID)
 		return
 	}
 
 	var date time.Time
 	switch data {
 	case "today":
-		date = time.Now()
+		date = b.now()
 	case "yesterday":
-		date = time.Now().AddDate(0, 0, -1)
+		date = b.now().AddDate(0, 0, -1)
 	case "2daysago":
-		date = time.Now().AddDate(0, 0, -2)
+		date = b.now().AddDate(0, 0, -2)
 	case "3daysago":
-		date = time.Now().AddDate(0, 0, -3)
+		date = b.now().AddDate(0, 0, -3)
 	default:
 		return
 	}
@@ -101,7 +113,7 @@ func (b *Bot) handleBookCallback(ctx context.Context, query *models.CallbackQuer
 	}
 
 	selectedBook := books[bookIdx]
-	state.Data["book"] = selectedBook.Name
+	state.Data["book"] = selectedBook
 	state.Step = 3
 
 	// Get participants and show selection
@@ -112,51 +124,176 @@ func (b *Bot) handleBookCallback(ctx context.Context, query *models.CallbackQuer
 		return
 	}
 
-	// Create inline keyboard for participant selection
-	var rows [][]models.InlineKeyboardButton
-	for _, p := range participants {
-		emoji := "👶"
-		if p.IsParent {
-			emoji = "👨"
-		}
-		button := models.InlineKeyboardButton{
-			Text:         fmt.Sprintf("%s %s", emoji, p.Name),
-			CallbackData: fmt.Sprintf("participant:%s", p.Name),
+	state.Data["participant_list"] = participants
+	state.Data["selected_participants"] = []string{}
+
+	keyboard := participantToggleKeyboard("participant:", participants, nil)
+	b.sendMessageInThreadWithMarkup(ctx, getChatIDFromQuery(query), "👤 Select who was reading, then press Done:", state.MessageThreadID, keyboard)
+}
+
+// handleParticipantCallback toggles a reader for the event, or records the event on "done"
+func (b *Bot) handleParticipantCallback(ctx context.Context, query *models.CallbackQuery, state *ConversationState) {
+	data := strings.TrimPrefix(query.Data, "participant:")
+
+	participants, _ := state.Data["participant_list"].([]libmodels.Participant)
+	selected, _ := state.Data["selected_participants"].([]string)
+
+	if data != "done" {
+		if _, ok := findParticipant(participants, data); !ok {
+			b.sendMessageInThread(ctx, getChatIDFromQuery(query), "Error: Invalid participant selection", state.MessageThreadID)
+			state.Step = -1
+			return
 		}
-		rows = append(rows, []models.InlineKeyboardButton{button})
+		selected = toggleID(selected, data)
+		state.Data["selected_participants"] = selected
+		b.editMessageMarkup(ctx, query, participantToggleKeyboard("participant:", participants, selected))
+		return
+	}
+
+	readers := selectedParticipants(participants, selected)
+	if len(readers) == 0 {
+		b.sendMessageInThread(ctx, getChatIDFromQuery(query), "Please select at least one participant.", state.MessageThreadID)
+		return
 	}
 
+	b.askSessionDetails(ctx, getChatIDFromQuery(query), state, readers)
+}
+
+// askSessionDetails stores the chosen readers and asks for the optional duration and progress
+func (b *Bot) askSessionDetails(ctx context.Context, chatID int64, state *ConversationState, readers []libmodels.Participant) {
+	state.Data["readers"] = readers
+	state.Step = 4
+
 	keyboard := &models.InlineKeyboardMarkup{
-		InlineKeyboard: rows,
+		InlineKeyboard: [][]models.InlineKeyboardButton{
+			{
+				{Text: "⏭ Skip", CallbackData: "details:skip"},
+			},
+		},
 	}
-	b.sendMessageInThreadWithMarkup(ctx, getChatIDFromQuery(query), "👤 Select a participant:", state.MessageThreadID, keyboard)
+	text := "⏱ How long did you read and how far did you get?\n\n" +
+		"Examples: 20m 15p, 30m 2ch, 25m\n\nOr press Skip."
+	b.sendMessageInThreadWithMarkup(ctx, chatID, text, state.MessageThreadID, keyboard)
 }
 
-// handleParticipantCallback processes participant selection from inline keyboard
-func (b *Bot) handleParticipantCallback(ctx context.Context, query *models.CallbackQuery, state *ConversationState) {
-	participantName := strings.TrimPrefix(query.Data, "participant:")
+// handleDetailsCallback records the event without session details
+func (b *Bot) handleDetailsCallback(ctx context.Context, query *models.CallbackQuery, state *ConversationState) {
+	if state.Step != 4 {
+		b.sendMessageInThread(ctx, getChatIDFromQuery(query), "Error: Nothing to record", state.MessageThreadID)
+		state.Step = -1
+		return
+	}
+
+	state.Data["details"] = sessionDetails{}
+	b.recordReadingEvent(ctx, getChatIDFromQuery(query), state, false)
+}
+
+// recordReadingEvent creates the event collected by the /read conversation.
+// Unless force is set, an existing event for the same book, any of the readers and day
+// is reported and the user is asked whether to record it anyway.
+func (b *Bot) recordReadingEvent(ctx context.Context, chatID int64, state *ConversationState, force bool) {
+	date, hasDate := state.Data["date"].(time.Time)
+	book, hasBook := state.Data["book"].(libmodels.Book)
+	readers, hasReaders := state.Data["readers"].([]libmodels.Participant)
+	details, _ := state.Data["details"].(sessionDetails)
+	if !hasDate || !hasBook || !hasReaders {
+		b.sendMessageInThread(ctx, chatID, "Error: Nothing to record", state.MessageThreadID)
+		state.Step = -1
+		return
+	}
+
+	ids := make([]string, len(readers))
+	names := make([]string, len(readers))
+	for i, p := range readers {
+		ids[i] = p.ID
+		names[i] = p.Name
+	}
 
-	date := state.Data["date"].(time.Time)
-	bookName := state.Data["book"].(string)
+	if !force {
+		existing, err := b.db.FindEventOnDay(ctx, date, book.ID, ids)
+		if err == nil {
+			state.Step = 5
+
+			keyboard := &models.InlineKeyboardMarkup{
+				InlineKeyboard: [][]models.InlineKeyboardButton{
+					{
+						{Text: "✅ Record anyway", CallbackData: "dup_confirm:yes"},
+						{Text: "✖️ Cancel", CallbackData: "dup_confirm:no"},
+					},
+				},
+			}
+			text := fmt.Sprintf("⚠️ %s already has a reading of '%s' on %s.\n\nRecord it again?",
+				strings.Join(existing.ParticipantNames, ", "), book.Name, date.Format("2006-01-02"))
+			b.sendMessageInThreadWithMarkup(ctx, chatID, text, state.MessageThreadID, keyboard)
+			return
+		}
+		if !errors.Is(err, storage.ErrNotFound) {
+			b.logger.Error("Failed to check for duplicate event",
+				zap.Error(err),
+				zap.String("book", book.Name),
+				zap.Strings("participants", names),
+			)
+			b.sendMessageInThread(ctx, chatID, fmt.Sprintf("Error creating event: %v", err), state.MessageThreadID)
+			state.Step = -1
+			return
+		}
+	}
 
 	// Create the event
-	err := b.db.CreateEvent(ctx, date, bookName, participantName)
+	eventID, err := b.db.CreateEvent(ctx, libmodels.Event{
+		Date:            date,
+		BookID:          book.ID,
+		ParticipantIDs:  ids,
+		DurationMinutes: details.DurationMinutes,
+		Progress:        details.Progress,
+		ProgressUnit:    details.ProgressUnit,
+	})
 	if err != nil {
-		b.sendMessageInThread(ctx, getChatIDFromQuery(query), fmt.Sprintf("Error creating event: %v", err), state.MessageThreadID)
+		b.sendMessageInThread(ctx, chatID, fmt.Sprintf("Error creating event: %v", err), state.MessageThreadID)
 	} else {
-		text := fmt.Sprintf("✅ Reading event recorded!\n\n📅 Date: %s\n📚 Book: %s\n👤 Reader: %s",
-			date.Format("2006-01-02"), bookName, participantName)
-		b.sendMessageInThread(ctx, getChatIDFromQuery(query), text, state.MessageThreadID)
+		text := fmt.Sprintf("✅ Reading event recorded!\n\n📅 Date: %s\n📚 Book: %s\n👤 Readers: %s",
+			b.formatEventTime(date), book.Name, strings.Join(names, ", "))
+		if summary := details.String(); summary != "" {
+			text += "\n⏱ Session: " + summary
+		}
+		b.sendMessageInThreadWithMarkup(ctx, chatID, text, state.MessageThreadID, undoEventKeyboard(eventID))
+		b.notifyEvent(ctx, libmodels.Event{
+			Date:             date,
+			BookName:         book.Name,
+			ParticipantNames: names,
+			DurationMinutes:  details.DurationMinutes,
+			Progress:         details.Progress,
+			ProgressUnit:     details.ProgressUnit,
+		}, false, "/read", NotificationRoute{ChatID: chatID, ThreadID: state.MessageThreadID})
+		b.celebrate(ctx, ids, date)
+		b.awardBadges(ctx, eventID, chatID, state.MessageThreadID)
 	}
 
 	state.Step = -1 // Mark conversation as complete
 }
 
+// handleDuplicateConfirmCallback records or drops an event flagged as a duplicate
+func (b *Bot) handleDuplicateConfirmCallback(ctx context.Context, query *models.CallbackQuery, state *ConversationState) {
+	if state.Step != 5 {
+		b.sendMessageInThread(ctx, getChatIDFromQuery(query), "Error: Nothing to confirm", state.MessageThreadID)
+		state.Step = -1
+		return
+	}
+
+	if strings.TrimPrefix(query.Data, "dup_confirm:") != "yes" {
+		b.sendMessageInThread(ctx, getChatIDFromQuery(query), "Reading event not recorded.", state.MessageThreadID)
+		state.Step = -1
+		return
+	}
+
+	b.recordReadingEvent(ctx, getChatIDFromQuery(query), state, true)
+}
+
 // handleStatsPeriodCallback processes time period selection for statistics
 func (b *Bot) handleStatsPeriodCallback(ctx context.Context, query *models.CallbackQuery, state *ConversationState) {
 	periodType := strings.TrimPrefix(query.Data, "stats_period:")
 
-	now := time.Now()
+	now := b.now()
 	var startDate, endDate time.Time
 
 	switch periodType {
@@ -325,45 +462,189 @@ func (b *Bot) generateAndSendStatsReport(ctx context.Context, chatID int64, star
 		text.WriteString(fmt.Sprintf("%d. %s - %d reads\n", i+1, stat.BookName, stat.ReadCount))
 	}
 
+	sessions, err := b.db.GetSessionStats(ctx, startDate, endDate, participantName)
+	if err != nil {
+		// Session details are optional; keep the report without them
+		b.logger.Warn("Failed to get session stats for stats report",
+			zap.Error(err),
+			zap.Int64("chat_id", chatID),
+			zap.String("participant", participantName),
+		)
+	} else {
+		text.WriteString(formatSessionStats(sessions))
+	}
+
+	hours, err := b.db.GetHourlyStats(ctx, startDate, endDate, participantName, b.loc())
+	if err != nil {
+		b.logger.Warn("Failed to get hourly stats for stats report",
+			zap.Error(err),
+			zap.Int64("chat_id", chatID),
+			zap.String("participant", participantName),
+		)
+	} else {
+		text.WriteString(formatTimeOfDay(hours))
+	}
+
 	b.sendMessageInThread(ctx, chatID, text.String(), messageThreadID)
 }
 
-// handleRareLabelCallback processes label selection for rare books command
+// dayParts splits the day into the periods shown in the time-of-day breakdown
+var dayParts = []struct {
+	label    string
+	from, to int // hours, from inclusive, to exclusive
+}{
+	{"🌅 Morning (5–12)", 5, 12},
+	{"☀️ Afternoon (12–17)", 12, 17},
+	{"🌆 Evening (17–21)", 17, 21},
+	{"🌙 Night (21–5)", 21, 29},
+}
+
+// formatTimeOfDay renders session counts per part of the day
+func formatTimeOfDay(hours []libmodels.HourStat) string {
+	if len(hours) == 0 {
+		return ""
+	}
+
+	var text strings.Builder
+	text.WriteString("\n🕐 Time of day:\n")
+	for _, part := range dayParts {
+		count := 0
+		for _, h := range hours {
+			hour := h.Hour
+			if hour < 5 {
+				hour += 24 // after midnight belongs to the night
+			}
+			if hour >= part.from && hour < part.to {
+				count += h.ReadCount
+			}
+		}
+		if count > 0 {
+			text.WriteString(fmt.Sprintf("   %s: %d\n", part.label, count))
+		}
+	}
+	return text.String()
+}
+
+// formatSessionStats renders duration and progress totals with per-session averages.
+// Averages only count sessions that recorded the corresponding detail.
+func formatSessionStats(stats libmodels.SessionStats) string {
+	if stats.TimedSessions == 0 && stats.PageSessions == 0 && stats.ChapterSessions == 0 {
+		return ""
+	}
+
+	var text strings.Builder
+	text.WriteString(fmt.Sprintf("\n⏱ Sessions: %d\n", stats.Sessions))
+	if stats.TimedSessions > 0 {
+		text.WriteString(fmt.Sprintf("   Time: %d min total, %d min on average\n",
+			stats.TotalMinutes, stats.TotalMinutes/stats.TimedSessions))
+	}
+	if stats.PageSessions > 0 {
+		text.WriteString(fmt.Sprintf("   Pages: %d total, %d on average\n",
+			stats.TotalPages, stats.TotalPages/stats.PageSessions))
+	}
+	if stats.ChapterSessions > 0 {
+		text.WriteString(fmt.Sprintf("   Chapters: %d total, %.1f on average\n",
+			stats.TotalChapters, float64(stats.TotalChapters)/float64(stats.ChapterSessions)))
+	}
+	return text.String()
+}
+
+// handleRareLabelCallback adds the selected label to the filter of the rare books command.
+// "All books" (an empty label) shows the books right away.
 func (b *Bot) handleRareLabelCallback(ctx context.Context, query *models.CallbackQuery, state *ConversationState) {
 	label := strings.TrimPrefix(query.Data, "rare_label:")
+	if label == "" {
+		b.sendRareBooks(ctx, getChatIDFromQuery(query), state.MessageThreadID, query.From.ID, nil)
+		state.Step = -1
+		return
+	}
+
+	prev, _ := state.Data["filter"].(labels.Expr)
+	op, _ := state.Data["op"].(string)
+	filter := combineFilters(prev, op, labelTerm(label))
+	state.Data["filter"] = filter
+	delete(state.Data, "op")
+
+	keyboard := &models.InlineKeyboardMarkup{
+		InlineKeyboard: [][]models.InlineKeyboardButton{
+			{
+				{Text: "🔍 Show books", CallbackData: "rare_show"},
+			},
+			{
+				{Text: "➕ AND", CallbackData: "rare_op:and"},
+				{Text: "➕ OR", CallbackData: "rare_op:or"},
+				{Text: "➖ AND NOT", CallbackData: "rare_op:not"},
+			},
+		},
+	}
+	b.sendMessageInThreadWithMarkup(ctx, getChatIDFromQuery(query), fmt.Sprintf("🏷 Filter: %s", filter), state.MessageThreadID, keyboard)
+}
+
+// handleRareOpCallback asks for the label to combine with the filter of the rare books command
+func (b *Bot) handleRareOpCallback(ctx context.Context, query *models.CallbackQuery, state *ConversationState) {
+	op := strings.TrimPrefix(query.Data, "rare_op:")
+	filter, ok := state.Data["filter"].(labels.Expr)
+	if !ok {
+		b.sendMessageInThread(ctx, getChatIDFromQuery(query), "Error: Filter not selected", state.MessageThreadID)
+		state.Step = -1
+		return
+	}
+
+	allLabels, err := b.db.GetAllLabels(ctx)
+	if err != nil {
+		b.logger.Error("Failed to get labels",
+			zap.Error(err),
+			zap.Int64("user_id", query.From.ID),
+		)
+		b.sendMessageInThread(ctx, getChatIDFromQuery(query), fmt.Sprintf("Error: %v", err), state.MessageThreadID)
+		state.Step = -1
+		return
+	}
+
+	state.Data["op"] = op
+	b.sendMessageInThreadWithMarkup(ctx, getChatIDFromQuery(query), fmt.Sprintf("🏷 %s %s …", filter, rareOpText[op]), state.MessageThreadID, labelFilterKeyboard("rare_label:", allLabels))
+}
+
+// handleRareShowCallback shows the rarely read books matching the filter built so far
+func (b *Bot) handleRareShowCallback(ctx context.Context, query *models.CallbackQuery, state *ConversationState) {
+	filter, _ := state.Data["filter"].(labels.Expr)
+	b.sendRareBooks(ctx, getChatIDFromQuery(query), state.MessageThreadID, query.From.ID, filter)
+	state.Step = -1
+}
+
+// sendRareBooks sends the rarely read books by children and by everyone whose labels match filter
+func (b *Bot) sendRareBooks(ctx context.Context, chatID int64, threadID int, userID int64, filter labels.Expr) {
 	const limit = 10
 
 	// Exclude books with "Сами" label from rare books results
-	excludeLabels := []string{"Сами"}
+	query := labels.Join(filter, labels.Not{Expr: labels.Label("Сами")})
 
 	// Get rarely read books by children
-	childrenStats, err := b.db.GetRarelyReadBooks(ctx, limit, true, label, excludeLabels)
+	childrenStats, err := b.db.GetRarelyReadBooks(ctx, limit, true, query)
 	if err != nil {
 		b.logger.Error("Failed to get rarely read books by children",
 			zap.Error(err),
-			zap.Int64("user_id", query.From.ID),
+			zap.Int64("user_id", userID),
 		)
-		b.sendMessageInThread(ctx, getChatIDFromQuery(query), fmt.Sprintf("Error: %v", err), state.MessageThreadID)
-		state.Step = -1
+		b.sendMessageInThread(ctx, chatID, fmt.Sprintf("Error: %v", err), threadID)
 		return
 	}
 
 	// Get rarely read books by all participants
-	allStats, err := b.db.GetRarelyReadBooks(ctx, limit, false, label, excludeLabels)
+	allStats, err := b.db.GetRarelyReadBooks(ctx, limit, false, query)
 	if err != nil {
 		b.logger.Error("Failed to get rarely read books by all",
 			zap.Error(err),
-			zap.Int64("user_id", query.From.ID),
+			zap.Int64("user_id", userID),
 		)
-		b.sendMessageInThread(ctx, getChatIDFromQuery(query), fmt.Sprintf("Error: %v", err), state.MessageThreadID)
-		state.Step = -1
+		b.sendMessageInThread(ctx, chatID, fmt.Sprintf("Error: %v", err), threadID)
 		return
 	}
 
 	var text strings.Builder
 	text.WriteString("📚 Rarely read books")
-	if label != "" {
-		text.WriteString(fmt.Sprintf(" (label: %s)", label))
+	if filter != nil {
+		text.WriteString(fmt.Sprintf(" (filter: %s)", filter))
 	}
 	text.WriteString(":\n\n")
 
@@ -400,8 +681,7 @@ func (b *Bot) handleRareLabelCallback(ctx context.Context, query *models.Callbac
 		}
 	}
 
-	b.sendMessageInThread(ctx, getChatIDFromQuery(query), text.String(), state.MessageThreadID)
-	state.Step = -1 // Mark conversation as complete
+	b.sendMessageInThread(ctx, chatID, text.String(), threadID)
 }
 
 // handleBookLabelsCallback processes book selection for the book_labels command
@@ -420,9 +700,14 @@ func (b *Bot) handleBookLabelsCallback(ctx context.Context, query *models.Callba
 	}
 
 	selectedBook := books[bookIdx]
+	if selectedBook.Cover != "" {
+		b.sendPhotoInThread(ctx, getChatIDFromQuery(query), selectedBook.Cover, state.MessageThreadID)
+	}
 
 	var text strings.Builder
-	text.WriteString(fmt.Sprintf("🏷 Labels for \"%s\":\n\n", selectedBook.Name))
+	text.WriteString(fmt.Sprintf("📖 \"%s\"\n", selectedBook.Name))
+	text.WriteString(formatBookMetadata(selectedBook.BookMetadata))
+	text.WriteString("\n🏷 Labels:\n")
 
 	if len(selectedBook.Labels) == 0 {
 		text.WriteString("No labels found for this book.")
@@ -439,21 +724,29 @@ func (b *Bot) handleBookLabelsCallback(ctx context.Context, query *models.Callba
 // handleBooksByLabelCallback processes label selection for the books_by_label command
 func (b *Bot) handleBooksByLabelCallback(ctx context.Context, query *models.CallbackQuery, state *ConversationState) {
 	label := strings.TrimPrefix(query.Data, "booksbylabel:")
+	b.sendBooksByLabel(ctx, getChatIDFromQuery(query), state.MessageThreadID, query.From.ID, labelTerm(label))
+	state.Step = -1
+}
 
-	books, err := b.db.GetBooksByLabel(ctx, label)
+// sendBooksByLabel sends the readable books whose labels match filter
+func (b *Bot) sendBooksByLabel(ctx context.Context, chatID int64, threadID int, userID int64, filter labels.Expr) {
+	books, err := b.db.GetBooksByLabel(ctx, filter)
 	if err != nil {
 		b.logger.Error("Failed to get books by label",
 			zap.Error(err),
-			zap.Int64("user_id", query.From.ID),
-			zap.String("label", label),
+			zap.Int64("user_id", userID),
+			zap.Stringer("filter", filter),
 		)
-		b.sendMessageInThread(ctx, getChatIDFromQuery(query), fmt.Sprintf("Error: %v", err), state.MessageThreadID)
-		state.Step = -1
+		b.sendMessageInThread(ctx, chatID, fmt.Sprintf("Error: %v", err), threadID)
 		return
 	}
 
 	var text strings.Builder
-	text.WriteString(fmt.Sprintf("📚 Books with label \"%s\":\n\n", label))
+	if label, ok := filter.(labels.Label); ok {
+		text.WriteString(fmt.Sprintf("📚 Books with label \"%s\":\n\n", string(label)))
+	} else {
+		text.WriteString(fmt.Sprintf("📚 Books matching %s:\n\n", filter))
+	}
 
 	if len(books) == 0 {
 		text.WriteString("No books found with this label.")
@@ -463,52 +756,599 @@ func (b *Bot) handleBooksByLabelCallback(ctx context.Context, query *models.Call
 		}
 	}
 
-	b.sendMessageInThread(ctx, getChatIDFromQuery(query), text.String(), state.MessageThreadID)
-	state.Step = -1
+	b.sendMessageInThread(ctx, chatID, text.String(), threadID)
 }
 
 // handleAddLabelBookCallback processes book selection for add label command
 func (b *Bot) handleAddLabelBookCallback(ctx context.Context, query *models.CallbackQuery, state *ConversationState) {
-	indexStr := strings.TrimPrefix(query.Data, "addlabel_book:")
-	bookIdx, err := strconv.Atoi(indexStr)
+	data := strings.TrimPrefix(query.Data, "addlabel_book:")
+
+	// Get books from state
+	books, ok := state.Data["books"].([]libmodels.Book)
+	if !ok {
+		b.sendMessageInThread(ctx, getChatIDFromQuery(query), "Error: Book list not found", state.MessageThreadID)
+		state.Step = -1
+		return
+	}
+	selected, _ := state.Data["selected_books"].([]string)
+	page, _ := state.Data["page"].(int)
+
+	switch {
+	case data == "done":
+		b.addLabelToSelectedBooks(ctx, query, state, books, selected)
+		return
+	case data == "all":
+		if len(selected) == len(books) {
+			selected = []string{}
+		} else {
+			selected = make([]string, 0, len(books))
+			for _, book := range books {
+				selected = append(selected, book.ID)
+			}
+		}
+	case strings.HasPrefix(data, "page:"):
+		p, err := strconv.Atoi(strings.TrimPrefix(data, "page:"))
+		if err != nil || p < 0 || p*bookTogglePageSize >= len(books) {
+			return
+		}
+		page = p
+	default:
+		bookIdx, err := strconv.Atoi(data)
+		if err != nil || bookIdx < 0 || bookIdx >= len(books) {
+			b.sendMessageInThread(ctx, getChatIDFromQuery(query), "Error: Invalid book selection", state.MessageThreadID)
+			state.Step = -1
+			return
+		}
+		selected = toggleID(selected, books[bookIdx].ID)
+	}
+
+	state.Data["selected_books"] = selected
+	state.Data["page"] = page
+	b.editMessageMarkup(ctx, query, bookToggleKeyboard("addlabel_book:", books, selected, page))
+}
+
+// addLabelToSelectedBooks applies the label of the add_label conversation to all selected books at once
+func (b *Bot) addLabelToSelectedBooks(ctx context.Context, query *models.CallbackQuery, state *ConversationState, books []libmodels.Book, selected []string) {
+	if len(selected) == 0 {
+		b.sendMessageInThread(ctx, getChatIDFromQuery(query), "Please select at least one book.", state.MessageThreadID)
+		return
+	}
+	label := state.Data["label"].(string)
+
+	var names []string
+	for _, book := range books {
+		if slices.Contains(selected, book.ID) {
+			names = append(names, book.Name)
+		}
+	}
+
+	if err := b.db.AddLabelToBooks(ctx, selected, label); err != nil {
+		b.logger.Error("Failed to add label to books",
+			zap.Error(err),
+			zap.Int64("user_id", query.From.ID),
+			zap.Strings("books", names),
+			zap.String("label", label),
+		)
+		b.sendMessageInThread(ctx, getChatIDFromQuery(query), fmt.Sprintf("Error: %v", err), state.MessageThreadID)
+		state.Step = -1
+		return
+	}
+
+	text := fmt.Sprintf("✅ Label '%s' added to book '%s'", label, names[0])
+	if len(names) > 1 {
+		text = fmt.Sprintf("✅ Label '%s' added to %d books:\n\n%s", label, len(names), strings.Join(names, "\n"))
+	}
+	b.sendMessageInThread(ctx, getChatIDFromQuery(query), text, state.MessageThreadID)
+	b.notifyLabelAdded(ctx, names, label, NotificationRoute{ChatID: getChatIDFromQuery(query), ThreadID: state.MessageThreadID})
+	state.Step = -1 // Mark conversation as complete
+}
+
+// handleParticipantsSelectCallback processes participant selection for the participants command
+func (b *Bot) handleParticipantsSelectCallback(ctx context.Context, query *models.CallbackQuery, state *ConversationState) {
+	indexStr := strings.TrimPrefix(query.Data, "participants_select:")
+	idx, err := strconv.Atoi(indexStr)
 	if err != nil {
 		return
 	}
 
-	// Get books from state
-	booksInterface, ok := state.Data["books"]
+	participants, ok := state.Data["participants"].([]libmodels.Participant)
+	if !ok || idx < 0 || idx >= len(participants) {
+		b.sendMessageInThread(ctx, getChatIDFromQuery(query), "Error: Invalid participant selection", state.MessageThreadID)
+		state.Step = -1
+		return
+	}
+
+	selected := participants[idx]
+	state.Data["participant"] = selected
+
+	roleText := "🔄 Make parent"
+	if selected.IsParent {
+		roleText = "🔄 Make child"
+	}
+
+	keyboard := &models.InlineKeyboardMarkup{
+		InlineKeyboard: [][]models.InlineKeyboardButton{
+			{
+				{Text: "✏️ Rename", CallbackData: "participants_action:rename"},
+				{Text: roleText, CallbackData: "participants_action:role"},
+			},
+			{
+				{Text: "🗄 Archive", CallbackData: "participants_action:archive"},
+			},
+		},
+	}
+	b.sendMessageInThreadWithMarkup(ctx, getChatIDFromQuery(query), fmt.Sprintf("👤 %s — what would you like to do?", selected.Name), state.MessageThreadID, keyboard)
+}
+
+// handleParticipantsActionCallback applies the chosen action to the selected participant
+func (b *Bot) handleParticipantsActionCallback(ctx context.Context, query *models.CallbackQuery, state *ConversationState) {
+	action := strings.TrimPrefix(query.Data, "participants_action:")
+
+	participant, ok := state.Data["participant"].(libmodels.Participant)
 	if !ok {
-		b.sendMessageInThread(ctx, getChatIDFromQuery(query), "Error: Book list not found", state.MessageThreadID)
+		b.sendMessageInThread(ctx, getChatIDFromQuery(query), "Error: Participant not selected", state.MessageThreadID)
 		state.Step = -1
 		return
 	}
 
-	books, ok := booksInterface.([]libmodels.Book)
-	if !ok || bookIdx < 0 || bookIdx >= len(books) {
+	var err error
+	var text string
+	switch action {
+	case "rename":
+		state.Data["awaiting_rename"] = true
+		state.Step = 2
+		b.sendMessageInThread(ctx, getChatIDFromQuery(query), fmt.Sprintf("✏️ Enter the new name for %s:", participant.Name), state.MessageThreadID)
+		return
+	case "role":
+		err = b.db.UpdateParticipant(ctx, participant.ID, participant.Name, !participant.IsParent)
+		role := "a child"
+		if !participant.IsParent {
+			role = "a parent"
+		}
+		text = fmt.Sprintf("✅ %s is now %s", participant.Name, role)
+	case "archive":
+		err = b.db.ArchiveParticipant(ctx, participant.ID)
+		text = fmt.Sprintf("✅ %s archived. Reading history is kept.", participant.Name)
+	default:
+		return
+	}
+
+	if err != nil {
+		b.logger.Error("Failed to update participant",
+			zap.Error(err),
+			zap.Int64("user_id", query.From.ID),
+			zap.String("participant", participant.Name),
+			zap.String("action", action),
+		)
+		b.sendMessageInThread(ctx, getChatIDFromQuery(query), fmt.Sprintf("Error: %v", err), state.MessageThreadID)
+	} else {
+		b.sendMessageInThread(ctx, getChatIDFromQuery(query), text, state.MessageThreadID)
+	}
+	state.Step = -1
+}
+
+// handleParticipantsAddCallback starts adding a new participant
+func (b *Bot) handleParticipantsAddCallback(ctx context.Context, query *models.CallbackQuery, state *ConversationState) {
+	state.Data["awaiting_new_name"] = true
+	state.Step = 2
+	b.sendMessageInThread(ctx, getChatIDFromQuery(query), "👤 Enter the name of the new participant:", state.MessageThreadID)
+}
+
+// handleParticipantsRoleCallback creates the new participant with the selected role
+func (b *Bot) handleParticipantsRoleCallback(ctx context.Context, query *models.CallbackQuery, state *ConversationState) {
+	role := strings.TrimPrefix(query.Data, "participants_role:")
+
+	name, ok := state.Data["new_name"].(string)
+	if !ok {
+		b.sendMessageInThread(ctx, getChatIDFromQuery(query), "Error: Participant name not found", state.MessageThreadID)
+		state.Step = -1
+		return
+	}
+	isParent := role == "parent"
+
+	if _, err := b.db.CreateParticipant(ctx, name, isParent); err != nil {
+		b.logger.Error("Failed to create participant",
+			zap.Error(err),
+			zap.Int64("user_id", query.From.ID),
+			zap.String("participant", name),
+		)
+		b.sendMessageInThread(ctx, getChatIDFromQuery(query), fmt.Sprintf("Error: %v", err), state.MessageThreadID)
+	} else {
+		b.sendMessageInThread(ctx, getChatIDFromQuery(query), fmt.Sprintf("✅ Participant '%s' added as %s", name, role), state.MessageThreadID)
+	}
+	state.Step = -1
+}
+
+// selectedBookFromState resolves "<prefix><index>" callback data against the books stored in state
+func selectedBookFromState(data, prefix string, state *ConversationState) (libmodels.Book, bool) {
+	idx, err := strconv.Atoi(strings.TrimPrefix(data, prefix))
+	if err != nil {
+		return libmodels.Book{}, false
+	}
+
+	books, ok := state.Data["books"].([]libmodels.Book)
+	if !ok || idx < 0 || idx >= len(books) {
+		return libmodels.Book{}, false
+	}
+	return books[idx], true
+}
+
+// handleBookReadableCallback retires or restores the selected book
+func (b *Bot) handleBookReadableCallback(ctx context.Context, query *models.CallbackQuery, state *ConversationState) {
+	retire := strings.HasPrefix(query.Data, "retire_book:")
+	prefix := "restore_book:"
+	if retire {
+		prefix = "retire_book:"
+	}
+
+	book, ok := selectedBookFromState(query.Data, prefix, state)
+	if !ok {
 		b.sendMessageInThread(ctx, getChatIDFromQuery(query), "Error: Invalid book selection", state.MessageThreadID)
 		state.Step = -1
 		return
 	}
 
-	selectedBook := books[bookIdx]
-	label := state.Data["label"].(string)
+	var err error
+	var text string
+	if retire {
+		err = b.db.RetireBook(ctx, book.ID)
+		text = fmt.Sprintf("📦 Book '%s' retired. It is hidden from /read but stays in statistics.", book.Name)
+	} else {
+		err = b.db.RestoreBook(ctx, book.ID)
+		text = fmt.Sprintf("♻️ Book '%s' restored.", book.Name)
+	}
 
-	// Add label to book
-	err = b.db.AddLabelToBook(ctx, selectedBook.Name, label)
 	if err != nil {
-		b.logger.Error("Failed to add label to book",
+		b.logger.Error("Failed to update book",
 			zap.Error(err),
 			zap.Int64("user_id", query.From.ID),
-			zap.String("book", selectedBook.Name),
-			zap.String("label", label),
+			zap.String("book", book.Name),
+			zap.Bool("retire", retire),
 		)
 		b.sendMessageInThread(ctx, getChatIDFromQuery(query), fmt.Sprintf("Error: %v", err), state.MessageThreadID)
+	} else {
+		b.sendMessageInThread(ctx, getChatIDFromQuery(query), text, state.MessageThreadID)
+	}
+	state.Step = -1
+}
+
+// handleRenameBookCallback asks for a new name for the selected book
+func (b *Bot) handleRenameBookCallback(ctx context.Context, query *models.CallbackQuery, state *ConversationState) {
+	book, ok := selectedBookFromState(query.Data, "rename_book:", state)
+	if !ok {
+		b.sendMessageInThread(ctx, getChatIDFromQuery(query), "Error: Invalid book selection", state.MessageThreadID)
 		state.Step = -1
 		return
 	}
 
-	b.sendMessageInThread(ctx, getChatIDFromQuery(query),
-		fmt.Sprintf("✅ Label '%s' added to book '%s'", label, selectedBook.Name),
-		state.MessageThreadID)
-	state.Step = -1 // Mark conversation as complete
+	state.Data["book"] = book
+	state.Step = 2
+	b.sendMessageInThread(ctx, getChatIDFromQuery(query), fmt.Sprintf("✏️ Enter the new name for '%s':", book.Name), state.MessageThreadID)
+}
+
+// handleDeleteBookCallback asks to confirm deletion of the selected book
+func (b *Bot) handleDeleteBookCallback(ctx context.Context, query *models.CallbackQuery, state *ConversationState) {
+	book, ok := selectedBookFromState(query.Data, "delete_book:", state)
+	if !ok {
+		b.sendMessageInThread(ctx, getChatIDFromQuery(query), "Error: Invalid book selection", state.MessageThreadID)
+		state.Step = -1
+		return
+	}
+
+	state.Data["book"] = book
+	state.Step = 2
+
+	keyboard := &models.InlineKeyboardMarkup{
+		InlineKeyboard: [][]models.InlineKeyboardButton{
+			{
+				{Text: "🗑 Delete", CallbackData: "delete_book_confirm:yes"},
+				{Text: "✖️ Cancel", CallbackData: "delete_book_confirm:no"},
+			},
+		},
+	}
+	b.sendMessageInThreadWithMarkup(ctx, getChatIDFromQuery(query), fmt.Sprintf("Delete '%s'? This cannot be undone.", book.Name), state.MessageThreadID, keyboard)
+}
+
+// handleDeleteBookConfirmCallback deletes the selected book after confirmation
+func (b *Bot) handleDeleteBookConfirmCallback(ctx context.Context, query *models.CallbackQuery, state *ConversationState) {
+	book, ok := state.Data["book"].(libmodels.Book)
+	if !ok {
+		b.sendMessageInThread(ctx, getChatIDFromQuery(query), "Error: Book not selected", state.MessageThreadID)
+		state.Step = -1
+		return
+	}
+
+	if strings.TrimPrefix(query.Data, "delete_book_confirm:") != "yes" {
+		b.sendMessageInThread(ctx, getChatIDFromQuery(query), "Deletion cancelled.", state.MessageThreadID)
+		state.Step = -1
+		return
+	}
+
+	if err := b.db.DeleteBook(ctx, book.ID); err != nil {
+		b.logger.Error("Failed to delete book",
+			zap.Error(err),
+			zap.Int64("user_id", query.From.ID),
+			zap.String("book", book.Name),
+		)
+		b.sendMessageInThread(ctx, getChatIDFromQuery(query), fmt.Sprintf("Error: %v", err), state.MessageThreadID)
+	} else {
+		b.sendMessageInThread(ctx, getChatIDFromQuery(query), fmt.Sprintf("🗑 Book '%s' deleted.", book.Name), state.MessageThreadID)
+	}
+	state.Step = -1
+}
+
+// handleUndoEventCallback deletes the event behind an "Undo" button
+func (b *Bot) handleUndoEventCallback(ctx context.Context, query *models.CallbackQuery) {
+	eventID := strings.TrimPrefix(query.Data, "undo_event:")
+	chatID := getChatIDFromQuery(query)
+	threadID := getThreadIDFromQuery(query)
+
+	event, err := b.db.GetEvent(ctx, eventID)
+	if err == nil {
+		err = b.db.DeleteEvent(ctx, eventID)
+	}
+	if errors.Is(err, storage.ErrNotFound) {
+		b.editMessageText(ctx, query, "This reading event was already removed.")
+		return
+	}
+	if err != nil {
+		b.logger.Error("Failed to undo event",
+			zap.Error(err),
+			zap.Int64("user_id", query.From.ID),
+			zap.String("event_id", eventID),
+		)
+		b.sendMessageInThread(ctx, chatID, fmt.Sprintf("Error: %v", err), threadID)
+		return
+	}
+
+	b.logger.Info("Event undone",
+		zap.Int64("user_id", query.From.ID),
+		zap.String("event_id", eventID),
+	)
+	// Replace the confirmation so its "Undo" button cannot be pressed again
+	text := fmt.Sprintf("↩️ Reading event undone.\n\n📅 Date: %s\n📚 Book: %s\n👤 Readers: %s",
+		b.formatEventTime(event.Date), event.BookName, strings.Join(event.ParticipantNames, ", "))
+	b.editMessageText(ctx, query, text)
+	b.notifyEvent(ctx, event, true, "/read", NotificationRoute{ChatID: chatID, ThreadID: threadID})
+}
+
+// handleEditLastEventCallback shows the possible changes for the selected event
+func (b *Bot) handleEditLastEventCallback(ctx context.Context, query *models.CallbackQuery, state *ConversationState) {
+	idx, err := strconv.Atoi(strings.TrimPrefix(query.Data, "editlast_event:"))
+	if err != nil {
+		return
+	}
+
+	events, ok := state.Data["events"].([]libmodels.Event)
+	if !ok || idx < 0 || idx >= len(events) {
+		b.sendMessageInThread(ctx, getChatIDFromQuery(query), "Error: Invalid event selection", state.MessageThreadID)
+		state.Step = -1
+		return
+	}
+
+	event := events[idx]
+	state.Data["event"] = event
+	state.Step = 2
+
+	keyboard := &models.InlineKeyboardMarkup{
+		InlineKeyboard: [][]models.InlineKeyboardButton{
+			{
+				{Text: "📅 Date", CallbackData: "editlast_action:date"},
+				{Text: "📚 Book", CallbackData: "editlast_action:book"},
+			},
+			{
+				{Text: "👤 Readers", CallbackData: "editlast_action:participant"},
+				{Text: "🗑 Delete", CallbackData: "editlast_action:delete"},
+			},
+		},
+	}
+	text := fmt.Sprintf("📅 %s · 📚 %s · 👤 %s\n\nWhat would you like to change?",
+		b.formatEventTime(event.Date), event.BookName, strings.Join(event.ParticipantNames, ", "))
+	b.sendMessageInThreadWithMarkup(ctx, getChatIDFromQuery(query), text, state.MessageThreadID, keyboard)
+}
+
+// handleEditLastActionCallback applies or starts the chosen change for the selected event
+func (b *Bot) handleEditLastActionCallback(ctx context.Context, query *models.CallbackQuery, state *ConversationState) {
+	action := strings.TrimPrefix(query.Data, "editlast_action:")
+	chatID := getChatIDFromQuery(query)
+
+	event, ok := state.Data["event"].(libmodels.Event)
+	if !ok {
+		b.sendMessageInThread(ctx, chatID, "Error: Event not selected", state.MessageThreadID)
+		state.Step = -1
+		return
+	}
+
+	switch action {
+	case "date":
+		state.Data["awaiting_date"] = true
+		state.Step = 3
+		b.sendMessageInThread(ctx, chatID, "📝 Please enter the new date in format YYYY-MM-DD, optionally with the time (HH:MM)\n\nExample: 2024-01-15 or 2024-01-15 19:30", state.MessageThreadID)
+	case "book":
+		books, err := b.db.ListReadableBooks(ctx)
+		if err != nil {
+			b.sendMessageInThread(ctx, chatID, fmt.Sprintf("Error: %v", err), state.MessageThreadID)
+			state.Step = -1
+			return
+		}
+		state.Data["books"] = books
+		state.Step = 3
+
+		var rows [][]models.InlineKeyboardButton
+		var currentRow []models.InlineKeyboardButton
+		for i, book := range books {
+			currentRow = append(currentRow, models.InlineKeyboardButton{
+				Text:         book.Name,
+				CallbackData: fmt.Sprintf("editlast_book:%d", i),
+			})
+
+			if len(currentRow) == 2 || i == len(books)-1 {
+				rows = append(rows, currentRow)
+				currentRow = []models.InlineKeyboardButton{}
+			}
+		}
+		keyboard := &models.InlineKeyboardMarkup{
+			InlineKeyboard: rows,
+		}
+		b.sendMessageInThreadWithMarkup(ctx, chatID, "📚 Select the correct book:", state.MessageThreadID, keyboard)
+	case "participant":
+		participants, err := b.db.ListParticipants(ctx)
+		if err != nil {
+			b.sendMessageInThread(ctx, chatID, fmt.Sprintf("Error: %v", err), state.MessageThreadID)
+			state.Step = -1
+			return
+		}
+		state.Data["participant_list"] = participants
+		state.Data["selected_participants"] = append([]string(nil), event.ParticipantIDs...)
+		state.Step = 3
+
+		keyboard := participantToggleKeyboard("editlast_participant:", participants, event.ParticipantIDs)
+		b.sendMessageInThreadWithMarkup(ctx, chatID, "👤 Select who was reading, then press Done:", state.MessageThreadID, keyboard)
+	case "delete":
+		if err := b.db.DeleteEvent(ctx, event.ID); err != nil {
+			b.logger.Error("Failed to delete event",
+				zap.Error(err),
+				zap.Int64("user_id", query.From.ID),
+				zap.String("event_id", event.ID),
+			)
+			b.sendMessageInThread(ctx, chatID, fmt.Sprintf("Error: %v", err), state.MessageThreadID)
+		} else {
+			b.sendMessageInThread(ctx, chatID, fmt.Sprintf("🗑 Reading event deleted: %s · %s (%s)",
+				b.formatEventTime(event.Date), event.BookName, strings.Join(event.ParticipantNames, ", ")), state.MessageThreadID)
+		}
+		state.Step = -1
+	}
+}
+
+// handleEditLastBookCallback replaces the book of the selected event
+func (b *Bot) handleEditLastBookCallback(ctx context.Context, query *models.CallbackQuery, state *ConversationState) {
+	book, ok := selectedBookFromState(query.Data, "editlast_book:", state)
+	event, hasEvent := state.Data["event"].(libmodels.Event)
+	if !ok || !hasEvent {
+		b.sendMessageInThread(ctx, getChatIDFromQuery(query), "Error: Invalid book selection", state.MessageThreadID)
+		state.Step = -1
+		return
+	}
+
+	event.BookID = book.ID
+	event.BookName = book.Name
+	b.saveEditedEvent(ctx, getChatIDFromQuery(query), query.From.ID, event, state)
+}
+
+// handleEditLastParticipantCallback toggles readers of the selected event and saves them on "done"
+func (b *Bot) handleEditLastParticipantCallback(ctx context.Context, query *models.CallbackQuery, state *ConversationState) {
+	data := strings.TrimPrefix(query.Data, "editlast_participant:")
+
+	participants, _ := state.Data["participant_list"].([]libmodels.Participant)
+	selected, _ := state.Data["selected_participants"].([]string)
+	event, hasEvent := state.Data["event"].(libmodels.Event)
+	if !hasEvent {
+		b.sendMessageInThread(ctx, getChatIDFromQuery(query), "Error: Event not selected", state.MessageThreadID)
+		state.Step = -1
+		return
+	}
+
+	if data != "done" {
+		if _, ok := findParticipant(participants, data); !ok {
+			b.sendMessageInThread(ctx, getChatIDFromQuery(query), "Error: Invalid participant selection", state.MessageThreadID)
+			state.Step = -1
+			return
+		}
+		selected = toggleID(selected, data)
+		state.Data["selected_participants"] = selected
+		b.editMessageMarkup(ctx, query, participantToggleKeyboard("editlast_participant:", participants, selected))
+		return
+	}
+
+	readers := selectedParticipants(participants, selected)
+	if len(readers) == 0 {
+		b.sendMessageInThread(ctx, getChatIDFromQuery(query), "Please select at least one participant.", state.MessageThreadID)
+		return
+	}
+
+	event.ParticipantIDs = make([]string, len(readers))
+	event.ParticipantNames = make([]string, len(readers))
+	for i, p := range readers {
+		event.ParticipantIDs[i] = p.ID
+		event.ParticipantNames[i] = p.Name
+	}
+	b.saveEditedEvent(ctx, getChatIDFromQuery(query), query.From.ID, event, state)
+}
+
+// saveEditedEvent stores the changed event and completes the edit_last conversation
+func (b *Bot) saveEditedEvent(ctx context.Context, chatID, userID int64, event libmodels.Event, state *ConversationState) {
+	if err := b.db.UpdateEvent(ctx, event); err != nil {
+		b.logger.Error("Failed to update event",
+			zap.Error(err),
+			zap.Int64("user_id", userID),
+			zap.String("event_id", event.ID),
+		)
+		b.sendMessageInThread(ctx, chatID, fmt.Sprintf("Error: %v", err), state.MessageThreadID)
+	} else {
+		text := fmt.Sprintf("✅ Reading event updated!\n\n📅 Date: %s\n📚 Book: %s\n👤 Readers: %s",
+			b.formatEventTime(event.Date), event.BookName, strings.Join(event.ParticipantNames, ", "))
+		b.sendMessageInThread(ctx, chatID, text, state.MessageThreadID)
+	}
+	state.Step = -1
+}
+
+// handleRotationCallback changes the rotation strategy or toggles a skipped child for the chat
+func (b *Bot) handleRotationCallback(ctx context.Context, query *models.CallbackQuery) {
+	chatID := getChatIDFromQuery(query)
+	threadID := getThreadIDFromQuery(query)
+
+	settings, err := b.db.GetChatSettings(ctx, chatID)
+	if err != nil {
+		b.logger.Error("Failed to get chat settings",
+			zap.Error(err),
+			zap.Int64("chat_id", chatID),
+		)
+		b.sendMessageInThread(ctx, chatID, fmt.Sprintf("Error: %v", err), threadID)
+		return
+	}
+
+	participants, err := b.db.ListParticipants(ctx)
+	if err != nil {
+		b.sendMessageInThread(ctx, chatID, fmt.Sprintf("Error: %v", err), threadID)
+		return
+	}
+
+	var confirmation string
+	if name, ok := strings.CutPrefix(query.Data, "rotation_strategy:"); ok {
+		strategy := rotationStrategyByName(name)
+		if strategy.Name() != name {
+			b.sendMessageInThread(ctx, chatID, "Error: Unknown rotation policy", threadID)
+			return
+		}
+		settings.RotationStrategy = name
+		confirmation = fmt.Sprintf("✅ Rotation policy set: %s", strategy.Description())
+	} else {
+		id := strings.TrimPrefix(query.Data, "rotation_skip:")
+		if p, ok := findParticipant(participants, id); !ok || p.IsParent {
+			b.sendMessageInThread(ctx, chatID, "Error: Invalid participant selection", threadID)
+			return
+		}
+		settings.RotationSkipIDs = toggleID(settings.RotationSkipIDs, id)
+	}
+
+	if err := b.db.SaveChatSettings(ctx, settings); err != nil {
+		b.logger.Error("Failed to save chat settings",
+			zap.Error(err),
+			zap.Int64("chat_id", chatID),
+		)
+		b.sendMessageInThread(ctx, chatID, fmt.Sprintf("Error: %v", err), threadID)
+		return
+	}
+
+	b.logger.Info("Rotation settings changed",
+		zap.Int64("user_id", query.From.ID),
+		zap.Int64("chat_id", chatID),
+		zap.String("strategy", settings.RotationStrategy),
+		zap.Strings("skipped", settings.RotationSkipIDs),
+	)
+
+	b.editMessageMarkup(ctx, query, rotationKeyboard(settings, participants))
+	if confirmation != "" {
+		b.sendMessageInThread(ctx, chatID, confirmation, threadID)
+	}
 }
@@ -2,9 +2,14 @@ package bot
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 
+	"library/internal/labels"
+	libmodels "library/internal/models"
+	"library/internal/storage"
+
 	"github.com/go-telegram/bot/models"
 	"go.uber.org/zap"
 )
@@ -19,10 +24,28 @@ Available commands:
 /who_is_next - See who should read next
 /last - Show last 10 reading events
 /stats - View reading statistics
-/rare - Show rarely read books
+/rare - Show rarely read books, e.g. /rare genre=fairy-tale AND lang=en
 /add_label - Add a label to a book
-/book_labels - Show labels for a book
-/books_by_label - Show books by label
+/remove_label - Remove a label from a book
+/labels - Rename or merge labels
+/book_labels - Show details and labels of a book
+/books_by_label - Show books by label or filter
+/participants - Add, rename or archive participants
+/retire_book - Retire a book (keeps its history)
+/restore_book - Restore a retired book
+/rename_book - Rename a book
+/delete_book - Delete a book without reading history
+/edit_last - Change or delete a recent reading event
+/rotation - Choose how /who_is_next picks the next reader
+/away - Mark someone as away: /away <name> <until YYYY-MM-DD or 3d>
+/back - Mark someone as back: /back <name>
+/remind - Post who reads tonight here every day: /remind on|off
+/goals - Show or set reading goals: /goals set <name> <reads> [week|month]
+/streaks - Show reading streaks
+/badges - Show badges of a participant: /badges <name>
+/digest - Preview the weekly or monthly digest: /digest now [week|month]
+/audit - Show who changed what recently: /audit [count]
+/token - Manage API tokens for scripts: /token create <name> [read|write], list, revoke <name>
 /ask - Ask a question about your library (AI)`
 
 	b.sendMessageInThread(ctx, message.Chat.ID, text, message.MessageThreadID)
@@ -95,10 +118,19 @@ func (b *Bot) handleReadStart(ctx context.Context, message *models.Message) {
 
 // handleWhoIsNext shows who should read next based on rotation logic
 func (b *Bot) handleWhoIsNext(ctx context.Context, message *models.Message) {
-	// Get all participants
-	participants, err := b.db.ListParticipants(ctx)
+	settings, err := b.db.GetChatSettings(ctx, message.Chat.ID)
 	if err != nil {
-		b.logger.Error("Failed to list participants",
+		b.logger.Error("Failed to get chat settings",
+			zap.Error(err),
+			zap.Int64("chat_id", message.Chat.ID),
+		)
+		b.sendMessageInThread(ctx, message.Chat.ID, fmt.Sprintf("Error: %v", err), message.MessageThreadID)
+		return
+	}
+
+	text, err := b.whoIsNextText(ctx, settings)
+	if err != nil {
+		b.logger.Error("Failed to compute next reader",
 			zap.Error(err),
 			zap.Int64("user_id", message.From.ID),
 		)
@@ -106,39 +138,206 @@ func (b *Bot) handleWhoIsNext(ctx context.Context, message *models.Message) {
 		return
 	}
 
+	b.sendMessageInThread(ctx, message.Chat.ID, text, message.MessageThreadID)
+}
+
+// whoIsNextText computes the next reader with the chat's rotation strategy and
+// formats the answer, listing participants who were skipped
+func (b *Bot) whoIsNextText(ctx context.Context, settings libmodels.ChatSettings) (string, error) {
+	participants, err := b.db.ListParticipants(ctx)
+	if err != nil {
+		return "", err
+	}
 	if len(participants) == 0 {
-		b.logger.Warn("No participants found", zap.Int64("user_id", message.From.ID))
-		b.sendMessageInThread(ctx, message.Chat.ID, "No participants found in database", message.MessageThreadID)
-		return
+		return "No participants found in database", nil
+	}
+
+	input, err := b.rotationInput(ctx, participants, settings)
+	if err != nil {
+		return "", err
+	}
+
+	nextReader := rotationStrategyByName(settings.RotationStrategy).Next(input)
+	skipped := formatSkipped(participants, input.Skip)
+	if nextReader == "" {
+		if skipped != "" {
+			return "Nobody is available to read next.\n\n" + skipped, nil
+		}
+		return "No child participants found in database", nil
+	}
+
+	text := fmt.Sprintf("Next to read: %s", nextReader)
+	if skipped != "" {
+		text += "\n\n" + skipped
+	}
+	return text, nil
+}
+
+// rotationInput collects the reading history rotation strategies need
+func (b *Bot) rotationInput(ctx context.Context, participants []libmodels.Participant, settings libmodels.ChatSettings) (RotationInput, error) {
+	input := RotationInput{
+		Participants: participants,
+		Activity:     make(map[string]libmodels.ReaderActivity),
+		Skip:         make(map[string]string),
 	}
 
-	// Get the last event to determine last reader
 	events, err := b.db.GetLastEvents(ctx, 1)
 	if err != nil {
-		b.logger.Error("Failed to get last events",
+		return RotationInput{}, err
+	}
+	if len(events) > 0 {
+		input.LastReaders = events[0].ParticipantNames
+	}
+
+	activity, err := b.db.GetReaderActivity(ctx, startOfWeek(b.now()))
+	if err != nil {
+		return RotationInput{}, err
+	}
+	for _, a := range activity {
+		input.Activity[a.ParticipantName] = a
+	}
+
+	for _, p := range selectedParticipants(participants, settings.RotationSkipIDs) {
+		input.Skip[p.Name] = "paused in /rotation"
+	}
+
+	absences, err := b.db.ListAbsences(ctx, b.now())
+	if err != nil {
+		return RotationInput{}, err
+	}
+	for _, absence := range absences {
+		input.Skip[absence.ParticipantName] = "away until " + b.formatAwayUntil(absence.Until)
+	}
+	return input, nil
+}
+
+// handleRotationStart shows the rotation policy of the chat and lets users change it
+func (b *Bot) handleRotationStart(ctx context.Context, message *models.Message) {
+	settings, err := b.db.GetChatSettings(ctx, message.Chat.ID)
+	if err != nil {
+		b.logger.Error("Failed to get chat settings",
+			zap.Error(err),
+			zap.Int64("chat_id", message.Chat.ID),
+		)
+		b.sendMessageInThread(ctx, message.Chat.ID, fmt.Sprintf("Error: %v", err), message.MessageThreadID)
+		return
+	}
+
+	participants, err := b.db.ListParticipants(ctx)
+	if err != nil {
+		b.sendMessageInThread(ctx, message.Chat.ID, fmt.Sprintf("Error: %v", err), message.MessageThreadID)
+		return
+	}
+
+	strategy := rotationStrategyByName(settings.RotationStrategy)
+	text := fmt.Sprintf("🔄 Rotation policy: %s\n\nChoose a policy, or tap a child to skip them in /who_is_next:", strategy.Description())
+	b.sendMessageInThreadWithMarkup(ctx, message.Chat.ID, text, message.MessageThreadID, rotationKeyboard(settings, participants))
+}
+
+// formatSkipped lists participants left out of the rotation and why, in participant order
+func formatSkipped(participants []libmodels.Participant, skip map[string]string) string {
+	var lines []string
+	for _, p := range participants {
+		if reason, ok := skip[p.Name]; ok {
+			lines = append(lines, fmt.Sprintf("• %s: %s", p.Name, reason))
+		}
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return "⏭ Skipped:\n" + strings.Join(lines, "\n")
+}
+
+// handleAway marks a participant as away: /away <name> <until>
+func (b *Bot) handleAway(ctx context.Context, message *models.Message) {
+	const usage = "Usage: /away <name> <until>\n\n<until> is the last day away (YYYY-MM-DD) or a number of days, e.g. /away Alice 2024-05-20 or /away Alice 3d"
+
+	fields := strings.Fields(commandArgs(message.Text))
+	if len(fields) < 2 {
+		b.sendMessageInThread(ctx, message.Chat.ID, usage, message.MessageThreadID)
+		return
+	}
+	name := strings.Join(fields[:len(fields)-1], " ")
+
+	until, err := parseAwayUntil(fields[len(fields)-1], b.now())
+	if err != nil {
+		b.sendMessageInThread(ctx, message.Chat.ID, fmt.Sprintf("❌ %v\n\n%s", err, usage), message.MessageThreadID)
+		return
+	}
+
+	participant, err := b.findActiveParticipant(ctx, name)
+	if err != nil {
+		b.sendParticipantLookupError(ctx, message, name, err)
+		return
+	}
+
+	if err := b.db.SetAbsence(ctx, participant.ID, until); err != nil {
+		b.logger.Error("Failed to set absence",
 			zap.Error(err),
 			zap.Int64("user_id", message.From.ID),
+			zap.String("participant", participant.Name),
 		)
 		b.sendMessageInThread(ctx, message.Chat.ID, fmt.Sprintf("Error: %v", err), message.MessageThreadID)
 		return
 	}
 
-	// Determine last participant name
-	var lastParticipant string
-	if len(events) > 0 {
-		lastParticipant = events[0].ParticipantName
+	b.logger.Info("Participant marked as away",
+		zap.Int64("user_id", message.From.ID),
+		zap.String("participant", participant.Name),
+		zap.Time("until", until),
+	)
+	b.sendMessageInThread(ctx, message.Chat.ID,
+		fmt.Sprintf("🧳 %s is away until %s and will be skipped by /who_is_next.", participant.Name, b.formatAwayUntil(until)),
+		message.MessageThreadID)
+}
+
+// handleBack ends a participant's absence: /back <name>
+func (b *Bot) handleBack(ctx context.Context, message *models.Message) {
+	name := strings.TrimSpace(commandArgs(message.Text))
+	if name == "" {
+		b.sendMessageInThread(ctx, message.Chat.ID, "Usage: /back <name>", message.MessageThreadID)
+		return
 	}
 
-	// Compute next participant using rotation algorithm
-	nextReader := ComputeNextParticipant(participants, lastParticipant)
+	participant, err := b.findActiveParticipant(ctx, name)
+	if err != nil {
+		b.sendParticipantLookupError(ctx, message, name, err)
+		return
+	}
 
-	if nextReader == "" {
-		b.sendMessageInThread(ctx, message.Chat.ID, "No child participants found in database", message.MessageThreadID)
+	err = b.db.ClearAbsence(ctx, participant.ID)
+	if errors.Is(err, storage.ErrNotFound) {
+		b.sendMessageInThread(ctx, message.Chat.ID, fmt.Sprintf("%s is not marked as away.", participant.Name), message.MessageThreadID)
+		return
+	}
+	if err != nil {
+		b.logger.Error("Failed to clear absence",
+			zap.Error(err),
+			zap.Int64("user_id", message.From.ID),
+			zap.String("participant", participant.Name),
+		)
+		b.sendMessageInThread(ctx, message.Chat.ID, fmt.Sprintf("Error: %v", err), message.MessageThreadID)
 		return
 	}
 
-	text := fmt.Sprintf("Next to read: %s", nextReader)
-	b.sendMessageInThread(ctx, message.Chat.ID, text, message.MessageThreadID)
+	b.logger.Info("Participant is back",
+		zap.Int64("user_id", message.From.ID),
+		zap.String("participant", participant.Name),
+	)
+	b.sendMessageInThread(ctx, message.Chat.ID, fmt.Sprintf("🏠 Welcome back, %s!", participant.Name), message.MessageThreadID)
+}
+
+// sendParticipantLookupError reports a failed participant lookup for /away and /back
+func (b *Bot) sendParticipantLookupError(ctx context.Context, message *models.Message, name string, err error) {
+	if errors.Is(err, storage.ErrNotFound) {
+		b.sendMessageInThread(ctx, message.Chat.ID, fmt.Sprintf("❌ Unknown participant: %s", name), message.MessageThreadID)
+		return
+	}
+	b.logger.Error("Failed to look up participant",
+		zap.Error(err),
+		zap.String("participant", name),
+	)
+	b.sendMessageInThread(ctx, message.Chat.ID, fmt.Sprintf("Error: %v", err), message.MessageThreadID)
 }
 
 // handleLast shows the last 10 reading events
@@ -169,9 +368,9 @@ func (b *Bot) handleLast(ctx context.Context, message *models.Message) {
 	for i, event := range events {
 		text.WriteString(fmt.Sprintf("%d. %s - %s (%s)\n",
 			i+1,
-			event.Date.Format("2006-01-02"),
+			b.formatEventTime(event.Date),
 			event.BookName,
-			event.ParticipantName))
+			strings.Join(event.ParticipantNames, ", ")))
 	}
 
 	b.sendMessageInThread(ctx, message.Chat.ID, text.String(), message.MessageThreadID)
@@ -210,12 +409,18 @@ func (b *Bot) handleStatsStart(ctx context.Context, message *models.Message) {
 	b.sendMessageInThreadWithMarkup(ctx, message.Chat.ID, "📊 Select time period for statistics:", message.MessageThreadID, keyboard)
 }
 
-// handleRareStart starts the rare books command with label selection
+// handleRareStart starts the rare books command with label selection.
+// A filter expression can be given right away: /rare genre=fairy-tale AND lang=en
 func (b *Bot) handleRareStart(ctx context.Context, message *models.Message) {
 	userID := message.From.ID
 
+	if args := commandArgs(message.Text); args != "" {
+		b.handleRareFilter(ctx, message, args)
+		return
+	}
+
 	// Get all available labels
-	labels, err := b.db.GetAllLabels(ctx)
+	allLabels, err := b.db.GetAllLabels(ctx)
 	if err != nil {
 		b.logger.Error("Failed to get labels",
 			zap.Error(err),
@@ -236,26 +441,24 @@ func (b *Bot) handleRareStart(ctx context.Context, message *models.Message) {
 	b.statesMu.Unlock()
 
 	// Build keyboard with "All books" option and available labels
-	keyboard := &models.InlineKeyboardMarkup{
-		InlineKeyboard: [][]models.InlineKeyboardButton{
-			{
-				{Text: "📚 All books", CallbackData: "rare_label:"},
-			},
+	keyboard := labelFilterKeyboard("rare_label:", allLabels)
+	keyboard.InlineKeyboard = append([][]models.InlineKeyboardButton{
+		{
+			{Text: "📚 All books", CallbackData: "rare_label:"},
 		},
-	}
+	}, keyboard.InlineKeyboard...)
 
-	// Add labels in two columns
-	for i := 0; i < len(labels); i += 2 {
-		row := []models.InlineKeyboardButton{
-			{Text: labels[i], CallbackData: fmt.Sprintf("rare_label:%s", labels[i])},
-		}
-		if i+1 < len(labels) {
-			row = append(row, models.InlineKeyboardButton{Text: labels[i+1], CallbackData: fmt.Sprintf("rare_label:%s", labels[i+1])})
-		}
-		keyboard.InlineKeyboard = append(keyboard.InlineKeyboard, row)
-	}
+	b.sendMessageInThreadWithMarkup(ctx, message.Chat.ID, "🏷 Filter by label, or type a filter such as genre=fairy-tale AND NOT lang=en:", message.MessageThreadID, keyboard)
+}
 
-	b.sendMessageInThreadWithMarkup(ctx, message.Chat.ID, "🏷 Filter by label:", message.MessageThreadID, keyboard)
+// handleRareFilter shows the rarely read books matching a typed filter expression
+func (b *Bot) handleRareFilter(ctx context.Context, message *models.Message, expr string) {
+	filter, err := labels.Parse(expr)
+	if err != nil {
+		b.sendMessageInThread(ctx, message.Chat.ID, fmt.Sprintf("❌ Invalid filter: %v\n\n%s", err, labelFilterHelp), message.MessageThreadID)
+		return
+	}
+	b.sendRareBooks(ctx, message.Chat.ID, message.MessageThreadID, message.From.ID, filter)
 }
 
 // handleBookLabelsStart starts the book labels query command
@@ -308,11 +511,22 @@ func (b *Bot) handleBookLabelsStart(ctx context.Context, message *models.Message
 	b.sendMessageInThreadWithMarkup(ctx, message.Chat.ID, "📚 Select a book to see its labels:", message.MessageThreadID, keyboard)
 }
 
-// handleBooksByLabelStart starts the books by label query command
+// handleBooksByLabelStart starts the books by label query command.
+// A filter expression can be given right away: /books_by_label genre:* AND NOT owner:library
 func (b *Bot) handleBooksByLabelStart(ctx context.Context, message *models.Message) {
 	userID := message.From.ID
 
-	labels, err := b.db.GetAllLabels(ctx)
+	if args := commandArgs(message.Text); args != "" {
+		filter, err := labels.Parse(args)
+		if err != nil {
+			b.sendMessageInThread(ctx, message.Chat.ID, fmt.Sprintf("❌ Invalid filter: %v\n\n%s", err, labelFilterHelp), message.MessageThreadID)
+			return
+		}
+		b.sendBooksByLabel(ctx, message.Chat.ID, message.MessageThreadID, userID, filter)
+		return
+	}
+
+	allLabels, err := b.db.GetAllLabels(ctx)
 	if err != nil {
 		b.logger.Error("Failed to get labels",
 			zap.Error(err),
@@ -322,7 +536,7 @@ func (b *Bot) handleBooksByLabelStart(ctx context.Context, message *models.Messa
 		return
 	}
 
-	if len(labels) == 0 {
+	if len(allLabels) == 0 {
 		b.sendMessageInThread(ctx, message.Chat.ID, "No labels found. Use /add_label to add labels to books.", message.MessageThreadID)
 		return
 	}
@@ -336,41 +550,246 @@ func (b *Bot) handleBooksByLabelStart(ctx context.Context, message *models.Messa
 	}
 	b.statesMu.Unlock()
 
-	// Build inline keyboard with labels in 2 columns
+	// Build inline keyboard with namespaces and labels in 2 columns
+	keyboard := labelFilterKeyboard("booksbylabel:", allLabels)
+	b.sendMessageInThreadWithMarkup(ctx, message.Chat.ID, "🏷 Select a label to see its books:", message.MessageThreadID, keyboard)
+}
+
+// handleAddLabelStart starts the add label command
+func (b *Bot) handleAddLabelStart(ctx context.Context, message *models.Message) {
+	userID := message.From.ID
+
+	// Set conversation state
+	b.statesMu.Lock()
+	b.states[userID] = &ConversationState{
+		Command:         "add_label",
+		Step:            1,
+		Data:            make(map[string]interface{}),
+		MessageThreadID: message.MessageThreadID,
+	}
+	b.statesMu.Unlock()
+
+	b.sendMessageInThread(ctx, message.Chat.ID, "🏷 Which label?\n\nTyped labels are key:value, with these keys:\n"+labels.Describe(), message.MessageThreadID)
+}
+
+// handleParticipantsStart shows participants with management options
+func (b *Bot) handleParticipantsStart(ctx context.Context, message *models.Message) {
+	userID := message.From.ID
+
+	participants, err := b.db.ListParticipants(ctx)
+	if err != nil {
+		b.logger.Error("Failed to list participants",
+			zap.Error(err),
+			zap.Int64("user_id", userID),
+		)
+		b.sendMessageInThread(ctx, message.Chat.ID, fmt.Sprintf("Error: %v", err), message.MessageThreadID)
+		return
+	}
+
+	b.statesMu.Lock()
+	b.states[userID] = &ConversationState{
+		Command:         "participants",
+		Step:            1,
+		Data:            map[string]interface{}{"participants": participants},
+		MessageThreadID: message.MessageThreadID,
+	}
+	b.statesMu.Unlock()
+
+	var text strings.Builder
+	text.WriteString("👥 Participants:\n\n")
+	if len(participants) == 0 {
+		text.WriteString("No participants yet.\n")
+	}
+
 	var rows [][]models.InlineKeyboardButton
-	var currentRow []models.InlineKeyboardButton
-	for i, label := range labels {
-		button := models.InlineKeyboardButton{
-			Text:         label,
-			CallbackData: fmt.Sprintf("booksbylabel:%s", label),
+	for i, p := range participants {
+		emoji, role := "👶", "child"
+		if p.IsParent {
+			emoji, role = "👨", "parent"
 		}
-		currentRow = append(currentRow, button)
+		text.WriteString(fmt.Sprintf("%s %s (%s)\n", emoji, p.Name, role))
+		rows = append(rows, []models.InlineKeyboardButton{
+			{Text: fmt.Sprintf("%s %s", emoji, p.Name), CallbackData: fmt.Sprintf("participants_select:%d", i)},
+		})
+	}
+	rows = append(rows, []models.InlineKeyboardButton{
+		{Text: "➕ Add participant", CallbackData: "participants_add:"},
+	})
 
-		if len(currentRow) == 2 || i == len(labels)-1 {
-			rows = append(rows, currentRow)
-			currentRow = []models.InlineKeyboardButton{}
+	text.WriteString("\nSelect a participant to manage or add a new one:")
+
+	keyboard := &models.InlineKeyboardMarkup{
+		InlineKeyboard: rows,
+	}
+	b.sendMessageInThreadWithMarkup(ctx, message.Chat.ID, text.String(), message.MessageThreadID, keyboard)
+}
+
+// handleRetireBookStart shows readable books that can be retired
+func (b *Bot) handleRetireBookStart(ctx context.Context, message *models.Message) {
+	books, err := b.db.ListReadableBooks(ctx)
+	if err != nil {
+		b.logger.Error("Failed to list readable books",
+			zap.Error(err),
+			zap.Int64("user_id", message.From.ID),
+		)
+		b.sendMessageInThread(ctx, message.Chat.ID, fmt.Sprintf("Error: %v", err), message.MessageThreadID)
+		return
+	}
+
+	if len(books) == 0 {
+		b.sendMessageInThread(ctx, message.Chat.ID, "No readable books available.", message.MessageThreadID)
+		return
+	}
+
+	b.startBookSelection(ctx, message, "retire_book", books, "📦 Select a book to retire:")
+}
+
+// handleRestoreBookStart shows retired books that can be restored
+func (b *Bot) handleRestoreBookStart(ctx context.Context, message *models.Message) {
+	books, err := b.db.ListRetiredBooks(ctx)
+	if err != nil {
+		b.logger.Error("Failed to list retired books",
+			zap.Error(err),
+			zap.Int64("user_id", message.From.ID),
+		)
+		b.sendMessageInThread(ctx, message.Chat.ID, fmt.Sprintf("Error: %v", err), message.MessageThreadID)
+		return
+	}
+
+	if len(books) == 0 {
+		b.sendMessageInThread(ctx, message.Chat.ID, "No retired books.", message.MessageThreadID)
+		return
+	}
+
+	b.startBookSelection(ctx, message, "restore_book", books, "♻️ Select a book to restore:")
+}
+
+// handleRenameBookStart shows all books (readable and retired) that can be renamed
+func (b *Bot) handleRenameBookStart(ctx context.Context, message *models.Message) {
+	books, err := b.listAllBooks(ctx)
+	if err != nil {
+		b.logger.Error("Failed to list books",
+			zap.Error(err),
+			zap.Int64("user_id", message.From.ID),
+		)
+		b.sendMessageInThread(ctx, message.Chat.ID, fmt.Sprintf("Error: %v", err), message.MessageThreadID)
+		return
+	}
+
+	if len(books) == 0 {
+		b.sendMessageInThread(ctx, message.Chat.ID, "No books found. Please add books first with /new_book", message.MessageThreadID)
+		return
+	}
+
+	b.startBookSelection(ctx, message, "rename_book", books, "✏️ Select a book to rename:")
+}
+
+// handleDeleteBookStart shows all books that can be deleted
+func (b *Bot) handleDeleteBookStart(ctx context.Context, message *models.Message) {
+	books, err := b.listAllBooks(ctx)
+	if err != nil {
+		b.logger.Error("Failed to list books",
+			zap.Error(err),
+			zap.Int64("user_id", message.From.ID),
+		)
+		b.sendMessageInThread(ctx, message.Chat.ID, fmt.Sprintf("Error: %v", err), message.MessageThreadID)
+		return
+	}
+
+	if len(books) == 0 {
+		b.sendMessageInThread(ctx, message.Chat.ID, "No books found.", message.MessageThreadID)
+		return
+	}
+
+	b.startBookSelection(ctx, message, "delete_book", books, "🗑 Select a book to delete (only books without reading history can be deleted):")
+}
+
+// handleEditLastStart lists recent reading events so one can be changed or deleted
+func (b *Bot) handleEditLastStart(ctx context.Context, message *models.Message) {
+	events, err := b.db.GetLastEvents(ctx, 10)
+	if err != nil {
+		b.logger.Error("Failed to get last events",
+			zap.Error(err),
+			zap.Int64("user_id", message.From.ID),
+		)
+		b.sendMessageInThread(ctx, message.Chat.ID, fmt.Sprintf("Error: %v", err), message.MessageThreadID)
+		return
+	}
+
+	if len(events) == 0 {
+		b.sendMessageInThread(ctx, message.Chat.ID, "No reading events recorded yet.", message.MessageThreadID)
+		return
+	}
+
+	b.statesMu.Lock()
+	b.states[message.From.ID] = &ConversationState{
+		Command:         "edit_last",
+		Step:            1,
+		Data:            map[string]interface{}{"events": events},
+		MessageThreadID: message.MessageThreadID,
+	}
+	b.statesMu.Unlock()
+
+	var rows [][]models.InlineKeyboardButton
+	for i, event := range events {
+		button := models.InlineKeyboardButton{
+			Text:         fmt.Sprintf("%s · %s (%s)", b.formatEventTime(event.Date), event.BookName, strings.Join(event.ParticipantNames, ", ")),
+			CallbackData: fmt.Sprintf("editlast_event:%d", i),
 		}
+		rows = append(rows, []models.InlineKeyboardButton{button})
 	}
 
 	keyboard := &models.InlineKeyboardMarkup{
 		InlineKeyboard: rows,
 	}
-	b.sendMessageInThreadWithMarkup(ctx, message.Chat.ID, "🏷 Select a label to see its books:", message.MessageThreadID, keyboard)
+	b.sendMessageInThreadWithMarkup(ctx, message.Chat.ID, "✏️ Select a reading event to edit:", message.MessageThreadID, keyboard)
 }
 
-// handleAddLabelStart starts the add label command
-func (b *Bot) handleAddLabelStart(ctx context.Context, message *models.Message) {
-	userID := message.From.ID
+// listAllBooks returns readable books followed by retired ones
+func (b *Bot) listAllBooks(ctx context.Context) ([]libmodels.Book, error) {
+	readable, err := b.db.ListReadableBooks(ctx)
+	if err != nil {
+		return nil, err
+	}
+	retired, err := b.db.ListRetiredBooks(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return append(readable, retired...), nil
+}
 
-	// Set conversation state
+// startBookSelection stores the book list in a new conversation and shows it as a 2-column keyboard.
+// Callback data is "<command>:<index>".
+func (b *Bot) startBookSelection(ctx context.Context, message *models.Message, command string, books []libmodels.Book, prompt string) {
 	b.statesMu.Lock()
-	b.states[userID] = &ConversationState{
-		Command:         "add_label",
+	b.states[message.From.ID] = &ConversationState{
+		Command:         command,
 		Step:            1,
-		Data:            make(map[string]interface{}),
+		Data:            map[string]interface{}{"books": books},
 		MessageThreadID: message.MessageThreadID,
 	}
 	b.statesMu.Unlock()
 
-	b.sendMessageInThread(ctx, message.Chat.ID, "🏷 Which label?", message.MessageThreadID)
+	var rows [][]models.InlineKeyboardButton
+	var currentRow []models.InlineKeyboardButton
+	for i, book := range books {
+		text := book.Name
+		if !book.IsReadable {
+			text = "📦 " + book.Name
+		}
+		currentRow = append(currentRow, models.InlineKeyboardButton{
+			Text:         text,
+			CallbackData: fmt.Sprintf("%s:%d", command, i),
+		})
+
+		if len(currentRow) == 2 || i == len(books)-1 {
+			rows = append(rows, currentRow)
+			currentRow = []models.InlineKeyboardButton{}
+		}
+	}
+
+	keyboard := &models.InlineKeyboardMarkup{
+		InlineKeyboard: rows,
+	}
+	b.sendMessageInThreadWithMarkup(ctx, message.Chat.ID, prompt, message.MessageThreadID, keyboard)
 }
@@ -3,6 +3,7 @@ package bot
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/go-telegram/bot"
 	"github.com/go-telegram/bot/models"
@@ -12,7 +13,7 @@ import (
 )
 
 // NewBot creates a new Telegram bot
-func NewBot(token string, db storage.Storage, allowedUserIDs []int64, notificationChatID int64, notificationThreadID int, llmClient *llm.Client, logger *zap.Logger) (*Bot, error) {
+func NewBot(token string, db storage.Storage, allowedUserIDs []int64, notificationChatID int64, notificationThreadID int, location *time.Location, llmClient *llm.Client, logger *zap.Logger) (*Bot, error) {
 	allowedUsers := make(map[int64]bool)
 	for _, id := range allowedUserIDs {
 		allowedUsers[id] = true
@@ -27,6 +28,7 @@ func NewBot(token string, db storage.Storage, allowedUserIDs []int64, notificati
 		notificationChatID:   notificationChatID,
 		notificationThreadID: notificationThreadID,
 		llmClient:            llmClient,
+		location:             location,
 	}
 
 	// Create bot with handlers
@@ -68,6 +70,18 @@ func (b *Bot) handleUpdate(ctx context.Context, api *bot.Bot, update *models.Upd
 	}
 }
 
+// ConfigureReminder sets when the daily reading reminder is posted (HH:MM, "" = disabled)
+// and whether it is skipped on days with a reading already logged
+func (b *Bot) ConfigureReminder(at string, skipIfRead bool) {
+	b.reminderTime = at
+	b.reminderSkipIfRead = skipIfRead
+}
+
+// ConfigureDigest sets when the weekly and monthly digests are posted (HH:MM, "" = disabled)
+func (b *Bot) ConfigureDigest(at string) {
+	b.digestTime = at
+}
+
 // GetAPI returns the bot API for testing
 func (b *Bot) GetAPI() *bot.Bot {
 	return b.api
@@ -7,7 +7,11 @@ import (
 	"strings"
 	"time"
 
+	"library/internal/labels"
+	libmodels "library/internal/models"
+
 	"github.com/go-telegram/bot/models"
+	"go.uber.org/zap"
 )
 
 // handleConversation processes multi-step conversations
@@ -23,6 +27,17 @@ func (b *Bot) handleConversation(ctx context.Context, message *models.Message, s
 		b.handleStatsConversation(ctx, message, state)
 	case "add_label":
 		b.handleAddLabelConversation(ctx, message, state)
+	case "rare":
+		b.handleRareFilter(ctx, message, message.Text)
+		state.Step = -1
+	case "participants":
+		b.handleParticipantsConversation(ctx, message, state)
+	case "labels":
+		b.handleLabelsConversation(ctx, message, state)
+	case "rename_book":
+		b.handleRenameBookConversation(ctx, message, state)
+	case "edit_last":
+		b.handleEditLastConversation(ctx, message, state)
 	case "ask":
 		b.handleAskConversation(ctx, message, state)
 	}
@@ -44,12 +59,21 @@ func (b *Bot) handleNewBookConversation(ctx context.Context, message *models.Mes
 		id, err := b.db.CreateBook(ctx, name)
 		if err != nil {
 			b.sendMessageInThread(ctx, message.Chat.ID, fmt.Sprintf("Error creating book: %v", err), state.MessageThreadID)
-		} else {
-			text := fmt.Sprintf("Book created successfully!\nName: %s", id)
-			b.sendMessageInThread(ctx, message.Chat.ID, text, state.MessageThreadID)
+			state.Step = -1 // Mark conversation as complete
+			return
 		}
 
-		state.Step = -1 // Mark conversation as complete
+		text := fmt.Sprintf("Book created successfully!\nName: %s\n\nAdd optional details, or press Done.", name)
+		b.sendMessageInThread(ctx, message.Chat.ID, text, state.MessageThreadID)
+		b.notifyNewBook(ctx, name, NotificationRoute{ChatID: message.Chat.ID, ThreadID: state.MessageThreadID})
+
+		// Optional details follow, one step each
+		state.Data["book_id"] = id
+		state.Data["book_name"] = name
+		state.Step = 2
+		b.askBookMetadata(ctx, message.Chat.ID, state)
+	default: // Waiting for an optional detail
+		b.handleBookMetadataInput(ctx, message, state)
 	}
 }
 
@@ -64,16 +88,20 @@ func (b *Bot) handleReadConversation(ctx context.Context, message *models.Messag
 		}
 
 		var date time.Time
-		var err error
 
 		if strings.ToLower(message.Text) == "today" {
-			date = time.Now()
+			date = b.now()
 		} else {
-			date, err = time.Parse("2006-01-02", message.Text)
+			parsed, hasTime, err := parseEventDateTime(message.Text, b.loc())
 			if err != nil {
-				b.sendMessageInThread(ctx, message.Chat.ID, "❌ Invalid date format. Please use YYYY-MM-DD\n\nExample: 2024-01-15", state.MessageThreadID)
+				b.sendMessageInThread(ctx, message.Chat.ID, "❌ Invalid date format. Please use YYYY-MM-DD or YYYY-MM-DD HH:MM\n\nExample: 2024-01-15 19:30", state.MessageThreadID)
 				return
 			}
+			date = parsed
+			if !hasTime {
+				// No time given: assume the reading happened at the current time of day
+				date = withTimeOfDay(parsed, b.now())
+			}
 		}
 
 		// Clear the awaiting flag
@@ -132,7 +160,7 @@ func (b *Bot) handleReadConversation(ctx context.Context, message *models.Messag
 		}
 
 		selectedBook := bookList[bookIdx-1]
-		state.Data["book"] = selectedBook.Name
+		state.Data["book"] = selectedBook
 		state.Step = 3
 
 		// Show participant selection
@@ -144,7 +172,7 @@ func (b *Bot) handleReadConversation(ctx context.Context, message *models.Messag
 		}
 
 		var text strings.Builder
-		text.WriteString("Please select a participant by number:\n\n")
+		text.WriteString("Please select participants by number (several separated by commas):\n\n")
 		for i, p := range participants {
 			text.WriteString(fmt.Sprintf("%d. %s\n", i+1, p.Name))
 		}
@@ -152,12 +180,6 @@ func (b *Bot) handleReadConversation(ctx context.Context, message *models.Messag
 		b.sendMessageInThread(ctx, message.Chat.ID, text.String(), state.MessageThreadID)
 
 	case 3: // Waiting for participant selection
-		participantIdx, err := strconv.Atoi(message.Text)
-		if err != nil {
-			b.sendMessageInThread(ctx, message.Chat.ID, "Invalid selection. Please enter a valid number:", state.MessageThreadID)
-			return
-		}
-
 		// Get participants list to validate selection
 		participantList, err := b.db.ListParticipants(ctx)
 		if err != nil {
@@ -166,26 +188,27 @@ func (b *Bot) handleReadConversation(ctx context.Context, message *models.Messag
 			return
 		}
 
-		if participantIdx < 1 || participantIdx > len(participantList) {
-			b.sendMessageInThread(ctx, message.Chat.ID, "Invalid selection. Please enter a valid number:", state.MessageThreadID)
-			return
+		var readers []libmodels.Participant
+		for _, part := range strings.Split(message.Text, ",") {
+			participantIdx, err := strconv.Atoi(strings.TrimSpace(part))
+			if err != nil || participantIdx < 1 || participantIdx > len(participantList) {
+				b.sendMessageInThread(ctx, message.Chat.ID, "Invalid selection. Please enter valid numbers:", state.MessageThreadID)
+				return
+			}
+			readers = append(readers, participantList[participantIdx-1])
 		}
 
-		selectedParticipant := participantList[participantIdx-1]
-		date := state.Data["date"].(time.Time)
-		bookName := state.Data["book"].(string)
+		b.askSessionDetails(ctx, message.Chat.ID, state, readers)
 
-		// Create the event
-		err = b.db.CreateEvent(ctx, date, bookName, selectedParticipant.Name)
+	case 4: // Waiting for optional session details
+		details, err := parseSessionDetails(message.Text)
 		if err != nil {
-			b.sendMessageInThread(ctx, message.Chat.ID, fmt.Sprintf("Error creating event: %v", err), state.MessageThreadID)
-		} else {
-			text := fmt.Sprintf("Reading event recorded!\n\nDate: %s\nBook: %s\nReader: %s",
-				date.Format("2006-01-02"), bookName, selectedParticipant.Name)
-			b.sendMessageInThread(ctx, message.Chat.ID, text, state.MessageThreadID)
+			b.sendMessageInThread(ctx, message.Chat.ID, fmt.Sprintf("%v. Please try again, e.g. 20m 15p:", err), state.MessageThreadID)
+			return
 		}
 
-		state.Step = -1 // Mark conversation as complete
+		state.Data["details"] = details
+		b.recordReadingEvent(ctx, message.Chat.ID, state, false)
 	}
 }
 
@@ -203,7 +226,7 @@ func (b *Bot) handleStatsConversation(ctx context.Context, message *models.Messa
 			}
 
 			// Calculate start and end dates for the month
-			startDate := time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, time.UTC)
+			startDate := time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, b.loc())
 			endDate := startDate.AddDate(0, 1, 0).Add(-time.Second)
 
 			delete(state.Data, "awaiting_month")
@@ -227,8 +250,8 @@ func (b *Bot) handleStatsConversation(ctx context.Context, message *models.Messa
 			}
 
 			// Calculate start and end dates for the year
-			startDate := time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)
-			endDate := time.Date(year, 12, 31, 23, 59, 59, 0, time.UTC)
+			startDate := time.Date(year, 1, 1, 0, 0, 0, 0, b.loc())
+			endDate := time.Date(year, 12, 31, 23, 59, 59, 0, b.loc())
 
 			delete(state.Data, "awaiting_year")
 			state.Data["start_date"] = startDate
@@ -247,9 +270,9 @@ func (b *Bot) handleStatsConversation(ctx context.Context, message *models.Messa
 func (b *Bot) handleAddLabelConversation(ctx context.Context, message *models.Message, state *ConversationState) {
 	switch state.Step {
 	case 1: // Waiting for label name
-		label := strings.TrimSpace(message.Text)
-		if label == "" {
-			b.sendMessageInThread(ctx, message.Chat.ID, "Label cannot be empty. Please enter a label:", state.MessageThreadID)
+		label, err := labels.Normalize(message.Text)
+		if err != nil {
+			b.sendMessageInThread(ctx, message.Chat.ID, fmt.Sprintf("❌ Invalid label: %v. Please enter a label:", err), state.MessageThreadID)
 			return
 		}
 
@@ -273,27 +296,116 @@ func (b *Bot) handleAddLabelConversation(ctx context.Context, message *models.Me
 
 		// Store books in state for later use
 		state.Data["books"] = books
+		state.Data["selected_books"] = []string{}
+		state.Data["page"] = 0
 
-		// Create inline keyboard for book selection (2 columns)
-		var rows [][]models.InlineKeyboardButton
-		var currentRow []models.InlineKeyboardButton
-		for i, book := range books {
-			button := models.InlineKeyboardButton{
-				Text:         book.Name,
-				CallbackData: fmt.Sprintf("addlabel_book:%d", i),
-			}
-			currentRow = append(currentRow, button)
+		keyboard := bookToggleKeyboard("addlabel_book:", books, nil, 0)
+		b.sendMessageInThreadWithMarkup(ctx, message.Chat.ID, fmt.Sprintf("📚 Select the books to label '%s', then press Done:", label), state.MessageThreadID, keyboard)
+	}
+}
 
-			// Add row when we have 2 buttons or it's the last book
-			if len(currentRow) == 2 || i == len(books)-1 {
-				rows = append(rows, currentRow)
-				currentRow = []models.InlineKeyboardButton{}
-			}
+// handleParticipantsConversation handles text input for the participants management flow
+func (b *Bot) handleParticipantsConversation(ctx context.Context, message *models.Message, state *ConversationState) {
+	if state.Step != 2 {
+		return
+	}
+
+	name := strings.TrimSpace(message.Text)
+	if name == "" {
+		b.sendMessageInThread(ctx, message.Chat.ID, "Name cannot be empty. Please enter a name:", state.MessageThreadID)
+		return
+	}
+
+	// Waiting for a new name for an existing participant
+	if _, ok := state.Data["awaiting_rename"]; ok {
+		participant := state.Data["participant"].(libmodels.Participant)
+
+		err := b.db.UpdateParticipant(ctx, participant.ID, name, participant.IsParent)
+		if err != nil {
+			b.logger.Error("Failed to rename participant",
+				zap.Error(err),
+				zap.Int64("user_id", message.From.ID),
+				zap.String("participant", participant.Name),
+				zap.String("new_name", name),
+			)
+			b.sendMessageInThread(ctx, message.Chat.ID, fmt.Sprintf("Error: %v", err), state.MessageThreadID)
+		} else {
+			b.sendMessageInThread(ctx, message.Chat.ID,
+				fmt.Sprintf("✅ Participant '%s' renamed to '%s'", participant.Name, name),
+				state.MessageThreadID)
 		}
+		state.Step = -1
+		return
+	}
+
+	// Waiting for the name of a new participant
+	if _, ok := state.Data["awaiting_new_name"]; ok {
+		delete(state.Data, "awaiting_new_name")
+		state.Data["new_name"] = name
+		state.Step = 3
 
 		keyboard := &models.InlineKeyboardMarkup{
-			InlineKeyboard: rows,
+			InlineKeyboard: [][]models.InlineKeyboardButton{
+				{
+					{Text: "👶 Child", CallbackData: "participants_role:child"},
+					{Text: "👨 Parent", CallbackData: "participants_role:parent"},
+				},
+			},
 		}
-		b.sendMessageInThreadWithMarkup(ctx, message.Chat.ID, "📚 Select a book:", state.MessageThreadID, keyboard)
+		b.sendMessageInThreadWithMarkup(ctx, message.Chat.ID, fmt.Sprintf("Is %s a child or a parent?", name), state.MessageThreadID, keyboard)
 	}
 }
+
+// handleRenameBookConversation handles the new name input for the rename_book command
+func (b *Bot) handleRenameBookConversation(ctx context.Context, message *models.Message, state *ConversationState) {
+	if state.Step != 2 {
+		return
+	}
+
+	newName := strings.TrimSpace(message.Text)
+	if newName == "" {
+		b.sendMessageInThread(ctx, message.Chat.ID, "Name cannot be empty. Please enter a name:", state.MessageThreadID)
+		return
+	}
+
+	book := state.Data["book"].(libmodels.Book)
+	if err := b.db.RenameBook(ctx, book.ID, newName); err != nil {
+		b.logger.Error("Failed to rename book",
+			zap.Error(err),
+			zap.Int64("user_id", message.From.ID),
+			zap.String("book", book.Name),
+			zap.String("new_name", newName),
+		)
+		b.sendMessageInThread(ctx, message.Chat.ID, fmt.Sprintf("Error: %v", err), state.MessageThreadID)
+	} else {
+		b.sendMessageInThread(ctx, message.Chat.ID,
+			fmt.Sprintf("✅ Book '%s' renamed to '%s'", book.Name, newName),
+			state.MessageThreadID)
+	}
+	state.Step = -1
+}
+
+// handleEditLastConversation handles the new date input for the edit_last command
+func (b *Bot) handleEditLastConversation(ctx context.Context, message *models.Message, state *ConversationState) {
+	if _, ok := state.Data["awaiting_date"]; !ok {
+		return
+	}
+
+	date, hasTime, err := parseEventDateTime(message.Text, b.loc())
+	if err != nil {
+		b.sendMessageInThread(ctx, message.Chat.ID, "❌ Invalid date format. Please use YYYY-MM-DD or YYYY-MM-DD HH:MM\n\nExample: 2024-01-15 19:30", state.MessageThreadID)
+		return
+	}
+
+	event := state.Data["event"].(libmodels.Event)
+	if hasTime {
+		event.Date = date
+	} else {
+		// Keep the time of day of the original event
+		original := event.Date.In(b.loc())
+		event.Date = time.Date(date.Year(), date.Month(), date.Day(),
+			original.Hour(), original.Minute(), original.Second(), 0, date.Location())
+	}
+
+	b.saveEditedEvent(ctx, message.Chat.ID, message.From.ID, event, state)
+}
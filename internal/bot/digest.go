@@ -0,0 +1,158 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-telegram/bot/models"
+	"go.uber.org/zap"
+)
+
+// digestForgottenLimit is how many forgotten books a digest lists
+const digestForgottenLimit = 3
+
+// handleDigest previews a digest in the current chat: /digest now [week|month]
+func (b *Bot) handleDigest(ctx context.Context, message *models.Message) {
+	const usage = "Usage: /digest now [week|month]"
+
+	fields := strings.Fields(strings.ToLower(commandArgs(message.Text)))
+	if len(fields) == 0 || fields[0] != "now" || len(fields) > 2 {
+		text := usage
+		if b.digestTime != "" {
+			text = fmt.Sprintf("📰 Digests are posted at %s: weekly on Mondays, monthly on the 1st.\n\n%s", b.digestTime, usage)
+		}
+		b.sendMessageInThread(ctx, message.Chat.ID, text, message.MessageThreadID)
+		return
+	}
+
+	end := b.now()
+	title, start := "📰 Weekly digest", end.AddDate(0, 0, -7)
+	if len(fields) == 2 {
+		switch fields[1] {
+		case "week":
+		case "month":
+			title, start = "📰 Monthly digest", end.AddDate(0, -1, 0)
+		default:
+			b.sendMessageInThread(ctx, message.Chat.ID, usage, message.MessageThreadID)
+			return
+		}
+	}
+
+	text, err := b.buildDigest(ctx, title, start, end)
+	if err != nil {
+		b.logger.Error("Failed to build digest",
+			zap.Error(err),
+			zap.Int64("user_id", message.From.ID),
+		)
+		b.sendMessageInThread(ctx, message.Chat.ID, fmt.Sprintf("Error: %v", err), message.MessageThreadID)
+		return
+	}
+	b.sendMessageInThread(ctx, message.Chat.ID, text, message.MessageThreadID)
+}
+
+// SendWeeklyDigest posts the digest of the last seven days to the notification chat
+func (b *Bot) SendWeeklyDigest(ctx context.Context) error {
+	end := b.startOfToday()
+	return b.sendDigest(ctx, "📰 Weekly digest", end.AddDate(0, 0, -7), end)
+}
+
+// SendMonthlyDigest posts the digest of the last month to the notification chat
+func (b *Bot) SendMonthlyDigest(ctx context.Context) error {
+	end := b.startOfToday()
+	return b.sendDigest(ctx, "📰 Monthly digest", end.AddDate(0, -1, 0), end)
+}
+
+// sendDigest builds a digest for [start, end) and posts it to the notification chat
+func (b *Bot) sendDigest(ctx context.Context, title string, start, end time.Time) error {
+	if b.notificationChatID == 0 {
+		b.logger.Info("Skipping digest, NOTIFICATION_CHAT_ID is not set", zap.String("title", title))
+		return nil
+	}
+
+	text, err := b.buildDigest(ctx, title, start, end.Add(-time.Second))
+	if err != nil {
+		return fmt.Errorf("failed to build digest: %w", err)
+	}
+	b.sendMessageInThread(ctx, b.notificationChatID, text, b.notificationThreadID)
+	b.logger.Info("Digest sent",
+		zap.String("title", title),
+		zap.Int64("chat_id", b.notificationChatID),
+	)
+	return nil
+}
+
+// startOfToday returns midnight of the current day in the bot's timezone
+func (b *Bot) startOfToday() time.Time {
+	now := b.now()
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+}
+
+// buildDigest formats top books, reads per child, new books and forgotten books for the period
+func (b *Bot) buildDigest(ctx context.Context, title string, start, end time.Time) (string, error) {
+	topBooks, err := b.db.GetTopBooks(ctx, 5, start, end, "")
+	if err != nil {
+		return "", err
+	}
+	participantStats, err := b.db.GetParticipantStats(ctx, start, end, "", "")
+	if err != nil {
+		return "", err
+	}
+	participants, err := b.db.ListParticipants(ctx)
+	if err != nil {
+		return "", err
+	}
+	newBooks, err := b.db.GetNewBooks(ctx, start, end)
+	if err != nil {
+		return "", err
+	}
+	forgotten, err := b.db.GetRarelyReadBooks(ctx, digestForgottenLimit, true, nil)
+	if err != nil {
+		return "", err
+	}
+
+	var text strings.Builder
+	text.WriteString(title + "\n")
+	text.WriteString(fmt.Sprintf("📅 %s - %s\n\n", start.In(b.loc()).Format("2006-01-02"), end.In(b.loc()).Format("2006-01-02")))
+
+	text.WriteString("📚 Top books:\n")
+	if len(topBooks) == 0 {
+		text.WriteString("No reading events in this period.\n")
+	}
+	for i, stat := range topBooks {
+		text.WriteString(fmt.Sprintf("%d. %s - %d reads\n", i+1, stat.BookName, stat.ReadCount))
+	}
+
+	reads := make(map[string]int)
+	for _, stat := range participantStats {
+		reads[stat.ParticipantName] += stat.ReadCount
+	}
+	text.WriteString("\n👶 Reads per child:\n")
+	for _, p := range participants {
+		if p.IsParent {
+			continue
+		}
+		text.WriteString(fmt.Sprintf("%s - %d reads\n", p.Name, reads[p.Name]))
+	}
+
+	if len(newBooks) > 0 {
+		text.WriteString("\n🆕 New books:\n")
+		for _, book := range newBooks {
+			text.WriteString(fmt.Sprintf("• %s\n", book.Name))
+		}
+	}
+
+	if len(forgotten) > 0 {
+		text.WriteString("\n💤 Forgotten books:\n")
+		for _, stat := range forgotten {
+			if stat.LastReadDate == nil {
+				text.WriteString(fmt.Sprintf("• %s (never read)\n", stat.BookName))
+			} else {
+				text.WriteString(fmt.Sprintf("• %s (last read %d days ago)\n", stat.BookName, stat.DaysSinceLastRead))
+			}
+		}
+	}
+
+	return text.String(), nil
+}
@@ -0,0 +1,263 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	libmodels "library/internal/models"
+	"library/internal/storage"
+
+	"github.com/go-telegram/bot/models"
+	"go.uber.org/zap"
+)
+
+// streakMilestones are the streak lengths (in days) that get a congratulation message
+var streakMilestones = []int{3, 7, 14, 30, 50, 100, 365}
+
+// handleGoals lists, sets or clears reading goals:
+// /goals, /goals set <name> <reads> [week|month], /goals clear <name>
+func (b *Bot) handleGoals(ctx context.Context, message *models.Message) {
+	const usage = "Usage:\n/goals - Show goals and progress\n/goals set <name> <reads> [week|month] - e.g. /goals set Alice 5 week\n/goals clear <name>"
+
+	fields := strings.Fields(commandArgs(message.Text))
+	if len(fields) == 0 {
+		b.sendGoals(ctx, message)
+		return
+	}
+
+	switch strings.ToLower(fields[0]) {
+	case "set":
+		args := fields[1:]
+		period := libmodels.GoalPeriodWeek
+		if len(args) > 0 {
+			if last := strings.ToLower(args[len(args)-1]); last == libmodels.GoalPeriodWeek || last == libmodels.GoalPeriodMonth {
+				period = last
+				args = args[:len(args)-1]
+			}
+		}
+		if len(args) < 2 {
+			b.sendMessageInThread(ctx, message.Chat.ID, usage, message.MessageThreadID)
+			return
+		}
+		target, err := strconv.Atoi(args[len(args)-1])
+		if err != nil || target < 1 {
+			b.sendMessageInThread(ctx, message.Chat.ID, fmt.Sprintf("❌ Invalid number of reads %q\n\n%s", args[len(args)-1], usage), message.MessageThreadID)
+			return
+		}
+		name := strings.Join(args[:len(args)-1], " ")
+
+		participant, err := b.findActiveParticipant(ctx, name)
+		if err != nil {
+			b.sendParticipantLookupError(ctx, message, name, err)
+			return
+		}
+		if err := b.db.SetGoal(ctx, participant.ID, target, period); err != nil {
+			b.logger.Error("Failed to set goal",
+				zap.Error(err),
+				zap.Int64("user_id", message.From.ID),
+				zap.String("participant", participant.Name),
+			)
+			b.sendMessageInThread(ctx, message.Chat.ID, fmt.Sprintf("Error: %v", err), message.MessageThreadID)
+			return
+		}
+
+		b.logger.Info("Reading goal set",
+			zap.Int64("user_id", message.From.ID),
+			zap.String("participant", participant.Name),
+			zap.Int("target", target),
+			zap.String("period", period),
+		)
+		b.sendMessageInThread(ctx, message.Chat.ID,
+			fmt.Sprintf("🎯 Goal set: %s aims for %d reads per %s.", participant.Name, target, period),
+			message.MessageThreadID)
+
+	case "clear":
+		name := strings.Join(fields[1:], " ")
+		if name == "" {
+			b.sendMessageInThread(ctx, message.Chat.ID, usage, message.MessageThreadID)
+			return
+		}
+		participant, err := b.findActiveParticipant(ctx, name)
+		if err != nil {
+			b.sendParticipantLookupError(ctx, message, name, err)
+			return
+		}
+		err = b.db.ClearGoal(ctx, participant.ID)
+		if errors.Is(err, storage.ErrNotFound) {
+			b.sendMessageInThread(ctx, message.Chat.ID, fmt.Sprintf("%s has no reading goal.", participant.Name), message.MessageThreadID)
+			return
+		}
+		if err != nil {
+			b.logger.Error("Failed to clear goal",
+				zap.Error(err),
+				zap.Int64("user_id", message.From.ID),
+				zap.String("participant", participant.Name),
+			)
+			b.sendMessageInThread(ctx, message.Chat.ID, fmt.Sprintf("Error: %v", err), message.MessageThreadID)
+			return
+		}
+		b.sendMessageInThread(ctx, message.Chat.ID, fmt.Sprintf("🗑 Reading goal of %s removed.", participant.Name), message.MessageThreadID)
+
+	default:
+		b.sendMessageInThread(ctx, message.Chat.ID, usage, message.MessageThreadID)
+	}
+}
+
+// sendGoals shows every goal with the progress in the current period
+func (b *Bot) sendGoals(ctx context.Context, message *models.Message) {
+	goals, err := b.db.ListGoals(ctx)
+	if err != nil {
+		b.logger.Error("Failed to list goals",
+			zap.Error(err),
+			zap.Int64("user_id", message.From.ID),
+		)
+		b.sendMessageInThread(ctx, message.Chat.ID, fmt.Sprintf("Error: %v", err), message.MessageThreadID)
+		return
+	}
+	if len(goals) == 0 {
+		b.sendMessageInThread(ctx, message.Chat.ID, "No reading goals yet. Set one with /goals set <name> <reads> [week|month]", message.MessageThreadID)
+		return
+	}
+
+	var text strings.Builder
+	text.WriteString("🎯 Reading goals:\n\n")
+	for _, goal := range goals {
+		reads, err := b.readsSince(ctx, goal.ParticipantID, goalPeriodStart(goal.Period, b.now()))
+		if err != nil {
+			b.logger.Error("Failed to get goal progress",
+				zap.Error(err),
+				zap.String("participant", goal.ParticipantName),
+			)
+			b.sendMessageInThread(ctx, message.Chat.ID, fmt.Sprintf("Error: %v", err), message.MessageThreadID)
+			return
+		}
+		line := fmt.Sprintf("%s: %d/%d reads this %s", goal.ParticipantName, reads, goal.Target, goal.Period)
+		if reads >= goal.Target {
+			line += " ✅"
+		}
+		text.WriteString(line + "\n")
+	}
+
+	b.sendMessageInThread(ctx, message.Chat.ID, text.String(), message.MessageThreadID)
+}
+
+// handleStreaks shows current and longest consecutive reading day streaks
+func (b *Bot) handleStreaks(ctx context.Context, message *models.Message) {
+	streaks, err := b.db.GetStreaks(ctx, b.now())
+	if err != nil {
+		b.logger.Error("Failed to get streaks",
+			zap.Error(err),
+			zap.Int64("user_id", message.From.ID),
+		)
+		b.sendMessageInThread(ctx, message.Chat.ID, fmt.Sprintf("Error: %v", err), message.MessageThreadID)
+		return
+	}
+
+	var lines []string
+	for _, streak := range streaks {
+		if streak.Longest == 0 {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%s: %d now, longest %d", streak.ParticipantName, streak.Current, streak.Longest))
+	}
+	if len(lines) == 0 {
+		b.sendMessageInThread(ctx, message.Chat.ID, "No reading events yet.", message.MessageThreadID)
+		return
+	}
+
+	text := "🔥 Reading streaks (days in a row):\n\n" + strings.Join(lines, "\n")
+	b.sendMessageInThread(ctx, message.Chat.ID, text, message.MessageThreadID)
+}
+
+// goalPeriodStart returns the start of the week (Monday) or month containing now
+func goalPeriodStart(period string, now time.Time) time.Time {
+	if period == libmodels.GoalPeriodMonth {
+		return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	}
+	return startOfWeek(now)
+}
+
+// readsSince returns how many events a participant attended since the given time
+func (b *Bot) readsSince(ctx context.Context, participantID string, since time.Time) (int, error) {
+	activity, err := b.db.GetReaderActivity(ctx, since)
+	if err != nil {
+		return 0, err
+	}
+	for _, entry := range activity {
+		if entry.ParticipantID == participantID {
+			return entry.ReadsSince, nil
+		}
+	}
+	return 0, nil
+}
+
+// celebrations returns congratulation messages for goals met and streak milestones
+// reached by a newly recorded event
+func (b *Bot) celebrations(ctx context.Context, participantIDs []string, date time.Time) ([]string, error) {
+	now := b.now()
+	var messages []string
+
+	goals, err := b.db.ListGoals(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, goal := range goals {
+		start := goalPeriodStart(goal.Period, now)
+		if !slices.Contains(participantIDs, goal.ParticipantID) || date.Before(start) {
+			continue
+		}
+		reads, err := b.readsSince(ctx, goal.ParticipantID, start)
+		if err != nil {
+			return nil, err
+		}
+		// Only the event that reaches the target is celebrated
+		if reads == goal.Target {
+			messages = append(messages, fmt.Sprintf("🎯 %s reached the goal of %d reads this %s!", goal.ParticipantName, goal.Target, goal.Period))
+		}
+	}
+
+	startOfToday := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	if date.Before(startOfToday) || date.After(startOfToday.AddDate(0, 0, 1)) {
+		return messages, nil
+	}
+	streaks, err := b.db.GetStreaks(ctx, now)
+	if err != nil {
+		return nil, err
+	}
+	for _, streak := range streaks {
+		if !slices.Contains(participantIDs, streak.ParticipantID) || !slices.Contains(streakMilestones, streak.Current) {
+			continue
+		}
+		// A second reading on the same day does not extend the streak
+		readsToday, err := b.readsSince(ctx, streak.ParticipantID, startOfToday)
+		if err != nil {
+			return nil, err
+		}
+		if readsToday == 1 {
+			messages = append(messages, fmt.Sprintf("🔥 %s has read %d days in a row!", streak.ParticipantName, streak.Current))
+		}
+	}
+
+	return messages, nil
+}
+
+// celebrate posts congratulation messages for a newly recorded event to the notification chat
+func (b *Bot) celebrate(ctx context.Context, participantIDs []string, date time.Time) {
+	if b.notificationChatID == 0 {
+		return
+	}
+
+	messages, err := b.celebrations(ctx, participantIDs, date)
+	if err != nil {
+		b.logger.Warn("Failed to check goals and streaks", zap.Error(err))
+		return
+	}
+	for _, text := range messages {
+		b.sendMessageInThread(ctx, b.notificationChatID, text, b.notificationThreadID)
+	}
+}
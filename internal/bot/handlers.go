@@ -4,6 +4,8 @@ import (
 	"context"
 	"strings"
 
+	"library/internal/storage"
+
 	tgbot "github.com/go-telegram/bot"
 	"github.com/go-telegram/bot/models"
 	"go.uber.org/zap"
@@ -36,6 +38,7 @@ func (b *Bot) handleMessage(ctx context.Context, message *models.Message) {
 		b.sendMessageInThread(ctx, message.Chat.ID, "You are not authorized to use this bot.", message.MessageThreadID)
 		return
 	}
+	ctx = withActor(ctx, message.From, storage.SourceBot)
 
 	isCommand := len(message.Entities) > 0 && message.Entities[0].Type == models.MessageEntityTypeBotCommand && message.Entities[0].Offset == 0
 
@@ -109,10 +112,46 @@ func (b *Bot) handleMessage(ctx context.Context, message *models.Message) {
 			b.handleRareStart(ctx, message)
 		case "add_label":
 			b.handleAddLabelStart(ctx, message)
+		case "remove_label":
+			b.handleRemoveLabelStart(ctx, message)
+		case "labels":
+			b.handleLabelsStart(ctx, message)
 		case "book_labels":
 			b.handleBookLabelsStart(ctx, message)
 		case "books_by_label":
 			b.handleBooksByLabelStart(ctx, message)
+		case "participants":
+			b.handleParticipantsStart(ctx, message)
+		case "retire_book":
+			b.handleRetireBookStart(ctx, message)
+		case "restore_book":
+			b.handleRestoreBookStart(ctx, message)
+		case "rename_book":
+			b.handleRenameBookStart(ctx, message)
+		case "delete_book":
+			b.handleDeleteBookStart(ctx, message)
+		case "edit_last":
+			b.handleEditLastStart(ctx, message)
+		case "rotation":
+			b.handleRotationStart(ctx, message)
+		case "away":
+			b.handleAway(ctx, message)
+		case "back":
+			b.handleBack(ctx, message)
+		case "remind":
+			b.handleRemind(ctx, message)
+		case "digest":
+			b.handleDigest(ctx, message)
+		case "goals":
+			b.handleGoals(ctx, message)
+		case "streaks":
+			b.handleStreaks(ctx, message)
+		case "badges":
+			b.handleBadges(ctx, message)
+		case "audit":
+			b.handleAudit(ctx, message)
+		case "token":
+			b.handleToken(ctx, message)
 		case "ask":
 			b.handleAsk(ctx, message)
 		default:
@@ -149,6 +188,7 @@ func (b *Bot) handleCallbackQuery(ctx context.Context, query *models.CallbackQue
 		)
 		return
 	}
+	ctx = withActor(ctx, &query.From, storage.SourceBot)
 
 	b.logger.Debug("Received callback query",
 		zap.Int64("user_id", userID),
@@ -160,6 +200,18 @@ func (b *Bot) handleCallbackQuery(ctx context.Context, query *models.CallbackQue
 		CallbackQueryID: query.ID,
 	})
 
+	// Undo buttons stay on past confirmations, so they work outside of a conversation
+	if strings.HasPrefix(query.Data, "undo_event:") {
+		b.handleUndoEventCallback(ctx, query)
+		return
+	}
+
+	// Rotation settings belong to the chat, not to a conversation
+	if strings.HasPrefix(query.Data, "rotation_strategy:") || strings.HasPrefix(query.Data, "rotation_skip:") {
+		b.handleRotationCallback(ctx, query)
+		return
+	}
+
 	// Check if user is in a conversation
 	b.statesMu.RLock()
 	state, ok := b.states[userID]
@@ -181,20 +233,64 @@ func (b *Bot) handleCallbackQuery(ctx context.Context, query *models.CallbackQue
 		b.handleBookCallback(ctx, query, state)
 	} else if strings.HasPrefix(data, "participant:") {
 		b.handleParticipantCallback(ctx, query, state)
+	} else if strings.HasPrefix(data, "details:") {
+		b.handleDetailsCallback(ctx, query, state)
+	} else if strings.HasPrefix(data, "dup_confirm:") {
+		b.handleDuplicateConfirmCallback(ctx, query, state)
 	} else if strings.HasPrefix(data, "stats_period:") {
 		b.handleStatsPeriodCallback(ctx, query, state)
 	} else if strings.HasPrefix(data, "stats_participant:") {
 		b.handleStatsParticipantCallback(ctx, query, state)
 	} else if strings.HasPrefix(data, "stats_mode:") {
 		b.handleStatsModeCallback(ctx, query, state)
+	} else if strings.HasPrefix(data, "newbook:") {
+		b.handleNewBookCallback(ctx, query, state)
 	} else if strings.HasPrefix(data, "rare_label:") {
 		b.handleRareLabelCallback(ctx, query, state)
+	} else if strings.HasPrefix(data, "rare_op:") {
+		b.handleRareOpCallback(ctx, query, state)
+	} else if data == "rare_show" {
+		b.handleRareShowCallback(ctx, query, state)
 	} else if strings.HasPrefix(data, "addlabel_book:") {
 		b.handleAddLabelBookCallback(ctx, query, state)
+	} else if strings.HasPrefix(data, "rmlabel_book:") {
+		b.handleRemoveLabelBookCallback(ctx, query, state)
+	} else if strings.HasPrefix(data, "rmlabel_label:") {
+		b.handleRemoveLabelLabelCallback(ctx, query, state)
+	} else if strings.HasPrefix(data, "labels_select:") {
+		b.handleLabelsSelectCallback(ctx, query, state)
+	} else if strings.HasPrefix(data, "labels_action:") {
+		b.handleLabelsActionCallback(ctx, query, state)
+	} else if strings.HasPrefix(data, "labels_merge:") {
+		b.handleLabelsMergeCallback(ctx, query, state)
 	} else if strings.HasPrefix(data, "booklabels:") {
 		b.handleBookLabelsCallback(ctx, query, state)
 	} else if strings.HasPrefix(data, "booksbylabel:") {
 		b.handleBooksByLabelCallback(ctx, query, state)
+	} else if strings.HasPrefix(data, "participants_select:") {
+		b.handleParticipantsSelectCallback(ctx, query, state)
+	} else if strings.HasPrefix(data, "participants_action:") {
+		b.handleParticipantsActionCallback(ctx, query, state)
+	} else if strings.HasPrefix(data, "participants_add:") {
+		b.handleParticipantsAddCallback(ctx, query, state)
+	} else if strings.HasPrefix(data, "participants_role:") {
+		b.handleParticipantsRoleCallback(ctx, query, state)
+	} else if strings.HasPrefix(data, "retire_book:") || strings.HasPrefix(data, "restore_book:") {
+		b.handleBookReadableCallback(ctx, query, state)
+	} else if strings.HasPrefix(data, "rename_book:") {
+		b.handleRenameBookCallback(ctx, query, state)
+	} else if strings.HasPrefix(data, "delete_book:") {
+		b.handleDeleteBookCallback(ctx, query, state)
+	} else if strings.HasPrefix(data, "delete_book_confirm:") {
+		b.handleDeleteBookConfirmCallback(ctx, query, state)
+	} else if strings.HasPrefix(data, "editlast_event:") {
+		b.handleEditLastEventCallback(ctx, query, state)
+	} else if strings.HasPrefix(data, "editlast_action:") {
+		b.handleEditLastActionCallback(ctx, query, state)
+	} else if strings.HasPrefix(data, "editlast_book:") {
+		b.handleEditLastBookCallback(ctx, query, state)
+	} else if strings.HasPrefix(data, "editlast_participant:") {
+		b.handleEditLastParticipantCallback(ctx, query, state)
 	} else {
 		b.logger.Warn("Unknown callback prefix",
 			zap.String("callback_data", data),
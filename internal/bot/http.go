@@ -1,26 +1,59 @@
 package bot
 
 import (
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
+	tgbot "github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
 	"go.uber.org/zap"
+	"library/internal/achievements"
+	"library/internal/labels"
+	libmodels "library/internal/models"
+	"library/internal/openapi"
+	"library/internal/storage"
 	"library/web"
 )
 
+// defaultInitDataMaxAge is how long initData stays valid unless configured otherwise
+const defaultInitDataMaxAge = 24 * time.Hour
+
+// maxAuthDateSkew tolerates clocks that are slightly ahead of the server's
+const maxAuthDateSkew = time.Minute
+
+// Reasons for rejecting initData that are counted separately
+var (
+	errInitDataExpired = errors.New("initData is too old")
+	errUserNotAllowed  = errors.New("user not allowed")
+)
+
+// Reasons for rejecting a new event, whether the book or participant is given by name or ID
+var (
+	errParticipantArchived = errors.New("participant is archived")
+	errBookRetired         = errors.New("book is retired")
+)
+
 // HTTPServer handles HTTP requests for the Mini App
 type HTTPServer struct {
-	bot         *Bot
-	webhookMode bool   // If false (polling mode), skip authentication for easier local dev
-	botToken    string // Bot token for initData validation; set from api.Token() at construction
+	bot            *Bot
+	webhookMode    bool           // If false (polling mode), skip authentication for easier local dev
+	botToken       string         // Bot token for initData validation; set from api.Token() at construction
+	initDataMaxAge time.Duration  // Maximum age of auth_date in initData (0 = defaultInitDataMaxAge)
+	replays        replayCache    // Expected nonce of Mini App write sessions, to reject replays
+	rejections     authRejections // Rejected requests per reason, exposed at /metrics
 }
 
 // NewHTTPServer creates a new HTTP server for the Mini App
@@ -32,15 +65,47 @@ func NewHTTPServer(bot *Bot, webhookMode bool) *HTTPServer {
 	}
 }
 
+// ConfigureInitDataMaxAge sets how long after auth_date the Mini App initData is accepted
+func (hs *HTTPServer) ConfigureInitDataMaxAge(maxAge time.Duration) {
+	hs.initDataMaxAge = maxAge
+}
+
+// maxInitDataAge returns the configured maximum age of initData
+func (hs *HTTPServer) maxInitDataAge() time.Duration {
+	if hs.initDataMaxAge <= 0 {
+		return defaultInitDataMaxAge
+	}
+	return hs.initDataMaxAge
+}
+
 // RegisterRoutes registers Mini App routes on the provided mux
 func (hs *HTTPServer) RegisterRoutes(mux *http.ServeMux) {
 	// Static file serving for Mini App
 	mux.HandleFunc("/web-app", hs.handleIndex)
 
-	// API endpoints
+	// Authentication counters in the Prometheus text format (authenticated like the API)
+	mux.HandleFunc("/metrics", hs.handleMetrics)
+
+	// API endpoints, documented in internal/openapi/openapi.json
+	mux.HandleFunc("/api/openapi.json", hs.handleOpenAPI)
+	mux.HandleFunc("/api/nonce", hs.handleNonce)
 	mux.HandleFunc("/api/books", hs.handleBooks)
+	mux.HandleFunc("/api/books/cover", hs.handleBookCover)
 	mux.HandleFunc("/api/participants", hs.handleParticipants)
 	mux.HandleFunc("/api/events", hs.handleEvents)
+	mux.HandleFunc("/api/events/", hs.handleEvent)
+	mux.HandleFunc("/api/badges", hs.handleBadges)
+	mux.HandleFunc("/api/labels", hs.handleLabels)
+	mux.HandleFunc("/api/labels/bulk", hs.handleBulkLabel)
+	mux.HandleFunc("/api/labels/remove", hs.handleRemoveLabel)
+	mux.HandleFunc("/api/labels/rename", hs.handleRenameLabel)
+	mux.HandleFunc("/api/labels/merge", hs.handleMergeLabels)
+	mux.HandleFunc("/api/stats/top", hs.handleStatsTop)
+	mux.HandleFunc("/api/stats/rare", hs.handleStatsRare)
+	mux.HandleFunc("/api/stats/books", hs.handleStatsBooks)
+	mux.HandleFunc("/api/stats/participants", hs.handleStatsParticipants)
+	mux.HandleFunc("/api/stats/weekly", hs.handleStatsWeekly)
+	mux.HandleFunc("/api/stats/heatmap", hs.handleStatsHeatmap)
 }
 
 // handleIndex serves the Mini App HTML from embedded filesystem
@@ -62,8 +127,20 @@ func (hs *HTTPServer) handleIndex(w http.ResponseWriter, r *http.Request) {
 	w.Write(content)
 }
 
-// validateTelegramInitData validates the Telegram Mini App initData
-func validateTelegramInitData(initData string, botToken string, allowedUsers map[int64]bool) (int64, error) {
+// handleOpenAPI serves the OpenAPI specification of the API; it is public so other clients can discover the API
+func (hs *HTTPServer) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(openapi.Spec)
+}
+
+// validateTelegramInitData validates the Telegram Mini App initData; auth_date must not be older than maxAge.
+// Expired initData and users who are not allowed are reported with errInitDataExpired and errUserNotAllowed.
+func validateTelegramInitData(initData string, botToken string, allowedUsers map[int64]bool, maxAge time.Duration) (int64, error) {
 	if initData == "" {
 		return 0, fmt.Errorf("missing initData")
 	}
@@ -115,16 +192,22 @@ func validateTelegramInitData(initData string, botToken string, allowedUsers map
 		return 0, fmt.Errorf("invalid hash")
 	}
 
-	// Check auth_date (data should be recent, within 24 hours)
+	// Check auth_date, so captured initData stops working after maxAge
 	authDateStr := values.Get("auth_date")
 	if authDateStr == "" {
 		return 0, fmt.Errorf("missing auth_date")
 	}
 
-	var authDate int64
-	fmt.Sscanf(authDateStr, "%d", &authDate)
-	if time.Now().Unix()-authDate > 86400 {
-		return 0, fmt.Errorf("initData is too old")
+	authDateUnix, err := strconv.ParseInt(authDateStr, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid auth_date %q", authDateStr)
+	}
+	age := time.Since(time.Unix(authDateUnix, 0))
+	if age > maxAge {
+		return 0, fmt.Errorf("%w: issued %s ago", errInitDataExpired, age.Round(time.Second))
+	}
+	if age < -maxAuthDateSkew {
+		return 0, fmt.Errorf("auth_date is in the future")
 	}
 
 	// Extract user ID
@@ -142,15 +225,33 @@ func validateTelegramInitData(initData string, botToken string, allowedUsers map
 
 	// Check if user is allowed
 	if !allowedUsers[userData.ID] {
-		return 0, fmt.Errorf("user not allowed")
+		return 0, fmt.Errorf("%w: %d", errUserNotAllowed, userData.ID)
 	}
 
 	return userData.ID, nil
 }
 
-// authMiddleware validates Telegram Mini App authentication
+// initDataUser extracts the Telegram user from already validated initData
+func initDataUser(initData string) (models.User, error) {
+	values, err := url.ParseQuery(initData)
+	if err != nil {
+		return models.User{}, fmt.Errorf("invalid initData format: %w", err)
+	}
+
+	var user models.User
+	if err := json.Unmarshal([]byte(values.Get("user")), &user); err != nil {
+		return models.User{}, fmt.Errorf("invalid user data: %w", err)
+	}
+	return user, nil
+}
+
+// authMiddleware authenticates a request with Telegram Mini App initData (Authorization: tma ...)
+// or a personal API token (Authorization: Bearer ...), then validates it against the OpenAPI specification.
+// Write requests with initData after the first of a session must carry the X-Request-Nonce the server
+// returned with the previous write, so captured requests cannot be replayed.
 // In polling mode (webhookMode=false), authentication is skipped for easier local development
 func (hs *HTTPServer) authMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	next = hs.validateRequest(next)
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Skip authentication in polling mode (local development)
 		if !hs.webhookMode {
@@ -158,57 +259,330 @@ func (hs *HTTPServer) authMiddleware(next http.HandlerFunc) http.HandlerFunc {
 				zap.String("path", r.URL.Path),
 				zap.String("remote_addr", r.RemoteAddr),
 			)
-			next(w, r)
+			next(w, r.WithContext(withActor(r.Context(), nil, storage.SourceMiniApp)))
 			return
 		}
 
 		// Extract authorization header
 		authHeader := r.Header.Get("Authorization")
+		if secret, ok := strings.CutPrefix(authHeader, "Bearer "); ok {
+			hs.tokenAuth(next, secret)(w, r)
+			return
+		}
 		if authHeader == "" || !strings.HasPrefix(authHeader, "tma ") {
 			hs.bot.logger.Warn("Missing or invalid authorization header")
-			http.Error(w, `{"error":"Unauthorized"}`, http.StatusUnauthorized)
+			hs.rejections.inc(rejectMissingCredentials)
+			writeJSONError(w, http.StatusUnauthorized, "Unauthorized")
 			return
 		}
 
 		initData := strings.TrimPrefix(authHeader, "tma ")
 
 		// Validate initData
-		userID, err := validateTelegramInitData(initData, hs.botToken, hs.bot.allowedUsers)
+		userID, err := validateTelegramInitData(initData, hs.botToken, hs.bot.allowedUsers, hs.maxInitDataAge())
 		if err != nil {
 			hs.bot.logger.Warn("Failed to validate initData",
 				zap.Error(err),
 				zap.String("remote_addr", r.RemoteAddr),
 			)
-			http.Error(w, `{"error":"Unauthorized"}`, http.StatusUnauthorized)
+			switch {
+			case errors.Is(err, errInitDataExpired):
+				hs.rejections.inc(rejectExpired)
+			case errors.Is(err, errUserNotAllowed):
+				hs.rejections.inc(rejectUserNotAllowed)
+			default:
+				hs.rejections.inc(rejectInvalidInitData)
+			}
+			writeJSONError(w, http.StatusUnauthorized, "Unauthorized")
 			return
 		}
 
+		// initData is the same for every request of a Mini App session, so every write must send
+		// the session's current nonce (see replay.go)
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			nextNonce, err := hs.replays.next(initData, r.Header.Get(nonceHeader), time.Now())
+			if err != nil {
+				hs.bot.logger.Warn("Rejected write request",
+					zap.Error(err),
+					zap.Int64("user_id", userID),
+					zap.String("method", r.Method),
+					zap.String("path", r.URL.Path),
+					zap.String("remote_addr", r.RemoteAddr),
+				)
+				switch {
+				case errors.Is(err, errReplayed):
+					hs.rejections.inc(rejectReplay)
+					writeJSONError(w, http.StatusUnauthorized, "Request was replayed or is out of date, reopen the Mini App")
+				case errors.Is(err, errNoWriteSession):
+					hs.rejections.inc(rejectNoWriteSession)
+					writeJSONError(w, http.StatusUnauthorized, "Write session was not started, reopen the Mini App")
+				default:
+					writeJSONError(w, http.StatusInternalServerError, "Internal server error")
+				}
+				return
+			}
+			w.Header().Set(nextNonceHeader, nextNonce)
+		}
+
 		hs.bot.logger.Debug("Authenticated request",
 			zap.Int64("user_id", userID),
 			zap.String("path", r.URL.Path),
 		)
 
+		// The validated user is the actor of notifications and audit entries
+		user, err := initDataUser(initData)
+		if err != nil {
+			user = models.User{ID: userID}
+		}
+		r = r.WithContext(withActor(r.Context(), &user, storage.SourceMiniApp))
+
 		next(w, r)
 	}
 }
 
+// tokenAuth authenticates a request made with a personal API token; read-only tokens may only GET
+func (hs *HTTPServer) tokenAuth(next http.HandlerFunc, secret string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, err := hs.bot.authenticateAPIToken(r.Context(), strings.TrimSpace(secret))
+		if err != nil {
+			hs.bot.logger.Warn("Failed to validate API token",
+				zap.Error(err),
+				zap.String("remote_addr", r.RemoteAddr),
+			)
+			hs.rejections.inc(rejectInvalidToken)
+			writeJSONError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		if !tokenAllows(token, r.Method) {
+			hs.bot.logger.Warn("API token scope does not allow request",
+				zap.String("token", token.Name),
+				zap.String("scope", token.Scope),
+				zap.String("method", r.Method),
+				zap.String("path", r.URL.Path),
+			)
+			hs.rejections.inc(rejectTokenReadOnly)
+			writeJSONError(w, http.StatusForbidden, "Token is read-only")
+			return
+		}
+
+		hs.bot.logger.Debug("Authenticated request with API token",
+			zap.Int64("user_id", token.OwnerID),
+			zap.String("token", token.Name),
+			zap.String("path", r.URL.Path),
+		)
+
+		// Changes are attributed to the owner of the token
+		user := models.User{ID: token.OwnerID, FirstName: "token " + token.Name}
+		next(w, r.WithContext(withActor(r.Context(), &user, storage.SourceAPIToken)))
+	}
+}
+
+// requestSource names where an API request came from in chat notifications
+func requestSource(ctx context.Context) string {
+	if storage.ActorFromContext(ctx).Source == storage.SourceAPIToken {
+		return "API token"
+	}
+	return "Mini App"
+}
+
+// validateRequest rejects requests whose query parameters or body do not match the OpenAPI specification
+func (hs *HTTPServer) validateRequest(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Also bounds the bodies of operations the specification does not describe
+		if r.Body != nil {
+			r.Body = http.MaxBytesReader(w, r.Body, openapi.MaxBodyBytes)
+		}
+		if err := openapi.Validate(r); err != nil {
+			hs.bot.logger.Warn("Invalid API request",
+				zap.Error(err),
+				zap.String("method", r.Method),
+				zap.String("path", r.URL.Path),
+			)
+			status := http.StatusBadRequest
+			if errors.Is(err, openapi.ErrBodyTooLarge) {
+				status = http.StatusRequestEntityTooLarge
+			}
+			writeJSONError(w, status, "Invalid request: "+err.Error())
+			return
+		}
+		next(w, r)
+	}
+}
+
+// deprecatedFieldNames maps the snake_case request fields used before the API switched to
+// camelCase to their current names. They are documented as deprecated in the OpenAPI
+// specification and accepted until the next release.
+var deprecatedFieldNames = map[string]string{
+	"book_id":           "bookId",
+	"book_ids":          "bookIds",
+	"book_name":         "bookName",
+	"duration_minutes":  "durationMinutes",
+	"new_label":         "newLabel",
+	"participant_id":    "participantId",
+	"participant_ids":   "participantIds",
+	"participant_name":  "participantName",
+	"participant_names": "participantNames",
+	"progress_unit":     "progressUnit",
+}
+
+// decodeRequest decodes a JSON request body into v. Deprecated snake_case field names are
+// renamed to their camelCase names first; if both are sent, the camelCase field wins.
+func (hs *HTTPServer) decodeRequest(r *http.Request, v any) error {
+	var fields map[string]json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&fields); err != nil {
+		return err
+	}
+
+	var deprecated []string
+	for old, name := range deprecatedFieldNames {
+		value, ok := fields[old]
+		if !ok {
+			continue
+		}
+		deprecated = append(deprecated, old)
+		delete(fields, old)
+		if _, ok := fields[name]; !ok {
+			fields[name] = value
+		}
+	}
+	if len(deprecated) > 0 {
+		slices.Sort(deprecated)
+		hs.bot.logger.Warn("Deprecated request fields",
+			zap.Strings("fields", deprecated),
+			zap.String("path", r.URL.Path),
+		)
+	}
+
+	body, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, v)
+}
+
+// maxQueryLimit caps the limit query parameter of list endpoints
+const maxQueryLimit = 500
+
+// writeJSON writes v as a JSON response with the given status code
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeJSONError writes an {"error": message} response; every API error uses this shape
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
+
+// queryDateRange parses the optional since and until query parameters (YYYY-MM-DD in the bot's timezone).
+// Both bounds are inclusive days; a missing bound is returned as zero time.
+func (hs *HTTPServer) queryDateRange(r *http.Request) (since, until time.Time, err error) {
+	query := r.URL.Query()
+	if s := query.Get("since"); s != "" {
+		since, err = time.ParseInLocation("2006-01-02", s, hs.bot.loc())
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("since must be a date in YYYY-MM-DD format")
+		}
+	}
+	if s := query.Get("until"); s != "" {
+		until, err = time.ParseInLocation("2006-01-02", s, hs.bot.loc())
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("until must be a date in YYYY-MM-DD format")
+		}
+		until = until.AddDate(0, 0, 1).Add(-time.Second)
+	}
+	return since, until, nil
+}
+
+// queryLimit parses the optional limit query parameter, between 1 and maxQueryLimit
+func queryLimit(r *http.Request, defaultLimit int) (int, error) {
+	s := r.URL.Query().Get("limit")
+	if s == "" {
+		return defaultLimit, nil
+	}
+	limit, err := strconv.Atoi(s)
+	if err != nil || limit < 1 || limit > maxQueryLimit {
+		return 0, fmt.Errorf("limit must be a number from 1 to %d", maxQueryLimit)
+	}
+	return limit, nil
+}
+
 // handleBooks returns the list of readable books
 func (hs *HTTPServer) handleBooks(w http.ResponseWriter, r *http.Request) {
 	hs.authMiddleware(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
-			http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+			writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
 			return
 		}
 
 		books, err := hs.bot.db.ListReadableBooks(r.Context())
 		if err != nil {
 			hs.bot.logger.Error("Failed to list books", zap.Error(err))
-			http.Error(w, `{"error":"Failed to fetch books"}`, http.StatusInternalServerError)
+			writeJSONError(w, http.StatusInternalServerError, "Failed to fetch books")
+			return
+		}
+
+		writeJSON(w, http.StatusOK, books)
+	})(w, r)
+}
+
+// handleBookCover serves the cover of a book (?id=<book ID>). Image URLs are redirected to;
+// Telegram photos are downloaded through the Bot API so the bot token never reaches the Mini App.
+func (hs *HTTPServer) handleBookCover(w http.ResponseWriter, r *http.Request) {
+	hs.authMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		books, err := hs.bot.listAllBooks(r.Context())
+		if err != nil {
+			hs.bot.logger.Error("Failed to list books", zap.Error(err))
+			writeJSONError(w, http.StatusInternalServerError, "Failed to fetch cover")
+			return
+		}
+		id := r.URL.Query().Get("id")
+		i := slices.IndexFunc(books, func(book libmodels.Book) bool { return book.ID == id })
+		if i < 0 || books[i].Cover == "" || (hs.bot.api == nil && !isCoverURL(books[i].Cover)) {
+			writeJSONError(w, http.StatusNotFound, "Cover not found")
+			return
+		}
+
+		cover := books[i].Cover
+		if isCoverURL(cover) {
+			http.Redirect(w, r, cover, http.StatusFound)
+			return
+		}
+
+		file, err := hs.bot.api.GetFile(r.Context(), &tgbot.GetFileParams{FileID: cover})
+		if err != nil {
+			hs.bot.logger.Error("Failed to get cover file", zap.Error(err), zap.String("book", books[i].Name))
+			writeJSONError(w, http.StatusBadGateway, "Failed to fetch cover")
+			return
+		}
+		req, err := http.NewRequestWithContext(r.Context(), http.MethodGet, hs.bot.api.FileDownloadLink(file), nil)
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "Failed to fetch cover")
+			return
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			hs.bot.logger.Error("Failed to download cover", zap.Error(err), zap.String("book", books[i].Name))
+			writeJSONError(w, http.StatusBadGateway, "Failed to fetch cover")
+			return
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			hs.bot.logger.Error("Failed to download cover", zap.String("status", resp.Status), zap.String("book", books[i].Name))
+			writeJSONError(w, http.StatusBadGateway, "Failed to fetch cover")
 			return
 		}
 
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(books)
+		// Telegram stores photos as JPEG but serves files as application/octet-stream
+		w.Header().Set("Content-Type", "image/jpeg")
+		w.Header().Set("Cache-Control", "private, max-age=86400")
+		io.Copy(w, resp.Body)
 	})(w, r)
 }
 
@@ -216,89 +590,628 @@ func (hs *HTTPServer) handleBooks(w http.ResponseWriter, r *http.Request) {
 func (hs *HTTPServer) handleParticipants(w http.ResponseWriter, r *http.Request) {
 	hs.authMiddleware(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
-			http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+			writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
 			return
 		}
 
 		participants, err := hs.bot.db.ListParticipants(r.Context())
 		if err != nil {
 			hs.bot.logger.Error("Failed to list participants", zap.Error(err))
-			http.Error(w, `{"error":"Failed to fetch participants"}`, http.StatusInternalServerError)
+			writeJSONError(w, http.StatusInternalServerError, "Failed to fetch participants")
 			return
 		}
 
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(participants)
+		writeJSON(w, http.StatusOK, participants)
 	})(w, r)
 }
 
-// CreateEventRequest represents the request body for creating an event
-type CreateEventRequest struct {
-	Date            string `json:"date"`
-	BookName        string `json:"book_name"`
-	ParticipantName string `json:"participant_name"`
+// BulkLabelRequest names a label and the books to add it to or remove it from
+type BulkLabelRequest struct {
+	Label   string   `json:"label"`
+	BookIDs []string `json:"bookIds"`
 }
 
-// handleEvents creates a new reading event
-func (hs *HTTPServer) handleEvents(w http.ResponseWriter, r *http.Request) {
+// handleBulkLabel adds a label to all requested books in one storage call
+func (hs *HTTPServer) handleBulkLabel(w http.ResponseWriter, r *http.Request) {
 	hs.authMiddleware(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
-			http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+			writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
 			return
 		}
 
-		var req CreateEventRequest
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		var req BulkLabelRequest
+		if err := hs.decodeRequest(r, &req); err != nil {
 			hs.bot.logger.Warn("Failed to decode request body", zap.Error(err))
-			http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+			writeJSONError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		if strings.TrimSpace(req.Label) == "" || len(req.BookIDs) == 0 {
+			writeJSONError(w, http.StatusBadRequest, "Missing required fields")
+			return
+		}
+		label, err := labels.Normalize(req.Label)
+		if err != nil {
+			hs.bot.logger.Warn("Invalid label", zap.Error(err))
+			writeJSONError(w, http.StatusBadRequest, "Invalid label")
+			return
+		}
+		req.Label = label
+
+		books, err := hs.bot.listAllBooks(r.Context())
+		if err != nil {
+			hs.bot.logger.Error("Failed to list books", zap.Error(err))
+			writeJSONError(w, http.StatusInternalServerError, "Failed to add label")
+			return
+		}
+		var names []string
+		for _, id := range req.BookIDs {
+			i := slices.IndexFunc(books, func(book libmodels.Book) bool { return book.ID == id })
+			if i < 0 {
+				writeJSONError(w, http.StatusBadRequest, "Unknown book")
+				return
+			}
+			names = append(names, books[i].Name)
+		}
+
+		if err := hs.bot.db.AddLabelToBooks(r.Context(), req.BookIDs, req.Label); err != nil {
+			hs.bot.logger.Error("Failed to add label to books",
+				zap.Error(err),
+				zap.String("label", req.Label),
+				zap.Strings("books", names),
+			)
+			writeJSONError(w, http.StatusInternalServerError, "Failed to add label")
+			return
+		}
+
+		hs.bot.logger.Info("Label added via Mini App",
+			zap.String("label", req.Label),
+			zap.Strings("books", names),
+		)
+		hs.bot.notifyLabelAdded(r.Context(), names, req.Label, NotificationRoute{})
+
+		writeJSON(w, http.StatusOK, map[string]any{
+			"status": "success",
+			"count":  len(req.BookIDs),
+		})
+	})(w, r)
+}
+
+// handleLabels returns every label used by a book, in alphabetical order
+func (hs *HTTPServer) handleLabels(w http.ResponseWriter, r *http.Request) {
+	hs.authMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		allLabels, err := hs.bot.db.GetAllLabels(r.Context())
+		if err != nil {
+			hs.bot.logger.Error("Failed to get labels", zap.Error(err))
+			writeJSONError(w, http.StatusInternalServerError, "Failed to fetch labels")
+			return
+		}
+		if allLabels == nil {
+			allLabels = []string{}
+		}
+		writeJSON(w, http.StatusOK, allLabels)
+	})(w, r)
+}
+
+// handleRemoveLabel removes a label from the requested books; books without it are unchanged
+func (hs *HTTPServer) handleRemoveLabel(w http.ResponseWriter, r *http.Request) {
+	hs.authMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
 			return
 		}
 
-		// Validate request
-		if req.Date == "" || req.BookName == "" || req.ParticipantName == "" {
-			http.Error(w, `{"error":"Missing required fields"}`, http.StatusBadRequest)
+		var req BulkLabelRequest
+		if err := hs.decodeRequest(r, &req); err != nil {
+			hs.bot.logger.Warn("Failed to decode request body", zap.Error(err))
+			writeJSONError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		// The label is matched as stored, so labels saved before normalization can still be removed
+		req.Label = strings.TrimSpace(req.Label)
+		if req.Label == "" || len(req.BookIDs) == 0 {
+			writeJSONError(w, http.StatusBadRequest, "Missing required fields")
 			return
 		}
 
-		// Parse date
-		date, err := time.Parse("2006-01-02", req.Date)
+		// Resolve every book before changing any, so an unknown ID leaves all books untouched
+		books, err := hs.bot.listAllBooks(r.Context())
 		if err != nil {
-			hs.bot.logger.Warn("Failed to parse date",
+			hs.bot.logger.Error("Failed to list books", zap.Error(err))
+			writeJSONError(w, http.StatusInternalServerError, "Failed to remove label")
+			return
+		}
+		var names []string
+		for _, id := range req.BookIDs {
+			i := slices.IndexFunc(books, func(book libmodels.Book) bool { return book.ID == id })
+			if i < 0 {
+				writeJSONError(w, http.StatusBadRequest, "Unknown book")
+				return
+			}
+			names = append(names, books[i].Name)
+		}
+
+		if err := hs.bot.db.RemoveLabelFromBooks(r.Context(), req.BookIDs, req.Label); err != nil {
+			hs.bot.logger.Error("Failed to remove label from books",
+				zap.Error(err),
+				zap.String("label", req.Label),
+				zap.Strings("books", names),
+			)
+			writeJSONError(w, http.StatusInternalServerError, "Failed to remove label")
+			return
+		}
+
+		hs.bot.logger.Info("Label removed via Mini App",
+			zap.String("label", req.Label),
+			zap.Strings("books", names),
+		)
+		writeJSON(w, http.StatusOK, map[string]any{
+			"status": "success",
+			"count":  len(req.BookIDs),
+		})
+	})(w, r)
+}
+
+// RenameLabelRequest renames a label on every book
+type RenameLabelRequest struct {
+	Label    string `json:"label"`
+	NewLabel string `json:"newLabel"`
+}
+
+// handleRenameLabel renames a label on every book; renaming onto an existing label is a conflict (merge instead)
+func (hs *HTTPServer) handleRenameLabel(w http.ResponseWriter, r *http.Request) {
+	hs.authMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		var req RenameLabelRequest
+		if err := hs.decodeRequest(r, &req); err != nil {
+			hs.bot.logger.Warn("Failed to decode request body", zap.Error(err))
+			writeJSONError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		if req.Label == "" || strings.TrimSpace(req.NewLabel) == "" {
+			writeJSONError(w, http.StatusBadRequest, "Missing required fields")
+			return
+		}
+		newLabel, err := labels.Normalize(req.NewLabel)
+		if err != nil {
+			hs.bot.logger.Warn("Invalid label", zap.Error(err))
+			writeJSONError(w, http.StatusBadRequest, "Invalid label")
+			return
+		}
+
+		allLabels, err := hs.bot.db.GetAllLabels(r.Context())
+		if err != nil {
+			hs.bot.logger.Error("Failed to get labels", zap.Error(err))
+			writeJSONError(w, http.StatusInternalServerError, "Failed to rename label")
+			return
+		}
+		if slices.Contains(allLabels, newLabel) {
+			writeJSONError(w, http.StatusConflict, "Label already exists")
+			return
+		}
+
+		err = hs.bot.db.RenameLabel(r.Context(), req.Label, newLabel)
+		if errors.Is(err, storage.ErrNotFound) {
+			writeJSONError(w, http.StatusNotFound, "Label not found")
+			return
+		}
+		if err != nil {
+			hs.bot.logger.Error("Failed to rename label",
 				zap.Error(err),
-				zap.String("date", req.Date),
+				zap.String("label", req.Label),
+				zap.String("new_name", newLabel),
 			)
-			http.Error(w, `{"error":"Invalid date format"}`, http.StatusBadRequest)
+			writeJSONError(w, http.StatusInternalServerError, "Failed to rename label")
+			return
+		}
+
+		hs.bot.logger.Info("Label renamed via Mini App",
+			zap.String("label", req.Label),
+			zap.String("new_name", newLabel),
+		)
+		writeJSON(w, http.StatusOK, map[string]string{
+			"status": "success",
+			"label":  newLabel,
+		})
+	})(w, r)
+}
+
+// MergeLabelsRequest replaces several labels with a target label on every book
+type MergeLabelsRequest struct {
+	Labels []string `json:"labels"`
+	Target string   `json:"target"`
+}
+
+// handleMergeLabels merges the requested labels into the target label
+func (hs *HTTPServer) handleMergeLabels(w http.ResponseWriter, r *http.Request) {
+	hs.authMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		var req MergeLabelsRequest
+		if err := hs.decodeRequest(r, &req); err != nil {
+			hs.bot.logger.Warn("Failed to decode request body", zap.Error(err))
+			writeJSONError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		for i, label := range req.Labels {
+			req.Labels[i] = strings.TrimSpace(label)
+		}
+		if len(req.Labels) == 0 || slices.Contains(req.Labels, "") || strings.TrimSpace(req.Target) == "" {
+			writeJSONError(w, http.StatusBadRequest, "Missing required fields")
+			return
+		}
+		target, err := labels.Normalize(req.Target)
+		if err != nil {
+			hs.bot.logger.Warn("Invalid label", zap.Error(err))
+			writeJSONError(w, http.StatusBadRequest, "Invalid label")
 			return
 		}
 
-		// Create event
-		err = hs.bot.db.CreateEvent(r.Context(), date, req.BookName, req.ParticipantName)
+		err = hs.bot.db.MergeLabels(r.Context(), req.Labels, target)
+		if errors.Is(err, storage.ErrNotFound) {
+			writeJSONError(w, http.StatusNotFound, "Label not found")
+			return
+		}
 		if err != nil {
-			hs.bot.logger.Error("Failed to create event",
+			hs.bot.logger.Error("Failed to merge labels",
 				zap.Error(err),
-				zap.String("book", req.BookName),
-				zap.String("participant", req.ParticipantName),
+				zap.Strings("labels", req.Labels),
+				zap.String("target", target),
 			)
-			http.Error(w, `{"error":"Failed to create event"}`, http.StatusInternalServerError)
+			writeJSONError(w, http.StatusInternalServerError, "Failed to merge labels")
+			return
+		}
+
+		hs.bot.logger.Info("Labels merged via Mini App",
+			zap.Strings("labels", req.Labels),
+			zap.String("target", target),
+		)
+		writeJSON(w, http.StatusOK, map[string]string{
+			"status": "success",
+			"label":  target,
+		})
+	})(w, r)
+}
+
+// BadgeResponse is an unlocked badge with its display definition
+type BadgeResponse struct {
+	ParticipantID   string    `json:"participantId"`
+	ParticipantName string    `json:"participantName"`
+	Key             string    `json:"key"`
+	Emoji           string    `json:"emoji"`
+	Name            string    `json:"name"`
+	Description     string    `json:"description"`
+	UnlockedAt      time.Time `json:"unlockedAt"`
+}
+
+// handleBadges returns unlocked badges, optionally for one participant (?participant=<name>)
+func (hs *HTTPServer) handleBadges(w http.ResponseWriter, r *http.Request) {
+	hs.authMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		participantID := ""
+		if name := r.URL.Query().Get("participant"); name != "" {
+			participant, err := hs.bot.db.GetParticipantByName(r.Context(), name)
+			if errors.Is(err, storage.ErrNotFound) {
+				writeJSONError(w, http.StatusNotFound, "Participant not found")
+				return
+			}
+			if err != nil {
+				hs.bot.logger.Error("Failed to look up participant", zap.Error(err))
+				writeJSONError(w, http.StatusInternalServerError, "Failed to fetch badges")
+				return
+			}
+			participantID = participant.ID
+		}
+
+		badges, err := hs.bot.db.ListBadges(r.Context(), participantID)
+		if err != nil {
+			hs.bot.logger.Error("Failed to list badges", zap.Error(err))
+			writeJSONError(w, http.StatusInternalServerError, "Failed to fetch badges")
 			return
 		}
 
-		hs.bot.logger.Info("Event created via Mini App",
+		response := make([]BadgeResponse, 0, len(badges))
+		for _, badge := range badges {
+			def := achievements.Describe(badge.Key)
+			response = append(response, BadgeResponse{
+				ParticipantID:   badge.ParticipantID,
+				ParticipantName: badge.ParticipantName,
+				Key:             badge.Key,
+				Emoji:           def.Emoji,
+				Name:            def.Name,
+				Description:     def.Description,
+				UnlockedAt:      badge.UnlockedAt,
+			})
+		}
+
+		writeJSON(w, http.StatusOK, response)
+	})(w, r)
+}
+
+// CreateEventRequest represents the request body for creating an event.
+// Book and participants may be given either by ID or by name; IDs take precedence.
+// The single participantId/participantName fields are deprecated in favour of the lists.
+// Snake_case field names are accepted as deprecated aliases, see deprecatedFieldNames.
+type CreateEventRequest struct {
+	Date             string   `json:"date"`
+	Time             string   `json:"time"` // Optional "HH:MM"; defaults to the current time of day
+	BookID           string   `json:"bookId"`
+	BookName         string   `json:"bookName"`
+	ParticipantIDs   []string `json:"participantIds"`
+	ParticipantNames []string `json:"participantNames"`
+	ParticipantID    string   `json:"participantId"`
+	ParticipantName  string   `json:"participantName"`
+	DurationMinutes  int      `json:"durationMinutes"` // Optional session length
+	Progress         int      `json:"progress"`        // Optional amount read, in ProgressUnit
+	ProgressUnit     string   `json:"progressUnit"`    // "pages" or "chapters"; required when Progress is set
+	Force            bool     `json:"force"`           // Record even if the same reading already exists for that day
+}
+
+// resolveEventRefs fills in book and participant IDs and names for the request.
+// Names are looked up when IDs are missing, and vice versa. Retired books and archived
+// participants cannot get new events: retired books are rejected with errBookRetired and
+// archived participants with errParticipantArchived by name, and are not found by ID as
+// only active participants are listed.
+func (hs *HTTPServer) resolveEventRefs(ctx context.Context, req *CreateEventRequest) error {
+	var book libmodels.Book
+	var err error
+	if req.BookID == "" {
+		book, err = hs.bot.db.GetBookByName(ctx, req.BookName)
+	} else {
+		book, err = hs.bot.findBookByID(ctx, req.BookID)
+	}
+	if err != nil {
+		return err
+	}
+	if !book.IsReadable {
+		return fmt.Errorf("book %q: %w", book.Name, errBookRetired)
+	}
+	req.BookID, req.BookName = book.ID, book.Name
+
+	if req.ParticipantID != "" {
+		req.ParticipantIDs = append(req.ParticipantIDs, req.ParticipantID)
+	}
+	if req.ParticipantName != "" {
+		req.ParticipantNames = append(req.ParticipantNames, req.ParticipantName)
+	}
+
+	if len(req.ParticipantIDs) == 0 {
+		for _, name := range req.ParticipantNames {
+			participant, err := hs.bot.db.GetParticipantByName(ctx, name)
+			if err != nil {
+				return err
+			}
+			if participant.IsArchived {
+				return fmt.Errorf("participant %q: %w", name, errParticipantArchived)
+			}
+			req.ParticipantIDs = append(req.ParticipantIDs, participant.ID)
+		}
+	} else {
+		req.ParticipantNames = nil
+		for _, id := range req.ParticipantIDs {
+			participant, err := hs.bot.findParticipantByID(ctx, id)
+			if err != nil {
+				return err
+			}
+			req.ParticipantNames = append(req.ParticipantNames, participant.Name)
+		}
+	}
+	return nil
+}
+
+// handleEvents lists reading events (GET) or creates a new one (POST)
+func (hs *HTTPServer) handleEvents(w http.ResponseWriter, r *http.Request) {
+	hs.authMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			hs.listEvents(w, r)
+		case http.MethodPost:
+			hs.createEvent(w, r)
+		default:
+			writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		}
+	})(w, r)
+}
+
+// listEvents returns the most recent events, newest first.
+// Optional query parameters: since and until (YYYY-MM-DD, inclusive), participant (name) and limit.
+func (hs *HTTPServer) listEvents(w http.ResponseWriter, r *http.Request) {
+	since, until, err := hs.queryDateRange(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid query: "+err.Error())
+		return
+	}
+	limit, err := queryLimit(r, 50)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid query: "+err.Error())
+		return
+	}
+
+	events, err := hs.bot.db.GetLastEventsFiltered(r.Context(), limit, since, until, r.URL.Query().Get("participant"))
+	if err != nil {
+		hs.bot.logger.Error("Failed to list events", zap.Error(err))
+		writeJSONError(w, http.StatusInternalServerError, "Failed to fetch events")
+		return
+	}
+	if events == nil {
+		events = []libmodels.Event{}
+	}
+	writeJSON(w, http.StatusOK, events)
+}
+
+// createEvent records a new reading event
+func (hs *HTTPServer) createEvent(w http.ResponseWriter, r *http.Request) {
+	var req CreateEventRequest
+	if err := hs.decodeRequest(r, &req); err != nil {
+		hs.bot.logger.Warn("Failed to decode request body", zap.Error(err))
+		writeJSONError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	// Validate request
+	hasParticipants := req.ParticipantID != "" || req.ParticipantName != "" ||
+		len(req.ParticipantIDs) > 0 || len(req.ParticipantNames) > 0
+	if req.Date == "" || (req.BookID == "" && req.BookName == "") || !hasParticipants {
+		writeJSONError(w, http.StatusBadRequest, "Missing required fields")
+		return
+	}
+
+	// Parse date and optional time in the configured timezone
+	date, hasTime, err := parseEventDateTime(req.Date+" "+req.Time, hs.bot.loc())
+	if err != nil {
+		hs.bot.logger.Warn("Failed to parse date",
+			zap.Error(err),
 			zap.String("date", req.Date),
+			zap.String("time", req.Time),
+		)
+		writeJSONError(w, http.StatusBadRequest, "Invalid date format")
+		return
+	}
+	if !hasTime {
+		date = withTimeOfDay(date, hs.bot.now())
+	}
+
+	// Validate optional session details
+	validUnit := req.ProgressUnit == libmodels.ProgressPages || req.ProgressUnit == libmodels.ProgressChapters
+	if req.DurationMinutes < 0 || req.Progress < 0 || (req.Progress > 0 && !validUnit) {
+		writeJSONError(w, http.StatusBadRequest, "Invalid duration or progress")
+		return
+	}
+	if req.Progress == 0 {
+		req.ProgressUnit = ""
+	}
+
+	if err := hs.resolveEventRefs(r.Context(), &req); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			writeJSONError(w, http.StatusBadRequest, "Unknown book or participant")
+			return
+		}
+		if errors.Is(err, errParticipantArchived) {
+			writeJSONError(w, http.StatusBadRequest, "Participant is archived")
+			return
+		}
+		if errors.Is(err, errBookRetired) {
+			writeJSONError(w, http.StatusBadRequest, "Book is retired")
+			return
+		}
+		hs.bot.logger.Error("Failed to resolve event references", zap.Error(err))
+		writeJSONError(w, http.StatusInternalServerError, "Failed to create event")
+		return
+	}
+
+	// Reject duplicates (same book, reader and day) unless explicitly confirmed
+	if !req.Force {
+		_, err := hs.bot.db.FindEventOnDay(r.Context(), date, req.BookID, req.ParticipantIDs)
+		if err == nil {
+			writeJSON(w, http.StatusConflict, map[string]any{"error": "Duplicate event", "duplicate": true})
+			return
+		}
+		if !errors.Is(err, storage.ErrNotFound) {
+			hs.bot.logger.Error("Failed to check for duplicate event", zap.Error(err))
+			writeJSONError(w, http.StatusInternalServerError, "Failed to create event")
+			return
+		}
+	}
+
+	// Create event
+	eventID, err := hs.bot.db.CreateEvent(r.Context(), libmodels.Event{
+		Date:            date,
+		BookID:          req.BookID,
+		ParticipantIDs:  req.ParticipantIDs,
+		DurationMinutes: req.DurationMinutes,
+		Progress:        req.Progress,
+		ProgressUnit:    req.ProgressUnit,
+	})
+	if err != nil {
+		hs.bot.logger.Error("Failed to create event",
+			zap.Error(err),
 			zap.String("book", req.BookName),
-			zap.String("participant", req.ParticipantName),
+			zap.Strings("participants", req.ParticipantNames),
 		)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to create event")
+		return
+	}
+
+	hs.bot.logger.Info("Event created via Mini App",
+		zap.String("event_id", eventID),
+		zap.String("date", req.Date),
+		zap.String("book", req.BookName),
+		zap.Strings("participants", req.ParticipantNames),
+	)
+
+	hs.bot.notifyEvent(r.Context(), libmodels.Event{
+		Date:             date,
+		BookName:         req.BookName,
+		ParticipantNames: req.ParticipantNames,
+		DurationMinutes:  req.DurationMinutes,
+		Progress:         req.Progress,
+		ProgressUnit:     req.ProgressUnit,
+	}, false, requestSource(r.Context()), NotificationRoute{})
+	hs.bot.celebrate(r.Context(), req.ParticipantIDs, date)
+	hs.bot.awardBadges(r.Context(), eventID, hs.bot.notificationChatID, hs.bot.notificationThreadID)
+
+	writeJSON(w, http.StatusCreated, map[string]string{
+		"status": "success",
+		"id":     eventID,
+	})
+}
+
+// handleEvent deletes a single reading event (used by the Mini App undo button)
+func (hs *HTTPServer) handleEvent(w http.ResponseWriter, r *http.Request) {
+	hs.authMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		eventID := strings.TrimPrefix(r.URL.Path, "/api/events/")
+		if eventID == "" || strings.Contains(eventID, "/") {
+			writeJSONError(w, http.StatusNotFound, "Event not found")
+			return
+		}
 
-		// Send notification to configured chat
-		if hs.bot.notificationChatID != 0 {
-			notificationText := fmt.Sprintf("New reading event!\n\nDate: %s\nBook: %s\nReader: %s",
-				date.Format("2006-01-02"), req.BookName, req.ParticipantName)
-			hs.bot.sendMessageInThread(r.Context(), hs.bot.notificationChatID, notificationText, hs.bot.notificationThreadID)
+		event, err := hs.bot.db.GetEvent(r.Context(), eventID)
+		if err == nil {
+			err = hs.bot.db.DeleteEvent(r.Context(), eventID)
+		}
+		if errors.Is(err, storage.ErrNotFound) {
+			writeJSONError(w, http.StatusNotFound, "Event not found")
+			return
 		}
+		if err != nil {
+			hs.bot.logger.Error("Failed to delete event",
+				zap.Error(err),
+				zap.String("event_id", eventID),
+			)
+			writeJSONError(w, http.StatusInternalServerError, "Failed to delete event")
+			return
+		}
+
+		hs.bot.logger.Info("Event deleted via Mini App",
+			zap.String("event_id", eventID),
+			zap.String("book", event.BookName),
+			zap.Strings("participants", event.ParticipantNames),
+		)
+
+		// Let the chat know the earlier notification no longer applies
+		hs.bot.notifyEvent(r.Context(), event, true, requestSource(r.Context()), NotificationRoute{})
 
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusCreated)
-		json.NewEncoder(w).Encode(map[string]string{
+		writeJSON(w, http.StatusOK, map[string]string{
 			"status": "success",
 		})
 	})(w, r)
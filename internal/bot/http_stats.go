@@ -0,0 +1,280 @@
+package bot
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+	"library/internal/labels"
+	libmodels "library/internal/models"
+)
+
+// handleStatsTop returns the most read books.
+// Query parameters: since, until, participant (default: all children) and limit (default 10).
+func (hs *HTTPServer) handleStatsTop(w http.ResponseWriter, r *http.Request) {
+	hs.authMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		since, until, err := hs.queryDateRange(r)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, "Invalid query: "+err.Error())
+			return
+		}
+		limit, err := queryLimit(r, 10)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, "Invalid query: "+err.Error())
+			return
+		}
+		if until.IsZero() {
+			until = hs.bot.now()
+		}
+
+		stats, err := hs.bot.db.GetTopBooks(r.Context(), limit, since, until, r.URL.Query().Get("participant"))
+		if err != nil {
+			hs.bot.logger.Error("Failed to get top books", zap.Error(err))
+			writeJSONError(w, http.StatusInternalServerError, "Failed to fetch statistics")
+			return
+		}
+		if stats == nil {
+			stats = []libmodels.BookStat{}
+		}
+		writeJSON(w, http.StatusOK, stats)
+	})(w, r)
+}
+
+// handleStatsRare returns the books that were not read for the longest time.
+// Query parameters: filter (label filter expression), children=false to count reads by parents too, and limit (default 10).
+func (hs *HTTPServer) handleStatsRare(w http.ResponseWriter, r *http.Request) {
+	hs.authMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		query := r.URL.Query()
+		limit, err := queryLimit(r, 10)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, "Invalid query: "+err.Error())
+			return
+		}
+		filter, err := labels.Parse(query.Get("filter"))
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, "Invalid label filter: "+err.Error())
+			return
+		}
+		childrenOnly := query.Get("children") != "false"
+
+		stats, err := hs.bot.db.GetRarelyReadBooks(r.Context(), limit, childrenOnly, filter)
+		if err != nil {
+			hs.bot.logger.Error("Failed to get rarely read books", zap.Error(err))
+			writeJSONError(w, http.StatusInternalServerError, "Failed to fetch statistics")
+			return
+		}
+		if stats == nil {
+			stats = []libmodels.RareBookStat{}
+		}
+		writeJSON(w, http.StatusOK, stats)
+	})(w, r)
+}
+
+// handleStatsBooks returns per-participant read counts for every book.
+// Query parameters: since, until, book and participant; all optional.
+func (hs *HTTPServer) handleStatsBooks(w http.ResponseWriter, r *http.Request) {
+	hs.authMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		since, until, err := hs.queryDateRange(r)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, "Invalid query: "+err.Error())
+			return
+		}
+
+		query := r.URL.Query()
+		stats, err := hs.bot.db.GetDetailedBookStats(r.Context(), since, until, query.Get("book"), query.Get("participant"))
+		if err != nil {
+			hs.bot.logger.Error("Failed to get book stats", zap.Error(err))
+			writeJSONError(w, http.StatusInternalServerError, "Failed to fetch statistics")
+			return
+		}
+		if stats == nil {
+			stats = []libmodels.DetailedBookStat{}
+		}
+		writeJSON(w, http.StatusOK, stats)
+	})(w, r)
+}
+
+// handleStatsParticipants returns per-book read counts for every participant.
+// Query parameters: since, until, book and participant; all optional.
+func (hs *HTTPServer) handleStatsParticipants(w http.ResponseWriter, r *http.Request) {
+	hs.authMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		since, until, err := hs.queryDateRange(r)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, "Invalid query: "+err.Error())
+			return
+		}
+
+		query := r.URL.Query()
+		stats, err := hs.bot.db.GetParticipantStats(r.Context(), since, until, query.Get("book"), query.Get("participant"))
+		if err != nil {
+			hs.bot.logger.Error("Failed to get participant stats", zap.Error(err))
+			writeJSONError(w, http.StatusInternalServerError, "Failed to fetch statistics")
+			return
+		}
+		if stats == nil {
+			stats = []libmodels.ParticipantBookStat{}
+		}
+		writeJSON(w, http.StatusOK, stats)
+	})(w, r)
+}
+
+// WeeklyStatsResponse holds reads per week for every child, ready to be charted
+type WeeklyStatsResponse struct {
+	Weeks   []string             `json:"weeks"` // Mondays (YYYY-MM-DD), oldest first
+	Readers []WeeklyReaderCounts `json:"readers"`
+}
+
+// WeeklyReaderCounts are a child's reads, index-aligned with WeeklyStatsResponse.Weeks
+type WeeklyReaderCounts struct {
+	ParticipantID   string `json:"participantId"`
+	ParticipantName string `json:"participantName"`
+	Counts          []int  `json:"counts"`
+}
+
+// handleStatsWeekly returns reads per week for every child over the last weeks (?weeks=, default 12),
+// including the current one. Weeks without reads are zero.
+func (hs *HTTPServer) handleStatsWeekly(w http.ResponseWriter, r *http.Request) {
+	hs.authMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		weekCount := 12
+		if s := r.URL.Query().Get("weeks"); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil || n < 1 || n > 104 {
+				writeJSONError(w, http.StatusBadRequest, "Invalid query: weeks must be a number from 1 to 104")
+				return
+			}
+			weekCount = n
+		}
+
+		now := hs.bot.now()
+		first := startOfWeek(now).AddDate(0, 0, -7*(weekCount-1))
+		stats, err := hs.bot.db.GetWeeklyReaderStats(r.Context(), first, now, hs.bot.loc())
+		if err != nil {
+			hs.bot.logger.Error("Failed to get weekly reader stats", zap.Error(err))
+			writeJSONError(w, http.StatusInternalServerError, "Failed to fetch statistics")
+			return
+		}
+		participants, err := hs.bot.db.ListParticipants(r.Context())
+		if err != nil {
+			hs.bot.logger.Error("Failed to list participants", zap.Error(err))
+			writeJSONError(w, http.StatusInternalServerError, "Failed to fetch statistics")
+			return
+		}
+
+		response := WeeklyStatsResponse{Weeks: make([]string, weekCount), Readers: []WeeklyReaderCounts{}}
+		weekIndex := make(map[string]int, weekCount)
+		for i := range response.Weeks {
+			week := first.AddDate(0, 0, 7*i).Format("2006-01-02")
+			response.Weeks[i] = week
+			weekIndex[week] = i
+		}
+
+		// Every active child gets a row; archived children only if they read in the period
+		readerIndex := make(map[string]int)
+		addReader := func(id, name string) int {
+			if i, ok := readerIndex[id]; ok {
+				return i
+			}
+			readerIndex[id] = len(response.Readers)
+			response.Readers = append(response.Readers, WeeklyReaderCounts{ParticipantID: id, ParticipantName: name, Counts: make([]int, weekCount)})
+			return readerIndex[id]
+		}
+		for _, p := range participants {
+			if !p.IsParent {
+				addReader(p.ID, p.Name)
+			}
+		}
+		for _, stat := range stats {
+			week, ok := weekIndex[stat.WeekStart.In(hs.bot.loc()).Format("2006-01-02")]
+			if !ok {
+				continue
+			}
+			response.Readers[addReader(stat.ParticipantID, stat.ParticipantName)].Counts[week] += stat.ReadCount
+		}
+
+		writeJSON(w, http.StatusOK, response)
+	})(w, r)
+}
+
+// HeatmapResponse holds the reading days of a period for a calendar heatmap
+type HeatmapResponse struct {
+	Since string       `json:"since"` // first day of the period (YYYY-MM-DD)
+	Until string       `json:"until"` // last day of the period (YYYY-MM-DD)
+	Days  []HeatmapDay `json:"days"`  // days with sessions, oldest first
+}
+
+// HeatmapDay is the number of sessions on a day
+type HeatmapDay struct {
+	Date      string `json:"date"`
+	ReadCount int    `json:"readCount"`
+}
+
+// handleStatsHeatmap returns the number of sessions per day. Query parameters: since and until
+// (default: the last 52 weeks up to today, starting on a Monday) and participant (default: all children).
+func (hs *HTTPServer) handleStatsHeatmap(w http.ResponseWriter, r *http.Request) {
+	hs.authMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		since, until, err := hs.queryDateRange(r)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, "Invalid query: "+err.Error())
+			return
+		}
+		if until.IsZero() {
+			now := hs.bot.now()
+			until = time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, now.Location()).Add(-time.Second)
+		}
+		if since.IsZero() {
+			since = startOfWeek(until).AddDate(0, 0, -7*51)
+		}
+		if since.After(until) {
+			writeJSONError(w, http.StatusBadRequest, "Invalid query: since must not be after until")
+			return
+		}
+
+		stats, err := hs.bot.db.GetDailyStats(r.Context(), since, until, r.URL.Query().Get("participant"), hs.bot.loc())
+		if err != nil {
+			hs.bot.logger.Error("Failed to get daily stats", zap.Error(err))
+			writeJSONError(w, http.StatusInternalServerError, "Failed to fetch statistics")
+			return
+		}
+
+		response := HeatmapResponse{
+			Since: since.Format("2006-01-02"),
+			Until: until.Format("2006-01-02"),
+			Days:  make([]HeatmapDay, 0, len(stats)),
+		}
+		for _, stat := range stats {
+			response.Days = append(response.Days, HeatmapDay{Date: stat.Date.In(hs.bot.loc()).Format("2006-01-02"), ReadCount: stat.ReadCount})
+		}
+		writeJSON(w, http.StatusOK, response)
+	})(w, r)
+}
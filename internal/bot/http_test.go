@@ -2,9 +2,12 @@ package bot
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -12,7 +15,10 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
+	"library/internal/labels"
 	"library/internal/models"
+	"library/internal/openapi"
+	"library/internal/storage"
 	"library/internal/storage/stubs"
 )
 
@@ -83,7 +89,7 @@ func TestHandleParticipants(t *testing.T) {
 func TestHandleEvents_Success(t *testing.T) {
 	hs, mockDB := newTestHTTPServer(t)
 
-	body := `{"date":"2026-03-23","book_name":"Book 1","participant_name":"Alice"}`
+	body := `{"date":"2026-03-23","bookName":"The Hobbit","participantName":"Alice"}`
 	req := httptest.NewRequest(http.MethodPost, "/api/events", bytes.NewBufferString(body))
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
@@ -100,8 +106,313 @@ func TestHandleEvents_Success(t *testing.T) {
 	events, err := mockDB.GetLastEvents(nil, 1)
 	require.NoError(t, err)
 	require.Len(t, events, 1)
-	assert.Equal(t, "Book 1", events[0].BookName)
-	assert.Equal(t, "Alice", events[0].ParticipantName)
+	assert.Equal(t, "The Hobbit", events[0].BookName)
+	assert.Equal(t, []string{"Alice"}, events[0].ParticipantNames)
+	assert.Equal(t, events[0].ID, resp["id"])
+}
+
+func TestHandleEvents_ByID(t *testing.T) {
+	hs, mockDB := newTestHTTPServer(t)
+
+	book, err := mockDB.GetBookByName(nil, "Matilda")
+	require.NoError(t, err)
+	participant, err := mockDB.GetParticipantByName(nil, "Bob")
+	require.NoError(t, err)
+
+	body := fmt.Sprintf(`{"date":"2026-03-23","bookId":%q,"participantId":%q}`, book.ID, participant.ID)
+	req := httptest.NewRequest(http.MethodPost, "/api/events", bytes.NewBufferString(body))
+	rec := httptest.NewRecorder()
+
+	hs.handleEvents(rec, req)
+
+	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	events, err := mockDB.GetLastEvents(nil, 1)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, book.ID, events[0].BookID)
+	assert.Equal(t, "Matilda", events[0].BookName)
+	assert.Equal(t, []string{"Bob"}, events[0].ParticipantNames)
+}
+
+func TestHandleEvents_MultipleParticipants(t *testing.T) {
+	hs, mockDB := newTestHTTPServer(t)
+
+	alice, err := mockDB.GetParticipantByName(nil, "Alice")
+	require.NoError(t, err)
+	bob, err := mockDB.GetParticipantByName(nil, "Bob")
+	require.NoError(t, err)
+
+	body := fmt.Sprintf(`{"date":"2026-03-23","bookName":"The Hobbit","participantIds":[%q,%q]}`, alice.ID, bob.ID)
+	rec := httptest.NewRecorder()
+	hs.handleEvents(rec, httptest.NewRequest(http.MethodPost, "/api/events", bytes.NewBufferString(body)))
+
+	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	events, err := mockDB.GetLastEvents(nil, 10)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, []string{"Alice", "Bob"}, events[0].ParticipantNames)
+}
+
+func TestHandleEvents_SessionDetails(t *testing.T) {
+	hs, mockDB := newTestHTTPServer(t)
+
+	body := `{"date":"2026-03-23","bookName":"The Hobbit","participantName":"Alice","durationMinutes":25,"progress":3,"progressUnit":"chapters"}`
+	rec := httptest.NewRecorder()
+	hs.handleEvents(rec, httptest.NewRequest(http.MethodPost, "/api/events", bytes.NewBufferString(body)))
+	require.Equal(t, http.StatusCreated, rec.Code)
+
+	events, err := mockDB.GetLastEvents(nil, 10)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, 25, events[0].DurationMinutes)
+	assert.Equal(t, 3, events[0].Progress)
+	assert.Equal(t, models.ProgressChapters, events[0].ProgressUnit)
+
+	// Negative values and unknown units are rejected
+	for _, details := range []string{`"durationMinutes":-5`, `"progress":10`, `"progress":10,"progressUnit":"lines"`} {
+		body := `{"date":"2026-03-24","bookName":"The Hobbit","participantName":"Alice",` + details + `}`
+		rec := httptest.NewRecorder()
+		hs.handleEvents(rec, httptest.NewRequest(http.MethodPost, "/api/events", bytes.NewBufferString(body)))
+		assert.Equal(t, http.StatusBadRequest, rec.Code, details)
+	}
+}
+
+func TestHandleBadges(t *testing.T) {
+	hs, _ := newTestHTTPServer(t)
+
+	// The first reading of a book unlocks a badge through the event endpoint
+	body := `{"date":"2026-03-23","bookName":"The Hobbit","participantNames":["Alice","Mom"]}`
+	rec := httptest.NewRecorder()
+	hs.handleEvents(rec, httptest.NewRequest(http.MethodPost, "/api/events", bytes.NewBufferString(body)))
+	require.Equal(t, http.StatusCreated, rec.Code)
+
+	rec = httptest.NewRecorder()
+	hs.handleBadges(rec, httptest.NewRequest(http.MethodGet, "/api/badges?participant=Alice", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var badges []BadgeResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&badges))
+	require.Len(t, badges, 1)
+	assert.Equal(t, "Alice", badges[0].ParticipantName)
+	assert.Equal(t, "first_reader", badges[0].Key)
+	assert.NotEmpty(t, badges[0].Name)
+
+	// Parents do not collect badges
+	rec = httptest.NewRecorder()
+	hs.handleBadges(rec, httptest.NewRequest(http.MethodGet, "/api/badges?participant=Mom", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `[]`, rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	hs.handleBadges(rec, httptest.NewRequest(http.MethodGet, "/api/badges?participant=Nobody", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestHandleBookCover(t *testing.T) {
+	hs, db := newTestHTTPServer(t)
+
+	hobbit, err := db.GetBookByName(nil, "The Hobbit")
+	require.NoError(t, err)
+	require.NoError(t, db.UpdateBookMetadata(nil, hobbit.ID, models.BookMetadata{Author: "J. R. R. Tolkien", Cover: "https://example.com/hobbit.jpg"}))
+	cat, err := db.GetBookByName(nil, "The Cat in the Hat")
+	require.NoError(t, err)
+	require.NoError(t, db.UpdateBookMetadata(nil, cat.ID, models.BookMetadata{Cover: "telegram-file-id"}))
+
+	// Metadata is part of the book list
+	rec := httptest.NewRecorder()
+	hs.handleBooks(rec, httptest.NewRequest(http.MethodGet, "/api/books", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"author":"J. R. R. Tolkien"`)
+
+	rec = httptest.NewRecorder()
+	hs.handleBookCover(rec, httptest.NewRequest(http.MethodGet, "/api/books/cover?id="+hobbit.ID, nil))
+	assert.Equal(t, http.StatusFound, rec.Code)
+	assert.Equal(t, "https://example.com/hobbit.jpg", rec.Header().Get("Location"))
+
+	// Without a bot API Telegram photos cannot be fetched
+	rec = httptest.NewRecorder()
+	hs.handleBookCover(rec, httptest.NewRequest(http.MethodGet, "/api/books/cover?id="+cat.ID, nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = httptest.NewRecorder()
+	hs.handleBookCover(rec, httptest.NewRequest(http.MethodGet, "/api/books/cover?id=missing", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestHandleBulkLabel(t *testing.T) {
+	hs, db := newTestHTTPServer(t)
+
+	hobbit, err := db.GetBookByName(nil, "The Hobbit")
+	require.NoError(t, err)
+	cat, err := db.GetBookByName(nil, "The Cat in the Hat")
+	require.NoError(t, err)
+
+	body := fmt.Sprintf(`{"label":" winter ","bookIds":[%q,%q]}`, hobbit.ID, cat.ID)
+	rec := httptest.NewRecorder()
+	hs.handleBulkLabel(rec, httptest.NewRequest(http.MethodPost, "/api/labels/bulk", bytes.NewBufferString(body)))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"status":"success","count":2}`, rec.Body.String())
+
+	books, err := db.GetBooksByLabel(nil, labels.Label("winter"))
+	require.NoError(t, err)
+	assert.Len(t, books, 2)
+
+	rec = httptest.NewRecorder()
+	hs.handleBulkLabel(rec, httptest.NewRequest(http.MethodPost, "/api/labels/bulk", bytes.NewBufferString(`{"label":"winter","bookIds":["missing"]}`)))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = httptest.NewRecorder()
+	hs.handleBulkLabel(rec, httptest.NewRequest(http.MethodPost, "/api/labels/bulk", bytes.NewBufferString(`{"label":"","bookIds":[]}`)))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = httptest.NewRecorder()
+	body = fmt.Sprintf(`{"label":"lang:english","bookIds":[%q]}`, hobbit.ID)
+	hs.handleBulkLabel(rec, httptest.NewRequest(http.MethodPost, "/api/labels/bulk", bytes.NewBufferString(body)))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Invalid label")
+
+	rec = httptest.NewRecorder()
+	hs.handleBulkLabel(rec, httptest.NewRequest(http.MethodGet, "/api/labels/bulk", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
+
+func TestHandleEvents_Time(t *testing.T) {
+	hs, mockDB := newTestHTTPServer(t)
+	hs.bot.location = time.FixedZone("UTC+3", 3*60*60)
+
+	body := `{"date":"2026-03-23","time":"07:45","bookName":"The Hobbit","participantName":"Alice"}`
+	rec := httptest.NewRecorder()
+	hs.handleEvents(rec, httptest.NewRequest(http.MethodPost, "/api/events", bytes.NewBufferString(body)))
+	require.Equal(t, http.StatusCreated, rec.Code)
+
+	events, err := mockDB.GetLastEvents(nil, 10)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.True(t, events[0].Date.Equal(time.Date(2026, 3, 23, 4, 45, 0, 0, time.UTC)), "got %v", events[0].Date)
+
+	body = `{"date":"2026-03-24","time":"25:00","bookName":"The Hobbit","participantName":"Alice"}`
+	rec = httptest.NewRecorder()
+	hs.handleEvents(rec, httptest.NewRequest(http.MethodPost, "/api/events", bytes.NewBufferString(body)))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestHandleEvents_UnknownBook(t *testing.T) {
+	hs, mockDB := newTestHTTPServer(t)
+
+	body := `{"date":"2026-03-23","bookName":"No Such Book","participantName":"Alice"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/events", bytes.NewBufferString(body))
+	rec := httptest.NewRecorder()
+
+	hs.handleEvents(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	events, err := mockDB.GetLastEvents(nil, 1)
+	require.NoError(t, err)
+	assert.Empty(t, events)
+}
+
+func TestHandleEvents_ArchivedParticipant(t *testing.T) {
+	hs, mockDB := newTestHTTPServer(t)
+
+	bob, err := mockDB.GetParticipantByName(nil, "Bob")
+	require.NoError(t, err)
+	require.NoError(t, mockDB.ArchiveParticipant(nil, bob.ID))
+
+	// The same rule applies whether the participant is given by name or by ID
+	for _, body := range []string{
+		`{"date":"2026-03-23","bookName":"The Hobbit","participantName":"Bob"}`,
+		fmt.Sprintf(`{"date":"2026-03-23","bookName":"The Hobbit","participantId":%q}`, bob.ID),
+	} {
+		rec := httptest.NewRecorder()
+		hs.handleEvents(rec, httptest.NewRequest(http.MethodPost, "/api/events", bytes.NewBufferString(body)))
+		assert.Equal(t, http.StatusBadRequest, rec.Code, body)
+	}
+
+	events, err := mockDB.GetLastEvents(nil, 1)
+	require.NoError(t, err)
+	assert.Empty(t, events)
+}
+
+func TestHandleEvents_RetiredBook(t *testing.T) {
+	hs, mockDB := newTestHTTPServer(t)
+
+	book, err := mockDB.GetBookByName(nil, "Matilda")
+	require.NoError(t, err)
+	require.NoError(t, mockDB.RetireBook(nil, book.ID))
+
+	for _, body := range []string{
+		`{"date":"2026-03-23","bookName":"Matilda","participantName":"Alice"}`,
+		fmt.Sprintf(`{"date":"2026-03-23","bookId":%q,"participantName":"Alice"}`, book.ID),
+	} {
+		rec := httptest.NewRecorder()
+		hs.handleEvents(rec, httptest.NewRequest(http.MethodPost, "/api/events", bytes.NewBufferString(body)))
+		assert.Equal(t, http.StatusBadRequest, rec.Code, body)
+		assert.JSONEq(t, `{"error":"Book is retired"}`, rec.Body.String())
+	}
+
+	events, err := mockDB.GetLastEvents(nil, 1)
+	require.NoError(t, err)
+	assert.Empty(t, events)
+}
+
+func TestHandleEvents_Duplicate(t *testing.T) {
+	hs, mockDB := newTestHTTPServer(t)
+
+	body := `{"date":"2026-03-23","bookName":"The Hobbit","participantName":"Alice"}`
+	rec := httptest.NewRecorder()
+	hs.handleEvents(rec, httptest.NewRequest(http.MethodPost, "/api/events", bytes.NewBufferString(body)))
+	require.Equal(t, http.StatusCreated, rec.Code)
+
+	// Same book, reader and day is rejected
+	rec = httptest.NewRecorder()
+	hs.handleEvents(rec, httptest.NewRequest(http.MethodPost, "/api/events", bytes.NewBufferString(body)))
+	assert.Equal(t, http.StatusConflict, rec.Code)
+
+	var resp map[string]interface{}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+	assert.Equal(t, true, resp["duplicate"])
+
+	// ...unless forced
+	forced := `{"date":"2026-03-23","bookName":"The Hobbit","participantName":"Alice","force":true}`
+	rec = httptest.NewRecorder()
+	hs.handleEvents(rec, httptest.NewRequest(http.MethodPost, "/api/events", bytes.NewBufferString(forced)))
+	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	events, err := mockDB.GetLastEvents(nil, 10)
+	require.NoError(t, err)
+	assert.Len(t, events, 2)
+}
+
+func TestHandleEvent_Delete(t *testing.T) {
+	hs, mockDB := newTestHTTPServer(t)
+
+	book, err := mockDB.GetBookByName(nil, "Matilda")
+	require.NoError(t, err)
+	participant, err := mockDB.GetParticipantByName(nil, "Bob")
+	require.NoError(t, err)
+	eventID, err := mockDB.CreateEvent(nil, models.Event{Date: time.Now(), BookID: book.ID, ParticipantIDs: []string{participant.ID}})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/events/"+eventID, nil)
+	rec := httptest.NewRecorder()
+	hs.handleEvent(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	events, err := mockDB.GetLastEvents(nil, 1)
+	require.NoError(t, err)
+	assert.Empty(t, events)
+
+	// Already deleted
+	rec = httptest.NewRecorder()
+	hs.handleEvent(rec, httptest.NewRequest(http.MethodDelete, "/api/events/"+eventID, nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = httptest.NewRecorder()
+	hs.handleEvent(rec, httptest.NewRequest(http.MethodGet, "/api/events/"+eventID, nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
 }
 
 func TestHandleEvents_InvalidJSON(t *testing.T) {
@@ -119,7 +430,7 @@ func TestHandleEvents_InvalidJSON(t *testing.T) {
 func TestHandleEvents_MissingFields(t *testing.T) {
 	hs, _ := newTestHTTPServer(t)
 
-	body := `{"date":"2026-03-23","book_name":"Book 1"}`
+	body := `{"date":"2026-03-23","bookName":"Book 1"}`
 	req := httptest.NewRequest(http.MethodPost, "/api/events", bytes.NewBufferString(body))
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
@@ -132,7 +443,20 @@ func TestHandleEvents_MissingFields(t *testing.T) {
 func TestHandleEvents_EmptyFields(t *testing.T) {
 	hs, _ := newTestHTTPServer(t)
 
-	body := `{"date":"","book_name":"","participant_name":""}`
+	body := `{"date":"","bookName":"","participantName":""}`
+	req := httptest.NewRequest(http.MethodPost, "/api/events", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+
+	hs.handleEvents(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestHandleEvents_UnknownField(t *testing.T) {
+	hs, mockDB := newTestHTTPServer(t)
+
+	body := `{"date":"2026-03-23","bookTitle":"Book 1","participantName":"Alice"}`
 	req := httptest.NewRequest(http.MethodPost, "/api/events", bytes.NewBufferString(body))
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
@@ -140,17 +464,318 @@ func TestHandleEvents_EmptyFields(t *testing.T) {
 	hs.handleEvents(rec, req)
 
 	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.JSONEq(t, `{"error":"Invalid request: body: unknown field bookTitle"}`, rec.Body.String())
+	events, _ := mockDB.GetLastEvents(req.Context(), 10)
+	assert.Empty(t, events)
+}
+
+func TestHandleEvents_DeprecatedFieldNames(t *testing.T) {
+	hs, mockDB := newTestHTTPServer(t)
+
+	// Clients written before the switch to camelCase keep working; camelCase wins if both are sent
+	body := `{"date":"2026-03-23","book_name":"Matilda","bookName":"The Hobbit","participant_names":["Alice"],"duration_minutes":20,"progress":5,"progress_unit":"pages"}`
+	rec := httptest.NewRecorder()
+	hs.handleEvents(rec, httptest.NewRequest(http.MethodPost, "/api/events", bytes.NewBufferString(body)))
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	events, err := mockDB.GetLastEvents(nil, 1)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "The Hobbit", events[0].BookName)
+	assert.Equal(t, []string{"Alice"}, events[0].ParticipantNames)
+	assert.Equal(t, 20, events[0].DurationMinutes)
+	assert.Equal(t, "pages", events[0].ProgressUnit)
+
+	hobbit, err := mockDB.GetBookByName(nil, "The Hobbit")
+	require.NoError(t, err)
+	rec = httptest.NewRecorder()
+	hs.handleBulkLabel(rec, httptest.NewRequest(http.MethodPost, "/api/labels/bulk",
+		bytes.NewBufferString(fmt.Sprintf(`{"label":"winter","book_ids":[%q]}`, hobbit.ID))))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	rec = httptest.NewRecorder()
+	hs.handleRenameLabel(rec, httptest.NewRequest(http.MethodPost, "/api/labels/rename",
+		bytes.NewBufferString(`{"label":"winter","new_label":"snow"}`)))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	hobbit, err = mockDB.GetBookByName(nil, "The Hobbit")
+	require.NoError(t, err)
+	assert.Contains(t, hobbit.Labels, "snow")
+}
+
+func TestHandleEvents_BodyTooLarge(t *testing.T) {
+	hs, _ := newTestHTTPServer(t)
+
+	body := `{"date":"2026-03-23","bookName":"` + strings.Repeat("a", openapi.MaxBodyBytes) + `"}`
+	rec := httptest.NewRecorder()
+	hs.handleEvents(rec, httptest.NewRequest(http.MethodPost, "/api/events", strings.NewReader(body)))
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
 }
 
 func TestHandleEvents_WrongMethod(t *testing.T) {
 	hs, _ := newTestHTTPServer(t)
 
-	req := httptest.NewRequest(http.MethodGet, "/api/events", nil)
+	req := httptest.NewRequest(http.MethodPut, "/api/events", nil)
 	rec := httptest.NewRecorder()
 
 	hs.handleEvents(rec, req)
 
 	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"error":"Method not allowed"}`, rec.Body.String())
+}
+
+func TestHandleEvents_List(t *testing.T) {
+	hs, _ := newTestHTTPServer(t)
+
+	for _, body := range []string{
+		`{"date":"2026-03-20","bookName":"The Hobbit","participantName":"Alice"}`,
+		`{"date":"2026-03-22","bookName":"Matilda","participantName":"Bob"}`,
+		`{"date":"2026-03-24","bookName":"The Hobbit","participantName":"Bob"}`,
+	} {
+		rec := httptest.NewRecorder()
+		hs.handleEvents(rec, httptest.NewRequest(http.MethodPost, "/api/events", bytes.NewBufferString(body)))
+		require.Equal(t, http.StatusCreated, rec.Code)
+	}
+
+	list := func(query string) []models.Event {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		hs.handleEvents(rec, httptest.NewRequest(http.MethodGet, "/api/events"+query, nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+		var events []models.Event
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&events))
+		return events
+	}
+
+	assert.Len(t, list(""), 3)
+	assert.Len(t, list("?limit=1"), 1)
+	events := list("?participant=Bob&until=2026-03-22")
+	require.Len(t, events, 1)
+	assert.Equal(t, "Matilda", events[0].BookName)
+	assert.Len(t, list("?since=2026-03-22&until=2026-03-24"), 2)
+	assert.NotNil(t, list("?since=2027-01-01"))
+
+	for _, query := range []string{"?since=yesterday", "?until=2026-13-01", "?limit=0", "?limit=many"} {
+		rec := httptest.NewRecorder()
+		hs.handleEvents(rec, httptest.NewRequest(http.MethodGet, "/api/events"+query, nil))
+		assert.Equal(t, http.StatusBadRequest, rec.Code, query)
+		assert.Contains(t, rec.Body.String(), `"error":"Invalid request: query parameter`, query)
+	}
+}
+
+func TestHandleStats(t *testing.T) {
+	hs, db := newTestHTTPServer(t)
+
+	hobbit, err := db.GetBookByName(nil, "The Hobbit")
+	require.NoError(t, err)
+	require.NoError(t, db.AddLabelToBook(nil, hobbit.ID, "lang:en"))
+	for _, body := range []string{
+		`{"date":"2026-03-20","bookName":"The Hobbit","participantName":"Alice"}`,
+		`{"date":"2026-03-21","bookName":"The Hobbit","participantName":"Bob"}`,
+		`{"date":"2026-03-22","bookName":"Matilda","participantName":"Bob"}`,
+	} {
+		rec := httptest.NewRecorder()
+		hs.handleEvents(rec, httptest.NewRequest(http.MethodPost, "/api/events", bytes.NewBufferString(body)))
+		require.Equal(t, http.StatusCreated, rec.Code)
+	}
+
+	get := func(handler http.HandlerFunc, target string, v any) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		require.Equal(t, http.StatusOK, rec.Code, target)
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(v))
+	}
+
+	var top []models.BookStat
+	get(hs.handleStatsTop, "/api/stats/top?since=2026-03-01&limit=1", &top)
+	assert.Equal(t, []models.BookStat{{BookName: "The Hobbit", ReadCount: 2}}, top)
+
+	var rare []models.RareBookStat
+	get(hs.handleStatsRare, "/api/stats/rare?filter=lang=en", &rare)
+	require.Len(t, rare, 1)
+	assert.Equal(t, "The Hobbit", rare[0].BookName)
+
+	var books []models.DetailedBookStat
+	get(hs.handleStatsBooks, "/api/stats/books?book=The+Hobbit", &books)
+	require.Len(t, books, 4)
+	assert.Equal(t, "Alice", books[0].ParticipantName)
+	assert.Equal(t, 1, books[0].ReadCount)
+
+	var participants []models.ParticipantBookStat
+	get(hs.handleStatsParticipants, "/api/stats/participants?participant=Bob&since=2026-03-22", &participants)
+	require.NotEmpty(t, participants)
+	assert.Equal(t, models.ParticipantBookStat{ParticipantName: "Bob", BookName: "Matilda", ReadCount: 1}, participants[0])
+
+	rec := httptest.NewRecorder()
+	hs.handleStatsRare(rec, httptest.NewRequest(http.MethodGet, "/api/stats/rare?filter=planet:*", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Invalid label filter")
+
+	rec = httptest.NewRecorder()
+	hs.handleStatsTop(rec, httptest.NewRequest(http.MethodPost, "/api/stats/top", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
+
+func TestHandleStats_WeeklyAndHeatmap(t *testing.T) {
+	hs, _ := newTestHTTPServer(t)
+
+	today := hs.bot.now()
+	lastWeek := today.AddDate(0, 0, -7)
+	for _, event := range []struct {
+		date    time.Time
+		readers string
+	}{
+		{today, `"Alice","Bob"`},
+		{today, `"Alice"`},
+		{lastWeek, `"Alice"`},
+		{lastWeek, `"Mom"`},
+	} {
+		body := fmt.Sprintf(`{"date":%q,"bookName":"The Hobbit","participantNames":[%s],"force":true}`, event.date.Format("2006-01-02"), event.readers)
+		rec := httptest.NewRecorder()
+		hs.handleEvents(rec, httptest.NewRequest(http.MethodPost, "/api/events", bytes.NewBufferString(body)))
+		require.Equal(t, http.StatusCreated, rec.Code)
+	}
+
+	rec := httptest.NewRecorder()
+	hs.handleStatsWeekly(rec, httptest.NewRequest(http.MethodGet, "/api/stats/weekly?weeks=2", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var weekly WeeklyStatsResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&weekly))
+	assert.Equal(t, []string{startOfWeek(lastWeek).Format("2006-01-02"), startOfWeek(today).Format("2006-01-02")}, weekly.Weeks)
+	counts := make(map[string][]int)
+	for _, reader := range weekly.Readers {
+		counts[reader.ParticipantName] = reader.Counts
+	}
+	assert.Equal(t, []int{1, 2}, counts["Alice"])
+	assert.Equal(t, []int{0, 1}, counts["Bob"])
+	assert.NotContains(t, counts, "Mom")
+
+	rec = httptest.NewRecorder()
+	hs.handleStatsHeatmap(rec, httptest.NewRequest(http.MethodGet, "/api/stats/heatmap", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var heatmap HeatmapResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&heatmap))
+	assert.Equal(t, today.Format("2006-01-02"), heatmap.Until)
+	assert.Equal(t, []HeatmapDay{
+		{Date: lastWeek.Format("2006-01-02"), ReadCount: 1},
+		{Date: today.Format("2006-01-02"), ReadCount: 2},
+	}, heatmap.Days)
+
+	rec = httptest.NewRecorder()
+	hs.handleStatsHeatmap(rec, httptest.NewRequest(http.MethodGet, "/api/stats/heatmap?participant=Mom&since="+lastWeek.Format("2006-01-02"), nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&heatmap))
+	assert.Equal(t, []HeatmapDay{{Date: lastWeek.Format("2006-01-02"), ReadCount: 1}}, heatmap.Days)
+
+	for _, target := range []string{"/api/stats/weekly?weeks=0", "/api/stats/heatmap?since=2026-05-02&until=2026-05-01"} {
+		rec = httptest.NewRecorder()
+		handler := hs.handleStatsWeekly
+		if strings.Contains(target, "heatmap") {
+			handler = hs.handleStatsHeatmap
+		}
+		handler(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		assert.Equal(t, http.StatusBadRequest, rec.Code, target)
+	}
+}
+
+func TestHandleLabels(t *testing.T) {
+	hs, db := newTestHTTPServer(t)
+
+	hobbit, err := db.GetBookByName(nil, "The Hobbit")
+	require.NoError(t, err)
+	cat, err := db.GetBookByName(nil, "The Cat in the Hat")
+	require.NoError(t, err)
+	require.NoError(t, db.AddLabelToBooks(nil, []string{hobbit.ID, cat.ID}, "winter"))
+	require.NoError(t, db.AddLabelToBook(nil, hobbit.ID, "Bedtime"))
+	require.NoError(t, db.AddLabelToBook(nil, cat.ID, "bedtime"))
+
+	post := func(handler http.HandlerFunc, target, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodPost, target, bytes.NewBufferString(body)))
+		return rec
+	}
+
+	rec := httptest.NewRecorder()
+	hs.handleLabels(rec, httptest.NewRequest(http.MethodGet, "/api/labels", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `["Bedtime","bedtime","winter"]`, rec.Body.String())
+
+	// Remove
+	rec = post(hs.handleRemoveLabel, "/api/labels/remove", fmt.Sprintf(`{"label":"winter","bookIds":[%q]}`, cat.ID))
+	require.Equal(t, http.StatusOK, rec.Code)
+	books, err := db.GetBooksByLabel(nil, labels.Label("winter"))
+	require.NoError(t, err)
+	require.Len(t, books, 1)
+	assert.Equal(t, "The Hobbit", books[0].Name)
+	assert.Equal(t, http.StatusBadRequest, post(hs.handleRemoveLabel, "/api/labels/remove", `{"label":"winter","bookIds":["missing"]}`).Code)
+
+	// An unknown book changes nothing
+	rec = post(hs.handleRemoveLabel, "/api/labels/remove", fmt.Sprintf(`{"label":"winter","bookIds":[%q,"missing"]}`, hobbit.ID))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	books, err = db.GetBooksByLabel(nil, labels.Label("winter"))
+	require.NoError(t, err)
+	assert.Len(t, books, 1)
+
+	// A label stored before normalization is removed as it is stored
+	require.NoError(t, db.AddLabelToBooks(nil, []string{hobbit.ID, cat.ID}, "Genre:Fairy-Tale"))
+	rec = post(hs.handleRemoveLabel, "/api/labels/remove", fmt.Sprintf(`{"label":" Genre:Fairy-Tale ","bookIds":[%q,%q]}`, hobbit.ID, cat.ID))
+	require.Equal(t, http.StatusOK, rec.Code)
+	books, err = db.GetBooksByLabel(nil, labels.Label("Genre:Fairy-Tale"))
+	require.NoError(t, err)
+	assert.Empty(t, books)
+
+	// Rename
+	rec = post(hs.handleRenameLabel, "/api/labels/rename", `{"label":"winter","newLabel":"age:teen"}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	rec = post(hs.handleRenameLabel, "/api/labels/rename", `{"label":"winter","newLabel":"bedtime"}`)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	rec = post(hs.handleRenameLabel, "/api/labels/rename", `{"label":"summer","newLabel":"autumn"}`)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.JSONEq(t, `{"error":"Label not found"}`, rec.Body.String())
+	rec = post(hs.handleRenameLabel, "/api/labels/rename", `{"label":"winter","newLabel":"Genre:Winter"}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"status":"success","label":"genre:winter"}`, rec.Body.String())
+
+	// Merge
+	rec = post(hs.handleMergeLabels, "/api/labels/merge", `{"labels":["Bedtime"],"target":"bedtime"}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	books, err = db.GetBooksByLabel(nil, labels.Label("bedtime"))
+	require.NoError(t, err)
+	assert.Len(t, books, 2)
+	assert.Equal(t, http.StatusNotFound, post(hs.handleMergeLabels, "/api/labels/merge", `{"labels":["Bedtime"],"target":"bedtime"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, post(hs.handleMergeLabels, "/api/labels/merge", `{"labels":[],"target":"bedtime"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, post(hs.handleMergeLabels, "/api/labels/merge", `{"labels":["bedtime"," "],"target":"winter"}`).Code)
+
+	rec = httptest.NewRecorder()
+	hs.handleLabels(rec, httptest.NewRequest(http.MethodGet, "/api/labels", nil))
+	assert.JSONEq(t, `["bedtime","genre:winter"]`, rec.Body.String())
+}
+
+func TestHandleOpenAPI(t *testing.T) {
+	hs, _ := newTestHTTPServer(t)
+
+	rec := httptest.NewRecorder()
+	hs.handleOpenAPI(rec, httptest.NewRequest(http.MethodGet, "/api/openapi.json", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var spec struct {
+		OpenAPI string                    `json:"openapi"`
+		Paths   map[string]map[string]any `json:"paths"`
+	}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&spec))
+	assert.Equal(t, "3.0.3", spec.OpenAPI)
+
+	// Every documented path must be served
+	mux := http.NewServeMux()
+	hs.RegisterRoutes(mux)
+	for path := range spec.Paths {
+		target := strings.ReplaceAll(path, "{id}", "1")
+		_, pattern := mux.Handler(httptest.NewRequest(http.MethodGet, target, nil))
+		assert.NotEmpty(t, pattern, "documented path %s is not registered", path)
+	}
 }
 
 func TestHandleIndex(t *testing.T) {
@@ -271,3 +896,238 @@ func TestAuthMiddleware_PollingModeSkipsAuth(t *testing.T) {
 	assert.Equal(t, http.StatusOK, rec.Code)
 	assert.True(t, handlerCalled)
 }
+
+func TestAuthMiddleware_APIToken(t *testing.T) {
+	hs := newTestHTTPServerWebhook(t)
+	ctx := context.Background()
+
+	createToken := func(name, scope string, ownerID int64) string {
+		secret, hash, err := generateAPIToken()
+		require.NoError(t, err)
+		_, err = hs.bot.db.CreateAPIToken(ctx, models.APIToken{Name: name, Scope: scope, OwnerID: ownerID, Hash: hash})
+		require.NoError(t, err)
+		return secret
+	}
+	readToken := createToken("tablet", models.TokenScopeRead, 123)
+	writeToken := createToken("script", models.TokenScopeReadWrite, 123)
+	strangerToken := createToken("old", models.TokenScopeReadWrite, 999) // 999 is not in allowedUsers
+
+	var actor storage.Actor
+	handler := hs.authMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		actor = storage.ActorFromContext(r.Context())
+		w.WriteHeader(http.StatusOK)
+	})
+	request := func(method, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/labels/bulk", bytes.NewBufferString(`{"label":"winter","bookIds":["1"]}`))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec
+	}
+
+	assert.Equal(t, http.StatusOK, request(http.MethodGet, readToken).Code)
+	assert.Equal(t, int64(123), actor.ID)
+	assert.Equal(t, storage.SourceAPIToken, actor.Source)
+
+	rec := request(http.MethodPost, readToken)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.JSONEq(t, `{"error":"Token is read-only"}`, rec.Body.String())
+	assert.Equal(t, http.StatusOK, request(http.MethodPost, writeToken).Code)
+
+	assert.Equal(t, http.StatusUnauthorized, request(http.MethodGet, strangerToken).Code)
+	assert.Equal(t, http.StatusUnauthorized, request(http.MethodGet, "lib_unknown").Code)
+	assert.Equal(t, http.StatusUnauthorized, request(http.MethodGet, "").Code)
+
+	// Revoked tokens stop working
+	require.NoError(t, hs.bot.revokeAPIToken(ctx, 123, "TABLET"))
+	assert.Equal(t, http.StatusUnauthorized, request(http.MethodGet, readToken).Code)
+}
+
+func TestAuthMiddleware_ExpiredInitData(t *testing.T) {
+	hs := newTestHTTPServerWebhook(t)
+	hs.ConfigureInitDataMaxAge(time.Hour)
+
+	handler := hs.authMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/books", nil)
+	req.Header.Set("Authorization", "tma "+generateTestInitData(t, testBotToken, 123, time.Now().Add(-2*time.Hour)))
+	rec := httptest.NewRecorder()
+
+	handler(rec, req)
+
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Equal(t, int64(1), hs.rejections.get(rejectExpired))
+}
+
+func TestAuthMiddleware_ReplayedWrite(t *testing.T) {
+	hs := newTestHTTPServerWebhook(t)
+
+	calls := 0
+	handler := hs.authMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusOK)
+	})
+
+	initData := generateTestInitData(t, testBotToken, 123, time.Now())
+	request := func(method, nonce string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/labels/bulk", bytes.NewBufferString(`{"label":"winter","bookIds":["1"]}`))
+		req.Header.Set("Authorization", "tma "+initData)
+		if nonce != "" {
+			req.Header.Set(nonceHeader, nonce)
+		}
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec
+	}
+	startSession := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/nonce", nil)
+		req.Header.Set("Authorization", "tma "+initData)
+		rec := httptest.NewRecorder()
+		hs.handleNonce(rec, req)
+		return rec
+	}
+
+	// The same initData is reused for every request of a session; reads need no nonce
+	assert.Equal(t, http.StatusOK, request(http.MethodGet, "").Code)
+	rec := request(http.MethodGet, "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Header().Get(nextNonceHeader))
+
+	// Even the first write needs a nonce, which only the session start returns, and only once
+	assert.Equal(t, http.StatusUnauthorized, request(http.MethodPost, "").Code)
+	rec = startSession()
+	require.Equal(t, http.StatusOK, rec.Code)
+	var session struct {
+		Nonce string `json:"nonce"`
+	}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&session))
+	require.NotEmpty(t, session.Nonce)
+	assert.Equal(t, http.StatusConflict, startSession().Code)
+
+	// Every write returns the nonce of the next one
+	rec = request(http.MethodPost, session.Nonce)
+	require.Equal(t, http.StatusOK, rec.Code)
+	first := rec.Header().Get(nextNonceHeader)
+	require.NotEmpty(t, first)
+	rec = request(http.MethodPost, first)
+	require.Equal(t, http.StatusOK, rec.Code)
+	second := rec.Header().Get(nextNonceHeader)
+	require.NotEmpty(t, second)
+	assert.NotEqual(t, first, second)
+
+	// Replaying a captured write fails with or without its nonce, and so does a nonce the server did not issue
+	for _, nonce := range []string{"", session.Nonce, first, "client-chosen"} {
+		rec = request(http.MethodPost, nonce)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code, nonce)
+		assert.Empty(t, rec.Header().Get(nextNonceHeader))
+	}
+	assert.Equal(t, http.StatusOK, request(http.MethodPost, second).Code)
+	assert.Equal(t, 5, calls)
+
+	rec = httptest.NewRecorder()
+	metrics := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	metrics.Header.Set("Authorization", "tma "+initData)
+	hs.handleMetrics(rec, metrics)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `library_api_auth_rejections_total{reason="replay"} 5`)
+	assert.Contains(t, rec.Body.String(), `library_api_auth_rejections_total{reason="no_write_session"} 1`)
+	assert.Contains(t, rec.Body.String(), `library_api_auth_rejections_total{reason="expired"} 0`)
+	assert.Contains(t, rec.Body.String(), "library_api_replay_cache_entries 1")
+}
+
+func TestHandleNonce_PollingMode(t *testing.T) {
+	hs, _ := newTestHTTPServer(t)
+
+	// Writes are not checked in polling mode, but the Mini App starts its session the same way
+	rec := httptest.NewRecorder()
+	hs.handleNonce(rec, httptest.NewRequest(http.MethodGet, "/api/nonce", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"nonce"`)
+	assert.Equal(t, 0, hs.replays.size())
+}
+
+func TestHandleMetrics_RequiresAuth(t *testing.T) {
+	hs := newTestHTTPServerWebhook(t)
+
+	secret, hash, err := generateAPIToken()
+	require.NoError(t, err)
+	_, err = hs.bot.db.CreateAPIToken(context.Background(), models.APIToken{Name: "prometheus", Scope: models.TokenScopeRead, OwnerID: 123, Hash: hash})
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	hs.handleMetrics(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	// A scraper authenticates with a read token
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.Header.Set("Authorization", "Bearer "+secret)
+	rec = httptest.NewRecorder()
+	hs.handleMetrics(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `library_api_auth_rejections_total{reason="missing_credentials"} 1`)
+}
+
+func TestReplayCache(t *testing.T) {
+	var cache replayCache
+	now := time.Now()
+	expires := now.Add(time.Hour)
+
+	// Writes need a started session, and a session starts once per initData
+	_, err := cache.next("hash=abc", "", now)
+	assert.ErrorIs(t, err, errNoWriteSession)
+	nonce, err := cache.start("hash=abc", expires, now)
+	require.NoError(t, err)
+	_, err = cache.start("hash=abc", expires, now)
+	assert.ErrorIs(t, err, errReplayed)
+	_, err = cache.next("hash=abc", "", now)
+	assert.ErrorIs(t, err, errReplayed)
+	next, err := cache.next("hash=abc", nonce, now)
+	require.NoError(t, err)
+
+	// The session is remembered until its initData expires, however long ago it last wrote
+	later := now.Add(11 * time.Minute)
+	for _, replayed := range []string{"", nonce} {
+		_, err = cache.next("hash=abc", replayed, later)
+		assert.ErrorIs(t, err, errReplayed)
+	}
+	_, err = cache.start("hash=abc", expires, later)
+	assert.ErrorIs(t, err, errReplayed)
+	_, err = cache.next("hash=abc", next, later)
+	require.NoError(t, err)
+
+	// Expired sessions are forgotten, soonest expiry first
+	_, err = cache.start("hash=def", now.Add(time.Minute), now)
+	require.NoError(t, err)
+	assert.Equal(t, 2, cache.size())
+	_, err = cache.start("hash=ghi", expires, now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, 2, cache.size())
+	_, err = cache.next("hash=abc", "", expires)
+	assert.ErrorIs(t, err, errNoWriteSession)
+	assert.Equal(t, 0, cache.size())
+
+	// A full cache rejects new sessions instead of forgetting sessions that are still valid
+	full := replayCache{}
+	for i := 0; i < maxWriteSessions; i++ {
+		_, err := full.start(fmt.Sprintf("hash=%d", i), expires, now)
+		require.NoError(t, err)
+	}
+	_, err = full.start("hash=new", expires, now)
+	assert.ErrorIs(t, err, errTooManySessions)
+	_, err = full.start("hash=new", expires.Add(time.Hour), expires)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, full.size())
+}
+
+func TestInitDataExpiry(t *testing.T) {
+	authDate := time.Now().Add(-time.Minute).Truncate(time.Second)
+
+	expires, err := initDataExpiry(generateTestInitData(t, testBotToken, 123, authDate), time.Hour)
+	require.NoError(t, err)
+	assert.True(t, expires.Equal(authDate.Add(time.Hour)), expires)
+
+	_, err = initDataExpiry("hash=abc", time.Hour)
+	assert.Error(t, err)
+}
@@ -0,0 +1,345 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"library/internal/labels"
+	libmodels "library/internal/models"
+	"library/internal/storage"
+
+	"github.com/go-telegram/bot/models"
+	"go.uber.org/zap"
+)
+
+// twoColumnKeyboard lays out buttons in rows of two; callback data is "<prefix><index>"
+func twoColumnKeyboard(prefix string, texts []string) *models.InlineKeyboardMarkup {
+	var rows [][]models.InlineKeyboardButton
+	for i := 0; i < len(texts); i += 2 {
+		row := []models.InlineKeyboardButton{
+			{Text: texts[i], CallbackData: fmt.Sprintf("%s%d", prefix, i)},
+		}
+		if i+1 < len(texts) {
+			row = append(row, models.InlineKeyboardButton{Text: texts[i+1], CallbackData: fmt.Sprintf("%s%d", prefix, i+1)})
+		}
+		rows = append(rows, row)
+	}
+	return &models.InlineKeyboardMarkup{
+		InlineKeyboard: rows,
+	}
+}
+
+// labelFilterHelp explains the filter expressions accepted by /rare and /books_by_label
+const labelFilterHelp = `Combine labels with AND, OR, NOT and parentheses. key=value is the same as key:value, genre:* matches any genre and "quotes" keep a label exactly as written.
+Example: genre=fairy-tale AND (lang=en OR lang=de)`
+
+// rareOpText shows how the next label is combined with the filter of the rare books command
+var rareOpText = map[string]string{
+	"and": "AND",
+	"or":  "OR",
+	"not": "AND NOT",
+}
+
+// labelTerm returns the filter of a label picked on a keyboard; "key:*" matches the whole namespace
+func labelTerm(label string) labels.Expr {
+	if prefix, ok := strings.CutSuffix(label, labels.Separator+"*"); ok {
+		return labels.Prefix(prefix)
+	}
+	return labels.Label(label)
+}
+
+// combineFilters combines filter with term: "and", "or", or "not" for AND NOT.
+// Without a filter the term alone is returned.
+func combineFilters(filter labels.Expr, op string, term labels.Expr) labels.Expr {
+	if filter == nil {
+		return term
+	}
+	switch op {
+	case "or":
+		if or, ok := filter.(labels.Or); ok {
+			return append(or, term)
+		}
+		return labels.Or{filter, term}
+	case "not":
+		term = labels.Not{Expr: term}
+	}
+	if and, ok := filter.(labels.And); ok {
+		return append(and, term)
+	}
+	return labels.And{filter, term}
+}
+
+// labelFilterKeyboard lists the labels in two columns, preceded by a "key: any" button for every
+// namespace in use; callback data is "<prefix><label>" or "<prefix><key>:*"
+func labelFilterKeyboard(prefix string, all []string) *models.InlineKeyboardMarkup {
+	var rows [][]models.InlineKeyboardButton
+	var row []models.InlineKeyboardButton
+	seen := make(map[string]bool)
+	for _, label := range all {
+		key := labels.Namespace(label)
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		row = append(row, models.InlineKeyboardButton{Text: fmt.Sprintf("🗂 %s: any", key), CallbackData: prefix + key + labels.Separator + "*"})
+		if len(row) == 2 {
+			rows = append(rows, row)
+			row = nil
+		}
+	}
+	if len(row) > 0 {
+		rows = append(rows, row)
+	}
+
+	for i := 0; i < len(all); i += 2 {
+		row := []models.InlineKeyboardButton{
+			{Text: all[i], CallbackData: prefix + all[i]},
+		}
+		if i+1 < len(all) {
+			row = append(row, models.InlineKeyboardButton{Text: all[i+1], CallbackData: prefix + all[i+1]})
+		}
+		rows = append(rows, row)
+	}
+	return &models.InlineKeyboardMarkup{
+		InlineKeyboard: rows,
+	}
+}
+
+// handleRemoveLabelStart starts the remove label command with the books that have labels
+func (b *Bot) handleRemoveLabelStart(ctx context.Context, message *models.Message) {
+	userID := message.From.ID
+
+	books, err := b.db.ListReadableBooks(ctx)
+	if err != nil {
+		b.logger.Error("Failed to list readable books",
+			zap.Error(err),
+			zap.Int64("user_id", userID),
+		)
+		b.sendMessageInThread(ctx, message.Chat.ID, fmt.Sprintf("Error: %v", err), message.MessageThreadID)
+		return
+	}
+
+	var labeled []libmodels.Book
+	var names []string
+	for _, book := range books {
+		if len(book.Labels) > 0 {
+			labeled = append(labeled, book)
+			names = append(names, book.Name)
+		}
+	}
+	if len(labeled) == 0 {
+		b.sendMessageInThread(ctx, message.Chat.ID, "No books have labels.", message.MessageThreadID)
+		return
+	}
+
+	b.statesMu.Lock()
+	b.states[userID] = &ConversationState{
+		Command:         "remove_label",
+		Step:            1,
+		Data:            map[string]interface{}{"books": labeled},
+		MessageThreadID: message.MessageThreadID,
+	}
+	b.statesMu.Unlock()
+
+	b.sendMessageInThreadWithMarkup(ctx, message.Chat.ID, "📚 Select a book to remove a label from:", message.MessageThreadID, twoColumnKeyboard("rmlabel_book:", names))
+}
+
+// handleRemoveLabelBookCallback shows the labels of the selected book
+func (b *Bot) handleRemoveLabelBookCallback(ctx context.Context, query *models.CallbackQuery, state *ConversationState) {
+	idx, err := strconv.Atoi(strings.TrimPrefix(query.Data, "rmlabel_book:"))
+	if err != nil {
+		return
+	}
+
+	books, ok := state.Data["books"].([]libmodels.Book)
+	if !ok || idx < 0 || idx >= len(books) {
+		b.sendMessageInThread(ctx, getChatIDFromQuery(query), "Error: Invalid book selection", state.MessageThreadID)
+		state.Step = -1
+		return
+	}
+
+	book := books[idx]
+	state.Data["book"] = book
+	state.Step = 2
+	b.sendMessageInThreadWithMarkup(ctx, getChatIDFromQuery(query), fmt.Sprintf("🏷 Which label should be removed from '%s'?", book.Name), state.MessageThreadID, twoColumnKeyboard("rmlabel_label:", book.Labels))
+}
+
+// handleRemoveLabelLabelCallback removes the selected label from the selected book
+func (b *Bot) handleRemoveLabelLabelCallback(ctx context.Context, query *models.CallbackQuery, state *ConversationState) {
+	idx, err := strconv.Atoi(strings.TrimPrefix(query.Data, "rmlabel_label:"))
+	if err != nil {
+		return
+	}
+
+	book, ok := state.Data["book"].(libmodels.Book)
+	if !ok || idx < 0 || idx >= len(book.Labels) {
+		b.sendMessageInThread(ctx, getChatIDFromQuery(query), "Error: Invalid label selection", state.MessageThreadID)
+		state.Step = -1
+		return
+	}
+
+	label := book.Labels[idx]
+	if err := b.db.RemoveLabelFromBook(ctx, book.ID, label); err != nil {
+		b.logger.Error("Failed to remove label from book",
+			zap.Error(err),
+			zap.Int64("user_id", query.From.ID),
+			zap.String("book", book.Name),
+			zap.String("label", label),
+		)
+		b.sendMessageInThread(ctx, getChatIDFromQuery(query), fmt.Sprintf("Error: %v", err), state.MessageThreadID)
+	} else {
+		b.sendMessageInThread(ctx, getChatIDFromQuery(query), fmt.Sprintf("✅ Label '%s' removed from book '%s'", label, book.Name), state.MessageThreadID)
+	}
+	state.Step = -1
+}
+
+// handleLabelsStart lists all labels to rename or merge them
+func (b *Bot) handleLabelsStart(ctx context.Context, message *models.Message) {
+	userID := message.From.ID
+
+	labels, err := b.db.GetAllLabels(ctx)
+	if err != nil {
+		b.logger.Error("Failed to get labels",
+			zap.Error(err),
+			zap.Int64("user_id", userID),
+		)
+		b.sendMessageInThread(ctx, message.Chat.ID, fmt.Sprintf("Error: %v", err), message.MessageThreadID)
+		return
+	}
+
+	if len(labels) == 0 {
+		b.sendMessageInThread(ctx, message.Chat.ID, "No labels found. Use /add_label to add labels to books.", message.MessageThreadID)
+		return
+	}
+
+	b.statesMu.Lock()
+	b.states[userID] = &ConversationState{
+		Command:         "labels",
+		Step:            1,
+		Data:            map[string]interface{}{"labels": labels},
+		MessageThreadID: message.MessageThreadID,
+	}
+	b.statesMu.Unlock()
+
+	b.sendMessageInThreadWithMarkup(ctx, message.Chat.ID, "🏷 Select a label to manage:", message.MessageThreadID, twoColumnKeyboard("labels_select:", labels))
+}
+
+// handleLabelsSelectCallback shows the actions for the selected label
+func (b *Bot) handleLabelsSelectCallback(ctx context.Context, query *models.CallbackQuery, state *ConversationState) {
+	idx, err := strconv.Atoi(strings.TrimPrefix(query.Data, "labels_select:"))
+	if err != nil {
+		return
+	}
+
+	labels, ok := state.Data["labels"].([]string)
+	if !ok || idx < 0 || idx >= len(labels) {
+		b.sendMessageInThread(ctx, getChatIDFromQuery(query), "Error: Invalid label selection", state.MessageThreadID)
+		state.Step = -1
+		return
+	}
+
+	label := labels[idx]
+	state.Data["label"] = label
+
+	buttons := []models.InlineKeyboardButton{
+		{Text: "✏️ Rename", CallbackData: "labels_action:rename"},
+	}
+	if len(labels) > 1 {
+		buttons = append(buttons, models.InlineKeyboardButton{Text: "🔀 Merge into…", CallbackData: "labels_action:merge"})
+	}
+	keyboard := &models.InlineKeyboardMarkup{
+		InlineKeyboard: [][]models.InlineKeyboardButton{buttons},
+	}
+	b.sendMessageInThreadWithMarkup(ctx, getChatIDFromQuery(query), fmt.Sprintf("🏷 %s — what would you like to do?", label), state.MessageThreadID, keyboard)
+}
+
+// handleLabelsActionCallback asks for the new name or the label to merge into
+func (b *Bot) handleLabelsActionCallback(ctx context.Context, query *models.CallbackQuery, state *ConversationState) {
+	label, ok := state.Data["label"].(string)
+	if !ok {
+		b.sendMessageInThread(ctx, getChatIDFromQuery(query), "Error: Label not selected", state.MessageThreadID)
+		state.Step = -1
+		return
+	}
+
+	switch strings.TrimPrefix(query.Data, "labels_action:") {
+	case "rename":
+		state.Data["awaiting_rename"] = true
+		state.Step = 2
+		b.sendMessageInThread(ctx, getChatIDFromQuery(query), fmt.Sprintf("✏️ Enter the new name for label '%s':", label), state.MessageThreadID)
+	case "merge":
+		labels, _ := state.Data["labels"].([]string)
+		var targets []string
+		for _, other := range labels {
+			if other != label {
+				targets = append(targets, other)
+			}
+		}
+		state.Data["targets"] = targets
+		b.sendMessageInThreadWithMarkup(ctx, getChatIDFromQuery(query), fmt.Sprintf("🔀 Merge '%s' into which label?", label), state.MessageThreadID, twoColumnKeyboard("labels_merge:", targets))
+	}
+}
+
+// handleLabelsMergeCallback merges the selected label into the chosen target
+func (b *Bot) handleLabelsMergeCallback(ctx context.Context, query *models.CallbackQuery, state *ConversationState) {
+	idx, err := strconv.Atoi(strings.TrimPrefix(query.Data, "labels_merge:"))
+	if err != nil {
+		return
+	}
+
+	label, _ := state.Data["label"].(string)
+	targets, ok := state.Data["targets"].([]string)
+	if !ok || label == "" || idx < 0 || idx >= len(targets) {
+		b.sendMessageInThread(ctx, getChatIDFromQuery(query), "Error: Invalid label selection", state.MessageThreadID)
+		state.Step = -1
+		return
+	}
+
+	target := targets[idx]
+	if err := b.db.MergeLabels(ctx, []string{label}, target); err != nil {
+		b.logger.Error("Failed to merge labels",
+			zap.Error(err),
+			zap.Int64("user_id", query.From.ID),
+			zap.String("label", label),
+			zap.String("target", target),
+		)
+		b.sendMessageInThread(ctx, getChatIDFromQuery(query), fmt.Sprintf("Error: %v", err), state.MessageThreadID)
+	} else {
+		b.sendMessageInThread(ctx, getChatIDFromQuery(query), fmt.Sprintf("✅ Label '%s' merged into '%s'", label, target), state.MessageThreadID)
+	}
+	state.Step = -1
+}
+
+// handleLabelsConversation handles the new name input of the labels command
+func (b *Bot) handleLabelsConversation(ctx context.Context, message *models.Message, state *ConversationState) {
+	if _, ok := state.Data["awaiting_rename"]; !ok || state.Step != 2 {
+		return
+	}
+
+	newName, err := labels.Normalize(message.Text)
+	if err != nil {
+		b.sendMessageInThread(ctx, message.Chat.ID, fmt.Sprintf("❌ Invalid label: %v. Please enter a name:", err), state.MessageThreadID)
+		return
+	}
+
+	label := state.Data["label"].(string)
+	err = b.db.RenameLabel(ctx, label, newName)
+	switch {
+	case err == nil:
+		b.sendMessageInThread(ctx, message.Chat.ID, fmt.Sprintf("✅ Label '%s' renamed to '%s'", label, newName), state.MessageThreadID)
+	case errors.Is(err, storage.ErrNotFound):
+		b.sendMessageInThread(ctx, message.Chat.ID, fmt.Sprintf("❌ Label '%s' no longer exists.", label), state.MessageThreadID)
+	default:
+		b.logger.Error("Failed to rename label",
+			zap.Error(err),
+			zap.Int64("user_id", message.From.ID),
+			zap.String("label", label),
+			zap.String("new_name", newName),
+		)
+		b.sendMessageInThread(ctx, message.Chat.ID, fmt.Sprintf("Error: %v\n\nUse 🔀 Merge in /labels to combine two labels.", err), state.MessageThreadID)
+	}
+	state.Step = -1
+}
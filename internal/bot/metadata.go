@@ -0,0 +1,239 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"library/internal/labels"
+	libmodels "library/internal/models"
+
+	"github.com/go-telegram/bot/models"
+	"go.uber.org/zap"
+)
+
+// bookMetadataField is an optional step of /new_book that fills one detail of the book
+type bookMetadataField struct {
+	prompt string
+	set    func(meta *libmodels.BookMetadata, message *models.Message) error
+}
+
+// bookMetadataFields are asked in order after the book name; each can be skipped
+var bookMetadataFields = []bookMetadataField{
+	{prompt: "✍️ Who is the author?", set: func(meta *libmodels.BookMetadata, message *models.Message) error {
+		meta.Author = message.Text
+		return nil
+	}},
+	{prompt: "🔢 ISBN?", set: func(meta *libmodels.BookMetadata, message *models.Message) error {
+		isbn, err := normalizeISBN(message.Text)
+		meta.ISBN = isbn
+		return err
+	}},
+	{prompt: "📚 Series? Add the volume after #, e.g. Harry Potter #2", set: func(meta *libmodels.BookMetadata, message *models.Message) error {
+		series, volume, err := parseSeries(message.Text)
+		meta.Series, meta.SeriesVolume = series, volume
+		return err
+	}},
+	{prompt: "🖼 Send a cover photo or an image URL:", set: func(meta *libmodels.BookMetadata, message *models.Message) error {
+		if len(message.Photo) > 0 {
+			// Photo sizes are ordered from smallest to largest
+			meta.Cover = message.Photo[len(message.Photo)-1].FileID
+			return nil
+		}
+		u, err := url.Parse(message.Text)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("send a photo or an http(s) link to an image")
+		}
+		meta.Cover = message.Text
+		return nil
+	}},
+	{prompt: "🧒 Recommended age, e.g. 3-5 or 6+?", set: func(meta *libmodels.BookMetadata, message *models.Message) error {
+		meta.Age = message.Text
+		return labels.ValidateAge(message.Text)
+	}},
+	{prompt: "📝 Any notes?", set: func(meta *libmodels.BookMetadata, message *models.Message) error {
+		meta.Notes = message.Text
+		return nil
+	}},
+}
+
+// newBookKeyboard lets the user skip a detail or stop adding details
+var newBookKeyboard = &models.InlineKeyboardMarkup{
+	InlineKeyboard: [][]models.InlineKeyboardButton{
+		{
+			{Text: "⏭ Skip", CallbackData: "newbook:skip"},
+			{Text: "✅ Done", CallbackData: "newbook:done"},
+		},
+	},
+}
+
+// askBookMetadata asks for the detail of the current step, or saves the details after the last one
+func (b *Bot) askBookMetadata(ctx context.Context, chatID int64, state *ConversationState) {
+	idx := state.Step - 2
+	if idx >= len(bookMetadataFields) {
+		b.saveBookMetadata(ctx, chatID, state)
+		return
+	}
+	b.sendMessageInThreadWithMarkup(ctx, chatID, bookMetadataFields[idx].prompt, state.MessageThreadID, newBookKeyboard)
+}
+
+// handleBookMetadataInput stores the answer to the current /new_book detail and asks for the next one
+func (b *Bot) handleBookMetadataInput(ctx context.Context, message *models.Message, state *ConversationState) {
+	idx := state.Step - 2
+	if idx < 0 || idx >= len(bookMetadataFields) {
+		return
+	}
+
+	input := *message
+	input.Text = strings.TrimSpace(message.Text)
+	if input.Text == "" && len(input.Photo) == 0 {
+		b.sendMessageInThreadWithMarkup(ctx, message.Chat.ID, "Please answer with text, or press Skip.", state.MessageThreadID, newBookKeyboard)
+		return
+	}
+
+	meta, _ := state.Data["metadata"].(libmodels.BookMetadata)
+	if err := bookMetadataFields[idx].set(&meta, &input); err != nil {
+		b.sendMessageInThreadWithMarkup(ctx, message.Chat.ID, fmt.Sprintf("❌ %v\n\n%s", err, bookMetadataFields[idx].prompt), state.MessageThreadID, newBookKeyboard)
+		return
+	}
+	state.Data["metadata"] = meta
+
+	state.Step++
+	b.askBookMetadata(ctx, message.Chat.ID, state)
+}
+
+// handleNewBookCallback skips the current /new_book detail or finishes with the details given so far
+func (b *Bot) handleNewBookCallback(ctx context.Context, query *models.CallbackQuery, state *ConversationState) {
+	if state.Command != "new_book" || state.Step < 2 {
+		return
+	}
+
+	chatID := getChatIDFromQuery(query)
+	switch strings.TrimPrefix(query.Data, "newbook:") {
+	case "skip":
+		state.Step++
+		b.askBookMetadata(ctx, chatID, state)
+	case "done":
+		b.saveBookMetadata(ctx, chatID, state)
+	}
+}
+
+// saveBookMetadata stores the details collected by /new_book and completes the conversation
+func (b *Bot) saveBookMetadata(ctx context.Context, chatID int64, state *ConversationState) {
+	state.Step = -1
+
+	bookID, _ := state.Data["book_id"].(string)
+	name, _ := state.Data["book_name"].(string)
+	meta, _ := state.Data["metadata"].(libmodels.BookMetadata)
+	if meta == (libmodels.BookMetadata{}) {
+		b.sendMessageInThread(ctx, chatID, fmt.Sprintf("✅ Book '%s' saved without details.", name), state.MessageThreadID)
+		return
+	}
+
+	if err := b.db.UpdateBookMetadata(ctx, bookID, meta); err != nil {
+		b.logger.Error("Failed to update book metadata",
+			zap.Error(err),
+			zap.String("book", name),
+		)
+		b.sendMessageInThread(ctx, chatID, fmt.Sprintf("Error saving book details: %v", err), state.MessageThreadID)
+		return
+	}
+	b.sendMessageInThread(ctx, chatID, fmt.Sprintf("✅ Details saved for '%s':\n%s", name, formatBookMetadata(meta)), state.MessageThreadID)
+}
+
+// formatBookMetadata lists the known details of a book, one per line
+func formatBookMetadata(meta libmodels.BookMetadata) string {
+	var text strings.Builder
+	if meta.Author != "" {
+		text.WriteString(fmt.Sprintf("✍️ Author: %s\n", meta.Author))
+	}
+	if meta.Series != "" {
+		text.WriteString(fmt.Sprintf("📚 Series: %s", meta.Series))
+		if meta.SeriesVolume > 0 {
+			text.WriteString(fmt.Sprintf(", volume %d", meta.SeriesVolume))
+		}
+		text.WriteString("\n")
+	}
+	if meta.ISBN != "" {
+		text.WriteString(fmt.Sprintf("🔢 ISBN: %s\n", meta.ISBN))
+	}
+	if meta.Age != "" {
+		text.WriteString(fmt.Sprintf("🧒 Age: %s\n", meta.Age))
+	}
+	if meta.Cover != "" {
+		cover := "photo"
+		if isCoverURL(meta.Cover) {
+			cover = meta.Cover
+		}
+		text.WriteString(fmt.Sprintf("🖼 Cover: %s\n", cover))
+	}
+	if meta.Notes != "" {
+		text.WriteString(fmt.Sprintf("📝 Notes: %s\n", meta.Notes))
+	}
+	return text.String()
+}
+
+// isCoverURL reports whether a cover is an image URL rather than a Telegram file_id
+func isCoverURL(cover string) bool {
+	return strings.HasPrefix(cover, "http://") || strings.HasPrefix(cover, "https://")
+}
+
+// normalizeISBN removes spaces and hyphens from an ISBN-10 or ISBN-13 and verifies its check digit
+func normalizeISBN(input string) (string, error) {
+	isbn := strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(input))
+
+	sum := 0
+	switch len(isbn) {
+	case 10:
+		for i, r := range isbn {
+			switch {
+			case r >= '0' && r <= '9':
+				sum += int(r-'0') * (10 - i)
+			case r == 'X' && i == 9:
+				sum += 10
+			default:
+				return "", fmt.Errorf("invalid ISBN %q: unexpected %q", input, r)
+			}
+		}
+		if sum%11 != 0 {
+			return "", fmt.Errorf("invalid ISBN %q: wrong check digit", input)
+		}
+	case 13:
+		for i, r := range isbn {
+			if r < '0' || r > '9' {
+				return "", fmt.Errorf("invalid ISBN %q: unexpected %q", input, r)
+			}
+			digit := int(r - '0')
+			if i%2 == 1 {
+				digit *= 3
+			}
+			sum += digit
+		}
+		if sum%10 != 0 {
+			return "", fmt.Errorf("invalid ISBN %q: wrong check digit", input)
+		}
+	default:
+		return "", fmt.Errorf("invalid ISBN %q: expected 10 or 13 digits", input)
+	}
+	return isbn, nil
+}
+
+// parseSeries splits "Harry Potter #2" into the series name and the volume (0 if not given)
+func parseSeries(input string) (string, int, error) {
+	series, volumeText, hasVolume := strings.Cut(input, "#")
+	series = strings.TrimSpace(series)
+	if series == "" {
+		return "", 0, fmt.Errorf("series name cannot be empty")
+	}
+	if !hasVolume {
+		return series, 0, nil
+	}
+
+	volume, err := strconv.Atoi(strings.TrimSpace(volumeText))
+	if err != nil || volume < 1 || volume > 65535 {
+		return "", 0, fmt.Errorf("invalid volume %q, use a number such as #2", strings.TrimSpace(volumeText))
+	}
+	return series, volume, nil
+}
@@ -0,0 +1,75 @@
+package bot
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Reasons for rejecting an API request during authentication, used as metric labels
+const (
+	rejectMissingCredentials = "missing_credentials" // no or unknown Authorization header
+	rejectInvalidInitData    = "invalid_init_data"   // malformed or wrongly signed initData
+	rejectExpired            = "expired"             // auth_date older than INIT_DATA_MAX_AGE
+	rejectUserNotAllowed     = "user_not_allowed"
+	rejectReplay             = "replay"            // write without the current nonce, or a second GET /api/nonce
+	rejectNoWriteSession     = "no_write_session"  // write before GET /api/nonce
+	rejectTooManySessions    = "too_many_sessions" // replay cache full
+	rejectInvalidToken       = "invalid_token"
+	rejectTokenReadOnly      = "token_read_only"
+)
+
+// rejectReasons lists every reason, so all counters are exported from the start
+var rejectReasons = []string{
+	rejectMissingCredentials, rejectInvalidInitData, rejectExpired, rejectUserNotAllowed,
+	rejectReplay, rejectNoWriteSession, rejectTooManySessions, rejectInvalidToken, rejectTokenReadOnly,
+}
+
+// authRejections counts rejected API requests per reason. The zero value is ready to use.
+type authRejections struct {
+	mu     sync.Mutex
+	counts map[string]int64
+}
+
+// inc counts a rejected request
+func (r *authRejections) inc(reason string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.counts == nil {
+		r.counts = make(map[string]int64)
+	}
+	r.counts[reason]++
+}
+
+// get returns the number of requests rejected for a reason
+func (r *authRejections) get(reason string) int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.counts[reason]
+}
+
+// handleMetrics exposes the authentication counters in the Prometheus text format. It is served on
+// the public listener, so it needs the same authentication as the API; scrapers use a read token.
+func (hs *HTTPServer) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	hs.authMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		var text strings.Builder
+		text.WriteString("# HELP library_api_auth_rejections_total API requests rejected during authentication, by reason.\n")
+		text.WriteString("# TYPE library_api_auth_rejections_total counter\n")
+		for _, reason := range rejectReasons {
+			text.WriteString(fmt.Sprintf("library_api_auth_rejections_total{reason=%q} %d\n", reason, hs.rejections.get(reason)))
+		}
+		text.WriteString("# HELP library_api_replay_cache_entries Mini App sessions whose next write nonce is remembered.\n")
+		text.WriteString("# TYPE library_api_replay_cache_entries gauge\n")
+		text.WriteString(fmt.Sprintf("library_api_replay_cache_entries %d\n", hs.replays.size()))
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		w.Write([]byte(text.String()))
+	})(w, r)
+}
@@ -0,0 +1,153 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strings"
+
+	libmodels "library/internal/models"
+	"library/internal/storage"
+
+	tgbot "github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"go.uber.org/zap"
+)
+
+// Notification types; each can be routed to its own chat or topic
+const (
+	NotificationNewBook    = "new_book"
+	NotificationNewEvent   = "new_event"
+	NotificationLabelAdded = "label_added"
+)
+
+// NotificationRoute is the chat (and optional forum topic) a notification type is posted to
+type NotificationRoute struct {
+	ChatID   int64
+	ThreadID int // 0 = general/no topic
+}
+
+// SetNotificationRoutes overrides where notifications of the given types are posted.
+// Types without a route go to the notification chat; a route with ChatID 0 disables the type.
+func (b *Bot) SetNotificationRoutes(routes map[string]NotificationRoute) {
+	b.notificationRoutes = routes
+}
+
+// notificationRoute returns where notifications of a type are posted
+func (b *Bot) notificationRoute(kind string) NotificationRoute {
+	if route, ok := b.notificationRoutes[kind]; ok {
+		return route
+	}
+	return NotificationRoute{ChatID: b.notificationChatID, ThreadID: b.notificationThreadID}
+}
+
+// notify posts an HTML notification to the route of its type. It is skipped when the
+// route is disabled or is the chat and topic the change was made in (origin), since
+// the confirmation there already says the same.
+func (b *Bot) notify(ctx context.Context, kind, text string, origin NotificationRoute) {
+	route := b.notificationRoute(kind)
+	if route.ChatID == 0 || route == origin {
+		return
+	}
+
+	b.logger.Debug("Sending notification",
+		zap.String("type", kind),
+		zap.Int64("chat_id", route.ChatID),
+		zap.Int("thread_id", route.ThreadID),
+	)
+	b.sendHTMLMessageInThread(ctx, route.ChatID, text, route.ThreadID)
+}
+
+// sendHTMLMessageInThread sends an HTML formatted message to a specific thread/topic
+func (b *Bot) sendHTMLMessageInThread(ctx context.Context, chatID int64, text string, messageThreadID int) {
+	if b.api == nil {
+		return // For testing
+	}
+
+	params := &tgbot.SendMessageParams{
+		ChatID:    chatID,
+		Text:      text,
+		ParseMode: models.ParseModeHTML,
+	}
+	if messageThreadID != 0 {
+		params.MessageThreadID = messageThreadID
+	}
+
+	if _, err := b.api.SendMessage(ctx, params); err != nil {
+		b.logger.Warn("Failed to send notification", zap.Error(err), zap.Int64("chat_id", chatID))
+	}
+}
+
+// actorKey is the context key of the Telegram user who triggered the current request
+type actorKey struct{}
+
+// withActor returns a context carrying the Telegram user behind a request, who is also
+// the actor data changes are attributed to in the audit log
+func withActor(ctx context.Context, user *models.User, source string) context.Context {
+	if user == nil {
+		return storage.WithActor(ctx, storage.Actor{Source: source})
+	}
+	ctx = storage.WithActor(ctx, storage.Actor{ID: user.ID, Name: userDisplayName(*user), Source: source})
+	return context.WithValue(ctx, actorKey{}, *user)
+}
+
+// actorFromContext returns the Telegram user behind a request, if known
+func actorFromContext(ctx context.Context) (models.User, bool) {
+	user, ok := ctx.Value(actorKey{}).(models.User)
+	return user, ok
+}
+
+// actorLink formats the user from ctx as an HTML mention link, e.g. <a href="tg://user?id=1">Ann</a>
+func actorLink(ctx context.Context) string {
+	user, ok := actorFromContext(ctx)
+	if !ok {
+		return "someone"
+	}
+	return fmt.Sprintf(`<a href="tg://user?id=%d">%s</a>`, user.ID, html.EscapeString(userDisplayName(user)))
+}
+
+// userDisplayName returns the full name of a Telegram user, falling back to the username or ID
+func userDisplayName(user models.User) string {
+	name := strings.TrimSpace(user.FirstName + " " + user.LastName)
+	if name == "" {
+		name = user.Username
+	}
+	if name == "" {
+		name = fmt.Sprintf("user %d", user.ID)
+	}
+	return name
+}
+
+// notifyEvent announces a recorded (or undone) reading event; source says where it was logged
+func (b *Bot) notifyEvent(ctx context.Context, event libmodels.Event, undone bool, source string, origin NotificationRoute) {
+	title := "📖 <b>New reading event</b>"
+	if undone {
+		title = "↩️ <b>Reading event undone</b>"
+	}
+
+	var text strings.Builder
+	text.WriteString(title + "\n\n")
+	text.WriteString(fmt.Sprintf("📅 Date: %s\n", b.formatEventTime(event.Date)))
+	text.WriteString(fmt.Sprintf("📚 Book: <b>%s</b>\n", html.EscapeString(event.BookName)))
+	text.WriteString(fmt.Sprintf("👤 Readers: %s\n", html.EscapeString(strings.Join(event.ParticipantNames, ", "))))
+	details := sessionDetails{DurationMinutes: event.DurationMinutes, Progress: event.Progress, ProgressUnit: event.ProgressUnit}
+	if summary := details.String(); summary != "" && !undone {
+		text.WriteString(fmt.Sprintf("⏱ Session: %s\n", html.EscapeString(summary)))
+	}
+	text.WriteString(fmt.Sprintf("\nby %s via %s", actorLink(ctx), html.EscapeString(source)))
+
+	b.notify(ctx, NotificationNewEvent, text.String(), origin)
+}
+
+// notifyNewBook announces a newly registered book
+func (b *Bot) notifyNewBook(ctx context.Context, name string, origin NotificationRoute) {
+	text := fmt.Sprintf("📗 <b>New book</b>: %s\n\nadded by %s", html.EscapeString(name), actorLink(ctx))
+	b.notify(ctx, NotificationNewBook, text, origin)
+}
+
+// notifyLabelAdded announces a label added to one or more books
+func (b *Bot) notifyLabelAdded(ctx context.Context, bookNames []string, label string, origin NotificationRoute) {
+	text := fmt.Sprintf("🏷 Label <b>%s</b> added to <b>%s</b>\n\nby %s",
+		html.EscapeString(label), html.EscapeString(strings.Join(bookNames, ", ")), actorLink(ctx))
+	b.notify(ctx, NotificationLabelAdded, text, origin)
+}
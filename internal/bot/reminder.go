@@ -0,0 +1,113 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	libmodels "library/internal/models"
+
+	"github.com/go-telegram/bot/models"
+	"go.uber.org/zap"
+)
+
+// handleRemind registers or unregisters the current chat (and topic) for the daily reminder:
+// /remind on, /remind off, or /remind to show the current state
+func (b *Bot) handleRemind(ctx context.Context, message *models.Message) {
+	settings, err := b.db.GetChatSettings(ctx, message.Chat.ID)
+	if err != nil {
+		b.logger.Error("Failed to get chat settings",
+			zap.Error(err),
+			zap.Int64("chat_id", message.Chat.ID),
+		)
+		b.sendMessageInThread(ctx, message.Chat.ID, fmt.Sprintf("Error: %v", err), message.MessageThreadID)
+		return
+	}
+
+	switch strings.ToLower(commandArgs(message.Text)) {
+	case "":
+		b.sendMessageInThread(ctx, message.Chat.ID, b.reminderStatus(settings), message.MessageThreadID)
+		return
+	case "on":
+		settings.ReminderEnabled = true
+		settings.ReminderThreadID = message.MessageThreadID
+	case "off":
+		settings.ReminderEnabled = false
+		settings.ReminderThreadID = 0
+	default:
+		b.sendMessageInThread(ctx, message.Chat.ID, "Usage: /remind on|off", message.MessageThreadID)
+		return
+	}
+
+	if err := b.db.SaveChatSettings(ctx, settings); err != nil {
+		b.logger.Error("Failed to save chat settings",
+			zap.Error(err),
+			zap.Int64("chat_id", message.Chat.ID),
+		)
+		b.sendMessageInThread(ctx, message.Chat.ID, fmt.Sprintf("Error: %v", err), message.MessageThreadID)
+		return
+	}
+
+	b.logger.Info("Daily reminder updated",
+		zap.Int64("user_id", message.From.ID),
+		zap.Int64("chat_id", message.Chat.ID),
+		zap.Bool("enabled", settings.ReminderEnabled),
+	)
+	b.sendMessageInThread(ctx, message.Chat.ID, b.reminderStatus(settings), message.MessageThreadID)
+}
+
+// reminderStatus describes the daily reminder setup of a chat
+func (b *Bot) reminderStatus(settings libmodels.ChatSettings) string {
+	if !settings.ReminderEnabled {
+		return "🔕 The daily reminder is off for this chat. Turn it on with /remind on"
+	}
+	if b.reminderTime == "" {
+		return "🔔 This chat is registered for the daily reminder, but REMINDER_TIME is not set, so no reminders are posted yet."
+	}
+	text := fmt.Sprintf("🔔 Every day at %s I'll post who reads tonight here.", b.reminderTime)
+	if b.reminderSkipIfRead {
+		text += " Days with a reading already logged are skipped."
+	}
+	return text + "\nTurn it off with /remind off"
+}
+
+// SendReadingReminder posts who reads tonight to every chat registered with /remind,
+// or to the notification chat if none is registered
+func (b *Bot) SendReadingReminder(ctx context.Context) error {
+	if b.reminderSkipIfRead {
+		now := b.now()
+		startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+		events, err := b.db.GetLastEventsFiltered(ctx, 1, startOfDay, time.Time{}, "")
+		if err != nil {
+			return fmt.Errorf("failed to check today's readings: %w", err)
+		}
+		if len(events) > 0 {
+			b.logger.Info("Skipping daily reminder, a reading was already logged today")
+			return nil
+		}
+	}
+
+	chats, err := b.db.ListReminderChats(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list reminder chats: %w", err)
+	}
+	if len(chats) == 0 && b.notificationChatID != 0 {
+		settings, err := b.db.GetChatSettings(ctx, b.notificationChatID)
+		if err != nil {
+			return fmt.Errorf("failed to get chat settings: %w", err)
+		}
+		settings.ReminderThreadID = b.notificationThreadID
+		chats = append(chats, settings)
+	}
+
+	for _, settings := range chats {
+		text, err := b.whoIsNextText(ctx, settings)
+		if err != nil {
+			return fmt.Errorf("failed to compute next reader: %w", err)
+		}
+		b.sendMessageInThread(ctx, settings.ChatID, "🌙 Who reads tonight?\n\n"+text, settings.ReminderThreadID)
+		b.logger.Info("Daily reminder sent", zap.Int64("chat_id", settings.ChatID))
+	}
+	return nil
+}
@@ -0,0 +1,207 @@
+package bot
+
+import (
+	"container/heap"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// initData stays the same for the whole Mini App session, so a captured write request could be
+// sent again as long as its initData is valid. The Mini App therefore starts a write session with
+// GET /api/nonce, which returns the first nonce once per initData. Every write must send the
+// session's latest nonce in nonceHeader and gets the next one in nextNonceHeader, so each nonce
+// is used once and a captured request cannot be replayed.
+const (
+	nonceHeader     = "X-Request-Nonce"
+	nextNonceHeader = "X-Next-Nonce"
+)
+
+// maxWriteSessions caps the sessions remembered at once; while it is reached, new sessions are
+// rejected rather than forgetting sessions whose initData is still valid
+const maxWriteSessions = 10000
+
+var (
+	errReplayed        = errors.New("initData was already used without the current nonce")
+	errNoWriteSession  = errors.New("no write session was started for initData")
+	errTooManySessions = errors.New("too many active write sessions")
+)
+
+// writeSession is the nonce expected with the next write of a Mini App session
+type writeSession struct {
+	hash    string    // hash parameter of the session's initData
+	nonce   string    // expected with the next write
+	expires time.Time // when the session's initData expires
+}
+
+// sessionHeap orders write sessions by expiry, soonest first
+type sessionHeap []*writeSession
+
+func (h sessionHeap) Len() int           { return len(h) }
+func (h sessionHeap) Less(i, j int) bool { return h[i].expires.Before(h[j].expires) }
+func (h sessionHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *sessionHeap) Push(x any)        { *h = append(*h, x.(*writeSession)) }
+func (h *sessionHeap) Pop() any {
+	old := *h
+	session := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return session
+}
+
+// replayCache remembers the expected nonce of every write session until its initData expires, so
+// initData that has written once can never write again without the current nonce. Sessions are
+// kept in a heap by expiry, so expired sessions are dropped without scanning all of them.
+// The zero value is ready to use.
+type replayCache struct {
+	mu       sync.Mutex
+	sessions map[string]*writeSession // initData hash -> session
+	expiry   sessionHeap
+}
+
+// start begins the write session of initData that expires at expires and returns its first
+// nonce. A session can be started only once per initData.
+func (c *replayCache) start(initData string, expires, now time.Time) (string, error) {
+	hash := initDataHash(initData)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.evict(now)
+	if _, seen := c.sessions[hash]; seen {
+		return "", errReplayed
+	}
+	if len(c.sessions) >= maxWriteSessions {
+		return "", errTooManySessions
+	}
+
+	nonce, err := newNonce()
+	if err != nil {
+		return "", err
+	}
+	if c.sessions == nil {
+		c.sessions = make(map[string]*writeSession)
+	}
+	session := &writeSession{hash: hash, nonce: nonce, expires: expires}
+	c.sessions[hash] = session
+	heap.Push(&c.expiry, session)
+	return nonce, nil
+}
+
+// next checks the nonce of a write request and returns the nonce for the session's next write
+func (c *replayCache) next(initData, nonce string, now time.Time) (string, error) {
+	hash := initDataHash(initData)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.evict(now)
+	session, seen := c.sessions[hash]
+	if !seen {
+		return "", errNoWriteSession
+	}
+	if subtle.ConstantTimeCompare([]byte(nonce), []byte(session.nonce)) != 1 {
+		return "", errReplayed
+	}
+
+	next, err := newNonce()
+	if err != nil {
+		return "", err
+	}
+	session.nonce = next
+	return next, nil
+}
+
+// evict forgets the sessions whose initData has expired; callers hold c.mu
+func (c *replayCache) evict(now time.Time) {
+	for len(c.expiry) > 0 && !now.Before(c.expiry[0].expires) {
+		session := heap.Pop(&c.expiry).(*writeSession)
+		delete(c.sessions, session.hash)
+	}
+}
+
+// size returns the number of remembered write sessions
+func (c *replayCache) size() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.sessions)
+}
+
+// newNonce returns a random nonce
+func newNonce() (string, error) {
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(random), nil
+}
+
+// initDataHash returns the hash parameter that identifies initData
+func initDataHash(initData string) string {
+	values, _ := url.ParseQuery(initData)
+	return values.Get("hash")
+}
+
+// initDataExpiry returns when initData stops being accepted: maxAge after its auth_date
+func initDataExpiry(initData string, maxAge time.Duration) (time.Time, error) {
+	values, err := url.ParseQuery(initData)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to parse initData: %w", err)
+	}
+	authDate, err := strconv.ParseInt(values.Get("auth_date"), 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid auth_date %q", values.Get("auth_date"))
+	}
+	return time.Unix(authDate, 0).Add(maxAge), nil
+}
+
+// handleNonce starts the write session of the Mini App's initData and returns its first nonce.
+// In polling mode and for API tokens writes need no nonce; the returned one is not checked.
+func (hs *HTTPServer) handleNonce(w http.ResponseWriter, r *http.Request) {
+	hs.authMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		initData, ok := strings.CutPrefix(r.Header.Get("Authorization"), "tma ")
+		var nonce string
+		var err error
+		if hs.webhookMode && ok {
+			var expires time.Time
+			expires, err = initDataExpiry(initData, hs.maxInitDataAge())
+			if err == nil {
+				nonce, err = hs.replays.start(initData, expires, time.Now())
+			}
+		} else {
+			nonce, err = newNonce()
+		}
+		switch {
+		case errors.Is(err, errReplayed):
+			hs.bot.logger.Warn("Rejected second write session for initData", zap.String("remote_addr", r.RemoteAddr))
+			hs.rejections.inc(rejectReplay)
+			writeJSONError(w, http.StatusConflict, "Session was already started, reopen the Mini App")
+			return
+		case errors.Is(err, errTooManySessions):
+			hs.bot.logger.Warn("Rejected write session", zap.Error(err))
+			hs.rejections.inc(rejectTooManySessions)
+			writeJSONError(w, http.StatusServiceUnavailable, "Too many active sessions, try again later")
+			return
+		case err != nil:
+			hs.bot.logger.Error("Failed to start write session", zap.Error(err))
+			writeJSONError(w, http.StatusInternalServerError, "Internal server error")
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]string{"nonce": nonce})
+	})(w, r)
+}
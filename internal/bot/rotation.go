@@ -1,9 +1,153 @@
 package bot
 
 import (
+	"sort"
+	"time"
+
 	"library/internal/models"
 )
 
+// RotationInput is what a rotation strategy knows when picking the next reader
+type RotationInput struct {
+	Participants []models.Participant             // active participants, sorted by name
+	LastReaders  []string                         // names of the readers of the most recent event
+	Activity     map[string]models.ReaderActivity // reading activity by participant name
+	Skip         map[string]string                // participants to leave out: name -> reason
+}
+
+// skipped reports whether the participant is left out of the rotation
+func (input RotationInput) skipped(name string) bool {
+	_, ok := input.Skip[name]
+	return ok
+}
+
+// RotationStrategy decides who should read next for /who_is_next.
+// Strategies are selected per chat by Name and must return "" if nobody is eligible.
+type RotationStrategy interface {
+	Name() string
+	Description() string
+	Next(input RotationInput) string
+}
+
+// rotationStrategies lists the built-in strategies; the first one is the default
+var rotationStrategies = []RotationStrategy{
+	classicRotation{},
+	weeklyBalanceRotation{},
+	leastRecentRotation{},
+}
+
+// rotationStrategyByName returns the strategy with the given name, or the default one
+func rotationStrategyByName(name string) RotationStrategy {
+	for _, strategy := range rotationStrategies {
+		if strategy.Name() == name {
+			return strategy
+		}
+	}
+	return rotationStrategies[0]
+}
+
+// classicRotation walks the children alphabetically with a parent's turn after the last child
+type classicRotation struct{}
+
+func (classicRotation) Name() string { return "classic" }
+
+func (classicRotation) Description() string {
+	return "Children in alphabetical order, then a parent"
+}
+
+func (classicRotation) Next(input RotationInput) string {
+	var participants []models.Participant
+	for _, p := range input.Participants {
+		// Skipped parents are dropped; skipped children keep their place so the order continues past them
+		if p.IsParent && input.skipped(p.Name) {
+			continue
+		}
+		participants = append(participants, p)
+	}
+
+	last := LastRotationReader(participants, input.LastReaders)
+	for range participants {
+		next := ComputeNextParticipant(participants, last)
+		if !input.skipped(next) {
+			return next
+		}
+		last = next
+	}
+	return ""
+}
+
+// weeklyBalanceRotation picks the child with the fewest reads this week
+type weeklyBalanceRotation struct{}
+
+func (weeklyBalanceRotation) Name() string { return "weekly" }
+
+func (weeklyBalanceRotation) Description() string {
+	return "The child with the fewest readings this week"
+}
+
+func (weeklyBalanceRotation) Next(input RotationInput) string {
+	return pickChild(input, func(a, b models.ReaderActivity) bool {
+		if a.ReadsSince != b.ReadsSince {
+			return a.ReadsSince < b.ReadsSince
+		}
+		return readBefore(a, b)
+	})
+}
+
+// leastRecentRotation picks the child who has waited longest since their last reading
+type leastRecentRotation struct{}
+
+func (leastRecentRotation) Name() string { return "least_recent" }
+
+func (leastRecentRotation) Description() string {
+	return "The child who read least recently"
+}
+
+func (leastRecentRotation) Next(input RotationInput) string {
+	return pickChild(input, readBefore)
+}
+
+// pickChild returns the first eligible child ordered by less, with ties broken by name
+func pickChild(input RotationInput, less func(a, b models.ReaderActivity) bool) string {
+	var candidates []models.ReaderActivity
+	for _, p := range input.Participants {
+		if p.IsParent || input.skipped(p.Name) {
+			continue
+		}
+		activity, ok := input.Activity[p.Name]
+		if !ok {
+			activity = models.ReaderActivity{ParticipantID: p.ID, ParticipantName: p.Name}
+		}
+		candidates = append(candidates, activity)
+	}
+	if len(candidates) == 0 {
+		return ""
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return less(candidates[i], candidates[j])
+	})
+	return candidates[0].ParticipantName
+}
+
+// readBefore reports whether a last read earlier than b; never read comes first
+func readBefore(a, b models.ReaderActivity) bool {
+	switch {
+	case a.LastReadDate == nil:
+		return b.LastReadDate != nil
+	case b.LastReadDate == nil:
+		return false
+	default:
+		return a.LastReadDate.Before(*b.LastReadDate)
+	}
+}
+
+// startOfWeek returns Monday 00:00 of the week containing t, in t's location
+func startOfWeek(t time.Time) time.Time {
+	daysSinceMonday := (int(t.Weekday()) + 6) % 7
+	return time.Date(t.Year(), t.Month(), t.Day()-daysSinceMonday, 0, 0, 0, 0, t.Location())
+}
+
 // ComputeNextParticipant determines who should read next based on rotation logic.
 //
 // Rotation rules:
@@ -68,3 +212,27 @@ func ComputeNextParticipant(participants []models.Participant, lastParticipantNa
 	// Unknown participant, default to first child
 	return children[0]
 }
+
+// LastRotationReader picks the reader of a shared event that the rotation should continue from.
+//
+// A parent among the readers wins (so the rotation restarts with the first child);
+// otherwise the child furthest along the rotation order is used. Readers who are
+// not active participants are ignored. Returns "" if no reader is known.
+func LastRotationReader(participants []models.Participant, readerNames []string) string {
+	attended := make(map[string]bool, len(readerNames))
+	for _, name := range readerNames {
+		attended[name] = true
+	}
+
+	last := ""
+	for _, p := range participants {
+		if !attended[p.Name] {
+			continue
+		}
+		if p.IsParent {
+			return p.Name
+		}
+		last = p.Name
+	}
+	return last
+}
@@ -2,6 +2,7 @@ package bot
 
 import (
 	"testing"
+	"time"
 
 	"library/internal/models"
 
@@ -258,3 +259,96 @@ func TestComputeNextParticipant_FullRotationCycle(t *testing.T) {
 		})
 	}
 }
+
+func TestLastRotationReader(t *testing.T) {
+	participants := []models.Participant{
+		{Name: "Alice", IsParent: false},
+		{Name: "Bob", IsParent: false},
+		{Name: "Charlie", IsParent: false},
+		{Name: "Mom", IsParent: true},
+	}
+
+	testCases := []struct {
+		name     string
+		readers  []string
+		expected string
+	}{
+		{"single reader", []string{"Alice"}, "Alice"},
+		{"children continue from furthest child", []string{"Bob", "Alice"}, "Bob"},
+		{"parent wins", []string{"Charlie", "Mom"}, "Mom"},
+		{"unknown readers ignored", []string{"Grandma", "Alice"}, "Alice"},
+		{"no known readers", []string{"Grandma"}, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, LastRotationReader(participants, tc.readers))
+		})
+	}
+
+	// Alice and Bob read together, so Charlie is next
+	assert.Equal(t, "Charlie", ComputeNextParticipant(participants, LastRotationReader(participants, []string{"Alice", "Bob"})))
+}
+
+func TestRotationStrategies(t *testing.T) {
+	participants := []models.Participant{
+		{ID: "a", Name: "Alice"},
+		{ID: "b", Name: "Bob"},
+		{ID: "c", Name: "Charlie"},
+		{ID: "m", Name: "Mom", IsParent: true},
+	}
+	now := time.Date(2024, 5, 10, 20, 0, 0, 0, time.UTC)
+	daysAgo := func(days int) *time.Time {
+		t := now.AddDate(0, 0, -days)
+		return &t
+	}
+	activity := map[string]models.ReaderActivity{
+		"Alice":   {ParticipantName: "Alice", ReadsSince: 2, LastReadDate: daysAgo(1)},
+		"Bob":     {ParticipantName: "Bob", ReadsSince: 1, LastReadDate: daysAgo(0)},
+		"Charlie": {ParticipantName: "Charlie", ReadsSince: 1, LastReadDate: daysAgo(3)},
+		"Mom":     {ParticipantName: "Mom", ReadsSince: 0},
+	}
+
+	testCases := []struct {
+		name     string
+		strategy string
+		last     []string
+		skip     map[string]string
+		expected string
+	}{
+		{"classic continues alphabetically", "classic", []string{"Alice"}, nil, "Bob"},
+		{"classic skips an away child", "classic", []string{"Alice"}, map[string]string{"Bob": "away"}, "Charlie"},
+		{"classic skips to the parent", "classic", []string{"Bob"}, map[string]string{"Charlie": "away"}, "Mom"},
+		{"classic skipped parent", "classic", []string{"Charlie"}, map[string]string{"Mom": "away"}, "Alice"},
+		{"weekly prefers fewest reads, then least recent", "weekly", nil, nil, "Charlie"},
+		{"weekly with skip", "weekly", nil, map[string]string{"Charlie": "away"}, "Bob"},
+		{"least recent", "least_recent", nil, nil, "Charlie"},
+		{"least recent with skip", "least_recent", nil, map[string]string{"Charlie": "away"}, "Alice"},
+		{"everyone skipped", "least_recent", nil, map[string]string{"Alice": "away", "Bob": "away", "Charlie": "away"}, ""},
+		{"unknown name falls back to classic", "unknown", []string{"Charlie"}, nil, "Mom"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			input := RotationInput{
+				Participants: participants,
+				LastReaders:  tc.last,
+				Activity:     activity,
+				Skip:         tc.skip,
+			}
+			assert.Equal(t, tc.expected, rotationStrategyByName(tc.strategy).Next(input))
+		})
+	}
+
+	// Children who never read go first
+	input := RotationInput{Participants: participants, Activity: map[string]models.ReaderActivity{"Alice": activity["Alice"]}}
+	assert.Equal(t, "Bob", rotationStrategyByName("least_recent").Next(input))
+}
+
+func TestStartOfWeek(t *testing.T) {
+	loc := time.FixedZone("UTC+3", 3*60*60)
+	// Friday evening and Sunday late night both belong to the week starting Monday 2024-05-06
+	assert.Equal(t, time.Date(2024, 5, 6, 0, 0, 0, 0, loc), startOfWeek(time.Date(2024, 5, 10, 20, 0, 0, 0, loc)))
+	assert.Equal(t, time.Date(2024, 5, 6, 0, 0, 0, 0, loc), startOfWeek(time.Date(2024, 5, 12, 23, 59, 0, 0, loc)))
+	assert.Equal(t, time.Date(2024, 5, 13, 0, 0, 0, 0, loc), startOfWeek(time.Date(2024, 5, 13, 0, 0, 0, 0, loc)))
+}
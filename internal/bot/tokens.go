@@ -0,0 +1,197 @@
+package bot
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	libmodels "library/internal/models"
+	"library/internal/storage"
+
+	"github.com/go-telegram/bot/models"
+	"go.uber.org/zap"
+)
+
+// apiTokenPrefix marks secrets issued by /token, so they are easy to recognise in scripts and logs
+const apiTokenPrefix = "lib_"
+
+// maxTokenName limits the length of a token name
+const maxTokenName = 64
+
+// handleToken creates, lists or revokes the personal API tokens of the user:
+// /token create <name> [read|write], /token list, /token revoke <name>
+func (b *Bot) handleToken(ctx context.Context, message *models.Message) {
+	const usage = "Usage:\n/token create <name> [read|write] - e.g. /token create tablet read\n/token list\n/token revoke <name>"
+
+	fields := strings.Fields(commandArgs(message.Text))
+	if len(fields) == 0 {
+		b.sendMessageInThread(ctx, message.Chat.ID, usage, message.MessageThreadID)
+		return
+	}
+
+	switch strings.ToLower(fields[0]) {
+	case "create":
+		// The secret is shown once in the reply, so it must not end up in a group chat
+		if message.Chat.Type != models.ChatTypePrivate {
+			b.sendMessageInThread(ctx, message.Chat.ID, "🔒 Tokens are secret. Send /token create in a private chat with the bot.", message.MessageThreadID)
+			return
+		}
+
+		args := fields[1:]
+		scope := libmodels.TokenScopeRead
+		if len(args) > 1 {
+			switch strings.ToLower(args[len(args)-1]) {
+			case "read":
+				args = args[:len(args)-1]
+			case "write":
+				scope = libmodels.TokenScopeReadWrite
+				args = args[:len(args)-1]
+			}
+		}
+		name := strings.Join(args, " ")
+		if name == "" || len([]rune(name)) > maxTokenName {
+			b.sendMessageInThread(ctx, message.Chat.ID, usage, message.MessageThreadID)
+			return
+		}
+
+		secret, hash, err := generateAPIToken()
+		if err != nil {
+			b.logger.Error("Failed to generate API token", zap.Error(err))
+			b.sendMessageInThread(ctx, message.Chat.ID, fmt.Sprintf("Error: %v", err), message.MessageThreadID)
+			return
+		}
+		_, err = b.db.CreateAPIToken(ctx, libmodels.APIToken{Name: name, Scope: scope, OwnerID: message.From.ID, Hash: hash})
+		if err != nil {
+			b.logger.Error("Failed to create API token",
+				zap.Error(err),
+				zap.Int64("user_id", message.From.ID),
+				zap.String("name", name),
+			)
+			b.sendMessageInThread(ctx, message.Chat.ID, fmt.Sprintf("Error: %v", err), message.MessageThreadID)
+			return
+		}
+
+		b.logger.Info("API token created",
+			zap.Int64("user_id", message.From.ID),
+			zap.String("name", name),
+			zap.String("scope", scope),
+		)
+		b.sendMessageInThread(ctx, message.Chat.ID,
+			fmt.Sprintf("🔑 Token '%s' (%s) created. Copy it now, it will not be shown again:\n\n%s\n\nSend it with API requests as the header\nAuthorization: Bearer <token>",
+				name, scope, secret),
+			message.MessageThreadID)
+
+	case "list":
+		tokens, err := b.db.ListAPITokens(ctx, message.From.ID)
+		if err != nil {
+			b.logger.Error("Failed to list API tokens",
+				zap.Error(err),
+				zap.Int64("user_id", message.From.ID),
+			)
+			b.sendMessageInThread(ctx, message.Chat.ID, fmt.Sprintf("Error: %v", err), message.MessageThreadID)
+			return
+		}
+		if len(tokens) == 0 {
+			b.sendMessageInThread(ctx, message.Chat.ID, "You have no API tokens. Create one with /token create <name> [read|write]", message.MessageThreadID)
+			return
+		}
+
+		var text strings.Builder
+		text.WriteString("🔑 Your API tokens:\n")
+		for _, token := range tokens {
+			text.WriteString(fmt.Sprintf("\n%s - %s, created %s", token.Name, token.Scope, b.formatEventTime(token.CreatedAt)))
+		}
+		b.sendMessageInThread(ctx, message.Chat.ID, text.String(), message.MessageThreadID)
+
+	case "revoke":
+		name := strings.Join(fields[1:], " ")
+		if name == "" {
+			b.sendMessageInThread(ctx, message.Chat.ID, usage, message.MessageThreadID)
+			return
+		}
+		err := b.revokeAPIToken(ctx, message.From.ID, name)
+		if errors.Is(err, storage.ErrNotFound) {
+			b.sendMessageInThread(ctx, message.Chat.ID, fmt.Sprintf("You have no token named '%s'. See /token list", name), message.MessageThreadID)
+			return
+		}
+		if err != nil {
+			b.logger.Error("Failed to revoke API token",
+				zap.Error(err),
+				zap.Int64("user_id", message.From.ID),
+				zap.String("name", name),
+			)
+			b.sendMessageInThread(ctx, message.Chat.ID, fmt.Sprintf("Error: %v", err), message.MessageThreadID)
+			return
+		}
+
+		b.logger.Info("API token revoked",
+			zap.Int64("user_id", message.From.ID),
+			zap.String("name", name),
+		)
+		b.sendMessageInThread(ctx, message.Chat.ID, fmt.Sprintf("🗑 Token '%s' revoked.", name), message.MessageThreadID)
+
+	default:
+		b.sendMessageInThread(ctx, message.Chat.ID, usage, message.MessageThreadID)
+	}
+}
+
+// revokeAPIToken revokes the token of a user by name (case-insensitive).
+// Returns an error wrapping storage.ErrNotFound if the user has no such token.
+func (b *Bot) revokeAPIToken(ctx context.Context, ownerID int64, name string) error {
+	tokens, err := b.db.ListAPITokens(ctx, ownerID)
+	if err != nil {
+		return err
+	}
+	for _, token := range tokens {
+		if strings.EqualFold(token.Name, name) {
+			return b.db.RevokeAPIToken(ctx, token.ID)
+		}
+	}
+	return fmt.Errorf("token %q %w", name, storage.ErrNotFound)
+}
+
+// generateAPIToken returns a new random secret and the hash stored for it
+func generateAPIToken() (secret, hash string, err error) {
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return "", "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	secret = apiTokenPrefix + base64.RawURLEncoding.EncodeToString(random)
+	return secret, hashAPIToken(secret), nil
+}
+
+// hashAPIToken returns the hex encoded SHA-256 of a token secret
+func hashAPIToken(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// authenticateAPIToken returns the token of a bearer secret. Tokens of users who are no longer
+// allowed to use the bot are rejected.
+func (b *Bot) authenticateAPIToken(ctx context.Context, secret string) (libmodels.APIToken, error) {
+	if !strings.HasPrefix(secret, apiTokenPrefix) {
+		return libmodels.APIToken{}, fmt.Errorf("malformed token")
+	}
+	token, err := b.db.GetAPITokenByHash(ctx, hashAPIToken(secret))
+	if err != nil {
+		return libmodels.APIToken{}, err
+	}
+	if !b.allowedUsers[token.OwnerID] {
+		return libmodels.APIToken{}, fmt.Errorf("owner %d of token %q is not allowed", token.OwnerID, token.Name)
+	}
+	return token, nil
+}
+
+// tokenAllows reports whether a token's scope permits a request method
+func tokenAllows(token libmodels.APIToken, method string) bool {
+	if token.Scope == libmodels.TokenScopeReadWrite {
+		return true
+	}
+	return method == http.MethodGet || method == http.MethodHead
+}
@@ -2,6 +2,7 @@ package bot
 
 import (
 	"sync"
+	"time"
 
 	"github.com/go-telegram/bot"
 	"go.uber.org/zap"
@@ -17,9 +18,14 @@ type Bot struct {
 	states             map[int64]*ConversationState
 	statesMu           sync.RWMutex
 	logger               *zap.Logger
-	notificationChatID   int64 // Chat ID to send notifications when events are created via web-app (0 = disabled)
+	notificationChatID   int64 // Default chat ID for notifications (0 = disabled)
 	notificationThreadID int   // Thread/topic ID for forum groups (0 = general/no topic)
+	notificationRoutes   map[string]NotificationRoute
 	llmClient            *llm.Client
+	location             *time.Location // Timezone for reading times (nil = UTC)
+	reminderTime         string         // HH:MM of the daily reminder ("" = disabled)
+	reminderSkipIfRead   bool           // Skip the daily reminder if a reading was logged that day
+	digestTime           string         // HH:MM of the weekly and monthly digests ("" = disabled)
 }
 
 // ConversationState tracks the state of multi-step commands
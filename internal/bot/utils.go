@@ -2,6 +2,15 @@ package bot
 
 import (
 	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	libmodels "library/internal/models"
+	"library/internal/storage"
 
 	"github.com/go-telegram/bot"
 	"github.com/go-telegram/bot/models"
@@ -41,3 +50,391 @@ func (b *Bot) sendMessageInThreadWithMarkup(ctx context.Context, chatID int64, t
 
 	b.api.SendMessage(ctx, params)
 }
+
+// sendPhotoInThread sends a photo, given as a Telegram file_id or an image URL, to a specific thread/topic in a group
+func (b *Bot) sendPhotoInThread(ctx context.Context, chatID int64, photo string, messageThreadID int) {
+	if b.api == nil {
+		return // For testing
+	}
+
+	params := &bot.SendPhotoParams{
+		ChatID: chatID,
+		Photo:  &models.InputFileString{Data: photo},
+	}
+	if messageThreadID != 0 {
+		params.MessageThreadID = messageThreadID
+	}
+
+	b.api.SendPhoto(ctx, params)
+}
+
+// undoEventKeyboard returns the "Undo" button attached to reading event confirmations
+func undoEventKeyboard(eventID string) *models.InlineKeyboardMarkup {
+	return &models.InlineKeyboardMarkup{
+		InlineKeyboard: [][]models.InlineKeyboardButton{
+			{
+				{Text: "↩️ Undo", CallbackData: fmt.Sprintf("undo_event:%s", eventID)},
+			},
+		},
+	}
+}
+
+// editMessageText replaces the text of the message the callback came from and drops its inline
+// keyboard. If that message is no longer accessible, the text is sent as a new message instead.
+func (b *Bot) editMessageText(ctx context.Context, query *models.CallbackQuery, text string) {
+	if b.api == nil {
+		return // For testing
+	}
+	if query.Message.Message == nil {
+		b.sendMessageInThread(ctx, getChatIDFromQuery(query), text, getThreadIDFromQuery(query))
+		return
+	}
+
+	b.api.EditMessageText(ctx, &bot.EditMessageTextParams{
+		ChatID:    query.Message.Message.Chat.ID,
+		MessageID: query.Message.Message.ID,
+		Text:      text,
+	})
+}
+
+// editMessageMarkup replaces the inline keyboard of the message the callback came from
+func (b *Bot) editMessageMarkup(ctx context.Context, query *models.CallbackQuery, markup *models.InlineKeyboardMarkup) {
+	if b.api == nil || query.Message.Message == nil {
+		return // For testing
+	}
+
+	b.api.EditMessageReplyMarkup(ctx, &bot.EditMessageReplyMarkupParams{
+		ChatID:      query.Message.Message.Chat.ID,
+		MessageID:   query.Message.Message.ID,
+		ReplyMarkup: markup,
+	})
+}
+
+// participantToggleKeyboard builds a multi-select participant keyboard.
+// Selected participants are marked with ✅; callback data is "<prefix><id>" and "<prefix>done".
+func participantToggleKeyboard(prefix string, participants []libmodels.Participant, selected []string) *models.InlineKeyboardMarkup {
+	var rows [][]models.InlineKeyboardButton
+	for _, p := range participants {
+		emoji := "👶"
+		if p.IsParent {
+			emoji = "👨"
+		}
+		for _, id := range selected {
+			if id == p.ID {
+				emoji = "✅"
+				break
+			}
+		}
+		button := models.InlineKeyboardButton{
+			Text:         fmt.Sprintf("%s %s", emoji, p.Name),
+			CallbackData: prefix + p.ID,
+		}
+		rows = append(rows, []models.InlineKeyboardButton{button})
+	}
+	rows = append(rows, []models.InlineKeyboardButton{
+		{Text: "✔️ Done", CallbackData: prefix + "done"},
+	})
+
+	return &models.InlineKeyboardMarkup{
+		InlineKeyboard: rows,
+	}
+}
+
+// bookTogglePageSize is how many books a multi-select book keyboard shows per page
+const bookTogglePageSize = 30
+
+// bookToggleKeyboard builds a paged multi-select book keyboard in two columns.
+// Selected books are marked with ✅; callback data is "<prefix><index>", "<prefix>page:<n>",
+// "<prefix>all" and "<prefix>done".
+func bookToggleKeyboard(prefix string, books []libmodels.Book, selected []string, page int) *models.InlineKeyboardMarkup {
+	start := page * bookTogglePageSize
+	end := min(start+bookTogglePageSize, len(books))
+
+	var rows [][]models.InlineKeyboardButton
+	for i := start; i < end; i += 2 {
+		var row []models.InlineKeyboardButton
+		for j := i; j < min(i+2, end); j++ {
+			text := books[j].Name
+			if slices.Contains(selected, books[j].ID) {
+				text = "✅ " + text
+			}
+			row = append(row, models.InlineKeyboardButton{Text: text, CallbackData: fmt.Sprintf("%s%d", prefix, j)})
+		}
+		rows = append(rows, row)
+	}
+
+	var nav []models.InlineKeyboardButton
+	if page > 0 {
+		nav = append(nav, models.InlineKeyboardButton{Text: "◀️ Previous", CallbackData: fmt.Sprintf("%spage:%d", prefix, page-1)})
+	}
+	if end < len(books) {
+		nav = append(nav, models.InlineKeyboardButton{Text: "Next ▶️", CallbackData: fmt.Sprintf("%spage:%d", prefix, page+1)})
+	}
+	if len(nav) > 0 {
+		rows = append(rows, nav)
+	}
+
+	allText := "☑️ Select all"
+	if len(selected) == len(books) {
+		allText = "🔲 Clear all"
+	}
+	rows = append(rows, []models.InlineKeyboardButton{
+		{Text: allText, CallbackData: prefix + "all"},
+		{Text: fmt.Sprintf("✔️ Done (%d)", len(selected)), CallbackData: prefix + "done"},
+	})
+
+	return &models.InlineKeyboardMarkup{
+		InlineKeyboard: rows,
+	}
+}
+
+// rotationKeyboard lists the rotation strategies (current one marked) and the children
+// that can be skipped (skipped ones marked with ⏸)
+func rotationKeyboard(settings libmodels.ChatSettings, participants []libmodels.Participant) *models.InlineKeyboardMarkup {
+	current := rotationStrategyByName(settings.RotationStrategy).Name()
+
+	var rows [][]models.InlineKeyboardButton
+	for _, strategy := range rotationStrategies {
+		text := strategy.Description()
+		if strategy.Name() == current {
+			text = "✅ " + text
+		}
+		rows = append(rows, []models.InlineKeyboardButton{
+			{Text: text, CallbackData: "rotation_strategy:" + strategy.Name()},
+		})
+	}
+
+	for _, p := range participants {
+		if p.IsParent {
+			continue
+		}
+		text := "👶 " + p.Name
+		for _, id := range settings.RotationSkipIDs {
+			if id == p.ID {
+				text = "⏸ " + p.Name + " (skipped)"
+				break
+			}
+		}
+		rows = append(rows, []models.InlineKeyboardButton{
+			{Text: text, CallbackData: "rotation_skip:" + p.ID},
+		})
+	}
+
+	return &models.InlineKeyboardMarkup{
+		InlineKeyboard: rows,
+	}
+}
+
+// toggleID adds id to ids or removes it if already present
+func toggleID(ids []string, id string) []string {
+	for i, existing := range ids {
+		if existing == id {
+			return append(ids[:i:i], ids[i+1:]...)
+		}
+	}
+	return append(ids, id)
+}
+
+// findParticipant returns the participant with the given ID from the list
+func findParticipant(participants []libmodels.Participant, id string) (libmodels.Participant, bool) {
+	for _, p := range participants {
+		if p.ID == id {
+			return p, true
+		}
+	}
+	return libmodels.Participant{}, false
+}
+
+// selectedParticipants resolves selected IDs against the list, keeping selection order
+func selectedParticipants(participants []libmodels.Participant, selected []string) []libmodels.Participant {
+	var result []libmodels.Participant
+	for _, id := range selected {
+		if p, ok := findParticipant(participants, id); ok {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+// findActiveParticipant returns an active participant by name, ignoring case
+func (b *Bot) findActiveParticipant(ctx context.Context, name string) (libmodels.Participant, error) {
+	participants, err := b.db.ListParticipants(ctx)
+	if err != nil {
+		return libmodels.Participant{}, err
+	}
+	for _, p := range participants {
+		if strings.EqualFold(p.Name, strings.TrimSpace(name)) {
+			return p, nil
+		}
+	}
+	return libmodels.Participant{}, fmt.Errorf("participant %q %w", name, storage.ErrNotFound)
+}
+
+// findBookByID returns a readable or retired book by its ID
+func (b *Bot) findBookByID(ctx context.Context, id string) (libmodels.Book, error) {
+	books, err := b.listAllBooks(ctx)
+	if err != nil {
+		return libmodels.Book{}, err
+	}
+	for _, book := range books {
+		if book.ID == id {
+			return book, nil
+		}
+	}
+	return libmodels.Book{}, fmt.Errorf("book %s %w", id, storage.ErrNotFound)
+}
+
+// findParticipantByID returns an active participant by its ID
+func (b *Bot) findParticipantByID(ctx context.Context, id string) (libmodels.Participant, error) {
+	participants, err := b.db.ListParticipants(ctx)
+	if err != nil {
+		return libmodels.Participant{}, err
+	}
+	for _, p := range participants {
+		if p.ID == id {
+			return p, nil
+		}
+	}
+	return libmodels.Participant{}, fmt.Errorf("participant %s %w", id, storage.ErrNotFound)
+}
+
+// sessionDetails holds the optional duration and progress of a reading session
+type sessionDetails struct {
+	DurationMinutes int
+	Progress        int
+	ProgressUnit    string
+}
+
+// sessionDetailPattern matches one "<number><unit>" token such as "20m", "15p" or "2 ch"
+var sessionDetailPattern = regexp.MustCompile(`(\d+)\s*([a-z]+)`)
+
+// parseSessionDetails parses input like "20m 15p" or "30 min 2 chapters"
+func parseSessionDetails(input string) (sessionDetails, error) {
+	var details sessionDetails
+
+	text := strings.ToLower(strings.TrimSpace(input))
+	if text == "" {
+		return details, fmt.Errorf("nothing entered")
+	}
+
+	matches := sessionDetailPattern.FindAllStringSubmatchIndex(text, -1)
+	rest := text
+	for i := len(matches) - 1; i >= 0; i-- {
+		rest = rest[:matches[i][0]] + rest[matches[i][1]:]
+	}
+	if strings.Trim(rest, " ,") != "" {
+		return details, fmt.Errorf("could not understand %q", strings.TrimSpace(input))
+	}
+
+	for _, m := range matches {
+		value, err := strconv.Atoi(text[m[2]:m[3]])
+		if err != nil || value <= 0 {
+			return details, fmt.Errorf("invalid number %q", text[m[2]:m[3]])
+		}
+
+		switch unit := text[m[4]:m[5]]; unit {
+		case "m", "min", "mins", "minute", "minutes":
+			details.DurationMinutes = value
+		case "p", "pg", "page", "pages":
+			details.Progress = value
+			details.ProgressUnit = libmodels.ProgressPages
+		case "ch", "chapter", "chapters":
+			details.Progress = value
+			details.ProgressUnit = libmodels.ProgressChapters
+		default:
+			return details, fmt.Errorf("unknown unit %q", unit)
+		}
+	}
+
+	return details, nil
+}
+
+// String formats the details for confirmation messages, e.g. "20 min, 15 pages"
+func (d sessionDetails) String() string {
+	var parts []string
+	if d.DurationMinutes > 0 {
+		parts = append(parts, fmt.Sprintf("%d min", d.DurationMinutes))
+	}
+	if d.Progress > 0 && d.ProgressUnit != "" {
+		parts = append(parts, fmt.Sprintf("%d %s", d.Progress, d.ProgressUnit))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// eventTimeLayout is how reading event times are shown in chat
+const eventTimeLayout = "2006-01-02 15:04"
+
+// loc returns the timezone used for reading times
+func (b *Bot) loc() *time.Location {
+	if b.location == nil {
+		return time.UTC
+	}
+	return b.location
+}
+
+// now returns the current time in the bot's timezone
+func (b *Bot) now() time.Time {
+	return time.Now().In(b.loc())
+}
+
+// formatEventTime formats a reading time in the bot's timezone
+func (b *Bot) formatEventTime(t time.Time) string {
+	return t.In(b.loc()).Format(eventTimeLayout)
+}
+
+// parseEventDateTime parses "YYYY-MM-DD" or "YYYY-MM-DD HH:MM" in loc.
+// hasTime reports whether a time of day was given.
+func parseEventDateTime(input string, loc *time.Location) (t time.Time, hasTime bool, err error) {
+	input = strings.Join(strings.Fields(input), " ")
+	if t, err := time.ParseInLocation(eventTimeLayout, input, loc); err == nil {
+		return t, true, nil
+	}
+	t, err = time.ParseInLocation("2006-01-02", input, loc)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("invalid date %q: %w", input, err)
+	}
+	return t, false, nil
+}
+
+// withTimeOfDay returns date with the hour and minute of clock
+func withTimeOfDay(date, clock time.Time) time.Time {
+	return time.Date(date.Year(), date.Month(), date.Day(), clock.Hour(), clock.Minute(), 0, 0, date.Location())
+}
+
+// commandArgs returns the text after the command (and optional @botname) of a message
+func commandArgs(text string) string {
+	if !strings.HasPrefix(text, "/") {
+		return ""
+	}
+	_, args, _ := strings.Cut(text, " ")
+	return strings.TrimSpace(args)
+}
+
+// parseAwayUntil parses the end of an absence given as the last day away (YYYY-MM-DD)
+// or a number of days ("3d", today included). The returned time is the start of the
+// day the participant is back, in now's location.
+func parseAwayUntil(input string, now time.Time) (time.Time, error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	if days, ok := strings.CutSuffix(strings.ToLower(input), "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 1 {
+			return time.Time{}, fmt.Errorf("invalid number of days %q", input)
+		}
+		return today.AddDate(0, 0, n), nil
+	}
+
+	lastDay, err := time.ParseInLocation("2006-01-02", input, now.Location())
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q", input)
+	}
+	if lastDay.Before(today) {
+		return time.Time{}, fmt.Errorf("%s is in the past", input)
+	}
+	return lastDay.AddDate(0, 0, 1), nil
+}
+
+// formatAwayUntil shows the last day of an absence ending at until
+func (b *Bot) formatAwayUntil(until time.Time) string {
+	return until.In(b.loc()).AddDate(0, 0, -1).Format("2006-01-02")
+}
@@ -3,8 +3,10 @@ package config
 import (
 	"fmt"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // Config holds the application configuration
@@ -19,9 +21,26 @@ type Config struct {
 	// HTTP server configuration
 	HTTPPort int // Port for Mini App HTTP server (default: 8081)
 
+	// How long Mini App initData is accepted after Telegram issued it (default: 24h)
+	InitDataMaxAge time.Duration
+
 	// Notification configuration
-	NotificationChatID    int64 // Chat ID to send notifications when events are created via web-app (0 = disabled)
-	NotificationThreadID  int   // Thread/topic ID for forum groups (0 = general/no topic)
+	NotificationChatID   int64                        // Default chat ID for notifications (0 = disabled)
+	NotificationThreadID int                          // Thread/topic ID for forum groups (0 = general/no topic)
+	NotificationRoutes   map[string]NotificationRoute // Per-type chat overrides (new_book, new_event, label_added)
+
+	// Timezone used to enter, display and group reading times (default: UTC)
+	Timezone *time.Location
+
+	// Daily reminder configuration
+	ReminderTime       string // HH:MM in Timezone to post who reads tonight ("" = disabled)
+	ReminderSkipIfRead bool   // If true, no reminder is posted when a reading was already logged that day
+
+	// Digest configuration
+	DigestTime string // HH:MM in Timezone to post the weekly (Mondays) and monthly (1st) digests ("" = disabled)
+
+	// Bearer token for POST /scheduler/run-due, which lets an external trigger run due jobs ("" = disabled)
+	SchedulerToken string
 
 	// ClickHouse configuration
 	ClickHouseHost     string
@@ -39,6 +58,15 @@ type Config struct {
 	LLMModel   string
 }
 
+// NotificationRoute is the chat (and optional topic) a notification type is posted to
+type NotificationRoute struct {
+	ChatID   int64 // 0 = this notification type is disabled
+	ThreadID int   // 0 = general/no topic
+}
+
+// notificationTypes are the notification types that can be routed
+var notificationTypes = []string{"new_book", "new_event", "label_added"}
+
 // LoadFromEnv loads configuration from environment variables
 func LoadFromEnv() (*Config, error) {
 	config := &Config{}
@@ -85,6 +113,16 @@ func LoadFromEnv() (*Config, error) {
 		config.HTTPPort = port
 	}
 
+	// Mini App initData maximum age (default: 24h)
+	config.InitDataMaxAge = 24 * time.Hour
+	if maxAge := os.Getenv("INIT_DATA_MAX_AGE"); maxAge != "" {
+		d, err := time.ParseDuration(maxAge)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid INIT_DATA_MAX_AGE %q: use a duration such as 1h or 30m", maxAge)
+		}
+		config.InitDataMaxAge = d
+	}
+
 	// Notification chat ID (optional)
 	notificationChatIDStr := os.Getenv("NOTIFICATION_CHAT_ID")
 	if notificationChatIDStr != "" {
@@ -105,6 +143,44 @@ func LoadFromEnv() (*Config, error) {
 		config.NotificationThreadID = threadID
 	}
 
+	// Per-type notification routes (optional)
+	routes, err := parseNotificationRoutes(os.Getenv("NOTIFICATION_ROUTES"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid NOTIFICATION_ROUTES: %w", err)
+	}
+	config.NotificationRoutes = routes
+
+	// Timezone (default: UTC)
+	timezone := os.Getenv("TIMEZONE")
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	location, err := time.LoadLocation(timezone)
+	if err != nil || location.String() == "Local" {
+		return nil, fmt.Errorf("invalid TIMEZONE %q: use an IANA name such as Europe/Berlin", timezone)
+	}
+	config.Timezone = location
+
+	// Daily reminder (optional)
+	config.ReminderTime = os.Getenv("REMINDER_TIME")
+	if config.ReminderTime != "" {
+		if _, err := time.Parse("15:04", config.ReminderTime); err != nil {
+			return nil, fmt.Errorf("invalid REMINDER_TIME %q: use HH:MM", config.ReminderTime)
+		}
+	}
+	config.ReminderSkipIfRead = os.Getenv("REMINDER_SKIP_IF_READ") == "true"
+
+	// Digests (optional)
+	config.DigestTime = os.Getenv("DIGEST_TIME")
+	if config.DigestTime != "" {
+		if _, err := time.Parse("15:04", config.DigestTime); err != nil {
+			return nil, fmt.Errorf("invalid DIGEST_TIME %q: use HH:MM", config.DigestTime)
+		}
+	}
+
+	// External scheduler trigger (optional)
+	config.SchedulerToken = os.Getenv("SCHEDULER_TOKEN")
+
 	// Use Mock DB (default: false)
 	config.UseMockDB = os.Getenv("USE_MOCK_DB") == "true"
 
@@ -155,3 +231,41 @@ func LoadFromEnv() (*Config, error) {
 
 	return config, nil
 }
+
+// parseNotificationRoutes parses "type=chat_id[:thread_id]" pairs separated by commas,
+// e.g. "new_book=-100123:5,label_added=0"
+func parseNotificationRoutes(value string) (map[string]NotificationRoute, error) {
+	routes := make(map[string]NotificationRoute)
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		kind, target, ok := strings.Cut(pair, "=")
+		kind = strings.TrimSpace(kind)
+		if !ok {
+			return nil, fmt.Errorf("%q: expected type=chat_id[:thread_id]", pair)
+		}
+		if !slices.Contains(notificationTypes, kind) {
+			return nil, fmt.Errorf("unknown notification type %q (use %s)", kind, strings.Join(notificationTypes, ", "))
+		}
+
+		chat, thread, hasThread := strings.Cut(strings.TrimSpace(target), ":")
+		var route NotificationRoute
+		chatID, err := strconv.ParseInt(chat, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid chat ID for %s: %w", kind, err)
+		}
+		route.ChatID = chatID
+		if hasThread {
+			threadID, err := strconv.Atoi(thread)
+			if err != nil {
+				return nil, fmt.Errorf("invalid thread ID for %s: %w", kind, err)
+			}
+			route.ThreadID = threadID
+		}
+		routes[kind] = route
+	}
+	return routes, nil
+}
@@ -0,0 +1,347 @@
+package labels
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// Expr is a label filter: a condition on the labels of a book.
+// A nil Expr means no filter.
+type Expr interface {
+	// Match reports whether a book with the given labels passes the filter
+	Match(labels []string) bool
+	// String returns the expression in the syntax accepted by Parse
+	String() string
+}
+
+// Label matches books that have exactly this label
+type Label string
+
+// Prefix matches books with this label or any label below it:
+// Prefix("genre") matches every genre, Prefix("genre:fairy-tale") also matches "genre:fairy-tale:russian"
+type Prefix string
+
+// And matches books that pass every expression
+type And []Expr
+
+// Or matches books that pass at least one expression
+type Or []Expr
+
+// Not matches books that do not pass Expr
+type Not struct {
+	Expr Expr
+}
+
+// Match implements Expr
+func (l Label) Match(labels []string) bool {
+	return slices.Contains(labels, string(l))
+}
+
+// Match implements Expr
+func (p Prefix) Match(labels []string) bool {
+	return slices.ContainsFunc(labels, func(label string) bool {
+		return label == string(p) || strings.HasPrefix(label, string(p)+Separator)
+	})
+}
+
+// Match implements Expr
+func (a And) Match(labels []string) bool {
+	for _, expr := range a {
+		if !expr.Match(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+// Match implements Expr
+func (o Or) Match(labels []string) bool {
+	for _, expr := range o {
+		if expr.Match(labels) {
+			return true
+		}
+	}
+	return false
+}
+
+// Match implements Expr
+func (n Not) Match(labels []string) bool {
+	return !n.Expr.Match(labels)
+}
+
+// String implements Expr; labels that would not parse back as they are get quoted
+func (l Label) String() string {
+	s := string(l)
+	if s == "" || strings.ContainsAny(s, `()*=`) {
+		return `"` + s + `"`
+	}
+	for _, word := range strings.Fields(s) {
+		if isOperator(word) {
+			return `"` + s + `"`
+		}
+	}
+	return s
+}
+
+// String implements Expr
+func (p Prefix) String() string {
+	return string(p) + Separator + "*"
+}
+
+// String implements Expr
+func (a And) String() string {
+	parts := make([]string, len(a))
+	for i, expr := range a {
+		parts[i] = expr.String()
+		if _, ok := expr.(Or); ok {
+			parts[i] = "(" + parts[i] + ")"
+		}
+	}
+	return strings.Join(parts, " AND ")
+}
+
+// String implements Expr
+func (o Or) String() string {
+	parts := make([]string, len(o))
+	for i, expr := range o {
+		parts[i] = expr.String()
+	}
+	return strings.Join(parts, " OR ")
+}
+
+// String implements Expr
+func (n Not) String() string {
+	switch n.Expr.(type) {
+	case And, Or:
+		return "NOT (" + n.Expr.String() + ")"
+	}
+	return "NOT " + n.Expr.String()
+}
+
+// Join combines two filters with AND; either may be nil
+func Join(a, b Expr) Expr {
+	switch {
+	case a == nil:
+		return b
+	case b == nil:
+		return a
+	}
+	return And{a, b}
+}
+
+// isOperator reports whether word is one of the AND, OR and NOT operators (case-insensitive)
+func isOperator(word string) bool {
+	switch strings.ToUpper(word) {
+	case "AND", "OR", "NOT":
+		return true
+	}
+	return false
+}
+
+// token is a lexical element of a filter expression
+type token struct {
+	text   string
+	op     string // "AND", "OR", "NOT", "(" or ")"; empty for words
+	quoted bool
+}
+
+// tokenize splits a filter expression into words, quoted labels, operators and parentheses
+func tokenize(s string) ([]token, error) {
+	var tokens []token
+	runes := []rune(s)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(' || r == ')':
+			tokens = append(tokens, token{op: string(r)})
+			i++
+		case r == '"':
+			end := slices.Index(runes[i+1:], '"')
+			if end < 0 {
+				return nil, fmt.Errorf("missing closing quote")
+			}
+			tokens = append(tokens, token{text: string(runes[i+1 : i+1+end]), quoted: true})
+			i += end + 2
+		default:
+			start := i
+			for i < len(runes) && !unicode.IsSpace(runes[i]) && !strings.ContainsRune(`()"`, runes[i]) {
+				i++
+			}
+			word := string(runes[start:i])
+			if isOperator(word) {
+				tokens = append(tokens, token{op: strings.ToUpper(word)})
+			} else {
+				tokens = append(tokens, token{text: word})
+			}
+		}
+	}
+	return tokens, nil
+}
+
+// Parse parses a label filter expression such as
+//
+//	genre=fairy-tale AND (lang=en OR lang=de) AND NOT owner:library
+//
+// Operators are case-insensitive; NOT binds tighter than AND, which binds tighter than OR.
+// A term is a label: consecutive words form one label ("borrowed from library"), "key=value"
+// is the same as "key:value", and "genre:*" matches any label of the namespace (or below a value).
+// Terms are validated like Normalize; a quoted term ("Old: label") is matched exactly as written.
+// An empty expression returns a nil Expr.
+func Parse(s string) (Expr, error) {
+	tokens, err := tokenize(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, nil
+	}
+
+	p := &parser{tokens: tokens}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %s", p.tokens[p.pos].describe())
+	}
+	return expr, nil
+}
+
+// describe names a token in error messages
+func (t token) describe() string {
+	if t.op != "" {
+		return fmt.Sprintf("%q", t.op)
+	}
+	return fmt.Sprintf("label %q", t.text)
+}
+
+// parser is a recursive descent parser over the tokens of an expression
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+// peekOp returns the operator at the current position, or "" for a word or the end
+func (p *parser) peekOp() string {
+	if p.pos >= len(p.tokens) {
+		return ""
+	}
+	return p.tokens[p.pos].op
+}
+
+func (p *parser) parseOr() (Expr, error) {
+	first, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	exprs := []Expr{first}
+	for p.peekOp() == "OR" {
+		p.pos++
+		next, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, next)
+	}
+	if len(exprs) == 1 {
+		return first, nil
+	}
+	return Or(exprs), nil
+}
+
+func (p *parser) parseAnd() (Expr, error) {
+	first, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	exprs := []Expr{first}
+	for p.peekOp() == "AND" {
+		p.pos++
+		next, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, next)
+	}
+	if len(exprs) == 1 {
+		return first, nil
+	}
+	return And(exprs), nil
+}
+
+func (p *parser) parseNot() (Expr, error) {
+	if p.peekOp() == "NOT" {
+		p.pos++
+		expr, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return Not{Expr: expr}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (Expr, error) {
+	if p.pos >= len(p.tokens) {
+		return nil, fmt.Errorf("expected a label at the end of the expression")
+	}
+
+	tok := p.tokens[p.pos]
+	switch {
+	case tok.op == "(":
+		p.pos++
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peekOp() != ")" {
+			return nil, fmt.Errorf("missing closing parenthesis")
+		}
+		p.pos++
+		return expr, nil
+	case tok.op != "":
+		return nil, fmt.Errorf("expected a label, got %s", tok.describe())
+	case tok.quoted:
+		p.pos++
+		return Label(tok.text), nil
+	}
+
+	var words []string
+	for p.pos < len(p.tokens) && p.tokens[p.pos].op == "" && !p.tokens[p.pos].quoted {
+		words = append(words, p.tokens[p.pos].text)
+		p.pos++
+	}
+	return parseTerm(strings.Join(words, " "))
+}
+
+// parseTerm turns a single unquoted term into a Label or Prefix
+func parseTerm(term string) (Expr, error) {
+	if key, value, ok := strings.Cut(term, "="); ok && !strings.Contains(key, Separator) {
+		term = key + Separator + value
+	}
+
+	if prefix, ok := strings.CutSuffix(term, Separator+"*"); ok {
+		if !strings.Contains(prefix, Separator) {
+			key := strings.ToLower(strings.TrimSpace(prefix))
+			if _, known := namespaces[key]; !known {
+				return nil, fmt.Errorf("unknown label namespace %q, use one of: %s", key, strings.Join(Namespaces(), ", "))
+			}
+			return Prefix(key), nil
+		}
+		label, err := Normalize(prefix)
+		if err != nil {
+			return nil, err
+		}
+		return Prefix(label), nil
+	}
+
+	label, err := Normalize(term)
+	if err != nil {
+		return nil, err
+	}
+	return Label(label), nil
+}
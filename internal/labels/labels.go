@@ -0,0 +1,138 @@
+// Package labels validates book labels and parses label filter expressions.
+//
+// A label is either plain ("bedtime") or typed: "key:value" in one of the known
+// namespaces, e.g. "genre:fairy-tale", "age:3-5", "lang:en" or "owner:library".
+// Values may be hierarchical ("genre:fairy-tale:russian"). A label whose prefix is
+// not a known namespace ("foo:bar") is a plain label, as labels with a colon existed
+// before namespaces were introduced.
+package labels
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Separator separates the namespace, value and sub-values of a typed label
+const Separator = ":"
+
+// namespace describes a label namespace and how its values are validated
+type namespace struct {
+	description string
+	lower       bool // values are lowercased
+	validate    func(value string) error
+}
+
+var (
+	ageValue  = regexp.MustCompile(`^(\d{1,2})(?:-(\d{1,2})|\+)?$`)
+	langValue = regexp.MustCompile(`^[a-z]{2}$`)
+)
+
+var namespaces = map[string]namespace{
+	"genre": {description: "genre, e.g. genre:fairy-tale", lower: true},
+	"age":   {description: "age range, e.g. age:3-5 or age:6+", validate: ValidateAge},
+	"lang":  {description: "two-letter language code, e.g. lang:en", lower: true, validate: validateLang},
+	"owner": {description: "who the book belongs to, e.g. owner:library"},
+}
+
+// Namespaces returns the known namespaces in alphabetical order
+func Namespaces() []string {
+	keys := make([]string, 0, len(namespaces))
+	for key := range namespaces {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// Describe returns a one-line description of every namespace for help messages
+func Describe() string {
+	var lines []string
+	for _, key := range Namespaces() {
+		lines = append(lines, fmt.Sprintf("%s — %s", key, namespaces[key].description))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Namespace returns the namespace of a typed label, or "" for a plain label
+func Namespace(label string) string {
+	key, _, ok := strings.Cut(label, Separator)
+	if !ok {
+		return ""
+	}
+	if _, known := namespaces[key]; !known {
+		return ""
+	}
+	return key
+}
+
+// Normalize validates a label entered by a user and returns its canonical form:
+// surrounding spaces are trimmed, the namespace is lowercased and so are the values
+// of namespaces that are case-insensitive (genre, lang). Labels with an unknown
+// prefix are plain labels and are returned as entered.
+func Normalize(label string) (string, error) {
+	label = strings.TrimSpace(label)
+	if label == "" {
+		return "", fmt.Errorf("label cannot be empty")
+	}
+	if strings.ContainsAny(label, `*"()`) {
+		return "", fmt.Errorf("label %q cannot contain *, \", ( or )", label)
+	}
+
+	key, value, typed := strings.Cut(label, Separator)
+	if !typed {
+		return label, nil
+	}
+
+	key = strings.ToLower(strings.TrimSpace(key))
+	ns, ok := namespaces[key]
+	if !ok {
+		return label, nil
+	}
+
+	parts := strings.Split(value, Separator)
+	for i, part := range parts {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			return "", fmt.Errorf("label %q has an empty value", label)
+		}
+		if ns.lower {
+			part = strings.ToLower(part)
+		}
+		parts[i] = part
+	}
+	value = strings.Join(parts, Separator)
+
+	if ns.validate != nil {
+		if err := ns.validate(value); err != nil {
+			return "", fmt.Errorf("invalid %s label %q: %w", key, label, err)
+		}
+	}
+	return key + Separator + value, nil
+}
+
+// ValidateAge accepts an age ("5"), a range ("3-5") or an open range ("6+")
+func ValidateAge(value string) error {
+	m := ageValue.FindStringSubmatch(value)
+	if m == nil {
+		return fmt.Errorf("expected an age range such as 3-5 or 6+")
+	}
+	if m[2] != "" {
+		from, _ := strconv.Atoi(m[1])
+		to, _ := strconv.Atoi(m[2])
+		if from > to {
+			return fmt.Errorf("age range %s ends before it starts", value)
+		}
+	}
+	return nil
+}
+
+// validateLang accepts a two-letter ISO 639-1 language code
+func validateLang(value string) error {
+	if !langValue.MatchString(value) {
+		return fmt.Errorf("expected a two-letter language code such as en")
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package labels
+
+import (
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	valid := map[string]string{
+		"  bedtime ":             "bedtime",
+		"borrowed from library":  "borrowed from library",
+		"Genre:Fairy-Tale":       "genre:fairy-tale",
+		"genre: fairy-tale : ru": "genre:fairy-tale:ru",
+		"age:3-5":                "age:3-5",
+		"AGE:6+":                 "age:6+",
+		"lang:EN":                "lang:en",
+		"owner:Mom":              "owner:Mom",
+		// Unknown prefixes are plain labels, e.g. labels created before namespaces existed
+		"foo:bar":       "foo:bar",
+		" Color: Red ":  "Color: Red",
+		"to read:later": "to read:later",
+	}
+	for input, want := range valid {
+		got, err := Normalize(input)
+		if err != nil {
+			t.Errorf("Normalize(%q) failed: %v", input, err)
+		} else if got != want {
+			t.Errorf("Normalize(%q) = %q, want %q", input, got, want)
+		}
+	}
+
+	for _, input := range []string{"", "  ", "genre:", "genre::ru", "age:5-3", "age:teen", "lang:eng", "fairy*", `"quoted"`} {
+		if got, err := Normalize(input); err == nil {
+			t.Errorf("Normalize(%q) = %q, expected an error", input, got)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	books := map[string][]string{
+		"Hobbit":   {"genre:fantasy", "lang:en", "age:6+"},
+		"Kolobok":  {"genre:fairy-tale:russian", "lang:ru", "owner:library"},
+		"Gruffalo": {"genre:fairy-tale", "lang:en", "borrowed from library"},
+		"Atlas":    {"AND more"},
+		"Cookbook": {"foo:bar"},
+	}
+
+	tests := []struct {
+		expr   string
+		want   []string
+		string string
+	}{
+		{"genre=fairy-tale AND lang=en", []string{"Gruffalo"}, "genre:fairy-tale AND lang:en"},
+		{"genre:fairy-tale:* and not owner:library", []string{"Gruffalo"}, "genre:fairy-tale:* AND NOT owner:library"},
+		{"lang=ru OR age:6+", []string{"Hobbit", "Kolobok"}, "lang:ru OR age:6+"},
+		{"(lang=ru OR lang=de) AND genre:*", []string{"Kolobok"}, "(lang:ru OR lang:de) AND genre:*"},
+		{"NOT (genre:* OR borrowed from library OR foo:bar)", []string{"Atlas"}, "NOT (genre:* OR borrowed from library OR foo:bar)"},
+		{`"AND more"`, []string{"Atlas"}, `"AND more"`},
+		{"lang=en AND age=6+ OR owner:library", []string{"Hobbit", "Kolobok"}, "lang:en AND age:6+ OR owner:library"},
+		{"foo:bar", []string{"Cookbook"}, "foo:bar"},
+	}
+	for _, tt := range tests {
+		filter, err := Parse(tt.expr)
+		if err != nil {
+			t.Errorf("Parse(%q) failed: %v", tt.expr, err)
+			continue
+		}
+		if got := filter.String(); got != tt.string {
+			t.Errorf("Parse(%q).String() = %q, want %q", tt.expr, got, tt.string)
+		}
+		for name, bookLabels := range books {
+			want := false
+			for _, w := range tt.want {
+				want = want || w == name
+			}
+			if got := filter.Match(bookLabels); got != want {
+				t.Errorf("Parse(%q).Match(%s) = %v, want %v", tt.expr, name, got, want)
+			}
+		}
+
+		// The string form parses back to the same filter
+		again, err := Parse(filter.String())
+		if err != nil || again.String() != filter.String() {
+			t.Errorf("Parse(%q) does not round-trip: %v, %v", filter.String(), again, err)
+		}
+	}
+
+	if filter, err := Parse("   "); err != nil || filter != nil {
+		t.Errorf("Expected no filter for an empty expression, got %v, %v", filter, err)
+	}
+
+	for _, expr := range []string{"AND lang=en", "lang=en AND", "(lang=en", "lang=en)", "NOT", `"open`, "planet:*", "lang=english"} {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("Parse(%q) expected an error", expr)
+		}
+	}
+}
+
+func TestJoin(t *testing.T) {
+	if Join(nil, nil) != nil {
+		t.Error("Expected no filter when joining nothing")
+	}
+	if got := Join(nil, Label("a")); got != Label("a") {
+		t.Errorf("Join(nil, a) = %v", got)
+	}
+	if got := Join(Or{Label("a"), Label("b")}, Not{Expr: Label("c")}).String(); got != "(a OR b) AND NOT c" {
+		t.Errorf("Join = %q", got)
+	}
+}
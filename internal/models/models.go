@@ -4,22 +4,167 @@ import "time"
 
 // Book represents a book in the library
 type Book struct {
+	ID         string   `json:"id"`
 	Name       string   `json:"name"`
 	IsReadable bool     `json:"isReadable"`
 	Labels     []string `json:"labels"`
+	BookMetadata
+}
+
+// BookMetadata holds the optional details of a book; empty strings and zero mean unknown
+type BookMetadata struct {
+	Author       string `json:"author,omitempty"`
+	ISBN         string `json:"isbn,omitempty"`
+	Series       string `json:"series,omitempty"`
+	SeriesVolume int    `json:"seriesVolume,omitempty"`
+	Cover        string `json:"cover,omitempty"` // image URL or Telegram file_id
+	Age          string `json:"age,omitempty"`   // recommended age range, e.g. "3-5" or "6+"
+	Notes        string `json:"notes,omitempty"`
 }
 
 // Participant represents a family member
 type Participant struct {
-	Name     string `json:"name"`
-	IsParent bool   `json:"isParent"`
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	IsParent   bool   `json:"isParent"`
+	IsArchived bool   `json:"isArchived"`
 }
 
-// Event represents a reading event
+// Event represents a reading event attended by one or more participants.
+// BookName and ParticipantNames are resolved from the referenced IDs when events are read;
+// ParticipantNames is index-aligned with ParticipantIDs.
 type Event struct {
-	Date            time.Time `json:"date"`
-	BookName        string    `json:"bookName"`
+	ID               string    `json:"id"`
+	Date             time.Time `json:"date"`
+	BookID           string    `json:"bookId"`
+	ParticipantIDs   []string  `json:"participantIds"`
+	BookName         string    `json:"bookName"`
+	ParticipantNames []string  `json:"participantNames"`
+	DurationMinutes  int       `json:"durationMinutes"` // 0 if not recorded
+	Progress         int       `json:"progress"`        // pages or chapters read; 0 if not recorded
+	ProgressUnit     string    `json:"progressUnit"`    // ProgressPages, ProgressChapters or "" if not recorded
+}
+
+// Progress units for Event.ProgressUnit
+const (
+	ProgressPages    = "pages"
+	ProgressChapters = "chapters"
+)
+
+// SessionStats summarizes length and progress of reading sessions.
+// Averages are computed by callers from totals and the matching session counts.
+type SessionStats struct {
+	Sessions        int `json:"sessions"`
+	TimedSessions   int `json:"timedSessions"` // sessions with a recorded duration
+	TotalMinutes    int `json:"totalMinutes"`
+	PageSessions    int `json:"pageSessions"` // sessions with progress in pages
+	TotalPages      int `json:"totalPages"`
+	ChapterSessions int `json:"chapterSessions"` // sessions with progress in chapters
+	TotalChapters   int `json:"totalChapters"`
+}
+
+// HourStat counts reading sessions that started in a given hour of the day (0-23)
+type HourStat struct {
+	Hour      int `json:"hour"`
+	ReadCount int `json:"readCount"`
+}
+
+// DayStat counts reading sessions on a calendar day
+type DayStat struct {
+	Date      time.Time `json:"date"` // midnight of the day in the requested location
+	ReadCount int       `json:"readCount"`
+}
+
+// WeekReaderStat counts the sessions a participant attended in a week
+type WeekReaderStat struct {
+	WeekStart       time.Time `json:"weekStart"` // Monday midnight in the requested location
+	ParticipantID   string    `json:"participantId"`
+	ParticipantName string    `json:"participantName"`
+	ReadCount       int       `json:"readCount"`
+}
+
+// ReaderActivity summarizes how much and how recently a participant has read,
+// used by rotation strategies
+type ReaderActivity struct {
+	ParticipantID   string     `json:"participantId"`
+	ParticipantName string     `json:"participantName"`
+	ReadsSince      int        `json:"readsSince"`   // events attended since the requested time
+	LastReadDate    *time.Time `json:"lastReadDate"` // nil if never read
+}
+
+// Absence marks a participant as away; they are skipped by the rotation until Until
+type Absence struct {
+	ParticipantID   string    `json:"participantId"`
 	ParticipantName string    `json:"participantName"`
+	Until           time.Time `json:"until"` // when the participant is back (exclusive)
+}
+
+// Reading goal periods
+const (
+	GoalPeriodWeek  = "week"
+	GoalPeriodMonth = "month"
+)
+
+// ReadingGoal is the number of reads a participant aims for per week or month
+type ReadingGoal struct {
+	ParticipantID   string `json:"participantId"`
+	ParticipantName string `json:"participantName"`
+	Target          int    `json:"target"`
+	Period          string `json:"period"` // GoalPeriodWeek or GoalPeriodMonth
+}
+
+// Streak holds a participant's consecutive reading days
+type Streak struct {
+	ParticipantID   string     `json:"participantId"`
+	ParticipantName string     `json:"participantName"`
+	Current         int        `json:"current"` // days in a row up to today, or up to yesterday if not read today yet
+	Longest         int        `json:"longest"`
+	LastReadDate    *time.Time `json:"lastReadDate"` // nil if never read
+}
+
+// Badge is an achievement unlocked by a participant; Key identifies the achievement rule
+type Badge struct {
+	ParticipantID   string    `json:"participantId"`
+	ParticipantName string    `json:"participantName"`
+	Key             string    `json:"key"`
+	EventID         string    `json:"eventId"` // event that unlocked the badge
+	UnlockedAt      time.Time `json:"unlockedAt"`
+}
+
+// AuditEntry records one data change: who made it, from where, and with which arguments
+type AuditEntry struct {
+	ActorID   int64     `json:"actorId"`   // Telegram user ID (0 = the bot itself, e.g. scheduled jobs)
+	ActorName string    `json:"actorName"` // Telegram display name at the time of the change
+	Source    string    `json:"source"`    // "bot", "mini-app", "ask" or "system"
+	Action    string    `json:"action"`    // storage method, e.g. "CreateEvent"
+	Payload   string    `json:"payload"`   // JSON encoded arguments and result
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// API token scopes
+const (
+	TokenScopeRead      = "read"       // GET requests only
+	TokenScopeReadWrite = "read-write" // every request
+)
+
+// APIToken is a personal bearer token for the Mini App API, created with /token.
+// Only the SHA-256 hash of the secret is stored.
+type APIToken struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`    // unique per owner, e.g. "tablet"
+	Scope     string    `json:"scope"`   // TokenScopeRead or TokenScopeReadWrite
+	OwnerID   int64     `json:"ownerId"` // Telegram user who created the token; requests act as this user
+	Hash      string    `json:"-"`       // hex encoded SHA-256 of the secret
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// ChatSettings holds per-chat bot preferences
+type ChatSettings struct {
+	ChatID           int64    `json:"chatId"`
+	RotationStrategy string   `json:"rotationStrategy"` // empty means the default strategy
+	RotationSkipIDs  []string `json:"rotationSkipIds"`  // participants left out of the rotation
+	ReminderEnabled  bool     `json:"reminderEnabled"`  // daily "who reads tonight" reminder registered with /remind
+	ReminderThreadID int      `json:"reminderThreadId"` // topic the reminder is posted to (0 = general)
 }
 
 // BookStat represents book reading statistics
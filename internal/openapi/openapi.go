@@ -0,0 +1,441 @@
+// Package openapi holds the OpenAPI 3 specification of the Mini App API (/api/*) and
+// validates incoming requests against it.
+//
+// The validator only understands what the request side of openapi.json uses: query and
+// path parameters of type string, integer or boolean, and JSON bodies that are a $ref to a
+// component object schema. Schemas may use type (object, array, string, integer, boolean),
+// format (date), pattern, enum (of strings), minLength, minimum, maximum, minItems, items,
+// properties, required and additionalProperties: false. Paths may have templated segments
+// such as /api/events/{id}; a literal path always wins over a template.
+package openapi
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Spec is the OpenAPI document served at /api/openapi.json
+//
+//go:embed openapi.json
+var Spec []byte
+
+// MaxBodyBytes limits the request bodies read by Validate; API bodies are small JSON objects
+const MaxBodyBytes = 1 << 20
+
+// ErrBodyTooLarge is returned by Validate for a body over MaxBodyBytes
+var ErrBodyTooLarge = errors.New("request body is too large")
+
+// document is the part of an OpenAPI document the validator needs
+type document struct {
+	Paths      map[string]map[string]*operation `json:"paths"`
+	Components struct {
+		Schemas    map[string]*Schema    `json:"schemas"`
+		Parameters map[string]*parameter `json:"parameters"`
+	} `json:"components"`
+
+	templates []string // keys of Paths with {parameter} segments, sorted
+}
+
+// operation is a method of a path
+type operation struct {
+	Parameters  []parameter `json:"parameters"`
+	RequestBody *struct {
+		Required bool `json:"required"`
+		Content  map[string]struct {
+			Schema *Schema `json:"schema"`
+		} `json:"content"`
+	} `json:"requestBody"`
+}
+
+// parameter is a query or path parameter of an operation
+type parameter struct {
+	Ref      string  `json:"$ref"`
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required"`
+	Schema   *Schema `json:"schema"`
+}
+
+// Schema is a JSON Schema object of the specification
+type Schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 string             `json:"type"`
+	Format               string             `json:"format"`
+	Pattern              string             `json:"pattern"`
+	Enum                 []any              `json:"enum"`
+	MinLength            *int               `json:"minLength"`
+	Minimum              *float64           `json:"minimum"`
+	Maximum              *float64           `json:"maximum"`
+	MinItems             *int               `json:"minItems"`
+	Items                *Schema            `json:"items"`
+	Properties           map[string]*Schema `json:"properties"`
+	Required             []string           `json:"required"`
+	AdditionalProperties *bool              `json:"additionalProperties"`
+
+	pattern *regexp.Regexp // Pattern, compiled when the specification is loaded
+}
+
+var (
+	loadOnce sync.Once
+	spec     *document
+	loadErr  error
+)
+
+// load parses the embedded specification once
+func load() (*document, error) {
+	loadOnce.Do(func() {
+		var doc document
+		if err := json.Unmarshal(Spec, &doc); err != nil {
+			loadErr = fmt.Errorf("failed to parse OpenAPI specification: %w", err)
+			return
+		}
+		if err := doc.prepare(); err != nil {
+			loadErr = fmt.Errorf("invalid OpenAPI specification: %w", err)
+			return
+		}
+		spec = &doc
+	})
+	return spec, loadErr
+}
+
+// prepare compiles the patterns of all schemas and collects the templated paths
+func (doc *document) prepare() error {
+	var schemas []*Schema
+	for _, schema := range doc.Components.Schemas {
+		schemas = append(schemas, schema)
+	}
+	for _, param := range doc.Components.Parameters {
+		schemas = append(schemas, param.Schema)
+	}
+	for _, ops := range doc.Paths {
+		for _, op := range ops {
+			for _, param := range op.Parameters {
+				schemas = append(schemas, param.Schema)
+			}
+			if op.RequestBody != nil {
+				for _, media := range op.RequestBody.Content {
+					schemas = append(schemas, media.Schema)
+				}
+			}
+		}
+	}
+	for _, schema := range schemas {
+		if err := schema.compile(); err != nil {
+			return err
+		}
+	}
+
+	doc.templates = nil
+	for path := range doc.Paths {
+		if strings.Contains(path, "{") {
+			doc.templates = append(doc.templates, path)
+		}
+	}
+	slices.Sort(doc.templates)
+	return nil
+}
+
+// compile compiles the pattern of a schema and of the schemas nested in it
+func (schema *Schema) compile() error {
+	if schema == nil {
+		return nil
+	}
+	if schema.Pattern != "" && schema.pattern == nil {
+		re, err := regexp.Compile(schema.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", schema.Pattern, err)
+		}
+		schema.pattern = re
+	}
+	if err := schema.Items.compile(); err != nil {
+		return err
+	}
+	for _, property := range schema.Properties {
+		if err := property.compile(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Validate checks the query parameters and JSON body of a request against the operation
+// documented for its path and method. Requests for undocumented operations are not checked,
+// so handlers can still answer them (e.g. with 405 Method Not Allowed).
+// The request body is read and replaced, so handlers can decode it again; bodies over
+// MaxBodyBytes are rejected with ErrBodyTooLarge.
+func Validate(r *http.Request) error {
+	doc, err := load()
+	if err != nil {
+		return err
+	}
+	op, pathParams := doc.find(r.URL.Path, r.Method)
+	if op == nil {
+		return nil
+	}
+
+	query := r.URL.Query()
+	for _, param := range op.Parameters {
+		if param.Ref != "" {
+			name, _ := strings.CutPrefix(param.Ref, "#/components/parameters/")
+			if doc.Components.Parameters[name] == nil {
+				return fmt.Errorf("unknown parameter reference %q", param.Ref)
+			}
+			param = *doc.Components.Parameters[name]
+		}
+		var value string
+		var present bool
+		switch param.In {
+		case "query":
+			present = query.Has(param.Name)
+			value = query.Get(param.Name)
+		case "path":
+			value, present = pathParams[param.Name]
+		default:
+			continue
+		}
+		if !present || value == "" {
+			if param.Required {
+				return fmt.Errorf("%s parameter %s is required", param.In, param.Name)
+			}
+			continue
+		}
+		if err := doc.validateParam(param.Schema, value); err != nil {
+			return fmt.Errorf("%s parameter %s: %w", param.In, param.Name, err)
+		}
+	}
+
+	if op.RequestBody == nil {
+		return nil
+	}
+	media, ok := op.RequestBody.Content["application/json"]
+	if !ok || media.Schema == nil {
+		return nil
+	}
+
+	var raw []byte
+	if r.Body != nil {
+		raw, err = io.ReadAll(io.LimitReader(r.Body, MaxBodyBytes+1))
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) || len(raw) > MaxBodyBytes {
+			return fmt.Errorf("%w, the limit is %d bytes", ErrBodyTooLarge, MaxBodyBytes)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read request body: %w", err)
+		}
+		r.Body.Close()
+	}
+	r.Body = io.NopCloser(bytes.NewReader(raw))
+	if len(bytes.TrimSpace(raw)) == 0 {
+		if op.RequestBody.Required {
+			return fmt.Errorf("request body is required")
+		}
+		return nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var body any
+	if err := decoder.Decode(&body); err != nil {
+		return fmt.Errorf("request body is not valid JSON")
+	}
+	return doc.validate(media.Schema, body, "body")
+}
+
+// find returns the operation for a request path and method with its path parameters,
+// or nil if the specification does not document it. A documented literal path is used
+// before any template; templates are tried in sorted order.
+func (doc *document) find(path, method string) (*operation, map[string]string) {
+	if ops, ok := doc.Paths[path]; ok {
+		return ops[strings.ToLower(method)], nil
+	}
+	for _, template := range doc.templates {
+		params, ok := matchPath(template, path)
+		if !ok {
+			continue
+		}
+		return doc.Paths[template][strings.ToLower(method)], params
+	}
+	return nil, nil
+}
+
+// matchPath matches a request path against a path template such as /api/events/{id}
+func matchPath(template, path string) (map[string]string, bool) {
+	templateParts := strings.Split(template, "/")
+	pathParts := strings.Split(path, "/")
+	if len(templateParts) != len(pathParts) {
+		return nil, false
+	}
+
+	params := make(map[string]string)
+	for i, part := range templateParts {
+		if strings.HasPrefix(part, "{") && strings.HasSuffix(part, "}") {
+			if pathParts[i] == "" {
+				return nil, false
+			}
+			params[strings.Trim(part, "{}")] = pathParts[i]
+			continue
+		}
+		if part != pathParts[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+// resolve follows a $ref to a component schema
+func (doc *document) resolve(schema *Schema) (*Schema, error) {
+	for schema.Ref != "" {
+		name, ok := strings.CutPrefix(schema.Ref, "#/components/schemas/")
+		if !ok || doc.Components.Schemas[name] == nil {
+			return nil, fmt.Errorf("unknown schema reference %q", schema.Ref)
+		}
+		schema = doc.Components.Schemas[name]
+	}
+	return schema, nil
+}
+
+// validateParam converts a query or path parameter to the schema type and validates it
+func (doc *document) validateParam(schema *Schema, value string) error {
+	schema, err := doc.resolve(schema)
+	if err != nil {
+		return err
+	}
+
+	var typed any = value
+	switch schema.Type {
+	case "integer":
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return fmt.Errorf("expected an integer")
+		}
+		typed = json.Number(value)
+	case "boolean":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("expected true or false")
+		}
+		typed = b
+	}
+	return doc.validate(schema, typed, "")
+}
+
+// validate checks a decoded JSON value against a schema; path names the value in errors
+func (doc *document) validate(schema *Schema, value any, path string) error {
+	schema, err := doc.resolve(schema)
+	if err != nil {
+		return err
+	}
+	fail := func(format string, args ...any) error {
+		message := fmt.Sprintf(format, args...)
+		if path == "" {
+			return fmt.Errorf("%s", message)
+		}
+		return fmt.Errorf("%s: %s", path, message)
+	}
+
+	if value == nil {
+		return fail("must not be null")
+	}
+
+	switch schema.Type {
+	case "object":
+		object, ok := value.(map[string]any)
+		if !ok {
+			return fail("expected an object")
+		}
+		for _, name := range schema.Required {
+			if _, ok := object[name]; !ok {
+				return fail("missing required field %s", name)
+			}
+		}
+		names := make([]string, 0, len(object))
+		for name := range object {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		for _, name := range names {
+			property, ok := schema.Properties[name]
+			if !ok {
+				if schema.AdditionalProperties != nil && !*schema.AdditionalProperties {
+					return fail("unknown field %s", name)
+				}
+				continue
+			}
+			if err := doc.validate(property, object[name], joinPath(path, name)); err != nil {
+				return err
+			}
+		}
+	case "array":
+		array, ok := value.([]any)
+		if !ok {
+			return fail("expected an array")
+		}
+		if schema.MinItems != nil && len(array) < *schema.MinItems {
+			return fail("expected at least %d item(s)", *schema.MinItems)
+		}
+		if schema.Items != nil {
+			for i, item := range array {
+				if err := doc.validate(schema.Items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			return fail("expected a string")
+		}
+		if schema.MinLength != nil && len([]rune(s)) < *schema.MinLength {
+			return fail("must not be empty")
+		}
+		if schema.Format == "date" {
+			if _, err := time.Parse("2006-01-02", s); err != nil {
+				return fail("expected a date in YYYY-MM-DD format")
+			}
+		}
+		if schema.pattern != nil && !schema.pattern.MatchString(s) {
+			return fail("does not match %s", schema.Pattern)
+		}
+		if len(schema.Enum) > 0 && !slices.Contains(schema.Enum, any(s)) {
+			return fail("must be one of %v", schema.Enum)
+		}
+	case "integer":
+		number, ok := value.(json.Number)
+		if !ok {
+			return fail("expected an integer")
+		}
+		n, err := number.Int64()
+		if err != nil {
+			return fail("expected an integer")
+		}
+		if schema.Minimum != nil && float64(n) < *schema.Minimum {
+			return fail("must be at least %v", *schema.Minimum)
+		}
+		if schema.Maximum != nil && float64(n) > *schema.Maximum {
+			return fail("must be at most %v", *schema.Maximum)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fail("expected true or false")
+		}
+	}
+	return nil
+}
+
+// joinPath appends a field name to a value path used in errors
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
@@ -0,0 +1,189 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSpec(t *testing.T) {
+	doc, err := load()
+	if err != nil {
+		t.Fatalf("Failed to load specification: %v", err)
+	}
+	if len(doc.Paths) == 0 {
+		t.Fatal("Expected documented paths")
+	}
+
+	// Every reference must point to a component
+	var refs []string
+	var walk func(v any)
+	walk = func(v any) {
+		switch v := v.(type) {
+		case map[string]any:
+			for key, value := range v {
+				if ref, ok := value.(string); ok && key == "$ref" {
+					refs = append(refs, ref)
+				}
+				walk(value)
+			}
+		case []any:
+			for _, value := range v {
+				walk(value)
+			}
+		}
+	}
+	var raw map[string]any
+	if err := json.Unmarshal(Spec, &raw); err != nil {
+		t.Fatalf("Failed to parse specification: %v", err)
+	}
+	walk(raw)
+	components := raw["components"].(map[string]any)
+	for _, ref := range refs {
+		parts := strings.Split(strings.TrimPrefix(ref, "#/components/"), "/")
+		section, ok := components[parts[0]].(map[string]any)
+		if len(parts) != 2 || !ok || section[parts[1]] == nil {
+			t.Errorf("Unresolved reference %q", ref)
+		}
+	}
+}
+
+func TestSpec_RequestSubset(t *testing.T) {
+	doc, err := load()
+	if err != nil {
+		t.Fatalf("Failed to load specification: %v", err)
+	}
+	var raw struct {
+		Components struct {
+			Schemas map[string]map[string]any `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(Spec, &raw); err != nil {
+		t.Fatalf("Failed to parse specification: %v", err)
+	}
+
+	// Request schemas must only use keywords Validate checks, or keywords without effect on validation
+	supported := map[string]bool{
+		"type": true, "format": true, "pattern": true, "enum": true, "minLength": true, "minimum": true,
+		"maximum": true, "minItems": true, "items": true, "properties": true, "required": true,
+		"additionalProperties": true, "description": true, "deprecated": true, "default": true,
+	}
+	var check func(name string, schema map[string]any)
+	check = func(name string, schema map[string]any) {
+		for key, value := range schema {
+			if !supported[key] {
+				t.Errorf("%s: unsupported keyword %s", name, key)
+			}
+			switch key {
+			case "items":
+				check(name+"[]", value.(map[string]any))
+			case "properties":
+				for property, nested := range value.(map[string]any) {
+					check(name+"."+property, nested.(map[string]any))
+				}
+			}
+		}
+	}
+	for path, ops := range doc.Paths {
+		for method, op := range ops {
+			if op.RequestBody == nil {
+				continue
+			}
+			ref := op.RequestBody.Content["application/json"].Schema.Ref
+			name, ok := strings.CutPrefix(ref, "#/components/schemas/")
+			if !ok {
+				t.Errorf("%s %s: request body must be a component reference", method, path)
+				continue
+			}
+			check(name, raw.Components.Schemas[name])
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		method, target, body string
+		wantErr              string // empty if the request is valid
+	}{
+		{http.MethodPost, "/api/events", `{"date":"2026-03-23","bookName":"The Hobbit","participantNames":["Alice"],"durationMinutes":20,"progressUnit":"pages"}`, ""},
+		{http.MethodPost, "/api/events", `{"date":"2026-03-23","bookTitle":"The Hobbit"}`, "body: unknown field bookTitle"},
+		// Deprecated snake_case aliases are documented and validated like their camelCase fields
+		{http.MethodPost, "/api/events", `{"date":"2026-03-23","book_name":"The Hobbit","participant_names":["Alice"]}`, ""},
+		{http.MethodPost, "/api/events", `{"date":"2026-03-23","duration_minutes":-5}`, "body.duration_minutes: must be at least 0"},
+		{http.MethodPost, "/api/labels/bulk", `{"label":"winter","book_ids":["1"]}`, ""},
+		{http.MethodPost, "/api/events", `{"bookName":"The Hobbit"}`, "body: missing required field date"},
+		{http.MethodPost, "/api/events", `{"date":"23.03.2026"}`, "body.date: expected a date in YYYY-MM-DD format"},
+		{http.MethodPost, "/api/events", `{"date":"2026-03-23","time":"evening"}`, "body.time: does not match"},
+		{http.MethodPost, "/api/events", `{"date":"2026-03-23","durationMinutes":-5}`, "body.durationMinutes: must be at least 0"},
+		{http.MethodPost, "/api/events", `{"date":"2026-03-23","progress":1.5}`, "body.progress: expected an integer"},
+		{http.MethodPost, "/api/events", `{"date":"2026-03-23","progressUnit":"lines"}`, "body.progressUnit: must be one of"},
+		{http.MethodPost, "/api/events", `{"date":"2026-03-23","participantIds":["a",1]}`, "body.participantIds[1]: expected a string"},
+		{http.MethodPost, "/api/events", `{"date":"2026-03-23","force":"yes"}`, "body.force: expected true or false"},
+		{http.MethodPost, "/api/events", `[]`, "body: expected an object"},
+		{http.MethodPost, "/api/events", `not json`, "request body is not valid JSON"},
+		{http.MethodPost, "/api/events", ``, "request body is required"},
+		{http.MethodPost, "/api/labels/bulk", `{"label":"winter","bookIds":[]}`, "body.bookIds: expected at least 1 item(s)"},
+		{http.MethodPost, "/api/labels/rename", `{"label":"winter","newLabel":""}`, "body.newLabel: must not be empty"},
+		{http.MethodGet, "/api/events?since=2026-03-01&limit=10&participant=Alice", "", ""},
+		{http.MethodGet, "/api/events?limit=1000", "", "query parameter limit: must be at most 500"},
+		{http.MethodGet, "/api/stats/rare?children=maybe", "", "query parameter children: expected true or false"},
+		{http.MethodGet, "/api/books/cover", "", "query parameter id is required"},
+		{http.MethodDelete, "/api/events/42", "", ""},
+		// Undocumented operations are left to the handlers
+		{http.MethodPut, "/api/events", `{"anything":true}`, ""},
+		{http.MethodGet, "/web-app", "", ""},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+		err := Validate(r)
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("%s %s %s: unexpected error %v", tt.method, tt.target, tt.body, err)
+		case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+			t.Errorf("%s %s %s: expected error containing %q, got %v", tt.method, tt.target, tt.body, tt.wantErr, err)
+		}
+	}
+}
+
+func TestValidate_KeepsBody(t *testing.T) {
+	body := `{"label":"winter","bookIds":["1","2"]}`
+	r := httptest.NewRequest(http.MethodPost, "/api/labels/bulk", bytes.NewBufferString(body))
+	if err := Validate(r); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	got, _ := io.ReadAll(r.Body)
+	if string(got) != body {
+		t.Errorf("Expected handlers to read the original body, got %q", got)
+	}
+}
+
+func TestValidate_BodyTooLarge(t *testing.T) {
+	body := `{"date":"2026-03-23","bookName":"` + strings.Repeat("a", MaxBodyBytes) + `"}`
+	r := httptest.NewRequest(http.MethodPost, "/api/events", strings.NewReader(body))
+	if err := Validate(r); !errors.Is(err, ErrBodyTooLarge) {
+		t.Errorf("Expected ErrBodyTooLarge, got %v", err)
+	}
+}
+
+func TestFind_PrefersLiteralPaths(t *testing.T) {
+	doc := &document{Paths: map[string]map[string]*operation{
+		"/api/labels/{label}": {"get": {}},
+		"/api/labels/bulk":    {"get": {Parameters: []parameter{{Name: "literal"}}}},
+	}}
+	if err := doc.prepare(); err != nil {
+		t.Fatalf("Failed to prepare document: %v", err)
+	}
+
+	op, params := doc.find("/api/labels/bulk", http.MethodGet)
+	if op == nil || len(op.Parameters) != 1 || len(params) != 0 {
+		t.Errorf("Expected the literal path to match, got %+v %v", op, params)
+	}
+	if _, params := doc.find("/api/labels/winter", http.MethodGet); params["label"] != "winter" {
+		t.Errorf("Expected the label template to match, got %v", params)
+	}
+}
@@ -0,0 +1,143 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// RunStore remembers which occurrences of jobs already ran, so that restarts
+// neither skip nor repeat a run. ClaimScheduledRun must be atomic: when several
+// instances claim the same occurrence, only one of them may get true.
+type RunStore interface {
+	GetLastScheduledRun(ctx context.Context, job string) (time.Time, error)
+	ClaimScheduledRun(ctx context.Context, job string, scheduledFor time.Time) (bool, error)
+}
+
+// Job is a task that runs at a fixed time of day
+type Job struct {
+	Name   string
+	Hour   int
+	Minute int
+	On     func(day time.Time) bool // days the job runs on (nil = every day)
+	Run    func(ctx context.Context) error
+}
+
+// Weekly returns a day filter matching the given weekday
+func Weekly(weekday time.Weekday) func(time.Time) bool {
+	return func(day time.Time) bool { return day.Weekday() == weekday }
+}
+
+// Monthly returns a day filter matching the given day of the month
+func Monthly(monthDay int) func(time.Time) bool {
+	return func(day time.Time) bool { return day.Day() == monthDay }
+}
+
+// Scheduler runs daily jobs in a timezone. A job that was missed because the
+// process was down runs as soon as the scheduler starts, if it is still the same day.
+// Several instances may share a store: each occurrence is claimed by one of them.
+// Jobs only run while an instance is up, so either keep at least one instance
+// running or call RunDue from an external trigger.
+type Scheduler struct {
+	store  RunStore
+	loc    *time.Location
+	logger *zap.Logger
+	now    func() time.Time
+
+	mu   sync.Mutex
+	jobs []Job
+}
+
+// New creates a scheduler that evaluates job times in loc
+func New(store RunStore, loc *time.Location, logger *zap.Logger) *Scheduler {
+	return &Scheduler{
+		store:  store,
+		loc:    loc,
+		logger: logger,
+		now:    time.Now,
+	}
+}
+
+// Add registers a job
+func (s *Scheduler) Add(job Job) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs = append(s.jobs, job)
+}
+
+// ParseTimeOfDay parses "HH:MM" into hour and minute
+func ParseTimeOfDay(value string) (hour, minute int, err error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid time of day %q, expected HH:MM", value)
+	}
+	return t.Hour(), t.Minute(), nil
+}
+
+// Start checks for due jobs right away and then every minute until ctx is cancelled
+func (s *Scheduler) Start(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		s.RunDue(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunDue runs every job scheduled for today whose time has passed and has not run yet
+func (s *Scheduler) RunDue(ctx context.Context) {
+	s.mu.Lock()
+	jobs := append([]Job(nil), s.jobs...)
+	s.mu.Unlock()
+
+	now := s.now().In(s.loc)
+	for _, job := range jobs {
+		if job.On != nil && !job.On(now) {
+			continue
+		}
+		occurrence := time.Date(now.Year(), now.Month(), now.Day(), job.Hour, job.Minute, 0, 0, s.loc)
+		if now.Before(occurrence) {
+			continue
+		}
+
+		last, err := s.store.GetLastScheduledRun(ctx, job.Name)
+		if err != nil {
+			s.logger.Error("Failed to get last scheduled run", zap.Error(err), zap.String("job", job.Name))
+			continue
+		}
+		if !last.Before(occurrence) {
+			continue
+		}
+
+		// Claim first so that a failing job is not retried every minute, and so that
+		// only one instance runs it
+		claimed, err := s.store.ClaimScheduledRun(ctx, job.Name, occurrence)
+		if err != nil {
+			s.logger.Error("Failed to claim scheduled run", zap.Error(err), zap.String("job", job.Name))
+			continue
+		}
+		if !claimed {
+			s.logger.Info("Scheduled job already claimed by another instance",
+				zap.String("job", job.Name),
+				zap.Time("scheduled_for", occurrence),
+			)
+			continue
+		}
+
+		s.logger.Info("Running scheduled job",
+			zap.String("job", job.Name),
+			zap.Time("scheduled_for", occurrence),
+		)
+		if err := job.Run(ctx); err != nil {
+			s.logger.Error("Scheduled job failed", zap.Error(err), zap.String("job", job.Name))
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"library/internal/storage/stubs"
+)
+
+func TestScheduler_RunDue(t *testing.T) {
+	ctx := context.Background()
+	db := stubs.NewMockDB()
+	loc, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("timezone data not available: %v", err)
+	}
+
+	runs := 0
+	s := New(db, loc, zap.NewNop())
+	s.Add(Job{Name: "reminder", Hour: 19, Minute: 30, Run: func(ctx context.Context) error {
+		runs++
+		return nil
+	}})
+
+	at := func(day, hour, minute int) {
+		s.now = func() time.Time { return time.Date(2026, 10, day, hour, minute, 0, 0, loc) }
+		s.RunDue(ctx)
+	}
+
+	at(16, 19, 29)
+	if runs != 0 {
+		t.Fatalf("Job ran before its time")
+	}
+	at(16, 19, 30)
+	at(16, 19, 31)
+	if runs != 1 {
+		t.Fatalf("Expected one run on the first day, got %d", runs)
+	}
+
+	// A restarted scheduler does not repeat today's run
+	restarted := New(db, loc, zap.NewNop())
+	restarted.Add(s.jobs[0])
+	restarted.now = s.now
+	restarted.RunDue(ctx)
+	if runs != 1 {
+		t.Fatalf("Expected the run not to repeat after a restart, got %d", runs)
+	}
+
+	// A run missed while the process was down happens on start, the same day
+	at(17, 23, 0)
+	if runs != 2 {
+		t.Fatalf("Expected the missed run to catch up, got %d", runs)
+	}
+
+	// Yesterday's missed run is not caught up the next morning
+	at(19, 8, 0)
+	if runs != 2 {
+		t.Fatalf("Expected no run before today's time, got %d", runs)
+	}
+}
+
+func TestScheduler_FailedJobIsNotRetried(t *testing.T) {
+	ctx := context.Background()
+	calls := 0
+	s := New(stubs.NewMockDB(), time.UTC, zap.NewNop())
+	s.Add(Job{Name: "reminder", Hour: 8, Run: func(ctx context.Context) error {
+		calls++
+		return errors.New("telegram is down")
+	}})
+	s.now = func() time.Time { return time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC) }
+
+	s.RunDue(ctx)
+	s.RunDue(ctx)
+	if calls != 1 {
+		t.Errorf("Expected a single attempt, got %d", calls)
+	}
+}
+
+func TestScheduler_SeveralInstances(t *testing.T) {
+	ctx := context.Background()
+	db := stubs.NewMockDB()
+	now := func() time.Time { return time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC) }
+
+	var runs atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		s := New(db, time.UTC, zap.NewNop())
+		s.Add(Job{Name: "reminder", Hour: 8, Run: func(ctx context.Context) error {
+			runs.Add(1)
+			return nil
+		}})
+		s.now = now
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.RunDue(ctx)
+		}()
+	}
+	wg.Wait()
+
+	if got := runs.Load(); got != 1 {
+		t.Errorf("Expected one instance to run the job, got %d runs", got)
+	}
+}
+
+func TestScheduler_On(t *testing.T) {
+	ctx := context.Background()
+	var ran []string
+	s := New(stubs.NewMockDB(), time.UTC, zap.NewNop())
+	for _, job := range []Job{
+		{Name: "weekly", Hour: 9, On: Weekly(time.Monday)},
+		{Name: "monthly", Hour: 9, On: Monthly(1)},
+	} {
+		name := job.Name
+		job.Run = func(ctx context.Context) error {
+			ran = append(ran, name)
+			return nil
+		}
+		s.Add(job)
+	}
+
+	// 2026-10-12 is a Monday, 2026-11-01 a Sunday
+	for _, day := range []int{11, 12, 13} {
+		s.now = func() time.Time { return time.Date(2026, 10, day, 10, 0, 0, 0, time.UTC) }
+		s.RunDue(ctx)
+	}
+	s.now = func() time.Time { return time.Date(2026, 11, 1, 10, 0, 0, 0, time.UTC) }
+	s.RunDue(ctx)
+
+	if strings.Join(ran, ",") != "weekly,monthly" {
+		t.Errorf("Unexpected runs: %v", ran)
+	}
+}
+
+func TestParseTimeOfDay(t *testing.T) {
+	hour, minute, err := ParseTimeOfDay("07:05")
+	if err != nil || hour != 7 || minute != 5 {
+		t.Errorf("ParseTimeOfDay(07:05) = %d, %d, %v", hour, minute, err)
+	}
+	for _, input := range []string{"", "7pm", "25:00"} {
+		if _, _, err := ParseTimeOfDay(input); err == nil {
+			t.Errorf("ParseTimeOfDay(%q) expected error", input)
+		}
+	}
+}
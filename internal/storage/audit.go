@@ -0,0 +1,326 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"library/internal/models"
+
+	"go.uber.org/zap"
+)
+
+// Audit sources: where a data change was made
+const (
+	SourceBot      = "bot"
+	SourceMiniApp  = "mini-app"
+	SourceAsk      = "ask"
+	SourceAPIToken = "api-token" // Mini App API called with a personal token from /token
+	SourceSystem   = "system"    // scheduled jobs and other changes without a user
+)
+
+// Actor is who a data change is attributed to in the audit log
+type Actor struct {
+	ID     int64 // Telegram user ID (0 = unknown)
+	Name   string
+	Source string
+}
+
+// actorKey is the context key of the audit actor
+type actorKey struct{}
+
+// WithActor returns a context whose data changes are attributed to actor
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// WithSource returns a context whose data changes are attributed to the same actor but another source
+func WithSource(ctx context.Context, source string) context.Context {
+	actor := ActorFromContext(ctx)
+	actor.Source = source
+	return WithActor(ctx, actor)
+}
+
+// ActorFromContext returns the audit actor of ctx; without one, changes are made by the system
+func ActorFromContext(ctx context.Context) Actor {
+	if actor, ok := ctx.Value(actorKey{}).(Actor); ok {
+		return actor
+	}
+	return Actor{Source: SourceSystem}
+}
+
+// AuditedStorage wraps a Storage and records every successful data change in the audit log,
+// attributed to the actor of the request context. Failing to write the audit entry is logged
+// and does not fail the change. Scheduler bookkeeping (ClaimScheduledRun) is not recorded.
+type AuditedStorage struct {
+	Storage
+	logger *zap.Logger
+}
+
+// NewAudited returns inner with data changes recorded in its audit log
+func NewAudited(inner Storage, logger *zap.Logger) *AuditedStorage {
+	return &AuditedStorage{Storage: inner, logger: logger}
+}
+
+// record stores an audit entry for action; payload is encoded as JSON
+func (s *AuditedStorage) record(ctx context.Context, action string, payload map[string]any) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		s.logger.Warn("Failed to encode audit payload", zap.Error(err), zap.String("action", action))
+		return
+	}
+
+	actor := ActorFromContext(ctx)
+	entry := models.AuditEntry{
+		ActorID:   actor.ID,
+		ActorName: actor.Name,
+		Source:    actor.Source,
+		Action:    action,
+		Payload:   string(data),
+		CreatedAt: time.Now().UTC(),
+	}
+	// The change is already done, so record it even if the request is cancelled meanwhile
+	if err := s.Storage.AddAuditEntry(context.WithoutCancel(ctx), entry); err != nil {
+		s.logger.Warn("Failed to write audit entry", zap.Error(err), zap.String("action", action))
+	}
+}
+
+// CreateBook creates a book and records it
+func (s *AuditedStorage) CreateBook(ctx context.Context, name string) (string, error) {
+	id, err := s.Storage.CreateBook(ctx, name)
+	if err == nil {
+		s.record(ctx, "CreateBook", map[string]any{"id": id, "name": name})
+	}
+	return id, err
+}
+
+// AddLabelToBook adds a label and records it
+func (s *AuditedStorage) AddLabelToBook(ctx context.Context, bookID string, label string) error {
+	err := s.Storage.AddLabelToBook(ctx, bookID, label)
+	if err == nil {
+		s.record(ctx, "AddLabelToBook", map[string]any{"book_id": bookID, "label": label})
+	}
+	return err
+}
+
+// AddLabelToBooks adds a label to several books and records it
+func (s *AuditedStorage) AddLabelToBooks(ctx context.Context, bookIDs []string, label string) error {
+	err := s.Storage.AddLabelToBooks(ctx, bookIDs, label)
+	if err == nil {
+		s.record(ctx, "AddLabelToBooks", map[string]any{"book_ids": bookIDs, "label": label})
+	}
+	return err
+}
+
+// RemoveLabelFromBook removes a label and records it
+func (s *AuditedStorage) RemoveLabelFromBook(ctx context.Context, bookID string, label string) error {
+	err := s.Storage.RemoveLabelFromBook(ctx, bookID, label)
+	if err == nil {
+		s.record(ctx, "RemoveLabelFromBook", map[string]any{"book_id": bookID, "label": label})
+	}
+	return err
+}
+
+// RemoveLabelFromBooks removes a label from several books and records it
+func (s *AuditedStorage) RemoveLabelFromBooks(ctx context.Context, bookIDs []string, label string) error {
+	err := s.Storage.RemoveLabelFromBooks(ctx, bookIDs, label)
+	if err == nil {
+		s.record(ctx, "RemoveLabelFromBooks", map[string]any{"book_ids": bookIDs, "label": label})
+	}
+	return err
+}
+
+// RenameLabel renames a label and records it
+func (s *AuditedStorage) RenameLabel(ctx context.Context, oldLabel, newLabel string) error {
+	err := s.Storage.RenameLabel(ctx, oldLabel, newLabel)
+	if err == nil {
+		s.record(ctx, "RenameLabel", map[string]any{"label": oldLabel, "new_label": newLabel})
+	}
+	return err
+}
+
+// MergeLabels merges labels and records it
+func (s *AuditedStorage) MergeLabels(ctx context.Context, sources []string, target string) error {
+	err := s.Storage.MergeLabels(ctx, sources, target)
+	if err == nil {
+		s.record(ctx, "MergeLabels", map[string]any{"sources": sources, "target": target})
+	}
+	return err
+}
+
+// RetireBook retires a book and records it
+func (s *AuditedStorage) RetireBook(ctx context.Context, id string) error {
+	err := s.Storage.RetireBook(ctx, id)
+	if err == nil {
+		s.record(ctx, "RetireBook", map[string]any{"id": id})
+	}
+	return err
+}
+
+// RestoreBook restores a retired book and records it
+func (s *AuditedStorage) RestoreBook(ctx context.Context, id string) error {
+	err := s.Storage.RestoreBook(ctx, id)
+	if err == nil {
+		s.record(ctx, "RestoreBook", map[string]any{"id": id})
+	}
+	return err
+}
+
+// RenameBook renames a book and records it
+func (s *AuditedStorage) RenameBook(ctx context.Context, id, newName string) error {
+	err := s.Storage.RenameBook(ctx, id, newName)
+	if err == nil {
+		s.record(ctx, "RenameBook", map[string]any{"id": id, "name": newName})
+	}
+	return err
+}
+
+// UpdateBookMetadata updates the details of a book and records them
+func (s *AuditedStorage) UpdateBookMetadata(ctx context.Context, id string, meta models.BookMetadata) error {
+	err := s.Storage.UpdateBookMetadata(ctx, id, meta)
+	if err == nil {
+		s.record(ctx, "UpdateBookMetadata", map[string]any{"id": id, "metadata": meta})
+	}
+	return err
+}
+
+// DeleteBook deletes a book and records it
+func (s *AuditedStorage) DeleteBook(ctx context.Context, id string) error {
+	err := s.Storage.DeleteBook(ctx, id)
+	if err == nil {
+		s.record(ctx, "DeleteBook", map[string]any{"id": id})
+	}
+	return err
+}
+
+// CreateParticipant creates a participant and records it
+func (s *AuditedStorage) CreateParticipant(ctx context.Context, name string, isParent bool) (string, error) {
+	id, err := s.Storage.CreateParticipant(ctx, name, isParent)
+	if err == nil {
+		s.record(ctx, "CreateParticipant", map[string]any{"id": id, "name": name, "is_parent": isParent})
+	}
+	return id, err
+}
+
+// UpdateParticipant updates a participant and records it
+func (s *AuditedStorage) UpdateParticipant(ctx context.Context, id, newName string, isParent bool) error {
+	err := s.Storage.UpdateParticipant(ctx, id, newName, isParent)
+	if err == nil {
+		s.record(ctx, "UpdateParticipant", map[string]any{"id": id, "name": newName, "is_parent": isParent})
+	}
+	return err
+}
+
+// ArchiveParticipant archives a participant and records it
+func (s *AuditedStorage) ArchiveParticipant(ctx context.Context, id string) error {
+	err := s.Storage.ArchiveParticipant(ctx, id)
+	if err == nil {
+		s.record(ctx, "ArchiveParticipant", map[string]any{"id": id})
+	}
+	return err
+}
+
+// SetAbsence sets an absence and records it
+func (s *AuditedStorage) SetAbsence(ctx context.Context, participantID string, until time.Time) error {
+	err := s.Storage.SetAbsence(ctx, participantID, until)
+	if err == nil {
+		s.record(ctx, "SetAbsence", map[string]any{"participant_id": participantID, "until": until})
+	}
+	return err
+}
+
+// ClearAbsence ends an absence and records it
+func (s *AuditedStorage) ClearAbsence(ctx context.Context, participantID string) error {
+	err := s.Storage.ClearAbsence(ctx, participantID)
+	if err == nil {
+		s.record(ctx, "ClearAbsence", map[string]any{"participant_id": participantID})
+	}
+	return err
+}
+
+// SetGoal sets a reading goal and records it
+func (s *AuditedStorage) SetGoal(ctx context.Context, participantID string, target int, period string) error {
+	err := s.Storage.SetGoal(ctx, participantID, target, period)
+	if err == nil {
+		s.record(ctx, "SetGoal", map[string]any{"participant_id": participantID, "target": target, "period": period})
+	}
+	return err
+}
+
+// ClearGoal removes a reading goal and records it
+func (s *AuditedStorage) ClearGoal(ctx context.Context, participantID string) error {
+	err := s.Storage.ClearGoal(ctx, participantID)
+	if err == nil {
+		s.record(ctx, "ClearGoal", map[string]any{"participant_id": participantID})
+	}
+	return err
+}
+
+// AddBadge stores a badge and records it
+func (s *AuditedStorage) AddBadge(ctx context.Context, badge models.Badge) error {
+	err := s.Storage.AddBadge(ctx, badge)
+	if err == nil {
+		s.record(ctx, "AddBadge", map[string]any{"badge": badge})
+	}
+	return err
+}
+
+// CreateEvent creates a reading event and records it
+func (s *AuditedStorage) CreateEvent(ctx context.Context, event models.Event) (string, error) {
+	id, err := s.Storage.CreateEvent(ctx, event)
+	if err == nil {
+		event.ID = id
+		s.record(ctx, "CreateEvent", map[string]any{"event": event})
+	}
+	return id, err
+}
+
+// UpdateEvent updates a reading event and records it
+func (s *AuditedStorage) UpdateEvent(ctx context.Context, event models.Event) error {
+	err := s.Storage.UpdateEvent(ctx, event)
+	if err == nil {
+		s.record(ctx, "UpdateEvent", map[string]any{"event": event})
+	}
+	return err
+}
+
+// DeleteEvent deletes a reading event and records it together with what was deleted
+func (s *AuditedStorage) DeleteEvent(ctx context.Context, id string) error {
+	payload := map[string]any{"id": id}
+	if event, err := s.Storage.GetEvent(ctx, id); err == nil {
+		payload["event"] = event
+	}
+
+	err := s.Storage.DeleteEvent(ctx, id)
+	if err == nil {
+		s.record(ctx, "DeleteEvent", payload)
+	}
+	return err
+}
+
+// CreateAPIToken creates a token and records it; the hash of the secret is not recorded
+func (s *AuditedStorage) CreateAPIToken(ctx context.Context, token models.APIToken) (string, error) {
+	id, err := s.Storage.CreateAPIToken(ctx, token)
+	if err == nil {
+		s.record(ctx, "CreateAPIToken", map[string]any{"id": id, "name": token.Name, "scope": token.Scope, "owner_id": token.OwnerID})
+	}
+	return id, err
+}
+
+// RevokeAPIToken revokes a token and records it
+func (s *AuditedStorage) RevokeAPIToken(ctx context.Context, id string) error {
+	err := s.Storage.RevokeAPIToken(ctx, id)
+	if err == nil {
+		s.record(ctx, "RevokeAPIToken", map[string]any{"id": id})
+	}
+	return err
+}
+
+// SaveChatSettings saves chat settings and records them
+func (s *AuditedStorage) SaveChatSettings(ctx context.Context, settings models.ChatSettings) error {
+	err := s.Storage.SaveChatSettings(ctx, settings)
+	if err == nil {
+		s.record(ctx, "SaveChatSettings", map[string]any{"settings": settings})
+	}
+	return err
+}
@@ -0,0 +1,82 @@
+package storage_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"library/internal/models"
+	"library/internal/storage"
+	"library/internal/storage/stubs"
+
+	"go.uber.org/zap"
+)
+
+func TestAuditedStorage(t *testing.T) {
+	inner := stubs.NewMockDB()
+	ctx := context.Background()
+	if err := inner.Initialize(ctx); err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	db := storage.NewAudited(inner, zap.NewNop())
+
+	userCtx := storage.WithActor(ctx, storage.Actor{ID: 42, Name: "Ann", Source: storage.SourceBot})
+	bookID, err := db.CreateBook(userCtx, "Dune")
+	if err != nil {
+		t.Fatalf("CreateBook failed: %v", err)
+	}
+
+	// Failed changes are not recorded
+	if _, err := db.CreateBook(userCtx, "Dune"); err == nil {
+		t.Fatal("Expected duplicate book to fail")
+	}
+
+	alice, err := inner.GetParticipantByName(ctx, "Alice")
+	if err != nil {
+		t.Fatalf("Failed to find Alice: %v", err)
+	}
+	askCtx := storage.WithSource(userCtx, storage.SourceAsk)
+	eventID, err := db.CreateEvent(askCtx, models.Event{Date: time.Now(), BookID: bookID, ParticipantIDs: []string{alice.ID}})
+	if err != nil {
+		t.Fatalf("CreateEvent failed: %v", err)
+	}
+
+	// Without an actor the change is made by the system; deleted events keep their contents
+	if err := db.DeleteEvent(ctx, eventID); err != nil {
+		t.Fatalf("DeleteEvent failed: %v", err)
+	}
+
+	entries, err := db.ListAuditEntries(ctx, 10, 0)
+	if err != nil {
+		t.Fatalf("ListAuditEntries failed: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 audit entries, got %d: %+v", len(entries), entries)
+	}
+
+	deleted, created, book := entries[0], entries[1], entries[2]
+	if book.Action != "CreateBook" || book.ActorID != 42 || book.ActorName != "Ann" || book.Source != storage.SourceBot {
+		t.Errorf("Unexpected CreateBook entry: %+v", book)
+	}
+	if !strings.Contains(book.Payload, `"name":"Dune"`) || !strings.Contains(book.Payload, bookID) {
+		t.Errorf("Expected book name and ID in payload, got %s", book.Payload)
+	}
+	if created.Action != "CreateEvent" || created.ActorID != 42 || created.Source != storage.SourceAsk {
+		t.Errorf("Unexpected CreateEvent entry: %+v", created)
+	}
+	if deleted.Action != "DeleteEvent" || deleted.ActorID != 0 || deleted.Source != storage.SourceSystem {
+		t.Errorf("Unexpected DeleteEvent entry: %+v", deleted)
+	}
+	if !strings.Contains(deleted.Payload, `"bookName":"Dune"`) || !strings.Contains(deleted.Payload, "Alice") {
+		t.Errorf("Expected deleted event contents in payload, got %s", deleted.Payload)
+	}
+
+	entries, err = db.ListAuditEntries(ctx, 10, 42)
+	if err != nil {
+		t.Fatalf("ListAuditEntries failed: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("Expected 2 entries by user 42, got %d", len(entries))
+	}
+}
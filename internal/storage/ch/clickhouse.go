@@ -3,13 +3,19 @@ package ch
 import (
 	"context"
 	"crypto/tls"
+	"database/sql"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
 
+	"library/internal/labels"
 	"library/internal/models"
+	"library/internal/storage"
 
 	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+	"github.com/google/uuid"
 )
 
 type ClickHouseDB struct {
@@ -57,19 +63,55 @@ func (db *ClickHouseDB) Initialize(ctx context.Context) error {
 	return nil
 }
 
-// CreateBook creates a new book and returns the book name as identifier
+// CreateBook creates a new book and returns its generated ID
 func (db *ClickHouseDB) CreateBook(ctx context.Context, name string) (string, error) {
-	err := db.conn.Exec(ctx, `INSERT INTO books (name, is_readable) VALUES (?, ?)`,
-		name, true)
+	taken, err := db.bookNameTaken(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	if taken {
+		return "", fmt.Errorf("book %q already exists", name)
+	}
+
+	id := uuid.NewString()
+	err = db.conn.Exec(ctx, `INSERT INTO books (id, name, is_readable, labels, created_at) VALUES (?, ?, ?, ?, now())`,
+		id, name, true, []string{})
 	if err != nil {
 		return "", fmt.Errorf("failed to create book: %w", err)
 	}
-	return name, nil
+	return id, nil
+}
+
+// bookColumns are the books columns read by scanBook, in order
+const bookColumns = "id, name, is_readable, labels, author, isbn, series, series_volume, cover, age, notes"
+
+// scanBook scans a row of bookColumns into book
+func scanBook(row interface{ Scan(dest ...any) error }, book *models.Book) error {
+	var volume uint16
+	if err := row.Scan(&book.ID, &book.Name, &book.IsReadable, &book.Labels,
+		&book.Author, &book.ISBN, &book.Series, &volume, &book.Cover, &book.Age, &book.Notes); err != nil {
+		return err
+	}
+	book.SeriesVolume = int(volume)
+	return nil
+}
+
+// GetBookByName returns the book with the given name, readable or retired
+func (db *ClickHouseDB) GetBookByName(ctx context.Context, name string) (models.Book, error) {
+	var book models.Book
+	err := scanBook(db.conn.QueryRow(ctx, `SELECT `+bookColumns+` FROM books WHERE name = ? LIMIT 1`, name), &book)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Book{}, fmt.Errorf("book %q %w", name, storage.ErrNotFound)
+	}
+	if err != nil {
+		return models.Book{}, fmt.Errorf("failed to get book: %w", err)
+	}
+	return book, nil
 }
 
 // ListReadableBooks returns all books that are available to read
 func (db *ClickHouseDB) ListReadableBooks(ctx context.Context) ([]models.Book, error) {
-	rows, err := db.conn.Query(ctx, `SELECT name, is_readable, labels FROM books WHERE is_readable = true ORDER BY name`)
+	rows, err := db.conn.Query(ctx, `SELECT `+bookColumns+` FROM books WHERE is_readable = true ORDER BY name`)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list readable books: %w", err)
 	}
@@ -78,7 +120,7 @@ func (db *ClickHouseDB) ListReadableBooks(ctx context.Context) ([]models.Book, e
 	var books []models.Book
 	for rows.Next() {
 		var book models.Book
-		if err := rows.Scan(&book.Name, &book.IsReadable, &book.Labels); err != nil {
+		if err := scanBook(rows, &book); err != nil {
 			return nil, fmt.Errorf("failed to scan book: %w", err)
 		}
 		books = append(books, book)
@@ -87,23 +129,162 @@ func (db *ClickHouseDB) ListReadableBooks(ctx context.Context) ([]models.Book, e
 }
 
 // AddLabelToBook adds a label to a book's labels array
-func (db *ClickHouseDB) AddLabelToBook(ctx context.Context, bookName string, label string) error {
+func (db *ClickHouseDB) AddLabelToBook(ctx context.Context, bookID string, label string) error {
 	// Use lightweight UPDATE (available in ClickHouse 25+)
 	err := db.conn.Exec(ctx, `
 		UPDATE books
 		SET labels = arrayDistinct(arrayConcat(labels, [?]))
-		WHERE name = ?`,
-		label, bookName)
+		WHERE id = ?`,
+		label, bookID)
 	if err != nil {
 		return fmt.Errorf("failed to add label to book: %w", err)
 	}
 	return nil
 }
 
+// AddLabelToBooks adds a label to several books with a single UPDATE
+func (db *ClickHouseDB) AddLabelToBooks(ctx context.Context, bookIDs []string, label string) error {
+	if len(bookIDs) == 0 {
+		return nil
+	}
+
+	// Use lightweight UPDATE (available in ClickHouse 25+)
+	err := db.conn.Exec(ctx, `
+		UPDATE books
+		SET labels = arrayDistinct(arrayConcat(labels, [?]))
+		WHERE id IN ? AND NOT has(labels, ?)`,
+		label, bookIDs, label)
+	if err != nil {
+		return fmt.Errorf("failed to add label to books: %w", err)
+	}
+	return nil
+}
+
+// RemoveLabelFromBook removes a label from a book
+func (db *ClickHouseDB) RemoveLabelFromBook(ctx context.Context, bookID string, label string) error {
+	// Use lightweight UPDATE (available in ClickHouse 25+)
+	err := db.conn.Exec(ctx, `
+		UPDATE books
+		SET labels = arrayFilter(l -> l != ?, labels)
+		WHERE id = ? AND has(labels, ?)`,
+		label, bookID, label)
+	if err != nil {
+		return fmt.Errorf("failed to remove label from book: %w", err)
+	}
+	return nil
+}
+
+// RemoveLabelFromBooks removes a label from several books with a single UPDATE
+func (db *ClickHouseDB) RemoveLabelFromBooks(ctx context.Context, bookIDs []string, label string) error {
+	if len(bookIDs) == 0 {
+		return nil
+	}
+
+	// Use lightweight UPDATE (available in ClickHouse 25+)
+	err := db.conn.Exec(ctx, `
+		UPDATE books
+		SET labels = arrayFilter(l -> l != ?, labels)
+		WHERE id IN ? AND has(labels, ?)`,
+		label, bookIDs, label)
+	if err != nil {
+		return fmt.Errorf("failed to remove label from books: %w", err)
+	}
+	return nil
+}
+
+// RenameLabel renames a label on every book
+func (db *ClickHouseDB) RenameLabel(ctx context.Context, oldLabel, newLabel string) error {
+	used, err := db.labelUsed(ctx, []string{newLabel})
+	if err != nil {
+		return err
+	}
+	if used {
+		return fmt.Errorf("label %q already exists", newLabel)
+	}
+	return db.replaceLabels(ctx, []string{oldLabel}, newLabel)
+}
+
+// MergeLabels replaces every source label with target on every book
+func (db *ClickHouseDB) MergeLabels(ctx context.Context, sources []string, target string) error {
+	return db.replaceLabels(ctx, sources, target)
+}
+
+// replaceLabels replaces the labels in from with to, keeping each label once per book
+func (db *ClickHouseDB) replaceLabels(ctx context.Context, from []string, to string) error {
+	used, err := db.labelUsed(ctx, from)
+	if err != nil {
+		return err
+	}
+	if !used {
+		return fmt.Errorf("label %q %w", strings.Join(from, ", "), storage.ErrNotFound)
+	}
+
+	err = db.conn.Exec(ctx, `
+		UPDATE books
+		SET labels = arrayDistinct(arrayMap(l -> if(has(?, l), ?, l), labels))
+		WHERE hasAny(labels, ?)`,
+		from, to, from)
+	if err != nil {
+		return fmt.Errorf("failed to replace labels: %w", err)
+	}
+	return nil
+}
+
+// labelFilterSQL compiles a label filter into a condition on the labels column and its arguments;
+// a nil filter matches every book
+func labelFilterSQL(column string, filter labels.Expr) (string, []interface{}, error) {
+	switch f := filter.(type) {
+	case nil:
+		return "1 = 1", nil, nil
+	case labels.Label:
+		return "has(" + column + ", ?)", []interface{}{string(f)}, nil
+	case labels.Prefix:
+		return "arrayExists(l -> l = ? OR startsWith(l, ?), " + column + ")", []interface{}{string(f), string(f) + labels.Separator}, nil
+	case labels.Not:
+		condition, args, err := labelFilterSQL(column, f.Expr)
+		if err != nil {
+			return "", nil, err
+		}
+		return "NOT (" + condition + ")", args, nil
+	case labels.And:
+		return joinLabelFilters(column, f, " AND ", "1 = 1")
+	case labels.Or:
+		return joinLabelFilters(column, f, " OR ", "1 = 0")
+	}
+	return "", nil, fmt.Errorf("unsupported label filter %T", filter)
+}
+
+// joinLabelFilters compiles each filter and joins the conditions with op; empty is used for no filters
+func joinLabelFilters(column string, filters []labels.Expr, op, empty string) (string, []interface{}, error) {
+	if len(filters) == 0 {
+		return empty, nil, nil
+	}
+	var conditions []string
+	var args []interface{}
+	for _, filter := range filters {
+		condition, filterArgs, err := labelFilterSQL(column, filter)
+		if err != nil {
+			return "", nil, err
+		}
+		conditions = append(conditions, condition)
+		args = append(args, filterArgs...)
+	}
+	return "(" + strings.Join(conditions, op) + ")", args, nil
+}
+
+// labelUsed reports whether any book has one of the labels
+func (db *ClickHouseDB) labelUsed(ctx context.Context, labels []string) (bool, error) {
+	var count uint64
+	if err := db.conn.QueryRow(ctx, `SELECT count() FROM books WHERE hasAny(labels, ?)`, labels).Scan(&count); err != nil {
+		return false, fmt.Errorf("failed to check labels: %w", err)
+	}
+	return count > 0, nil
+}
+
 // GetBooksWithoutLabel returns books that don't have the specified label
 func (db *ClickHouseDB) GetBooksWithoutLabel(ctx context.Context, label string) ([]models.Book, error) {
 	rows, err := db.conn.Query(ctx, `
-		SELECT name, is_readable, labels
+		SELECT `+bookColumns+`
 		FROM books
 		WHERE is_readable = true AND NOT has(labels, ?)
 		ORDER BY name`,
@@ -116,7 +297,7 @@ func (db *ClickHouseDB) GetBooksWithoutLabel(ctx context.Context, label string)
 	var books []models.Book
 	for rows.Next() {
 		var book models.Book
-		if err := rows.Scan(&book.Name, &book.IsReadable, &book.Labels); err != nil {
+		if err := scanBook(rows, &book); err != nil {
 			return nil, fmt.Errorf("failed to scan book: %w", err)
 		}
 		books = append(books, book)
@@ -124,14 +305,18 @@ func (db *ClickHouseDB) GetBooksWithoutLabel(ctx context.Context, label string)
 	return books, nil
 }
 
-// GetBooksByLabel returns books that have the specified label
-func (db *ClickHouseDB) GetBooksByLabel(ctx context.Context, label string) ([]models.Book, error) {
+// GetBooksByLabel returns readable books whose labels match filter
+func (db *ClickHouseDB) GetBooksByLabel(ctx context.Context, filter labels.Expr) ([]models.Book, error) {
+	condition, args, err := labelFilterSQL("labels", filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get books by label: %w", err)
+	}
 	rows, err := db.conn.Query(ctx, `
-		SELECT name, is_readable, labels
+		SELECT `+bookColumns+`
 		FROM books
-		WHERE is_readable = true AND has(labels, ?)
+		WHERE is_readable = true AND `+condition+`
 		ORDER BY name`,
-		label)
+		args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get books by label: %w", err)
 	}
@@ -140,7 +325,7 @@ func (db *ClickHouseDB) GetBooksByLabel(ctx context.Context, label string) ([]mo
 	var books []models.Book
 	for rows.Next() {
 		var book models.Book
-		if err := rows.Scan(&book.Name, &book.IsReadable, &book.Labels); err != nil {
+		if err := scanBook(rows, &book); err != nil {
 			return nil, fmt.Errorf("failed to scan book: %w", err)
 		}
 		books = append(books, book)
@@ -171,274 +356,1219 @@ func (db *ClickHouseDB) GetAllLabels(ctx context.Context) ([]string, error) {
 	return labels, nil
 }
 
-// ListParticipants returns all participants
-func (db *ClickHouseDB) ListParticipants(ctx context.Context) ([]models.Participant, error) {
-	rows, err := db.conn.Query(ctx, `SELECT name, is_parent FROM participants ORDER BY name`)
+// ListRetiredBooks returns all books that are no longer readable
+func (db *ClickHouseDB) ListRetiredBooks(ctx context.Context) ([]models.Book, error) {
+	rows, err := db.conn.Query(ctx, `SELECT `+bookColumns+` FROM books WHERE is_readable = false ORDER BY name`)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list participants: %w", err)
+		return nil, fmt.Errorf("failed to list retired books: %w", err)
 	}
 	defer rows.Close()
 
-	var participants []models.Participant
+	var books []models.Book
 	for rows.Next() {
-		var participant models.Participant
-		if err := rows.Scan(&participant.Name, &participant.IsParent); err != nil {
-			return nil, fmt.Errorf("failed to scan participant: %w", err)
+		var book models.Book
+		if err := scanBook(rows, &book); err != nil {
+			return nil, fmt.Errorf("failed to scan book: %w", err)
 		}
-		participants = append(participants, participant)
+		books = append(books, book)
 	}
-	return participants, nil
+	return books, nil
+}
+
+// bookExists reports whether a book with the given ID exists (readable or retired)
+func (db *ClickHouseDB) bookExists(ctx context.Context, id string) (bool, error) {
+	var count uint64
+	if err := db.conn.QueryRow(ctx, `SELECT count() FROM books WHERE id = ?`, id).Scan(&count); err != nil {
+		return false, fmt.Errorf("failed to check book: %w", err)
+	}
+	return count > 0, nil
+}
+
+// bookNameTaken reports whether any book (readable or retired) already uses the given name
+func (db *ClickHouseDB) bookNameTaken(ctx context.Context, name string) (bool, error) {
+	var count uint64
+	if err := db.conn.QueryRow(ctx, `SELECT count() FROM books WHERE name = ?`, name).Scan(&count); err != nil {
+		return false, fmt.Errorf("failed to check book name: %w", err)
+	}
+	return count > 0, nil
 }
 
-// CreateEvent creates a new reading event
-func (db *ClickHouseDB) CreateEvent(ctx context.Context, date time.Time, bookName, participantName string) error {
-	err := db.conn.Exec(ctx, `INSERT INTO events (date, book_name, participant_name) VALUES (?, ?, ?)`,
-		date, bookName, participantName)
+// setBookReadable updates the is_readable flag of an existing book
+func (db *ClickHouseDB) setBookReadable(ctx context.Context, id string, readable bool) error {
+	exists, err := db.bookExists(ctx, id)
 	if err != nil {
-		return fmt.Errorf("failed to create event: %w", err)
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("book %s %w", id, storage.ErrNotFound)
+	}
+
+	if err := db.conn.Exec(ctx, `UPDATE books SET is_readable = ? WHERE id = ?`, readable, id); err != nil {
+		return fmt.Errorf("failed to update book: %w", err)
 	}
 	return nil
 }
 
-// GetLastEvents returns the last N events
-func (db *ClickHouseDB) GetLastEvents(ctx context.Context, limit int) ([]models.Event, error) {
-	rows, err := db.conn.Query(ctx, `SELECT date, book_name, participant_name FROM events ORDER BY date DESC LIMIT ?`, limit)
+// RetireBook marks a book as not readable
+func (db *ClickHouseDB) RetireBook(ctx context.Context, id string) error {
+	return db.setBookReadable(ctx, id, false)
+}
+
+// RestoreBook marks a retired book as readable again
+func (db *ClickHouseDB) RestoreBook(ctx context.Context, id string) error {
+	return db.setBookReadable(ctx, id, true)
+}
+
+// RenameBook changes the name of a book; events keep pointing at the same ID
+func (db *ClickHouseDB) RenameBook(ctx context.Context, id, newName string) error {
+	exists, err := db.bookExists(ctx, id)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get last events: %w", err)
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("book %s %w", id, storage.ErrNotFound)
 	}
-	defer rows.Close()
 
-	var events []models.Event
-	for rows.Next() {
-		var event models.Event
-		if err := rows.Scan(&event.Date, &event.BookName, &event.ParticipantName); err != nil {
-			return nil, fmt.Errorf("failed to scan event: %w", err)
-		}
-		events = append(events, event)
+	taken, err := db.bookNameTaken(ctx, newName)
+	if err != nil {
+		return err
 	}
-	return events, nil
+	if taken {
+		return fmt.Errorf("book %q already exists", newName)
+	}
+
+	if err := db.conn.Exec(ctx, `UPDATE books SET name = ? WHERE id = ?`, newName, id); err != nil {
+		return fmt.Errorf("failed to rename book: %w", err)
+	}
+	return nil
 }
 
-func (db *ClickHouseDB) GetLastEventsFiltered(ctx context.Context, limit int, since, until time.Time, participant string) ([]models.Event, error) {
-	query := `SELECT date, book_name, participant_name FROM events WHERE 1=1`
-	var args []interface{}
+// UpdateBookMetadata replaces the optional details of a book
+func (db *ClickHouseDB) UpdateBookMetadata(ctx context.Context, id string, meta models.BookMetadata) error {
+	exists, err := db.bookExists(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("book %s %w", id, storage.ErrNotFound)
+	}
 
-	if !since.IsZero() {
-		query += ` AND date >= ?`
-		args = append(args, since)
+	err = db.conn.Exec(ctx, `
+		UPDATE books
+		SET author = ?, isbn = ?, series = ?, series_volume = ?, cover = ?, age = ?, notes = ?
+		WHERE id = ?`,
+		meta.Author, meta.ISBN, meta.Series, uint16(meta.SeriesVolume), meta.Cover, meta.Age, meta.Notes, id)
+	if err != nil {
+		return fmt.Errorf("failed to update book metadata: %w", err)
 	}
-	if !until.IsZero() {
-		query += ` AND date <= ?`
-		args = append(args, until)
+	return nil
+}
+
+// DeleteBook removes a book without reading history
+func (db *ClickHouseDB) DeleteBook(ctx context.Context, id string) error {
+	exists, err := db.bookExists(ctx, id)
+	if err != nil {
+		return err
 	}
-	if participant != "" {
-		query += ` AND lower(participant_name) = lower(?)`
-		args = append(args, participant)
+	if !exists {
+		return fmt.Errorf("book %s %w", id, storage.ErrNotFound)
 	}
 
-	query += ` ORDER BY date DESC LIMIT ?`
-	args = append(args, limit)
+	var eventCount uint64
+	if err := db.conn.QueryRow(ctx, `SELECT count() FROM events WHERE book_id = ?`, id).Scan(&eventCount); err != nil {
+		return fmt.Errorf("failed to count book events: %w", err)
+	}
+	if eventCount > 0 {
+		return fmt.Errorf("book has %d reading events; retire it instead", eventCount)
+	}
 
-	rows, err := db.conn.Query(ctx, query, args...)
+	if err := db.conn.Exec(ctx, `DELETE FROM books WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete book: %w", err)
+	}
+	return nil
+}
+
+// ListParticipants returns all active (non-archived) participants
+func (db *ClickHouseDB) ListParticipants(ctx context.Context) ([]models.Participant, error) {
+	rows, err := db.conn.Query(ctx, `SELECT id, name, is_parent, is_archived FROM participants WHERE is_archived = false ORDER BY name`)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get filtered events: %w", err)
+		return nil, fmt.Errorf("failed to list participants: %w", err)
 	}
 	defer rows.Close()
 
-	var events []models.Event
+	var participants []models.Participant
 	for rows.Next() {
-		var event models.Event
-		if err := rows.Scan(&event.Date, &event.BookName, &event.ParticipantName); err != nil {
-			return nil, fmt.Errorf("failed to scan event: %w", err)
+		var participant models.Participant
+		if err := rows.Scan(&participant.ID, &participant.Name, &participant.IsParent, &participant.IsArchived); err != nil {
+			return nil, fmt.Errorf("failed to scan participant: %w", err)
 		}
-		events = append(events, event)
+		participants = append(participants, participant)
 	}
-	return events, nil
+	return participants, nil
 }
 
-// GetTopBooks returns top N books by read count within the specified time period
-// If participantName is empty, returns statistics for all children (IsParent=false)
-// If participantName is provided, returns statistics only for that participant
-func (db *ClickHouseDB) GetTopBooks(ctx context.Context, limit int, startDate, endDate time.Time, participantName string) ([]models.BookStat, error) {
-	var query string
-	var args []interface{}
+// GetParticipantByName returns the participant with the given name, active or archived
+func (db *ClickHouseDB) GetParticipantByName(ctx context.Context, name string) (models.Participant, error) {
+	var participant models.Participant
+	err := db.conn.QueryRow(ctx, `SELECT id, name, is_parent, is_archived FROM participants WHERE name = ? LIMIT 1`, name).
+		Scan(&participant.ID, &participant.Name, &participant.IsParent, &participant.IsArchived)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Participant{}, fmt.Errorf("participant %q %w", name, storage.ErrNotFound)
+	}
+	if err != nil {
+		return models.Participant{}, fmt.Errorf("failed to get participant: %w", err)
+	}
+	return participant, nil
+}
 
-	if participantName == "" {
-		// Get stats for all children
-		query = `
-			SELECT
-				e.book_name,
-				toInt32(COUNT(*)) as read_count
-			FROM events e
-			INNER JOIN participants p ON e.participant_name = p.name
-			WHERE e.date >= ?
-				AND e.date <= ?
-				AND p.is_parent = false
-			GROUP BY e.book_name
-			ORDER BY read_count DESC, e.book_name ASC
-		`
-		args = []interface{}{startDate, endDate}
-		if limit > 0 {
-			query += " LIMIT ?"
-			args = append(args, limit)
+// participantExists reports whether a participant with the given ID exists (archived or not)
+func (db *ClickHouseDB) participantExists(ctx context.Context, id string) (bool, error) {
+	var count uint64
+	if err := db.conn.QueryRow(ctx, `SELECT count() FROM participants WHERE id = ?`, id).Scan(&count); err != nil {
+		return false, fmt.Errorf("failed to check participant: %w", err)
+	}
+	return count > 0, nil
+}
+
+// participantNameTaken reports whether any participant (archived or not) already uses the given name
+func (db *ClickHouseDB) participantNameTaken(ctx context.Context, name string) (bool, error) {
+	var count uint64
+	if err := db.conn.QueryRow(ctx, `SELECT count() FROM participants WHERE name = ?`, name).Scan(&count); err != nil {
+		return false, fmt.Errorf("failed to check participant name: %w", err)
+	}
+	return count > 0, nil
+}
+
+// CreateParticipant adds a new participant and returns its generated ID
+func (db *ClickHouseDB) CreateParticipant(ctx context.Context, name string, isParent bool) (string, error) {
+	taken, err := db.participantNameTaken(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	if taken {
+		return "", fmt.Errorf("participant %q already exists", name)
+	}
+
+	id := uuid.NewString()
+	err = db.conn.Exec(ctx, `INSERT INTO participants (id, name, is_parent, is_archived) VALUES (?, ?, ?, ?)`,
+		id, name, isParent, false)
+	if err != nil {
+		return "", fmt.Errorf("failed to create participant: %w", err)
+	}
+	return id, nil
+}
+
+// UpdateParticipant renames a participant and/or changes its parent flag
+func (db *ClickHouseDB) UpdateParticipant(ctx context.Context, id, newName string, isParent bool) error {
+	var currentName string
+	err := db.conn.QueryRow(ctx, `SELECT name FROM participants WHERE id = ? LIMIT 1`, id).Scan(&currentName)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("participant %s %w", id, storage.ErrNotFound)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read participant: %w", err)
+	}
+
+	if newName != currentName {
+		taken, err := db.participantNameTaken(ctx, newName)
+		if err != nil {
+			return err
 		}
-	} else {
-		// Get stats for specific participant
-		query = `
-			SELECT
-				book_name,
-				toInt32(COUNT(*)) as read_count
-			FROM events
-			WHERE date >= ?
-				AND date <= ?
-				AND participant_name = ?
-			GROUP BY book_name
-			ORDER BY read_count DESC, book_name ASC
-		`
-		args = []interface{}{startDate, endDate, participantName}
-		if limit > 0 {
-			query += " LIMIT ?"
-			args = append(args, limit)
+		if taken {
+			return fmt.Errorf("participant %q already exists", newName)
 		}
 	}
 
-	rows, err := db.conn.Query(ctx, query, args...)
+	err = db.conn.Exec(ctx, `UPDATE participants SET name = ?, is_parent = ? WHERE id = ?`, newName, isParent, id)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get top books: %w", err)
+		return fmt.Errorf("failed to update participant: %w", err)
 	}
-	defer rows.Close()
+	return nil
+}
 
-	var stats []models.BookStat
-	for rows.Next() {
-		var bookName string
-		var readCount int32
-		if err := rows.Scan(&bookName, &readCount); err != nil {
-			return nil, fmt.Errorf("failed to scan book stat: %w", err)
-		}
-		stats = append(stats, models.BookStat{
-			BookName:  bookName,
-			ReadCount: int(readCount),
-		})
+// ArchiveParticipant marks a participant as archived
+func (db *ClickHouseDB) ArchiveParticipant(ctx context.Context, id string) error {
+	exists, err := db.participantExists(ctx, id)
+	if err != nil {
+		return err
 	}
-	return stats, nil
+	if !exists {
+		return fmt.Errorf("participant %s %w", id, storage.ErrNotFound)
+	}
+
+	err = db.conn.Exec(ctx, `UPDATE participants SET is_archived = true WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to archive participant: %w", err)
+	}
+	return nil
 }
 
-// GetRarelyReadBooks returns books ordered by how long ago they were last read
-// If childrenOnly is true, only considers reads by children (IsParent=false)
-// If childrenOnly is false, considers reads by all participants
-// If label is not empty, only returns books with that label
-// Books never read are included with DaysSinceLastRead=-1
-func (db *ClickHouseDB) GetRarelyReadBooks(ctx context.Context, limit int, childrenOnly bool, label string, excludeLabels []string) ([]models.RareBookStat, error) {
-	var query string
-	var args []interface{}
+// SetAbsence marks a participant as away until the given time, replacing an earlier absence
+func (db *ClickHouseDB) SetAbsence(ctx context.Context, participantID string, until time.Time) error {
+	exists, err := db.participantExists(ctx, participantID)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("participant %s %w", participantID, storage.ErrNotFound)
+	}
 
-	// Build dynamic WHERE conditions
-	excludeCondition := ""
-	if len(excludeLabels) > 0 {
-		excludeCondition = " AND NOT hasAny(b.labels, ?)"
+	if err := db.conn.Exec(ctx, `DELETE FROM absences WHERE participant_id = ?`, participantID); err != nil {
+		return fmt.Errorf("failed to set absence: %w", err)
 	}
+	if err := db.conn.Exec(ctx, `INSERT INTO absences (participant_id, until) VALUES (?, ?)`, participantID, until); err != nil {
+		return fmt.Errorf("failed to set absence: %w", err)
+	}
+	return nil
+}
 
-	if childrenOnly {
-		// Only consider reads by children
-		if label != "" {
-			query = `
-				SELECT
-					b.name as book_name,
-					max(e.date) as last_read_date,
-					if(max(e.date) <= toDateTime(0), -1, dateDiff('day', max(e.date), now())) as days_since_last_read
-				FROM books b
-				LEFT JOIN (
-					SELECT e.book_name, e.date
-					FROM events e
-					INNER JOIN participants p ON e.participant_name = p.name
-					WHERE p.is_parent = false
-				) e ON b.name = e.book_name
-				WHERE b.is_readable = true AND has(b.labels, ?)` + excludeCondition + `
-				GROUP BY b.name
-				ORDER BY
-					(max(e.date) <= toDateTime(0)) ASC,
-					days_since_last_read DESC,
-					book_name ASC
-				LIMIT ?
-			`
-			args = []interface{}{label}
-			if len(excludeLabels) > 0 {
-				args = append(args, excludeLabels)
-			}
-			args = append(args, limit)
-		} else {
-			query = `
-				SELECT
-					b.name as book_name,
-					max(e.date) as last_read_date,
-					if(max(e.date) <= toDateTime(0), -1, dateDiff('day', max(e.date), now())) as days_since_last_read
-				FROM books b
-				LEFT JOIN (
-					SELECT e.book_name, e.date
-					FROM events e
-					INNER JOIN participants p ON e.participant_name = p.name
-					WHERE p.is_parent = false
-				) e ON b.name = e.book_name
-				WHERE b.is_readable = true` + excludeCondition + `
-				GROUP BY b.name
-				ORDER BY
-					(max(e.date) <= toDateTime(0)) ASC,
-					days_since_last_read DESC,
-					book_name ASC
-				LIMIT ?
-			`
-			args = []interface{}{}
-			if len(excludeLabels) > 0 {
-				args = append(args, excludeLabels)
-			}
-			args = append(args, limit)
-		}
-	} else {
-		// Consider reads by all participants
-		if label != "" {
-			query = `
-				SELECT
-					b.name as book_name,
-					max(e.date) as last_read_date,
-					if(max(e.date) <= toDateTime(0), -1, dateDiff('day', max(e.date), now())) as days_since_last_read
-				FROM books b
-				LEFT JOIN events e ON b.name = e.book_name
-				WHERE b.is_readable = true AND has(b.labels, ?)` + excludeCondition + `
-				GROUP BY b.name
-				ORDER BY
-					(max(e.date) <= toDateTime(0)) ASC,
-					days_since_last_read DESC,
-					book_name ASC
-				LIMIT ?
-			`
-			args = []interface{}{label}
-			if len(excludeLabels) > 0 {
-				args = append(args, excludeLabels)
-			}
-			args = append(args, limit)
-		} else {
-			query = `
-				SELECT
-					b.name as book_name,
-					max(e.date) as last_read_date,
-					if(max(e.date) <= toDateTime(0), -1, dateDiff('day', max(e.date), now())) as days_since_last_read
-				FROM books b
-				LEFT JOIN events e ON b.name = e.book_name
-				WHERE b.is_readable = true` + excludeCondition + `
-				GROUP BY b.name
-				ORDER BY
-					(max(e.date) <= toDateTime(0)) ASC,
-					days_since_last_read DESC,
-					book_name ASC
-				LIMIT ?
-			`
-			args = []interface{}{}
-			if len(excludeLabels) > 0 {
-				args = append(args, excludeLabels)
-			}
-			args = append(args, limit)
-		}
+// ClearAbsence ends a participant's absence
+func (db *ClickHouseDB) ClearAbsence(ctx context.Context, participantID string) error {
+	var count uint64
+	err := db.conn.QueryRow(ctx, `SELECT count() FROM absences WHERE participant_id = ?`, participantID).Scan(&count)
+	if err != nil {
+		return fmt.Errorf("failed to check absence: %w", err)
 	}
+	if count == 0 {
+		return fmt.Errorf("absence of %s %w", participantID, storage.ErrNotFound)
+	}
+
+	if err := db.conn.Exec(ctx, `DELETE FROM absences WHERE participant_id = ?`, participantID); err != nil {
+		return fmt.Errorf("failed to clear absence: %w", err)
+	}
+	return nil
+}
+
+// ListAbsences returns absences still running at the given time, ordered by participant name
+func (db *ClickHouseDB) ListAbsences(ctx context.Context, at time.Time) ([]models.Absence, error) {
+	rows, err := db.conn.Query(ctx, `
+		SELECT toString(a.participant_id), p.name, a.until
+		FROM absences a
+		INNER JOIN participants p ON p.id = a.participant_id
+		WHERE a.until > ?
+		ORDER BY p.name`, at)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list absences: %w", err)
+	}
+	defer rows.Close()
+
+	var absences []models.Absence
+	for rows.Next() {
+		var absence models.Absence
+		if err := rows.Scan(&absence.ParticipantID, &absence.ParticipantName, &absence.Until); err != nil {
+			return nil, fmt.Errorf("failed to scan absence: %w", err)
+		}
+		absences = append(absences, absence)
+	}
+	return absences, nil
+}
+
+// SetGoal sets a participant's reading goal, replacing an earlier one
+func (db *ClickHouseDB) SetGoal(ctx context.Context, participantID string, target int, period string) error {
+	exists, err := db.participantExists(ctx, participantID)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("participant %s %w", participantID, storage.ErrNotFound)
+	}
+
+	if err := db.conn.Exec(ctx, `DELETE FROM reading_goals WHERE participant_id = ?`, participantID); err != nil {
+		return fmt.Errorf("failed to set goal: %w", err)
+	}
+	err = db.conn.Exec(ctx, `INSERT INTO reading_goals (participant_id, target, period) VALUES (?, ?, ?)`,
+		participantID, uint32(target), period)
+	if err != nil {
+		return fmt.Errorf("failed to set goal: %w", err)
+	}
+	return nil
+}
+
+// ClearGoal removes a participant's reading goal
+func (db *ClickHouseDB) ClearGoal(ctx context.Context, participantID string) error {
+	var count uint64
+	err := db.conn.QueryRow(ctx, `SELECT count() FROM reading_goals WHERE participant_id = ?`, participantID).Scan(&count)
+	if err != nil {
+		return fmt.Errorf("failed to check goal: %w", err)
+	}
+	if count == 0 {
+		return fmt.Errorf("goal of %s %w", participantID, storage.ErrNotFound)
+	}
+
+	if err := db.conn.Exec(ctx, `DELETE FROM reading_goals WHERE participant_id = ?`, participantID); err != nil {
+		return fmt.Errorf("failed to clear goal: %w", err)
+	}
+	return nil
+}
+
+// ListGoals returns the goals of active participants, ordered by participant name
+func (db *ClickHouseDB) ListGoals(ctx context.Context) ([]models.ReadingGoal, error) {
+	rows, err := db.conn.Query(ctx, `
+		SELECT toString(g.participant_id), p.name, toInt64(g.target), g.period
+		FROM reading_goals g
+		INNER JOIN participants p ON p.id = g.participant_id
+		WHERE p.is_archived = false
+		ORDER BY p.name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list goals: %w", err)
+	}
+	defer rows.Close()
+
+	var goals []models.ReadingGoal
+	for rows.Next() {
+		var goal models.ReadingGoal
+		var target int64
+		if err := rows.Scan(&goal.ParticipantID, &goal.ParticipantName, &target, &goal.Period); err != nil {
+			return nil, fmt.Errorf("failed to scan goal: %w", err)
+		}
+		goal.Target = int(target)
+		goals = append(goals, goal)
+	}
+	return goals, nil
+}
+
+// AddBadge stores an unlocked badge
+func (db *ClickHouseDB) AddBadge(ctx context.Context, badge models.Badge) error {
+	err := db.conn.Exec(ctx, `INSERT INTO badges (participant_id, badge, event_id, unlocked_at) VALUES (?, ?, ?, ?)`,
+		badge.ParticipantID, badge.Key, badge.EventID, badge.UnlockedAt)
+	if err != nil {
+		return fmt.Errorf("failed to add badge: %w", err)
+	}
+	return nil
+}
+
+// ListBadges returns unlocked badges in unlock order, optionally for one participant
+func (db *ClickHouseDB) ListBadges(ctx context.Context, participantID string) ([]models.Badge, error) {
+	query := `
+		SELECT toString(b.participant_id), p.name, b.badge, toString(b.event_id), b.unlocked_at
+		FROM badges b
+		INNER JOIN participants p ON p.id = b.participant_id`
+	var args []interface{}
+	if participantID != "" {
+		query += ` WHERE b.participant_id = ?`
+		args = append(args, participantID)
+	}
+	query += ` ORDER BY b.unlocked_at, b.badge`
+
+	rows, err := db.conn.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list badges: %w", err)
+	}
+	defer rows.Close()
+
+	var badges []models.Badge
+	for rows.Next() {
+		var badge models.Badge
+		if err := rows.Scan(&badge.ParticipantID, &badge.ParticipantName, &badge.Key, &badge.EventID, &badge.UnlockedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan badge: %w", err)
+		}
+		badges = append(badges, badge)
+	}
+	return badges, nil
+}
+
+// CreateEvent creates a new reading event and returns its generated ID
+func (db *ClickHouseDB) CreateEvent(ctx context.Context, event models.Event) (string, error) {
+	if len(event.ParticipantIDs) == 0 {
+		return "", fmt.Errorf("event needs at least one participant")
+	}
+
+	event.ID = uuid.NewString()
+	if err := db.insertEvent(ctx, event); err != nil {
+		return "", fmt.Errorf("failed to create event: %w", err)
+	}
+	return event.ID, nil
+}
+
+// insertEvent writes a single events row
+func (db *ClickHouseDB) insertEvent(ctx context.Context, event models.Event) error {
+	return db.conn.Exec(ctx, `
+		INSERT INTO events (id, date, book_id, participant_ids, duration_minutes, progress, progress_unit)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		event.ID, event.Date, event.BookID, event.ParticipantIDs,
+		uint32(event.DurationMinutes), uint32(event.Progress), event.ProgressUnit)
+}
+
+// eventsSelect selects events with the book name resolved; participant names are filled in by
+// resolveParticipantNames
+const eventsSelect = `
+	SELECT e.id, e.date, e.book_id, arrayMap(x -> toString(x), e.participant_ids), b.name,
+		toInt64(e.duration_minutes), toInt64(e.progress), e.progress_unit
+	FROM events e
+	LEFT JOIN books b ON b.id = e.book_id`
+
+// eventAttendees expands events into one row per attending participant, for statistics
+const eventAttendees = `(
+	SELECT id, date, book_id, participant_id
+	FROM events
+	ARRAY JOIN participant_ids AS participant_id
+)`
+
+// scanEvents reads rows produced by eventsSelect and resolves participant names
+func (db *ClickHouseDB) scanEvents(ctx context.Context, rows driver.Rows) ([]models.Event, error) {
+	var events []models.Event
+	for rows.Next() {
+		var event models.Event
+		var duration, progress int64
+		if err := rows.Scan(&event.ID, &event.Date, &event.BookID, &event.ParticipantIDs, &event.BookName,
+			&duration, &progress, &event.ProgressUnit); err != nil {
+			return nil, fmt.Errorf("failed to scan event: %w", err)
+		}
+		event.DurationMinutes = int(duration)
+		event.Progress = int(progress)
+		events = append(events, event)
+	}
+	if err := db.resolveParticipantNames(ctx, events); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// resolveParticipantNames fills ParticipantNames from ParticipantIDs
+func (db *ClickHouseDB) resolveParticipantNames(ctx context.Context, events []models.Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	rows, err := db.conn.Query(ctx, `SELECT toString(id), name FROM participants`)
+	if err != nil {
+		return fmt.Errorf("failed to get participant names: %w", err)
+	}
+	defer rows.Close()
+
+	names := make(map[string]string)
+	for rows.Next() {
+		var id, name string
+		if err := rows.Scan(&id, &name); err != nil {
+			return fmt.Errorf("failed to scan participant: %w", err)
+		}
+		names[id] = name
+	}
+
+	for i := range events {
+		events[i].ParticipantNames = make([]string, len(events[i].ParticipantIDs))
+		for j, id := range events[i].ParticipantIDs {
+			events[i].ParticipantNames[j] = names[id]
+		}
+	}
+	return nil
+}
+
+// GetEvent returns a single event by ID
+func (db *ClickHouseDB) GetEvent(ctx context.Context, id string) (models.Event, error) {
+	rows, err := db.conn.Query(ctx, eventsSelect+` WHERE e.id = ? LIMIT 1`, id)
+	if err != nil {
+		return models.Event{}, fmt.Errorf("failed to get event: %w", err)
+	}
+	defer rows.Close()
+
+	events, err := db.scanEvents(ctx, rows)
+	if err != nil {
+		return models.Event{}, err
+	}
+	if len(events) == 0 {
+		return models.Event{}, fmt.Errorf("event %s %w", id, storage.ErrNotFound)
+	}
+	return events[0], nil
+}
+
+// eventExists reports whether an event with the given ID exists
+func (db *ClickHouseDB) eventExists(ctx context.Context, id string) (bool, error) {
+	var count uint64
+	if err := db.conn.QueryRow(ctx, `SELECT count() FROM events WHERE id = ?`, id).Scan(&count); err != nil {
+		return false, fmt.Errorf("failed to check event: %w", err)
+	}
+	return count > 0, nil
+}
+
+// UpdateEvent replaces date, book and participants of an existing event
+func (db *ClickHouseDB) UpdateEvent(ctx context.Context, event models.Event) error {
+	if len(event.ParticipantIDs) == 0 {
+		return fmt.Errorf("event needs at least one participant")
+	}
+
+	// date is the sorting key and cannot be updated in place, so the new version is inserted with the
+	// same ID before the old one is deleted. Inserts get increasing block numbers, which tells the
+	// versions apart. If the delete fails, both versions remain and the next update cleans up;
+	// the event is never lost.
+	var count, lastBlock uint64
+	if err := db.conn.QueryRow(ctx, `SELECT count(), max(_block_number) FROM events WHERE id = ?`, event.ID).
+		Scan(&count, &lastBlock); err != nil {
+		return fmt.Errorf("failed to check event: %w", err)
+	}
+	if count == 0 {
+		return fmt.Errorf("event %s %w", event.ID, storage.ErrNotFound)
+	}
+
+	if err := db.insertEvent(ctx, event); err != nil {
+		return fmt.Errorf("failed to update event: %w", err)
+	}
+	if err := db.conn.Exec(ctx, `DELETE FROM events WHERE id = ? AND _block_number <= ?`, event.ID, lastBlock); err != nil {
+		return fmt.Errorf("failed to remove previous version of event %s: %w", event.ID, err)
+	}
+	return nil
+}
+
+// DeleteEvent removes a single event
+func (db *ClickHouseDB) DeleteEvent(ctx context.Context, id string) error {
+	exists, err := db.eventExists(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("event %s %w", id, storage.ErrNotFound)
+	}
+
+	if err := db.conn.Exec(ctx, `DELETE FROM events WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete event: %w", err)
+	}
+	return nil
+}
+
+// FindEventOnDay returns an event for the same book attended by any of the participants on the day of date
+func (db *ClickHouseDB) FindEventOnDay(ctx context.Context, date time.Time, bookID string, participantIDs []string) (models.Event, error) {
+	dayStart := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+	dayEnd := dayStart.AddDate(0, 0, 1)
+
+	rows, err := db.conn.Query(ctx, eventsSelect+`
+		WHERE e.book_id = ?
+			AND hasAny(arrayMap(x -> toString(x), e.participant_ids), ?)
+			AND e.date >= ? AND e.date < ?
+		ORDER BY e.date DESC
+		LIMIT 1`, bookID, participantIDs, dayStart, dayEnd)
+	if err != nil {
+		return models.Event{}, fmt.Errorf("failed to find event: %w", err)
+	}
+	defer rows.Close()
+
+	events, err := db.scanEvents(ctx, rows)
+	if err != nil {
+		return models.Event{}, err
+	}
+	if len(events) == 0 {
+		return models.Event{}, fmt.Errorf("event on %s %w", dayStart.Format("2006-01-02"), storage.ErrNotFound)
+	}
+	return events[0], nil
+}
+
+// GetLastEvents returns the last N events
+func (db *ClickHouseDB) GetLastEvents(ctx context.Context, limit int) ([]models.Event, error) {
+	rows, err := db.conn.Query(ctx, eventsSelect+` ORDER BY e.date DESC LIMIT ?`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last events: %w", err)
+	}
+	defer rows.Close()
+
+	return db.scanEvents(ctx, rows)
+}
+
+func (db *ClickHouseDB) GetLastEventsFiltered(ctx context.Context, limit int, since, until time.Time, participant string) ([]models.Event, error) {
+	query := eventsSelect + ` WHERE 1=1`
+	var args []interface{}
+
+	if !since.IsZero() {
+		query += ` AND e.date >= ?`
+		args = append(args, since)
+	}
+	if !until.IsZero() {
+		query += ` AND e.date <= ?`
+		args = append(args, until)
+	}
+	if participant != "" {
+		query += ` AND hasAny(e.participant_ids, (SELECT groupArray(id) FROM participants WHERE lower(name) = lower(?)))`
+		args = append(args, participant)
+	}
+
+	query += ` ORDER BY e.date DESC LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := db.conn.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get filtered events: %w", err)
+	}
+	defer rows.Close()
+
+	return db.scanEvents(ctx, rows)
+}
+
+// GetTopBooks returns top N books by read count within the specified time period
+// If participantName is empty, returns statistics for all children (IsParent=false)
+// If participantName is provided, returns statistics only for that participant
+func (db *ClickHouseDB) GetTopBooks(ctx context.Context, limit int, startDate, endDate time.Time, participantName string) ([]models.BookStat, error) {
+	var query string
+	var args []interface{}
+
+	if participantName == "" {
+		// Get stats for all children
+		query = `
+			SELECT
+				b.name AS book_name,
+				toInt32(COUNT(DISTINCT e.id)) as read_count
+			FROM ` + eventAttendees + ` e
+			INNER JOIN participants p ON e.participant_id = p.id
+			INNER JOIN books b ON e.book_id = b.id
+			WHERE e.date >= ?
+				AND e.date <= ?
+				AND p.is_parent = false
+			GROUP BY b.name
+			ORDER BY read_count DESC, book_name ASC
+		`
+		args = []interface{}{startDate, endDate}
+		if limit > 0 {
+			query += " LIMIT ?"
+			args = append(args, limit)
+		}
+	} else {
+		// Get stats for specific participant
+		query = `
+			SELECT
+				b.name AS book_name,
+				toInt32(COUNT(DISTINCT e.id)) as read_count
+			FROM ` + eventAttendees + ` e
+			INNER JOIN participants p ON e.participant_id = p.id
+			INNER JOIN books b ON e.book_id = b.id
+			WHERE e.date >= ?
+				AND e.date <= ?
+				AND p.name = ?
+			GROUP BY b.name
+			ORDER BY read_count DESC, book_name ASC
+		`
+		args = []interface{}{startDate, endDate, participantName}
+		if limit > 0 {
+			query += " LIMIT ?"
+			args = append(args, limit)
+		}
+	}
+
+	rows, err := db.conn.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get top books: %w", err)
+	}
+	defer rows.Close()
+
+	var stats []models.BookStat
+	for rows.Next() {
+		var bookName string
+		var readCount int32
+		if err := rows.Scan(&bookName, &readCount); err != nil {
+			return nil, fmt.Errorf("failed to scan book stat: %w", err)
+		}
+		stats = append(stats, models.BookStat{
+			BookName:  bookName,
+			ReadCount: int(readCount),
+		})
+	}
+	return stats, nil
+}
+
+// GetSessionStats returns duration and progress totals for sessions in the period
+func (db *ClickHouseDB) GetSessionStats(ctx context.Context, startDate, endDate time.Time, participantName string) (models.SessionStats, error) {
+	filter, args := sessionFilter(startDate, endDate, participantName)
+	query := `
+		SELECT
+			toInt64(count()),
+			toInt64(countIf(duration_minutes > 0)),
+			toInt64(sum(duration_minutes)),
+			toInt64(countIf(progress_unit = 'pages')),
+			toInt64(sumIf(progress, progress_unit = 'pages')),
+			toInt64(countIf(progress_unit = 'chapters')),
+			toInt64(sumIf(progress, progress_unit = 'chapters'))
+		FROM events
+		WHERE ` + filter
+
+	var sessions, timed, minutes, pageSessions, pages, chapterSessions, chapters int64
+	err := db.conn.QueryRow(ctx, query, args...).
+		Scan(&sessions, &timed, &minutes, &pageSessions, &pages, &chapterSessions, &chapters)
+	if err != nil {
+		return models.SessionStats{}, fmt.Errorf("failed to get session stats: %w", err)
+	}
+
+	return models.SessionStats{
+		Sessions:        int(sessions),
+		TimedSessions:   int(timed),
+		TotalMinutes:    int(minutes),
+		PageSessions:    int(pageSessions),
+		TotalPages:      int(pages),
+		ChapterSessions: int(chapterSessions),
+		TotalChapters:   int(chapters),
+	}, nil
+}
+
+// GetHourlyStats counts sessions per hour of the day in loc
+func (db *ClickHouseDB) GetHourlyStats(ctx context.Context, startDate, endDate time.Time, participantName string, loc *time.Location) ([]models.HourStat, error) {
+	filter, filterArgs := sessionFilter(startDate, endDate, participantName)
+	query := `
+		SELECT toInt64(toHour(date, ?)) AS hour, toInt64(count())
+		FROM events
+		WHERE ` + filter + `
+		GROUP BY hour
+		ORDER BY hour`
+	args := append([]interface{}{loc.String()}, filterArgs...)
+
+	rows, err := db.conn.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get hourly stats: %w", err)
+	}
+	defer rows.Close()
+
+	var stats []models.HourStat
+	for rows.Next() {
+		var hour, count int64
+		if err := rows.Scan(&hour, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan hourly stat: %w", err)
+		}
+		stats = append(stats, models.HourStat{Hour: int(hour), ReadCount: int(count)})
+	}
+	return stats, nil
+}
+
+// GetDailyStats counts sessions per calendar day in loc
+func (db *ClickHouseDB) GetDailyStats(ctx context.Context, startDate, endDate time.Time, participantName string, loc *time.Location) ([]models.DayStat, error) {
+	filter, filterArgs := sessionFilter(startDate, endDate, participantName)
+	query := `
+		SELECT toString(toDate(date, ?)) AS day, toInt64(count())
+		FROM events
+		WHERE ` + filter + `
+		GROUP BY day
+		ORDER BY day`
+	args := append([]interface{}{loc.String()}, filterArgs...)
+
+	rows, err := db.conn.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get daily stats: %w", err)
+	}
+	defer rows.Close()
+
+	var stats []models.DayStat
+	for rows.Next() {
+		var day string
+		var count int64
+		if err := rows.Scan(&day, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan daily stat: %w", err)
+		}
+		date, err := time.ParseInLocation("2006-01-02", day, loc)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse reading day %q: %w", day, err)
+		}
+		stats = append(stats, models.DayStat{Date: date, ReadCount: int(count)})
+	}
+	return stats, nil
+}
+
+// GetWeeklyReaderStats counts the sessions every child attended per week, weeks starting on Monday in loc
+func (db *ClickHouseDB) GetWeeklyReaderStats(ctx context.Context, startDate, endDate time.Time, loc *time.Location) ([]models.WeekReaderStat, error) {
+	query := `
+		SELECT toString(toMonday(toDate(e.date, ?))) AS week, toString(p.id), p.name, toInt64(count())
+		FROM ` + eventAttendees + ` e
+		JOIN participants p ON p.id = e.participant_id
+		WHERE e.date >= ? AND e.date <= ? AND p.is_parent = false
+		GROUP BY week, p.id, p.name
+		ORDER BY week, p.name`
+
+	rows, err := db.conn.Query(ctx, query, loc.String(), startDate, endDate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get weekly reader stats: %w", err)
+	}
+	defer rows.Close()
+
+	var stats []models.WeekReaderStat
+	for rows.Next() {
+		var stat models.WeekReaderStat
+		var week string
+		var count int64
+		if err := rows.Scan(&week, &stat.ParticipantID, &stat.ParticipantName, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan weekly reader stat: %w", err)
+		}
+		stat.WeekStart, err = time.ParseInLocation("2006-01-02", week, loc)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse week %q: %w", week, err)
+		}
+		stat.ReadCount = int(count)
+		stats = append(stats, stat)
+	}
+	return stats, nil
+}
+
+// sessionFilter builds the WHERE clause selecting events in the period attended by
+// the participant, or by any child if participantName is empty
+func sessionFilter(startDate, endDate time.Time, participantName string) (string, []interface{}) {
+	participants := `SELECT groupArray(id) FROM participants WHERE is_parent = false`
+	args := []interface{}{startDate, endDate}
+	if participantName != "" {
+		participants = `SELECT groupArray(id) FROM participants WHERE name = ?`
+		args = append(args, participantName)
+	}
+	return `date >= ? AND date <= ? AND hasAny(participant_ids, (` + participants + `))`, args
+}
+
+// GetNewBooks returns books created within the period, oldest first.
+// Books from before created_at was recorded have the epoch and never match.
+func (db *ClickHouseDB) GetNewBooks(ctx context.Context, startDate, endDate time.Time) ([]models.Book, error) {
+	rows, err := db.conn.Query(ctx, `
+		SELECT `+bookColumns+`
+		FROM books
+		WHERE created_at >= ? AND created_at <= ? AND created_at > toDateTime(0)
+		ORDER BY created_at, name`, startDate, endDate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get new books: %w", err)
+	}
+	defer rows.Close()
+
+	var books []models.Book
+	for rows.Next() {
+		var book models.Book
+		if err := scanBook(rows, &book); err != nil {
+			return nil, fmt.Errorf("failed to scan book: %w", err)
+		}
+		books = append(books, book)
+	}
+	return books, nil
+}
+
+// GetChatSettings returns saved settings for the chat, or defaults
+func (db *ClickHouseDB) GetChatSettings(ctx context.Context, chatID int64) (models.ChatSettings, error) {
+	settings := models.ChatSettings{ChatID: chatID}
+	var threadID int32
+	err := db.conn.QueryRow(ctx, `
+		SELECT rotation_strategy, arrayMap(x -> toString(x), rotation_skip_ids), reminder_enabled, reminder_thread_id
+		FROM chat_settings
+		WHERE chat_id = ?
+		LIMIT 1`, chatID).
+		Scan(&settings.RotationStrategy, &settings.RotationSkipIDs, &settings.ReminderEnabled, &threadID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return settings, nil
+	}
+	if err != nil {
+		return models.ChatSettings{}, fmt.Errorf("failed to get chat settings: %w", err)
+	}
+	settings.ReminderThreadID = int(threadID)
+	return settings, nil
+}
+
+// SaveChatSettings stores settings for settings.ChatID, replacing the previous row
+func (db *ClickHouseDB) SaveChatSettings(ctx context.Context, settings models.ChatSettings) error {
+	if err := db.conn.Exec(ctx, `DELETE FROM chat_settings WHERE chat_id = ?`, settings.ChatID); err != nil {
+		return fmt.Errorf("failed to save chat settings: %w", err)
+	}
+	skipIDs := settings.RotationSkipIDs
+	if skipIDs == nil {
+		skipIDs = []string{}
+	}
+	err := db.conn.Exec(ctx, `
+		INSERT INTO chat_settings (chat_id, rotation_strategy, rotation_skip_ids, reminder_enabled, reminder_thread_id)
+		VALUES (?, ?, ?, ?, ?)`,
+		settings.ChatID, settings.RotationStrategy, skipIDs, settings.ReminderEnabled, int32(settings.ReminderThreadID))
+	if err != nil {
+		return fmt.Errorf("failed to save chat settings: %w", err)
+	}
+	return nil
+}
+
+// ListReminderChats returns the settings of chats with the daily reminder enabled, ordered by chat ID
+func (db *ClickHouseDB) ListReminderChats(ctx context.Context) ([]models.ChatSettings, error) {
+	rows, err := db.conn.Query(ctx, `
+		SELECT chat_id, rotation_strategy, arrayMap(x -> toString(x), rotation_skip_ids), reminder_thread_id
+		FROM chat_settings
+		WHERE reminder_enabled
+		ORDER BY chat_id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list reminder chats: %w", err)
+	}
+	defer rows.Close()
+
+	var chats []models.ChatSettings
+	for rows.Next() {
+		settings := models.ChatSettings{ReminderEnabled: true}
+		var threadID int32
+		if err := rows.Scan(&settings.ChatID, &settings.RotationStrategy, &settings.RotationSkipIDs, &threadID); err != nil {
+			return nil, fmt.Errorf("failed to scan chat settings: %w", err)
+		}
+		settings.ReminderThreadID = int(threadID)
+		chats = append(chats, settings)
+	}
+	return chats, nil
+}
+
+// GetLastScheduledRun returns the occurrence a job last ran for, or zero time if it never ran
+func (db *ClickHouseDB) GetLastScheduledRun(ctx context.Context, job string) (time.Time, error) {
+	var count uint64
+	var last time.Time
+	err := db.conn.QueryRow(ctx, `SELECT count(), max(scheduled_for) FROM scheduled_runs WHERE job = ?`, job).
+		Scan(&count, &last)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get last run of %s: %w", job, err)
+	}
+	if count == 0 {
+		return time.Time{}, nil
+	}
+	return last, nil
+}
+
+// ClaimScheduledRun records that a job runs for the given occurrence and reports whether
+// this call claimed it. ClickHouse has no unique constraints, so every instance inserts its own
+// claim and the one inserted first wins: inserts get increasing block numbers, and an insert
+// that is not yet visible to a reader gets a higher number than the claims that reader saw.
+func (db *ClickHouseDB) ClaimScheduledRun(ctx context.Context, job string, scheduledFor time.Time) (bool, error) {
+	claimID := uuid.NewString()
+	err := db.conn.Exec(ctx, `INSERT INTO scheduled_runs (job, scheduled_for, claim_id) VALUES (?, ?, ?)`,
+		job, scheduledFor, claimID)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim run of %s: %w", job, err)
+	}
+
+	var winner string
+	err = db.conn.QueryRow(ctx, `
+		SELECT toString(claim_id) FROM scheduled_runs
+		WHERE job = ? AND scheduled_for = ?
+		ORDER BY _block_number, _block_offset
+		LIMIT 1`, job, scheduledFor).Scan(&winner)
+	if err != nil {
+		return false, fmt.Errorf("failed to check claim of %s: %w", job, err)
+	}
+	return winner == claimID, nil
+}
+
+// CreateAPIToken stores a token and returns its generated ID
+func (db *ClickHouseDB) CreateAPIToken(ctx context.Context, token models.APIToken) (string, error) {
+	var count uint64
+	err := db.conn.QueryRow(ctx, `SELECT count() FROM api_tokens WHERE owner_id = ? AND name = ?`, token.OwnerID, token.Name).Scan(&count)
+	if err != nil {
+		return "", fmt.Errorf("failed to check token name: %w", err)
+	}
+	if count > 0 {
+		return "", fmt.Errorf("token %q already exists", token.Name)
+	}
+
+	id := uuid.NewString()
+	err = db.conn.Exec(ctx, `INSERT INTO api_tokens (id, name, scope, owner_id, hash, created_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		id, token.Name, token.Scope, token.OwnerID, token.Hash, time.Now().UTC())
+	if err != nil {
+		return "", fmt.Errorf("failed to create token: %w", err)
+	}
+	return id, nil
+}
+
+// GetAPITokenByHash looks up a token by the SHA-256 hash of its secret
+func (db *ClickHouseDB) GetAPITokenByHash(ctx context.Context, hash string) (models.APIToken, error) {
+	var token models.APIToken
+	err := db.conn.QueryRow(ctx, `
+		SELECT toString(id), name, scope, owner_id, hash, created_at
+		FROM api_tokens
+		WHERE hash = ?
+		LIMIT 1`, hash).Scan(&token.ID, &token.Name, &token.Scope, &token.OwnerID, &token.Hash, &token.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.APIToken{}, fmt.Errorf("token %w", storage.ErrNotFound)
+	}
+	if err != nil {
+		return models.APIToken{}, fmt.Errorf("failed to get token: %w", err)
+	}
+	return token, nil
+}
+
+// ListAPITokens returns the tokens of a Telegram user, ordered by name
+func (db *ClickHouseDB) ListAPITokens(ctx context.Context, ownerID int64) ([]models.APIToken, error) {
+	rows, err := db.conn.Query(ctx, `
+		SELECT toString(id), name, scope, owner_id, hash, created_at
+		FROM api_tokens
+		WHERE owner_id = ?
+		ORDER BY name`, ownerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tokens: %w", err)
+	}
+	defer rows.Close()
+
+	var tokens []models.APIToken
+	for rows.Next() {
+		var token models.APIToken
+		if err := rows.Scan(&token.ID, &token.Name, &token.Scope, &token.OwnerID, &token.Hash, &token.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan token: %w", err)
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, nil
+}
+
+// RevokeAPIToken deletes a token
+func (db *ClickHouseDB) RevokeAPIToken(ctx context.Context, id string) error {
+	var count uint64
+	err := db.conn.QueryRow(ctx, `SELECT count() FROM api_tokens WHERE id = ?`, id).Scan(&count)
+	if err != nil {
+		return fmt.Errorf("failed to check token: %w", err)
+	}
+	if count == 0 {
+		return fmt.Errorf("token %s %w", id, storage.ErrNotFound)
+	}
+
+	if err := db.conn.Exec(ctx, `DELETE FROM api_tokens WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("failed to revoke token: %w", err)
+	}
+	return nil
+}
+
+// AddAuditEntry stores a record of a data change
+func (db *ClickHouseDB) AddAuditEntry(ctx context.Context, entry models.AuditEntry) error {
+	err := db.conn.Exec(ctx, `INSERT INTO audit_log (created_at, actor_id, actor_name, source, action, payload) VALUES (?, ?, ?, ?, ?, ?)`,
+		entry.CreatedAt, entry.ActorID, entry.ActorName, entry.Source, entry.Action, entry.Payload)
+	if err != nil {
+		return fmt.Errorf("failed to add audit entry: %w", err)
+	}
+	return nil
+}
+
+// ListAuditEntries returns the most recent audit entries, newest first, optionally for one actor
+func (db *ClickHouseDB) ListAuditEntries(ctx context.Context, limit int, actorID int64) ([]models.AuditEntry, error) {
+	query := `SELECT created_at, actor_id, actor_name, source, action, payload FROM audit_log`
+	var args []interface{}
+	if actorID != 0 {
+		query += ` WHERE actor_id = ?`
+		args = append(args, actorID)
+	}
+	query += ` ORDER BY created_at DESC LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := db.conn.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit entries: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []models.AuditEntry
+	for rows.Next() {
+		var entry models.AuditEntry
+		if err := rows.Scan(&entry.CreatedAt, &entry.ActorID, &entry.ActorName, &entry.Source, &entry.Action, &entry.Payload); err != nil {
+			return nil, fmt.Errorf("failed to scan audit entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// GetReaderActivity returns read counts since the given time and last read dates per active participant
+func (db *ClickHouseDB) GetReaderActivity(ctx context.Context, since time.Time) ([]models.ReaderActivity, error) {
+	query := `
+		SELECT
+			toString(p.id),
+			p.name,
+			toInt64(countIf(e.date >= ?)),
+			max(e.date)
+		FROM participants p
+		LEFT JOIN ` + eventAttendees + ` e ON e.participant_id = p.id
+		WHERE p.is_archived = false
+		GROUP BY p.id, p.name
+		ORDER BY p.name`
+
+	rows, err := db.conn.Query(ctx, query, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reader activity: %w", err)
+	}
+	defer rows.Close()
+
+	epoch := time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC)
+	var activity []models.ReaderActivity
+	for rows.Next() {
+		var entry models.ReaderActivity
+		var readsSince int64
+		var lastReadDate time.Time
+		if err := rows.Scan(&entry.ParticipantID, &entry.ParticipantName, &readsSince, &lastReadDate); err != nil {
+			return nil, fmt.Errorf("failed to scan reader activity: %w", err)
+		}
+		entry.ReadsSince = int(readsSince)
+		// ClickHouse returns epoch for participants without events
+		if lastReadDate.After(epoch) {
+			entry.LastReadDate = &lastReadDate
+		}
+		activity = append(activity, entry)
+	}
+	return activity, nil
+}
+
+// GetStreaks returns consecutive reading day streaks for every active participant, ordered by name.
+// Reading days are grouped in ClickHouse; streaks are computed with storage.Streaks.
+func (db *ClickHouseDB) GetStreaks(ctx context.Context, today time.Time) ([]models.Streak, error) {
+	query := `
+		SELECT
+			toString(p.id),
+			p.name,
+			arrayMap(d -> toString(d), arraySort(groupUniqArrayIf(toDate(e.date, ?), e.participant_id = p.id))),
+			max(e.date)
+		FROM participants p
+		LEFT JOIN ` + eventAttendees + ` e ON e.participant_id = p.id
+		WHERE p.is_archived = false
+		GROUP BY p.id, p.name
+		ORDER BY p.name`
+
+	loc := today.Location()
+	rows, err := db.conn.Query(ctx, query, loc.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get streaks: %w", err)
+	}
+	defer rows.Close()
+
+	epoch := time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC)
+	var streaks []models.Streak
+	for rows.Next() {
+		var streak models.Streak
+		var dayStrings []string
+		var lastReadDate time.Time
+		if err := rows.Scan(&streak.ParticipantID, &streak.ParticipantName, &dayStrings, &lastReadDate); err != nil {
+			return nil, fmt.Errorf("failed to scan streak: %w", err)
+		}
+
+		days := make([]time.Time, 0, len(dayStrings))
+		for _, s := range dayStrings {
+			day, err := time.ParseInLocation("2006-01-02", s, loc)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse reading day %q: %w", s, err)
+			}
+			days = append(days, day)
+		}
+		streak.Current, streak.Longest = storage.Streaks(days, today)
+		// ClickHouse returns epoch for participants without events
+		if lastReadDate.After(epoch) {
+			streak.LastReadDate = &lastReadDate
+		}
+		streaks = append(streaks, streak)
+	}
+	return streaks, nil
+}
+
+// GetRarelyReadBooks returns books ordered by how long ago they were last read
+// If childrenOnly is true, only considers reads by children (IsParent=false)
+// If childrenOnly is false, considers reads by all participants
+// If filter is not nil, only returns books whose labels match it
+// Books never read are included with DaysSinceLastRead=-1
+func (db *ClickHouseDB) GetRarelyReadBooks(ctx context.Context, limit int, childrenOnly bool, filter labels.Expr) ([]models.RareBookStat, error) {
+	// Only consider reads by children, or by all participants
+	source := "events"
+	if childrenOnly {
+		source = `(
+			SELECT e.book_id, e.date
+			FROM ` + eventAttendees + ` e
+			INNER JOIN participants p ON e.participant_id = p.id
+			WHERE p.is_parent = false
+		)`
+	}
+
+	labelCondition, args, err := labelFilterSQL("b.labels", filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rarely read books: %w", err)
+	}
+	query := `
+		SELECT
+			b.name as book_name,
+			max(e.date) as last_read_date,
+			if(max(e.date) <= toDateTime(0), -1, dateDiff('day', max(e.date), now())) as days_since_last_read
+		FROM books b
+		LEFT JOIN ` + source + ` e ON b.id = e.book_id
+		WHERE b.is_readable = true AND ` + labelCondition + `
+		GROUP BY b.id, b.name
+		ORDER BY
+			(max(e.date) <= toDateTime(0)) ASC,
+			days_since_last_read DESC,
+			book_name ASC
+		LIMIT ?
+	`
+	args = append(args, limit)
 
 	rows, err := db.conn.Query(ctx, query, args...)
 	if err != nil {
@@ -480,7 +1610,8 @@ func (db *ClickHouseDB) GetDetailedBookStats(ctx context.Context, startDate, end
 	var joinConditions []string
 	var args []interface{}
 
-	conditions = append(conditions, "b.is_readable = true")
+	// Retired books are included so their history stays visible
+	conditions = append(conditions, "1 = 1")
 
 	// Date filters go into JOIN condition to preserve zero counts in LEFT JOIN
 	if !startDate.IsZero() {
@@ -501,7 +1632,7 @@ func (db *ClickHouseDB) GetDetailedBookStats(ctx context.Context, startDate, end
 		args = append(args, participantName)
 	}
 
-	joinOn := "b.name = e.book_name AND p.name = e.participant_name"
+	joinOn := "b.id = e.book_id AND p.id = e.participant_id"
 	if len(joinConditions) > 0 {
 		joinOn += " AND " + strings.Join(joinConditions, " AND ")
 	}
@@ -514,11 +1645,11 @@ func (db *ClickHouseDB) GetDetailedBookStats(ctx context.Context, startDate, end
 			max(e.date) AS last_read_date
 		FROM books b
 		CROSS JOIN participants p
-		LEFT JOIN events e ON %s
+		LEFT JOIN %s e ON %s
 		WHERE %s
-		GROUP BY b.name, p.name
+		GROUP BY b.id, b.name, p.id, p.name
 		ORDER BY b.name ASC, read_count DESC, p.name ASC
-	`, joinOn, strings.Join(conditions, " AND "))
+	`, eventAttendees, joinOn, strings.Join(conditions, " AND "))
 
 	rows, err := db.conn.Query(ctx, query, args...)
 	if err != nil {
@@ -554,7 +1685,8 @@ func (db *ClickHouseDB) GetParticipantStats(ctx context.Context, startDate, endD
 	var joinConditions []string
 	var args []interface{}
 
-	conditions = append(conditions, "b.is_readable = true")
+	// Retired books are included so their history stays visible
+	conditions = append(conditions, "1 = 1")
 
 	if !startDate.IsZero() {
 		joinConditions = append(joinConditions, "e.date >= ?")
@@ -573,7 +1705,7 @@ func (db *ClickHouseDB) GetParticipantStats(ctx context.Context, startDate, endD
 		args = append(args, participantName)
 	}
 
-	joinOn := "p.name = e.participant_name AND b.name = e.book_name"
+	joinOn := "p.id = e.participant_id AND b.id = e.book_id"
 	if len(joinConditions) > 0 {
 		joinOn += " AND " + strings.Join(joinConditions, " AND ")
 	}
@@ -585,11 +1717,11 @@ func (db *ClickHouseDB) GetParticipantStats(ctx context.Context, startDate, endD
 			toInt32(count(e.date)) AS read_count
 		FROM participants p
 		CROSS JOIN books b
-		LEFT JOIN events e ON %s
+		LEFT JOIN %s e ON %s
 		WHERE %s
-		GROUP BY p.name, b.name
+		GROUP BY p.id, p.name, b.id, b.name
 		ORDER BY p.name ASC, read_count DESC, b.name ASC
-	`, joinOn, strings.Join(conditions, " AND "))
+	`, eventAttendees, joinOn, strings.Join(conditions, " AND "))
 
 	rows, err := db.conn.Query(ctx, query, args...)
 	if err != nil {
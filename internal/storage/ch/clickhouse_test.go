@@ -2,9 +2,15 @@ package ch
 
 import (
 	"context"
+	"strings"
 	"testing"
 	"time"
 
+	"library/internal/labels"
+	"library/internal/models"
+	"library/internal/storage"
+
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	clickhouseTC "github.com/testcontainers/testcontainers-go/modules/clickhouse"
@@ -16,15 +22,31 @@ func runMigrations(ctx context.Context, db *ClickHouseDB) error {
 	_ = db.conn.Exec(ctx, "DROP TABLE IF EXISTS events")
 	_ = db.conn.Exec(ctx, "DROP TABLE IF EXISTS participants")
 	_ = db.conn.Exec(ctx, "DROP TABLE IF EXISTS books")
+	_ = db.conn.Exec(ctx, "DROP TABLE IF EXISTS chat_settings")
+	_ = db.conn.Exec(ctx, "DROP TABLE IF EXISTS absences")
+	_ = db.conn.Exec(ctx, "DROP TABLE IF EXISTS scheduled_runs")
+	_ = db.conn.Exec(ctx, "DROP TABLE IF EXISTS reading_goals")
+	_ = db.conn.Exec(ctx, "DROP TABLE IF EXISTS badges")
+	_ = db.conn.Exec(ctx, "DROP TABLE IF EXISTS audit_log")
+	_ = db.conn.Exec(ctx, "DROP TABLE IF EXISTS api_tokens")
 
 	// Create books table with settings required for lightweight UPDATE support (ClickHouse 25.8+)
 	err := db.conn.Exec(ctx, `
 		CREATE TABLE IF NOT EXISTS books (
+			id UUID,
 			name String,
+			author String DEFAULT '',
 			is_readable Bool,
-			labels Array(String)
+			labels Array(String),
+			created_at DateTime DEFAULT toDateTime(0),
+			isbn String DEFAULT '',
+			series String DEFAULT '',
+			series_volume UInt16 DEFAULT 0,
+			cover String DEFAULT '',
+			age LowCardinality(String) DEFAULT '',
+			notes String DEFAULT ''
 		) ENGINE = MergeTree()
-		ORDER BY name
+		ORDER BY id
 		SETTINGS enable_block_number_column = 1, enable_block_offset_column = 1
 	`)
 	if err != nil {
@@ -34,10 +56,13 @@ func runMigrations(ctx context.Context, db *ClickHouseDB) error {
 	// Create participants table
 	err = db.conn.Exec(ctx, `
 		CREATE TABLE IF NOT EXISTS participants (
+			id UUID,
 			name String,
-			is_parent Bool
+			is_parent Bool,
+			is_archived Bool DEFAULT false
 		) ENGINE = MergeTree()
-		ORDER BY name
+		ORDER BY id
+		SETTINGS enable_block_number_column = 1, enable_block_offset_column = 1
 	`)
 	if err != nil {
 		return err
@@ -46,15 +71,145 @@ func runMigrations(ctx context.Context, db *ClickHouseDB) error {
 	// Create events table
 	err = db.conn.Exec(ctx, `
 		CREATE TABLE IF NOT EXISTS events (
+			id UUID,
 			date DateTime,
-			book_name String,
-			participant_name String
+			book_id UUID,
+			participant_ids Array(UUID),
+			duration_minutes UInt32 DEFAULT 0,
+			progress UInt32 DEFAULT 0,
+			progress_unit LowCardinality(String) DEFAULT ''
 		) ENGINE = MergeTree()
 		ORDER BY date
+		SETTINGS enable_block_number_column = 1, enable_block_offset_column = 1
+	`)
+	if err != nil {
+		return err
+	}
+
+	// Create chat settings table
+	err = db.conn.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS chat_settings (
+			chat_id Int64,
+			rotation_strategy LowCardinality(String) DEFAULT '',
+			rotation_skip_ids Array(UUID) DEFAULT [],
+			reminder_enabled Bool DEFAULT false,
+			reminder_thread_id Int32 DEFAULT 0
+		) ENGINE = MergeTree()
+		ORDER BY chat_id
+		SETTINGS enable_block_number_column = 1, enable_block_offset_column = 1
+	`)
+	if err != nil {
+		return err
+	}
+
+	// Create absences table
+	err = db.conn.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS absences (
+			participant_id UUID,
+			until DateTime,
+			created_at DateTime DEFAULT now()
+		) ENGINE = MergeTree()
+		ORDER BY participant_id
+		SETTINGS enable_block_number_column = 1, enable_block_offset_column = 1
+	`)
+	if err != nil {
+		return err
+	}
+
+	// Create scheduled runs table
+	err = db.conn.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS scheduled_runs (
+			job LowCardinality(String),
+			scheduled_for DateTime,
+			ran_at DateTime DEFAULT now(),
+			claim_id UUID
+		) ENGINE = MergeTree()
+		ORDER BY (job, scheduled_for)
+		SETTINGS enable_block_number_column = 1, enable_block_offset_column = 1
+	`)
+	if err != nil {
+		return err
+	}
+
+	// Create reading goals table
+	err = db.conn.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS reading_goals (
+			participant_id UUID,
+			target UInt32,
+			period LowCardinality(String),
+			created_at DateTime DEFAULT now()
+		) ENGINE = MergeTree()
+		ORDER BY participant_id
+		SETTINGS enable_block_number_column = 1, enable_block_offset_column = 1
+	`)
+	if err != nil {
+		return err
+	}
+
+	// Create badges table
+	err = db.conn.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS badges (
+			participant_id UUID,
+			badge LowCardinality(String),
+			event_id UUID,
+			unlocked_at DateTime DEFAULT now()
+		) ENGINE = MergeTree()
+		ORDER BY (participant_id, badge)
+		SETTINGS enable_block_number_column = 1, enable_block_offset_column = 1
+	`)
+	if err != nil {
+		return err
+	}
+
+	// Create audit_log table
+	err = db.conn.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS audit_log (
+			created_at DateTime64(3),
+			actor_id Int64,
+			actor_name String,
+			source LowCardinality(String),
+			action LowCardinality(String),
+			payload String
+		) ENGINE = MergeTree()
+		ORDER BY created_at
+		SETTINGS enable_block_number_column = 1, enable_block_offset_column = 1
+	`)
+	if err != nil {
+		return err
+	}
+
+	// Create api_tokens table
+	err = db.conn.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS api_tokens (
+			id UUID,
+			name String,
+			scope LowCardinality(String),
+			owner_id Int64,
+			hash String,
+			created_at DateTime DEFAULT now()
+		) ENGINE = MergeTree()
+		ORDER BY (owner_id, name)
+		SETTINGS enable_block_number_column = 1, enable_block_offset_column = 1
 	`)
 	return err
 }
 
+// bookID looks up a book ID by name for test setup
+func bookID(t *testing.T, db *ClickHouseDB, name string) string {
+	t.Helper()
+	book, err := db.GetBookByName(context.Background(), name)
+	require.NoError(t, err)
+	return book.ID
+}
+
+// participantID looks up a participant ID by name for test setup
+func participantID(t *testing.T, db *ClickHouseDB, name string) string {
+	t.Helper()
+	participant, err := db.GetParticipantByName(context.Background(), name)
+	require.NoError(t, err)
+	return participant.ID
+}
+
 // setupTestDB creates a test ClickHouse instance using testcontainers
 func setupTestDB(t *testing.T) (*ClickHouseDB, func()) {
 	ctx := context.Background()
@@ -100,16 +255,28 @@ func TestClickHouseDB_CreateBook(t *testing.T) {
 	ctx := context.Background()
 
 	// Test creating a book
-	bookName, err := db.CreateBook(ctx, "Harry Potter")
+	id, err := db.CreateBook(ctx, "Harry Potter")
 	require.NoError(t, err)
-	assert.Equal(t, "Harry Potter", bookName)
+	assert.NotEmpty(t, id)
 
 	// Verify the book exists
 	books, err := db.ListReadableBooks(ctx)
 	require.NoError(t, err)
 	assert.Len(t, books, 1)
+	assert.Equal(t, id, books[0].ID)
 	assert.Equal(t, "Harry Potter", books[0].Name)
 	assert.True(t, books[0].IsReadable)
+
+	// Duplicate names are rejected
+	_, err = db.CreateBook(ctx, "Harry Potter")
+	assert.Error(t, err)
+
+	// Lookup by name
+	book, err := db.GetBookByName(ctx, "Harry Potter")
+	require.NoError(t, err)
+	assert.Equal(t, id, book.ID)
+	_, err = db.GetBookByName(ctx, "Missing")
+	assert.ErrorIs(t, err, storage.ErrNotFound)
 }
 
 // TestClickHouseDB_ListReadableBooks tests listing readable books
@@ -149,11 +316,11 @@ func TestClickHouseDB_ListParticipants(t *testing.T) {
 	ctx := context.Background()
 
 	// Add test participants directly to database
-	err := db.conn.Exec(ctx, `INSERT INTO participants (name, is_parent) VALUES (?, ?)`, "Alice", false)
+	_, err := db.CreateParticipant(ctx, "Alice", false)
 	require.NoError(t, err)
-	err = db.conn.Exec(ctx, `INSERT INTO participants (name, is_parent) VALUES (?, ?)`, "Bob", false)
+	_, err = db.CreateParticipant(ctx, "Bob", false)
 	require.NoError(t, err)
-	err = db.conn.Exec(ctx, `INSERT INTO participants (name, is_parent) VALUES (?, ?)`, "Mom", true)
+	_, err = db.CreateParticipant(ctx, "Mom", true)
 	require.NoError(t, err)
 
 	// List participants
@@ -170,6 +337,135 @@ func TestClickHouseDB_ListParticipants(t *testing.T) {
 	assert.True(t, participants[2].IsParent)
 }
 
+// TestClickHouseDB_ParticipantManagement tests creating, updating and archiving participants
+func TestClickHouseDB_ParticipantManagement(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	aliceID, err := db.CreateParticipant(ctx, "Alice", false)
+	require.NoError(t, err)
+	momID, err := db.CreateParticipant(ctx, "Mom", true)
+	require.NoError(t, err)
+
+	// Duplicate names are rejected
+	_, err = db.CreateParticipant(ctx, "Alice", false)
+	assert.Error(t, err)
+
+	_, err = db.CreateBook(ctx, "Book 1")
+	require.NoError(t, err)
+	_, err = db.CreateEvent(ctx, models.Event{Date: time.Now(), BookID: bookID(t, db, "Book 1"), ParticipantIDs: []string{aliceID}})
+	require.NoError(t, err)
+
+	// Rename keeps history attached
+	require.NoError(t, db.UpdateParticipant(ctx, aliceID, "Alicia", false))
+	events, err := db.GetLastEvents(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, []string{aliceID}, events[0].ParticipantIDs)
+	assert.Equal(t, []string{"Alicia"}, events[0].ParticipantNames)
+
+	// Switch role
+	require.NoError(t, db.UpdateParticipant(ctx, aliceID, "Alicia", true))
+	participants, err := db.ListParticipants(ctx)
+	require.NoError(t, err)
+	require.Len(t, participants, 2)
+	assert.Equal(t, "Alicia", participants[0].Name)
+	assert.True(t, participants[0].IsParent)
+	assert.Error(t, db.UpdateParticipant(ctx, aliceID, "Mom", true))
+
+	// Archived participants are hidden from the list but can still be looked up
+	require.NoError(t, db.ArchiveParticipant(ctx, momID))
+	participants, err = db.ListParticipants(ctx)
+	require.NoError(t, err)
+	require.Len(t, participants, 1)
+	assert.Equal(t, "Alicia", participants[0].Name)
+	mom, err := db.GetParticipantByName(ctx, "Mom")
+	require.NoError(t, err)
+	assert.True(t, mom.IsArchived)
+
+	// Unknown participants return errors
+	unknownID := uuid.NewString()
+	assert.ErrorIs(t, db.UpdateParticipant(ctx, unknownID, "Somebody", false), storage.ErrNotFound)
+	assert.ErrorIs(t, db.ArchiveParticipant(ctx, unknownID), storage.ErrNotFound)
+	_, err = db.GetParticipantByName(ctx, "Nobody")
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+}
+
+// TestClickHouseDB_BookLifecycle tests retiring, restoring, renaming, describing and deleting books
+func TestClickHouseDB_BookLifecycle(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	aliceID, err := db.CreateParticipant(ctx, "Alice", false)
+	require.NoError(t, err)
+	book1, err := db.CreateBook(ctx, "Book 1")
+	require.NoError(t, err)
+	book2, err := db.CreateBook(ctx, "Book 2")
+	require.NoError(t, err)
+	require.NoError(t, db.AddLabelToBook(ctx, book1, "fairy-tale"))
+	_, err = db.CreateEvent(ctx, models.Event{Date: time.Now(), BookID: book1, ParticipantIDs: []string{aliceID}})
+	require.NoError(t, err)
+
+	// Retired books leave the readable list but stay in statistics
+	require.NoError(t, db.RetireBook(ctx, book1))
+	books, err := db.ListReadableBooks(ctx)
+	require.NoError(t, err)
+	require.Len(t, books, 1)
+	assert.Equal(t, "Book 2", books[0].Name)
+
+	retired, err := db.ListRetiredBooks(ctx)
+	require.NoError(t, err)
+	require.Len(t, retired, 1)
+	assert.Equal(t, "Book 1", retired[0].Name)
+
+	stats, err := db.GetDetailedBookStats(ctx, time.Time{}, time.Time{}, "Book 1", "")
+	require.NoError(t, err)
+	require.Len(t, stats, 1)
+	assert.Equal(t, 1, stats[0].ReadCount)
+
+	// Rename keeps labels, readability and history
+	require.NoError(t, db.RenameBook(ctx, book1, "Book One"))
+	assert.Error(t, db.RenameBook(ctx, book1, "Book 2"))
+	retired, err = db.ListRetiredBooks(ctx)
+	require.NoError(t, err)
+	require.Len(t, retired, 1)
+	assert.Equal(t, book1, retired[0].ID)
+	assert.Equal(t, "Book One", retired[0].Name)
+	assert.Equal(t, []string{"fairy-tale"}, retired[0].Labels)
+
+	events, err := db.GetLastEvents(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "Book One", events[0].BookName)
+
+	// Metadata is read back with every book listing
+	meta := models.BookMetadata{Author: "Author", ISBN: "9780306406157", Series: "Series", SeriesVolume: 2, Cover: "https://example.com/cover.jpg", Age: "3-5", Notes: "Notes"}
+	require.NoError(t, db.UpdateBookMetadata(ctx, book1, meta))
+	retired, err = db.ListRetiredBooks(ctx)
+	require.NoError(t, err)
+	require.Len(t, retired, 1)
+	assert.Equal(t, meta, retired[0].BookMetadata)
+	assert.ErrorIs(t, db.UpdateBookMetadata(ctx, uuid.NewString(), meta), storage.ErrNotFound)
+
+	require.NoError(t, db.RestoreBook(ctx, book1))
+	books, err = db.ListReadableBooks(ctx)
+	require.NoError(t, err)
+	assert.Len(t, books, 2)
+
+	// Books with history cannot be deleted
+	assert.Error(t, db.DeleteBook(ctx, book1))
+	require.NoError(t, db.DeleteBook(ctx, book2))
+	books, err = db.ListReadableBooks(ctx)
+	require.NoError(t, err)
+	require.Len(t, books, 1)
+
+	assert.ErrorIs(t, db.RetireBook(ctx, uuid.NewString()), storage.ErrNotFound)
+}
+
 // TestClickHouseDB_CreateEvent tests event creation
 func TestClickHouseDB_CreateEvent(t *testing.T) {
 	db, cleanup := setupTestDB(t)
@@ -181,12 +477,12 @@ func TestClickHouseDB_CreateEvent(t *testing.T) {
 	_, err := db.CreateBook(ctx, "Test Book")
 	require.NoError(t, err)
 
-	err = db.conn.Exec(ctx, `INSERT INTO participants (name, is_parent) VALUES (?, ?)`, "Alice", false)
+	_, err = db.CreateParticipant(ctx, "Alice", false)
 	require.NoError(t, err)
 
 	// Create event
 	eventDate := time.Now().UTC().Truncate(time.Second)
-	err = db.CreateEvent(ctx, eventDate, "Test Book", "Alice")
+	_, err = db.CreateEvent(ctx, models.Event{Date: eventDate, BookID: bookID(t, db, "Test Book"), ParticipantIDs: []string{participantID(t, db, "Alice")}})
 	require.NoError(t, err)
 
 	// Verify event was created
@@ -194,10 +490,630 @@ func TestClickHouseDB_CreateEvent(t *testing.T) {
 	require.NoError(t, err)
 	assert.Len(t, events, 1)
 	assert.Equal(t, "Test Book", events[0].BookName)
-	assert.Equal(t, "Alice", events[0].ParticipantName)
+	assert.Equal(t, []string{"Alice"}, events[0].ParticipantNames)
 	assert.WithinDuration(t, eventDate, events[0].Date, time.Second)
 }
 
+// TestClickHouseDB_EditEvents tests updating and deleting single events
+func TestClickHouseDB_EditEvents(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	book1, err := db.CreateBook(ctx, "Book 1")
+	require.NoError(t, err)
+	book2, err := db.CreateBook(ctx, "Book 2")
+	require.NoError(t, err)
+	aliceID, err := db.CreateParticipant(ctx, "Alice", false)
+	require.NoError(t, err)
+
+	eventDate := time.Date(2024, 1, 1, 19, 30, 0, 0, time.UTC)
+	eventID, err := db.CreateEvent(ctx, models.Event{Date: eventDate, BookID: book1, ParticipantIDs: []string{aliceID}})
+	require.NoError(t, err)
+	keptID, err := db.CreateEvent(ctx, models.Event{Date: eventDate.AddDate(0, 0, -1), BookID: book1, ParticipantIDs: []string{aliceID}})
+	require.NoError(t, err)
+
+	event, err := db.GetEvent(ctx, eventID)
+	require.NoError(t, err)
+	assert.Equal(t, "Book 1", event.BookName)
+	assert.Equal(t, []string{"Alice"}, event.ParticipantNames)
+
+	// Date (the sorting key) and book can both be changed
+	event.Date = eventDate.AddDate(0, 0, 1)
+	event.BookID = book2
+	require.NoError(t, db.UpdateEvent(ctx, event))
+
+	event, err = db.GetEvent(ctx, eventID)
+	require.NoError(t, err)
+	assert.Equal(t, "Book 2", event.BookName)
+	assert.True(t, event.Date.Equal(eventDate.AddDate(0, 0, 1)))
+
+	events, err := db.GetLastEvents(ctx, 10)
+	require.NoError(t, err)
+	assert.Len(t, events, 2)
+
+	require.NoError(t, db.DeleteEvent(ctx, eventID))
+	_, err = db.GetEvent(ctx, eventID)
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+	assert.ErrorIs(t, db.DeleteEvent(ctx, eventID), storage.ErrNotFound)
+
+	events, err = db.GetLastEvents(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, keptID, events[0].ID)
+}
+
+// TestClickHouseDB_FindEventOnDay tests duplicate event detection
+func TestClickHouseDB_FindEventOnDay(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	book1, err := db.CreateBook(ctx, "Book 1")
+	require.NoError(t, err)
+	aliceID, err := db.CreateParticipant(ctx, "Alice", false)
+	require.NoError(t, err)
+	bobID, err := db.CreateParticipant(ctx, "Bob", false)
+	require.NoError(t, err)
+
+	evening := time.Date(2024, 5, 10, 20, 0, 0, 0, time.UTC)
+	eventID, err := db.CreateEvent(ctx, models.Event{Date: evening, BookID: book1, ParticipantIDs: []string{aliceID}})
+	require.NoError(t, err)
+
+	event, err := db.FindEventOnDay(ctx, time.Date(2024, 5, 10, 0, 0, 0, 0, time.UTC), book1, []string{aliceID})
+	require.NoError(t, err)
+	assert.Equal(t, eventID, event.ID)
+
+	_, err = db.FindEventOnDay(ctx, evening.AddDate(0, 0, 1), book1, []string{aliceID})
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+	_, err = db.FindEventOnDay(ctx, evening, book1, []string{bobID})
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+}
+
+// TestClickHouseDB_SharedEvents tests events attended by several participants
+func TestClickHouseDB_SharedEvents(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	book1, err := db.CreateBook(ctx, "Book 1")
+	require.NoError(t, err)
+	aliceID, err := db.CreateParticipant(ctx, "Alice", false)
+	require.NoError(t, err)
+	bobID, err := db.CreateParticipant(ctx, "Bob", false)
+	require.NoError(t, err)
+
+	now := time.Now().UTC().Truncate(time.Second)
+	_, err = db.CreateEvent(ctx, models.Event{Date: now.Add(-time.Hour), BookID: book1, ParticipantIDs: []string{aliceID, bobID}})
+	require.NoError(t, err)
+	_, err = db.CreateEvent(ctx, models.Event{Date: now, BookID: book1, ParticipantIDs: nil})
+	assert.Error(t, err)
+
+	events, err := db.GetLastEvents(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, []string{aliceID, bobID}, events[0].ParticipantIDs)
+	assert.Equal(t, []string{"Alice", "Bob"}, events[0].ParticipantNames)
+
+	// A shared reading counts once for the children...
+	stats, err := db.GetTopBooks(ctx, 10, now.AddDate(0, 0, -1), now, "")
+	require.NoError(t, err)
+	require.Len(t, stats, 1)
+	assert.Equal(t, 1, stats[0].ReadCount)
+
+	// ...and once for each attendee
+	for _, name := range []string{"Alice", "Bob"} {
+		stats, err := db.GetTopBooks(ctx, 10, now.AddDate(0, 0, -1), now, name)
+		require.NoError(t, err)
+		require.Len(t, stats, 1)
+		assert.Equal(t, 1, stats[0].ReadCount)
+
+		detailed, err := db.GetDetailedBookStats(ctx, time.Time{}, time.Time{}, "Book 1", name)
+		require.NoError(t, err)
+		require.Len(t, detailed, 1)
+		assert.Equal(t, 1, detailed[0].ReadCount)
+
+		perParticipant, err := db.GetParticipantStats(ctx, time.Time{}, time.Time{}, "Book 1", name)
+		require.NoError(t, err)
+		require.Len(t, perParticipant, 1)
+		assert.Equal(t, 1, perParticipant[0].ReadCount)
+	}
+
+	filtered, err := db.GetLastEventsFiltered(ctx, 10, time.Time{}, time.Time{}, "bob")
+	require.NoError(t, err)
+	assert.Len(t, filtered, 1)
+}
+
+// TestClickHouseDB_SessionDetails tests storing and aggregating session duration and progress
+func TestClickHouseDB_SessionDetails(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	book1, err := db.CreateBook(ctx, "Book 1")
+	require.NoError(t, err)
+	aliceID, err := db.CreateParticipant(ctx, "Alice", false)
+	require.NoError(t, err)
+	bobID, err := db.CreateParticipant(ctx, "Bob", false)
+	require.NoError(t, err)
+
+	now := time.Now().UTC().Truncate(time.Second)
+	_, err = db.CreateEvent(ctx, models.Event{Date: now.Add(-2 * time.Hour), BookID: book1, ParticipantIDs: []string{aliceID},
+		DurationMinutes: 20, Progress: 15, ProgressUnit: models.ProgressPages})
+	require.NoError(t, err)
+	_, err = db.CreateEvent(ctx, models.Event{Date: now.Add(-time.Hour), BookID: book1, ParticipantIDs: []string{aliceID, bobID},
+		DurationMinutes: 30, Progress: 2, ProgressUnit: models.ProgressChapters})
+	require.NoError(t, err)
+	_, err = db.CreateEvent(ctx, models.Event{Date: now, BookID: book1, ParticipantIDs: []string{bobID}})
+	require.NoError(t, err)
+
+	events, err := db.GetLastEvents(ctx, 3)
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+	assert.Equal(t, 0, events[0].DurationMinutes)
+	assert.Equal(t, 30, events[1].DurationMinutes)
+	assert.Equal(t, 2, events[1].Progress)
+	assert.Equal(t, models.ProgressChapters, events[1].ProgressUnit)
+
+	stats, err := db.GetSessionStats(ctx, now.AddDate(0, 0, -1), now, "")
+	require.NoError(t, err)
+	assert.Equal(t, models.SessionStats{Sessions: 3, TimedSessions: 2, TotalMinutes: 50,
+		PageSessions: 1, TotalPages: 15, ChapterSessions: 1, TotalChapters: 2}, stats)
+
+	stats, err = db.GetSessionStats(ctx, now.AddDate(0, 0, -1), now, "Bob")
+	require.NoError(t, err)
+	assert.Equal(t, 2, stats.Sessions)
+	assert.Equal(t, 30, stats.TotalMinutes)
+	assert.Equal(t, 2, stats.TotalChapters)
+}
+
+// TestClickHouseDB_GetHourlyStats tests counting sessions per hour in a timezone
+func TestClickHouseDB_GetHourlyStats(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	book1, err := db.CreateBook(ctx, "Book 1")
+	require.NoError(t, err)
+	aliceID, err := db.CreateParticipant(ctx, "Alice", false)
+	require.NoError(t, err)
+	momID, err := db.CreateParticipant(ctx, "Mom", true)
+	require.NoError(t, err)
+
+	day := time.Date(2024, 5, 10, 0, 0, 0, 0, time.UTC)
+	for _, event := range []models.Event{
+		{Date: day.Add(6 * time.Hour), BookID: book1, ParticipantIDs: []string{aliceID}},
+		{Date: day.Add(6*time.Hour + 30*time.Minute), BookID: book1, ParticipantIDs: []string{aliceID}},
+		{Date: day.Add(18 * time.Hour), BookID: book1, ParticipantIDs: []string{aliceID}},
+		{Date: day.Add(20 * time.Hour), BookID: book1, ParticipantIDs: []string{momID}},
+	} {
+		_, err := db.CreateEvent(ctx, event)
+		require.NoError(t, err)
+	}
+
+	loc, err := time.LoadLocation("Europe/Kyiv")
+	require.NoError(t, err)
+
+	// Kyiv is UTC+3 in May
+	stats, err := db.GetHourlyStats(ctx, day, day.AddDate(0, 0, 1), "", loc)
+	require.NoError(t, err)
+	assert.Equal(t, []models.HourStat{{Hour: 9, ReadCount: 2}, {Hour: 21, ReadCount: 1}}, stats)
+
+	stats, err = db.GetHourlyStats(ctx, day, day.AddDate(0, 0, 1), "Mom", time.UTC)
+	require.NoError(t, err)
+	assert.Equal(t, []models.HourStat{{Hour: 20, ReadCount: 1}}, stats)
+}
+
+// TestClickHouseDB_GetDailyAndWeeklyStats tests counting sessions per day and per child and week in a timezone
+func TestClickHouseDB_GetDailyAndWeeklyStats(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	book1, err := db.CreateBook(ctx, "Book 1")
+	require.NoError(t, err)
+	aliceID, err := db.CreateParticipant(ctx, "Alice", false)
+	require.NoError(t, err)
+	bobID, err := db.CreateParticipant(ctx, "Bob", false)
+	require.NoError(t, err)
+	momID, err := db.CreateParticipant(ctx, "Mom", true)
+	require.NoError(t, err)
+
+	sunday := time.Date(2024, 5, 12, 0, 0, 0, 0, time.UTC)
+	for _, event := range []models.Event{
+		{Date: sunday.Add(20 * time.Hour), BookID: book1, ParticipantIDs: []string{aliceID}},
+		{Date: sunday.Add(23 * time.Hour), BookID: book1, ParticipantIDs: []string{aliceID, bobID}},
+		{Date: sunday.Add(30 * time.Hour), BookID: book1, ParticipantIDs: []string{bobID}},
+		{Date: sunday.Add(32 * time.Hour), BookID: book1, ParticipantIDs: []string{momID}},
+	} {
+		_, err := db.CreateEvent(ctx, event)
+		require.NoError(t, err)
+	}
+
+	// Kyiv is UTC+3 in May, so the 23:00 reading falls on Monday, the start of the next week
+	loc, err := time.LoadLocation("Europe/Kyiv")
+	require.NoError(t, err)
+
+	days, err := db.GetDailyStats(ctx, sunday, sunday.AddDate(0, 0, 2), "", loc)
+	require.NoError(t, err)
+	assert.Equal(t, []models.DayStat{
+		{Date: time.Date(2024, 5, 12, 0, 0, 0, 0, loc), ReadCount: 1},
+		{Date: time.Date(2024, 5, 13, 0, 0, 0, 0, loc), ReadCount: 2},
+	}, days)
+
+	weeks, err := db.GetWeeklyReaderStats(ctx, sunday, sunday.AddDate(0, 0, 2), loc)
+	require.NoError(t, err)
+	assert.Equal(t, []models.WeekReaderStat{
+		{WeekStart: time.Date(2024, 5, 6, 0, 0, 0, 0, loc), ParticipantID: aliceID, ParticipantName: "Alice", ReadCount: 1},
+		{WeekStart: time.Date(2024, 5, 13, 0, 0, 0, 0, loc), ParticipantID: aliceID, ParticipantName: "Alice", ReadCount: 1},
+		{WeekStart: time.Date(2024, 5, 13, 0, 0, 0, 0, loc), ParticipantID: bobID, ParticipantName: "Bob", ReadCount: 2},
+	}, weeks)
+}
+
+// TestClickHouseDB_ChatSettings tests saving and replacing per-chat settings
+func TestClickHouseDB_ChatSettings(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	settings, err := db.GetChatSettings(ctx, 42)
+	require.NoError(t, err)
+	assert.Equal(t, models.ChatSettings{ChatID: 42}, settings)
+
+	aliceID, err := db.CreateParticipant(ctx, "Alice", false)
+	require.NoError(t, err)
+
+	require.NoError(t, db.SaveChatSettings(ctx, models.ChatSettings{ChatID: 42, RotationStrategy: "weekly", RotationSkipIDs: []string{aliceID}}))
+	require.NoError(t, db.SaveChatSettings(ctx, models.ChatSettings{ChatID: 42, RotationStrategy: "least_recent"}))
+
+	settings, err = db.GetChatSettings(ctx, 42)
+	require.NoError(t, err)
+	assert.Equal(t, "least_recent", settings.RotationStrategy)
+	assert.Empty(t, settings.RotationSkipIDs)
+
+	// Other chats keep their defaults
+	settings, err = db.GetChatSettings(ctx, 7)
+	require.NoError(t, err)
+	assert.Empty(t, settings.RotationStrategy)
+}
+
+// TestClickHouseDB_GetNewBooks tests listing books registered within a period
+func TestClickHouseDB_GetNewBooks(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	// Books inserted without created_at (as before it was recorded) are never new
+	require.NoError(t, db.conn.Exec(ctx, `INSERT INTO books (id, name, is_readable, labels) VALUES (?, ?, ?, ?)`,
+		uuid.NewString(), "Old Book", true, []string{}))
+	_, err := db.CreateBook(ctx, "Book 1")
+	require.NoError(t, err)
+	_, err = db.CreateBook(ctx, "Book 2")
+	require.NoError(t, err)
+
+	now := time.Now()
+	books, err := db.GetNewBooks(ctx, now.Add(-time.Hour), now.Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, books, 2)
+	assert.Equal(t, "Book 1", books[0].Name)
+	assert.Equal(t, "Book 2", books[1].Name)
+
+	books, err = db.GetNewBooks(ctx, now.AddDate(0, 0, -7), now.Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Empty(t, books)
+}
+
+// TestClickHouseDB_ListReminderChats tests listing chats registered for the daily reminder
+func TestClickHouseDB_ListReminderChats(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	require.NoError(t, db.SaveChatSettings(ctx, models.ChatSettings{ChatID: 42, ReminderEnabled: true, ReminderThreadID: 5}))
+	require.NoError(t, db.SaveChatSettings(ctx, models.ChatSettings{ChatID: 7, RotationStrategy: "weekly"}))
+	require.NoError(t, db.SaveChatSettings(ctx, models.ChatSettings{ChatID: 1, ReminderEnabled: true}))
+
+	chats, err := db.ListReminderChats(ctx)
+	require.NoError(t, err)
+	require.Len(t, chats, 2)
+	assert.Equal(t, int64(1), chats[0].ChatID)
+	assert.Equal(t, int64(42), chats[1].ChatID)
+	assert.Equal(t, 5, chats[1].ReminderThreadID)
+
+	settings, err := db.GetChatSettings(ctx, 42)
+	require.NoError(t, err)
+	assert.True(t, settings.ReminderEnabled)
+	assert.Equal(t, 5, settings.ReminderThreadID)
+}
+
+// TestClickHouseDB_ScheduledRuns tests recording scheduled job runs
+func TestClickHouseDB_ScheduledRuns(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	last, err := db.GetLastScheduledRun(ctx, "reminder")
+	require.NoError(t, err)
+	assert.True(t, last.IsZero())
+
+	day := time.Date(2026, 10, 16, 19, 0, 0, 0, time.UTC)
+	for _, occurrence := range []time.Time{day.AddDate(0, 0, -1), day} {
+		claimed, err := db.ClaimScheduledRun(ctx, "reminder", occurrence)
+		require.NoError(t, err)
+		assert.True(t, claimed)
+	}
+	claimed, err := db.ClaimScheduledRun(ctx, "digest", day.AddDate(0, 0, 1))
+	require.NoError(t, err)
+	assert.True(t, claimed)
+
+	last, err = db.GetLastScheduledRun(ctx, "reminder")
+	require.NoError(t, err)
+	assert.True(t, last.Equal(day))
+
+	// A second instance cannot claim the same occurrence
+	claimed, err = db.ClaimScheduledRun(ctx, "reminder", day)
+	require.NoError(t, err)
+	assert.False(t, claimed)
+}
+
+// TestClickHouseDB_GetReaderActivity tests per-participant read counts and last read dates
+func TestClickHouseDB_GetReaderActivity(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	book1, err := db.CreateBook(ctx, "Book 1")
+	require.NoError(t, err)
+	aliceID, err := db.CreateParticipant(ctx, "Alice", false)
+	require.NoError(t, err)
+	bobID, err := db.CreateParticipant(ctx, "Bob", false)
+	require.NoError(t, err)
+	_, err = db.CreateParticipant(ctx, "Charlie", false)
+	require.NoError(t, err)
+
+	now := time.Now().UTC().Truncate(time.Second)
+	_, err = db.CreateEvent(ctx, models.Event{Date: now.AddDate(0, 0, -10), BookID: book1, ParticipantIDs: []string{aliceID}})
+	require.NoError(t, err)
+	_, err = db.CreateEvent(ctx, models.Event{Date: now.AddDate(0, 0, -1), BookID: book1, ParticipantIDs: []string{aliceID, bobID}})
+	require.NoError(t, err)
+	_, err = db.CreateEvent(ctx, models.Event{Date: now, BookID: book1, ParticipantIDs: []string{bobID}})
+	require.NoError(t, err)
+
+	activity, err := db.GetReaderActivity(ctx, now.AddDate(0, 0, -7))
+	require.NoError(t, err)
+	require.Len(t, activity, 3)
+
+	assert.Equal(t, "Alice", activity[0].ParticipantName)
+	assert.Equal(t, 1, activity[0].ReadsSince)
+	require.NotNil(t, activity[0].LastReadDate)
+	assert.True(t, activity[0].LastReadDate.Equal(now.AddDate(0, 0, -1)))
+
+	assert.Equal(t, "Bob", activity[1].ParticipantName)
+	assert.Equal(t, 2, activity[1].ReadsSince)
+
+	assert.Equal(t, "Charlie", activity[2].ParticipantName)
+	assert.Equal(t, 0, activity[2].ReadsSince)
+	assert.Nil(t, activity[2].LastReadDate)
+}
+
+// TestClickHouseDB_Absences tests marking participants as away and back
+func TestClickHouseDB_Absences(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	aliceID, err := db.CreateParticipant(ctx, "Alice", false)
+	require.NoError(t, err)
+	bobID, err := db.CreateParticipant(ctx, "Bob", false)
+	require.NoError(t, err)
+
+	now := time.Now().UTC().Truncate(time.Second)
+	require.NoError(t, db.SetAbsence(ctx, bobID, now.AddDate(0, 0, 3)))
+	require.NoError(t, db.SetAbsence(ctx, aliceID, now.AddDate(0, 0, 1)))
+	// A new absence replaces the previous one
+	require.NoError(t, db.SetAbsence(ctx, aliceID, now.AddDate(0, 0, 2)))
+
+	absences, err := db.ListAbsences(ctx, now)
+	require.NoError(t, err)
+	require.Len(t, absences, 2)
+	assert.Equal(t, "Alice", absences[0].ParticipantName)
+	assert.True(t, absences[0].Until.Equal(now.AddDate(0, 0, 2)))
+	assert.Equal(t, "Bob", absences[1].ParticipantName)
+
+	// Finished absences are not listed
+	absences, err = db.ListAbsences(ctx, now.AddDate(0, 0, 2))
+	require.NoError(t, err)
+	require.Len(t, absences, 1)
+	assert.Equal(t, bobID, absences[0].ParticipantID)
+
+	require.NoError(t, db.ClearAbsence(ctx, bobID))
+	err = db.ClearAbsence(ctx, bobID)
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+
+	err = db.SetAbsence(ctx, uuid.NewString(), now)
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+}
+
+// TestClickHouseDB_Goals tests setting, listing and clearing reading goals
+func TestClickHouseDB_Goals(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	aliceID, err := db.CreateParticipant(ctx, "Alice", false)
+	require.NoError(t, err)
+	bobID, err := db.CreateParticipant(ctx, "Bob", false)
+	require.NoError(t, err)
+
+	require.NoError(t, db.SetGoal(ctx, bobID, 10, models.GoalPeriodMonth))
+	require.NoError(t, db.SetGoal(ctx, aliceID, 3, models.GoalPeriodWeek))
+	// A new goal replaces the previous one
+	require.NoError(t, db.SetGoal(ctx, aliceID, 5, models.GoalPeriodWeek))
+
+	goals, err := db.ListGoals(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []models.ReadingGoal{
+		{ParticipantID: aliceID, ParticipantName: "Alice", Target: 5, Period: models.GoalPeriodWeek},
+		{ParticipantID: bobID, ParticipantName: "Bob", Target: 10, Period: models.GoalPeriodMonth},
+	}, goals)
+
+	require.NoError(t, db.ClearGoal(ctx, bobID))
+	assert.ErrorIs(t, db.ClearGoal(ctx, bobID), storage.ErrNotFound)
+	assert.ErrorIs(t, db.SetGoal(ctx, uuid.NewString(), 1, models.GoalPeriodWeek), storage.ErrNotFound)
+}
+
+func TestClickHouseDB_APITokens(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	hash := strings.Repeat("a", 64)
+	id, err := db.CreateAPIToken(ctx, models.APIToken{Name: "tablet", Scope: models.TokenScopeRead, OwnerID: 1, Hash: hash})
+	require.NoError(t, err)
+	_, err = db.CreateAPIToken(ctx, models.APIToken{Name: "tablet", Scope: models.TokenScopeRead, OwnerID: 1, Hash: strings.Repeat("b", 64)})
+	assert.Error(t, err)
+	_, err = db.CreateAPIToken(ctx, models.APIToken{Name: "script", Scope: models.TokenScopeReadWrite, OwnerID: 1, Hash: strings.Repeat("c", 64)})
+	require.NoError(t, err)
+
+	token, err := db.GetAPITokenByHash(ctx, hash)
+	require.NoError(t, err)
+	assert.Equal(t, id, token.ID)
+	assert.Equal(t, "tablet", token.Name)
+	assert.Equal(t, models.TokenScopeRead, token.Scope)
+	assert.Equal(t, int64(1), token.OwnerID)
+
+	tokens, err := db.ListAPITokens(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, tokens, 2)
+	assert.Equal(t, "script", tokens[0].Name)
+
+	require.NoError(t, db.RevokeAPIToken(ctx, id))
+	assert.ErrorIs(t, db.RevokeAPIToken(ctx, id), storage.ErrNotFound)
+	_, err = db.GetAPITokenByHash(ctx, hash)
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+}
+
+// TestClickHouseDB_GetStreaks tests consecutive reading day streaks
+func TestClickHouseDB_GetStreaks(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	book1, err := db.CreateBook(ctx, "Book 1")
+	require.NoError(t, err)
+	aliceID, err := db.CreateParticipant(ctx, "Alice", false)
+	require.NoError(t, err)
+	bobID, err := db.CreateParticipant(ctx, "Bob", false)
+	require.NoError(t, err)
+	_, err = db.CreateParticipant(ctx, "Charlie", false)
+	require.NoError(t, err)
+
+	today := time.Date(2026, 10, 16, 20, 0, 0, 0, time.UTC)
+	for _, event := range []models.Event{
+		{Date: today.AddDate(0, 0, -6), BookID: book1, ParticipantIDs: []string{aliceID, bobID}},
+		{Date: today.AddDate(0, 0, -5), BookID: book1, ParticipantIDs: []string{aliceID, bobID}},
+		{Date: today.AddDate(0, 0, -4), BookID: book1, ParticipantIDs: []string{aliceID}},
+		{Date: today.AddDate(0, 0, -1), BookID: book1, ParticipantIDs: []string{aliceID}},
+		{Date: today.Add(-time.Hour), BookID: book1, ParticipantIDs: []string{aliceID}},
+		{Date: today.Add(-2 * time.Hour), BookID: book1, ParticipantIDs: []string{aliceID}},
+	} {
+		_, err = db.CreateEvent(ctx, event)
+		require.NoError(t, err)
+	}
+
+	streaks, err := db.GetStreaks(ctx, today)
+	require.NoError(t, err)
+	require.Len(t, streaks, 3)
+
+	assert.Equal(t, "Alice", streaks[0].ParticipantName)
+	assert.Equal(t, 2, streaks[0].Current)
+	assert.Equal(t, 3, streaks[0].Longest)
+	require.NotNil(t, streaks[0].LastReadDate)
+	assert.True(t, streaks[0].LastReadDate.Equal(today.Add(-time.Hour)))
+
+	assert.Equal(t, "Bob", streaks[1].ParticipantName)
+	assert.Equal(t, 0, streaks[1].Current)
+	assert.Equal(t, 2, streaks[1].Longest)
+
+	assert.Equal(t, 0, streaks[2].Longest)
+	assert.Nil(t, streaks[2].LastReadDate)
+}
+
+// TestClickHouseDB_Badges tests storing and listing unlocked badges
+func TestClickHouseDB_Badges(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	aliceID, err := db.CreateParticipant(ctx, "Alice", false)
+	require.NoError(t, err)
+	bobID, err := db.CreateParticipant(ctx, "Bob", false)
+	require.NoError(t, err)
+
+	now := time.Now().UTC().Truncate(time.Second)
+	eventID := uuid.NewString()
+	require.NoError(t, db.AddBadge(ctx, models.Badge{ParticipantID: aliceID, Key: "streak_7", EventID: eventID, UnlockedAt: now}))
+	require.NoError(t, db.AddBadge(ctx, models.Badge{ParticipantID: bobID, Key: "books_10", EventID: eventID, UnlockedAt: now.Add(-time.Hour)}))
+
+	badges, err := db.ListBadges(ctx, "")
+	require.NoError(t, err)
+	require.Len(t, badges, 2)
+	assert.Equal(t, "Bob", badges[0].ParticipantName)
+	assert.Equal(t, "books_10", badges[0].Key)
+
+	badges, err = db.ListBadges(ctx, aliceID)
+	require.NoError(t, err)
+	require.Len(t, badges, 1)
+	assert.Equal(t, models.Badge{ParticipantID: aliceID, ParticipantName: "Alice", Key: "streak_7", EventID: eventID, UnlockedAt: badges[0].UnlockedAt}, badges[0])
+	assert.True(t, badges[0].UnlockedAt.Equal(now))
+}
+
+// TestClickHouseDB_AuditLog tests storing and listing audit entries
+func TestClickHouseDB_AuditLog(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	first := models.AuditEntry{ActorID: 1, ActorName: "Mom", Source: "bot", Action: "CreateBook", Payload: `{"name":"Dune"}`, CreatedAt: now.Add(-time.Minute)}
+	second := models.AuditEntry{ActorID: 2, ActorName: "Dad", Source: "mini-app", Action: "DeleteEvent", Payload: `{"id":"x"}`, CreatedAt: now}
+	require.NoError(t, db.AddAuditEntry(ctx, first))
+	require.NoError(t, db.AddAuditEntry(ctx, second))
+
+	entries, err := db.ListAuditEntries(ctx, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "DeleteEvent", entries[0].Action)
+	assert.True(t, entries[0].CreatedAt.Equal(now))
+
+	entries, err = db.ListAuditEntries(ctx, 10, 1)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "Mom", entries[0].ActorName)
+	assert.Equal(t, `{"name":"Dune"}`, entries[0].Payload)
+
+	entries, err = db.ListAuditEntries(ctx, 1, 0)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+}
+
 // TestClickHouseDB_GetLastEvents tests retrieving last events
 func TestClickHouseDB_GetLastEvents(t *testing.T) {
 	db, cleanup := setupTestDB(t)
@@ -209,14 +1125,14 @@ func TestClickHouseDB_GetLastEvents(t *testing.T) {
 	_, err := db.CreateBook(ctx, "Book 1")
 	require.NoError(t, err)
 
-	err = db.conn.Exec(ctx, `INSERT INTO participants (name, is_parent) VALUES (?, ?)`, "Alice", false)
+	_, err = db.CreateParticipant(ctx, "Alice", false)
 	require.NoError(t, err)
 
 	// Create multiple events with different dates
 	baseTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
 	for i := 0; i < 5; i++ {
 		eventDate := baseTime.Add(time.Duration(i) * 24 * time.Hour)
-		err = db.CreateEvent(ctx, eventDate, "Book 1", "Alice")
+		_, err = db.CreateEvent(ctx, models.Event{Date: eventDate, BookID: bookID(t, db, "Book 1"), ParticipantIDs: []string{participantID(t, db, "Alice")}})
 		require.NoError(t, err)
 	}
 
@@ -251,11 +1167,11 @@ func TestClickHouseDB_GetTopBooks(t *testing.T) {
 	}
 
 	// Add participants
-	err := db.conn.Exec(ctx, `INSERT INTO participants (name, is_parent) VALUES (?, ?)`, "Alice", false)
+	_, err := db.CreateParticipant(ctx, "Alice", false)
 	require.NoError(t, err)
-	err = db.conn.Exec(ctx, `INSERT INTO participants (name, is_parent) VALUES (?, ?)`, "Bob", false)
+	_, err = db.CreateParticipant(ctx, "Bob", false)
 	require.NoError(t, err)
-	err = db.conn.Exec(ctx, `INSERT INTO participants (name, is_parent) VALUES (?, ?)`, "Mom", true)
+	_, err = db.CreateParticipant(ctx, "Mom", true)
 	require.NoError(t, err)
 
 	// Create events
@@ -280,7 +1196,7 @@ func TestClickHouseDB_GetTopBooks(t *testing.T) {
 	}
 
 	for _, e := range events {
-		err = db.CreateEvent(ctx, e.date, e.book, e.participant)
+		_, err = db.CreateEvent(ctx, models.Event{Date: e.date, BookID: bookID(t, db, e.book), ParticipantIDs: []string{participantID(t, db, e.participant)}})
 		require.NoError(t, err)
 	}
 
@@ -371,9 +1287,12 @@ func TestClickHouseDB_ConcurrentOperations(t *testing.T) {
 	_, err := db.CreateBook(ctx, "Concurrent Book")
 	require.NoError(t, err)
 
-	err = db.conn.Exec(ctx, `INSERT INTO participants (name, is_parent) VALUES (?, ?)`, "Alice", false)
+	_, err = db.CreateParticipant(ctx, "Alice", false)
 	require.NoError(t, err)
 
+	book := bookID(t, db, "Concurrent Book")
+	participant := participantID(t, db, "Alice")
+
 	// Create events concurrently
 	numGoroutines := 10
 	done := make(chan bool, numGoroutines)
@@ -381,7 +1300,7 @@ func TestClickHouseDB_ConcurrentOperations(t *testing.T) {
 	for i := 0; i < numGoroutines; i++ {
 		go func(idx int) {
 			eventDate := time.Now().Add(time.Duration(idx) * time.Minute)
-			err := db.CreateEvent(ctx, eventDate, "Concurrent Book", "Alice")
+			_, err := db.CreateEvent(ctx, models.Event{Date: eventDate, BookID: book, ParticipantIDs: []string{participant}})
 			assert.NoError(t, err)
 			done <- true
 		}(i)
@@ -413,33 +1332,33 @@ func TestClickHouseDB_GetRarelyReadBooks(t *testing.T) {
 	}
 
 	// Add participants
-	err := db.conn.Exec(ctx, `INSERT INTO participants (name, is_parent) VALUES (?, ?)`, "Alice", false)
+	_, err := db.CreateParticipant(ctx, "Alice", false)
 	require.NoError(t, err)
-	err = db.conn.Exec(ctx, `INSERT INTO participants (name, is_parent) VALUES (?, ?)`, "Bob", false)
+	_, err = db.CreateParticipant(ctx, "Bob", false)
 	require.NoError(t, err)
-	err = db.conn.Exec(ctx, `INSERT INTO participants (name, is_parent) VALUES (?, ?)`, "Mom", true)
+	_, err = db.CreateParticipant(ctx, "Mom", true)
 	require.NoError(t, err)
 
 	// Create events with different dates
 	now := time.Now().UTC()
 
 	// Book A - read 30 days ago by Alice (child)
-	err = db.CreateEvent(ctx, now.AddDate(0, 0, -30), "Book A", "Alice")
+	_, err = db.CreateEvent(ctx, models.Event{Date: now.AddDate(0, 0, -30), BookID: bookID(t, db, "Book A"), ParticipantIDs: []string{participantID(t, db, "Alice")}})
 	require.NoError(t, err)
 
 	// Book B - read 10 days ago by Bob (child)
-	err = db.CreateEvent(ctx, now.AddDate(0, 0, -10), "Book B", "Bob")
+	_, err = db.CreateEvent(ctx, models.Event{Date: now.AddDate(0, 0, -10), BookID: bookID(t, db, "Book B"), ParticipantIDs: []string{participantID(t, db, "Bob")}})
 	require.NoError(t, err)
 
 	// Book C - read 20 days ago by Mom (parent)
-	err = db.CreateEvent(ctx, now.AddDate(0, 0, -20), "Book C", "Mom")
+	_, err = db.CreateEvent(ctx, models.Event{Date: now.AddDate(0, 0, -20), BookID: bookID(t, db, "Book C"), ParticipantIDs: []string{participantID(t, db, "Mom")}})
 	require.NoError(t, err)
 
 	// Book D - never read
 	// Book E - never read
 
 	t.Run("Children only - includes books never read by children", func(t *testing.T) {
-		stats, err := db.GetRarelyReadBooks(ctx, 10, true, "", nil)
+		stats, err := db.GetRarelyReadBooks(ctx, 10, true, nil)
 		require.NoError(t, err)
 		require.Len(t, stats, 5)
 
@@ -462,7 +1381,7 @@ func TestClickHouseDB_GetRarelyReadBooks(t *testing.T) {
 	})
 
 	t.Run("All participants - includes all reads", func(t *testing.T) {
-		stats, err := db.GetRarelyReadBooks(ctx, 10, false, "", nil)
+		stats, err := db.GetRarelyReadBooks(ctx, 10, false, nil)
 		require.NoError(t, err)
 		require.Len(t, stats, 5)
 
@@ -486,7 +1405,7 @@ func TestClickHouseDB_GetRarelyReadBooks(t *testing.T) {
 	})
 
 	t.Run("Limit results", func(t *testing.T) {
-		stats, err := db.GetRarelyReadBooks(ctx, 3, false, "", nil)
+		stats, err := db.GetRarelyReadBooks(ctx, 3, false, nil)
 		require.NoError(t, err)
 		assert.Len(t, stats, 3)
 	})
@@ -496,7 +1415,7 @@ func TestClickHouseDB_GetRarelyReadBooks(t *testing.T) {
 		dbEmpty, cleanupEmpty := setupTestDB(t)
 		defer cleanupEmpty()
 
-		stats, err := dbEmpty.GetRarelyReadBooks(ctx, 10, false, "", nil)
+		stats, err := dbEmpty.GetRarelyReadBooks(ctx, 10, false, nil)
 		require.NoError(t, err)
 		assert.Empty(t, stats)
 	})
@@ -511,7 +1430,7 @@ func TestClickHouseDB_GetRarelyReadBooks(t *testing.T) {
 		_, err = dbNoEvents.CreateBook(ctx, "Never Read 2")
 		require.NoError(t, err)
 
-		stats, err := dbNoEvents.GetRarelyReadBooks(ctx, 10, false, "", nil)
+		stats, err := dbNoEvents.GetRarelyReadBooks(ctx, 10, false, nil)
 		require.NoError(t, err)
 		require.Len(t, stats, 2)
 
@@ -523,6 +1442,51 @@ func TestClickHouseDB_GetRarelyReadBooks(t *testing.T) {
 	})
 }
 
+// TestClickHouseDB_LabelManagement tests removing, renaming and merging labels
+func TestClickHouseDB_LabelManagement(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	first, err := db.CreateBook(ctx, "First")
+	require.NoError(t, err)
+	second, err := db.CreateBook(ctx, "Second")
+	require.NoError(t, err)
+	require.NoError(t, db.AddLabelToBook(ctx, first, "bedtime"))
+	require.NoError(t, db.AddLabelToBook(ctx, first, "Bedtime"))
+	require.NoError(t, db.AddLabelToBook(ctx, second, "Bedtime"))
+	require.NoError(t, db.AddLabelToBook(ctx, second, "Rhymes"))
+
+	assert.Error(t, db.RenameLabel(ctx, "Rhymes", "bedtime"))
+	assert.ErrorIs(t, db.RenameLabel(ctx, "Missing", "x"), storage.ErrNotFound)
+	require.NoError(t, db.RenameLabel(ctx, "Rhymes", "rhymes"))
+
+	require.NoError(t, db.MergeLabels(ctx, []string{"Bedtime"}, "bedtime"))
+	labels, err := db.GetAllLabels(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"bedtime", "rhymes"}, labels)
+
+	book, err := db.GetBookByName(ctx, "First")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"bedtime"}, book.Labels)
+
+	require.NoError(t, db.AddLabelToBooks(ctx, []string{first, second}, "rhymes"))
+	book, err = db.GetBookByName(ctx, "First")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"bedtime", "rhymes"}, book.Labels)
+
+	require.NoError(t, db.RemoveLabelFromBook(ctx, second, "bedtime"))
+	book, err = db.GetBookByName(ctx, "Second")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"rhymes"}, book.Labels)
+
+	require.NoError(t, db.RemoveLabelFromBooks(ctx, []string{first, second}, "rhymes"))
+	labels, err = db.GetAllLabels(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"bedtime"}, labels)
+}
+
 // TestClickHouseDB_AddLabelToBook tests adding labels to books
 func TestClickHouseDB_AddLabelToBook(t *testing.T) {
 	db, cleanup := setupTestDB(t)
@@ -535,7 +1499,7 @@ func TestClickHouseDB_AddLabelToBook(t *testing.T) {
 	require.NoError(t, err)
 
 	// Add first label
-	err = db.AddLabelToBook(ctx, "Test Book", "fiction")
+	err = db.AddLabelToBook(ctx, bookID(t, db, "Test Book"), "fiction")
 	require.NoError(t, err)
 
 	// Verify label was added
@@ -545,7 +1509,7 @@ func TestClickHouseDB_AddLabelToBook(t *testing.T) {
 	assert.Contains(t, books[0].Labels, "fiction")
 
 	// Add second label
-	err = db.AddLabelToBook(ctx, "Test Book", "kids")
+	err = db.AddLabelToBook(ctx, bookID(t, db, "Test Book"), "kids")
 	require.NoError(t, err)
 
 	// Verify both labels exist
@@ -557,7 +1521,7 @@ func TestClickHouseDB_AddLabelToBook(t *testing.T) {
 	assert.Len(t, books[0].Labels, 2)
 
 	// Adding duplicate label should not create duplicate
-	err = db.AddLabelToBook(ctx, "Test Book", "fiction")
+	err = db.AddLabelToBook(ctx, bookID(t, db, "Test Book"), "fiction")
 	require.NoError(t, err)
 
 	books, err = db.ListReadableBooks(ctx)
@@ -582,11 +1546,11 @@ func TestClickHouseDB_GetBooksWithoutLabel(t *testing.T) {
 	require.NoError(t, err)
 
 	// Add labels
-	err = db.AddLabelToBook(ctx, "Book A", "fiction")
+	err = db.AddLabelToBook(ctx, bookID(t, db, "Book A"), "fiction")
 	require.NoError(t, err)
-	err = db.AddLabelToBook(ctx, "Book B", "fiction")
+	err = db.AddLabelToBook(ctx, bookID(t, db, "Book B"), "fiction")
 	require.NoError(t, err)
-	err = db.AddLabelToBook(ctx, "Book B", "kids")
+	err = db.AddLabelToBook(ctx, bookID(t, db, "Book B"), "kids")
 	require.NoError(t, err)
 	// Book C has no labels
 
@@ -627,13 +1591,13 @@ func TestClickHouseDB_GetAllLabels(t *testing.T) {
 	_, err = db.CreateBook(ctx, "Book B")
 	require.NoError(t, err)
 
-	err = db.AddLabelToBook(ctx, "Book A", "fiction")
+	err = db.AddLabelToBook(ctx, bookID(t, db, "Book A"), "fiction")
 	require.NoError(t, err)
-	err = db.AddLabelToBook(ctx, "Book A", "kids")
+	err = db.AddLabelToBook(ctx, bookID(t, db, "Book A"), "kids")
 	require.NoError(t, err)
-	err = db.AddLabelToBook(ctx, "Book B", "kids")
+	err = db.AddLabelToBook(ctx, bookID(t, db, "Book B"), "kids")
 	require.NoError(t, err)
-	err = db.AddLabelToBook(ctx, "Book B", "adventure")
+	err = db.AddLabelToBook(ctx, bookID(t, db, "Book B"), "adventure")
 	require.NoError(t, err)
 
 	// Get all labels
@@ -662,30 +1626,30 @@ func TestClickHouseDB_GetRarelyReadBooks_WithLabel(t *testing.T) {
 	}
 
 	// Add labels
-	err := db.AddLabelToBook(ctx, "Book A", "fiction")
+	err := db.AddLabelToBook(ctx, bookID(t, db, "Book A"), "fiction")
 	require.NoError(t, err)
-	err = db.AddLabelToBook(ctx, "Book B", "fiction")
+	err = db.AddLabelToBook(ctx, bookID(t, db, "Book B"), "fiction")
 	require.NoError(t, err)
-	err = db.AddLabelToBook(ctx, "Book C", "kids")
+	err = db.AddLabelToBook(ctx, bookID(t, db, "Book C"), "kids")
 	require.NoError(t, err)
 	// Book D has no labels
 
 	// Add participants
-	err = db.conn.Exec(ctx, `INSERT INTO participants (name, is_parent) VALUES (?, ?)`, "Alice", false)
+	_, err = db.CreateParticipant(ctx, "Alice", false)
 	require.NoError(t, err)
 
 	// Create events
 	now := time.Now().UTC()
-	err = db.CreateEvent(ctx, now.AddDate(0, 0, -30), "Book A", "Alice")
+	_, err = db.CreateEvent(ctx, models.Event{Date: now.AddDate(0, 0, -30), BookID: bookID(t, db, "Book A"), ParticipantIDs: []string{participantID(t, db, "Alice")}})
 	require.NoError(t, err)
-	err = db.CreateEvent(ctx, now.AddDate(0, 0, -10), "Book B", "Alice")
+	_, err = db.CreateEvent(ctx, models.Event{Date: now.AddDate(0, 0, -10), BookID: bookID(t, db, "Book B"), ParticipantIDs: []string{participantID(t, db, "Alice")}})
 	require.NoError(t, err)
-	err = db.CreateEvent(ctx, now.AddDate(0, 0, -20), "Book C", "Alice")
+	_, err = db.CreateEvent(ctx, models.Event{Date: now.AddDate(0, 0, -20), BookID: bookID(t, db, "Book C"), ParticipantIDs: []string{participantID(t, db, "Alice")}})
 	require.NoError(t, err)
 	// Book D never read
 
 	t.Run("Filter by fiction label", func(t *testing.T) {
-		stats, err := db.GetRarelyReadBooks(ctx, 10, true, "fiction", nil)
+		stats, err := db.GetRarelyReadBooks(ctx, 10, true, labels.Label("fiction"))
 		require.NoError(t, err)
 		require.Len(t, stats, 2)
 
@@ -699,7 +1663,7 @@ func TestClickHouseDB_GetRarelyReadBooks_WithLabel(t *testing.T) {
 	})
 
 	t.Run("Filter by kids label", func(t *testing.T) {
-		stats, err := db.GetRarelyReadBooks(ctx, 10, true, "kids", nil)
+		stats, err := db.GetRarelyReadBooks(ctx, 10, true, labels.Label("kids"))
 		require.NoError(t, err)
 		require.Len(t, stats, 1)
 
@@ -709,14 +1673,14 @@ func TestClickHouseDB_GetRarelyReadBooks_WithLabel(t *testing.T) {
 	})
 
 	t.Run("Filter by non-existent label", func(t *testing.T) {
-		stats, err := db.GetRarelyReadBooks(ctx, 10, true, "nonexistent", nil)
+		stats, err := db.GetRarelyReadBooks(ctx, 10, true, labels.Label("nonexistent"))
 		require.NoError(t, err)
 		assert.Empty(t, stats)
 	})
 
 	t.Run("Exclude books by label", func(t *testing.T) {
 		// Exclude fiction books, should only return kids (Book C) and unlabeled (Book D)
-		stats, err := db.GetRarelyReadBooks(ctx, 10, true, "", []string{"fiction"})
+		stats, err := db.GetRarelyReadBooks(ctx, 10, true, labels.Not{Expr: labels.Label("fiction")})
 		require.NoError(t, err)
 		require.Len(t, stats, 2)
 
@@ -731,16 +1695,36 @@ func TestClickHouseDB_GetRarelyReadBooks_WithLabel(t *testing.T) {
 
 	t.Run("Include label and exclude label combined", func(t *testing.T) {
 		// Include fiction but exclude Book A specifically by adding a unique label
-		err := db.AddLabelToBook(ctx, "Book A", "exclude-me")
+		err := db.AddLabelToBook(ctx, bookID(t, db, "Book A"), "exclude-me")
 		require.NoError(t, err)
 
-		stats, err := db.GetRarelyReadBooks(ctx, 10, true, "fiction", []string{"exclude-me"})
+		stats, err := db.GetRarelyReadBooks(ctx, 10, true, labels.And{labels.Label("fiction"), labels.Not{Expr: labels.Label("exclude-me")}})
 		require.NoError(t, err)
 		require.Len(t, stats, 1)
 
 		// Only Book B has fiction but not exclude-me
 		assert.Equal(t, "Book B", stats[0].BookName)
 	})
+
+	t.Run("Typed labels with OR and namespace", func(t *testing.T) {
+		require.NoError(t, db.AddLabelToBook(ctx, bookID(t, db, "Book C"), "genre:fairy-tale"))
+		require.NoError(t, db.AddLabelToBook(ctx, bookID(t, db, "Book D"), "genre:poems"))
+
+		stats, err := db.GetRarelyReadBooks(ctx, 10, false, labels.And{labels.Prefix("genre"), labels.Not{Expr: labels.Label("genre:poems")}})
+		require.NoError(t, err)
+		require.Len(t, stats, 1)
+		assert.Equal(t, "Book C", stats[0].BookName)
+
+		stats, err = db.GetRarelyReadBooks(ctx, 10, false, labels.Or{labels.Label("exclude-me"), labels.Label("genre:poems")})
+		require.NoError(t, err)
+		require.Len(t, stats, 2)
+		assert.Equal(t, "Book A", stats[0].BookName)
+		assert.Equal(t, "Book D", stats[1].BookName)
+
+		books, err := db.GetBooksByLabel(ctx, labels.Or{labels.Prefix("genre"), labels.Label("fiction")})
+		require.NoError(t, err)
+		assert.Len(t, books, 4)
+	})
 }
 
 // TestClickHouseDB_Close tests connection closing
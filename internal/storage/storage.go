@@ -2,31 +2,154 @@ package storage
 
 import (
 	"context"
+	"errors"
 	"time"
 
+	"library/internal/labels"
 	"library/internal/models"
 )
 
+// ErrNotFound is returned (wrapped) when a book, participant or event lookup has no match
+var ErrNotFound = errors.New("not found")
+
 // Storage defines the interface for data storage operations
 type Storage interface {
 	// Book operations
+	// CreateBook registers a readable book and returns its generated ID; fails if the name is already taken
 	CreateBook(ctx context.Context, name string) (string, error)
+	// GetBookByName looks up a book (readable or retired) by its exact name.
+	// Returns an error wrapping ErrNotFound if there is no such book.
+	GetBookByName(ctx context.Context, name string) (models.Book, error)
 	ListReadableBooks(ctx context.Context) ([]models.Book, error)
-	AddLabelToBook(ctx context.Context, bookName string, label string) error
+	AddLabelToBook(ctx context.Context, bookID string, label string) error
+	// AddLabelToBooks adds a label to several books at once; books that already have it are unchanged
+	AddLabelToBooks(ctx context.Context, bookIDs []string, label string) error
+	// RemoveLabelFromBook removes a label from a book; removing a label the book does not have is a no-op
+	RemoveLabelFromBook(ctx context.Context, bookID string, label string) error
+	// RemoveLabelFromBooks removes a label from several books at once; books without it are unchanged
+	RemoveLabelFromBooks(ctx context.Context, bookIDs []string, label string) error
+	// RenameLabel renames a label on every book (readable or retired).
+	// Returns an error wrapping ErrNotFound if no book has oldLabel, and fails if newLabel
+	// is already used (use MergeLabels to combine two labels).
+	RenameLabel(ctx context.Context, oldLabel, newLabel string) error
+	// MergeLabels replaces every source label with target on every book, keeping each label once per book.
+	// Returns an error wrapping ErrNotFound if no book has any of the sources.
+	MergeLabels(ctx context.Context, sources []string, target string) error
 	GetBooksWithoutLabel(ctx context.Context, label string) ([]models.Book, error)
-	GetBooksByLabel(ctx context.Context, label string) ([]models.Book, error)
+	// GetBooksByLabel returns readable books whose labels match filter (nil = every readable book), ordered by name
+	GetBooksByLabel(ctx context.Context, filter labels.Expr) ([]models.Book, error)
 	GetAllLabels(ctx context.Context) ([]string, error)
+	// ListRetiredBooks returns books that were retired (IsReadable=false)
+	ListRetiredBooks(ctx context.Context) ([]models.Book, error)
+	// RetireBook hides a book from reading lists; its history stays in statistics
+	RetireBook(ctx context.Context, id string) error
+	// RestoreBook makes a retired book readable again
+	RestoreBook(ctx context.Context, id string) error
+	// RenameBook renames a book. Events reference books by ID, so history follows the new name.
+	RenameBook(ctx context.Context, id, newName string) error
+	// UpdateBookMetadata replaces the optional details of a book (author, ISBN, series, cover, age, notes).
+	// Returns an error wrapping ErrNotFound if there is no such book.
+	UpdateBookMetadata(ctx context.Context, id string, meta models.BookMetadata) error
+	// DeleteBook removes a book that has no reading events.
+	// Books with history cannot be deleted and should be retired instead.
+	DeleteBook(ctx context.Context, id string) error
 
 	// Participant operations
+	// ListParticipants returns active (non-archived) participants ordered by name
 	ListParticipants(ctx context.Context) ([]models.Participant, error)
+	// GetParticipantByName looks up a participant (active or archived) by its exact name.
+	// Returns an error wrapping ErrNotFound if there is no such participant.
+	GetParticipantByName(ctx context.Context, name string) (models.Participant, error)
+	// CreateParticipant adds a new participant and returns its generated ID; fails if the name is already taken
+	CreateParticipant(ctx context.Context, name string, isParent bool) (string, error)
+	// UpdateParticipant renames a participant and/or changes its parent flag.
+	// Events reference participants by ID, so history follows the new name.
+	UpdateParticipant(ctx context.Context, id, newName string, isParent bool) error
+	// ArchiveParticipant hides a participant from rotation and selection lists.
+	// Reading history is kept and still counted in statistics.
+	ArchiveParticipant(ctx context.Context, id string) error
+
+	// Absences
+	// SetAbsence marks a participant as away until the given time, replacing an earlier absence
+	SetAbsence(ctx context.Context, participantID string, until time.Time) error
+	// ClearAbsence ends a participant's absence.
+	// Returns an error wrapping ErrNotFound if the participant is not away.
+	ClearAbsence(ctx context.Context, participantID string) error
+	// ListAbsences returns absences still running at the given time, ordered by participant name
+	ListAbsences(ctx context.Context, at time.Time) ([]models.Absence, error)
+
+	// Reading goals
+	// SetGoal sets a participant's reading goal, replacing an earlier one.
+	// Returns an error wrapping ErrNotFound if the participant does not exist.
+	SetGoal(ctx context.Context, participantID string, target int, period string) error
+	// ClearGoal removes a participant's reading goal.
+	// Returns an error wrapping ErrNotFound if the participant has no goal.
+	ClearGoal(ctx context.Context, participantID string) error
+	// ListGoals returns the goals of active participants, ordered by participant name
+	ListGoals(ctx context.Context) ([]models.ReadingGoal, error)
+
+	// Badges
+	// AddBadge stores an unlocked badge; ParticipantName is ignored
+	AddBadge(ctx context.Context, badge models.Badge) error
+	// ListBadges returns unlocked badges in unlock order, for one participant or for everyone if participantID is empty
+	ListBadges(ctx context.Context, participantID string) ([]models.Badge, error)
 
 	// Event operations
-	CreateEvent(ctx context.Context, date time.Time, bookName, participantName string) error
+	// CreateEvent records that one or more participants read a book together. Date, BookID,
+	// ParticipantIDs and the optional duration and progress fields of event are stored;
+	// ID and names are ignored. Returns the generated event ID.
+	CreateEvent(ctx context.Context, event models.Event) (string, error)
+	// GetEvent returns a single event with book and participant names resolved.
+	// Returns an error wrapping ErrNotFound if there is no such event.
+	GetEvent(ctx context.Context, id string) (models.Event, error)
+	// UpdateEvent replaces date, book, participants, duration and progress of the event with event.ID
+	UpdateEvent(ctx context.Context, event models.Event) error
+	// DeleteEvent removes a single event
+	DeleteEvent(ctx context.Context, id string) error
+	// FindEventOnDay returns an existing event for the same book attended by any of the given
+	// participants on the calendar day of date (in date's location), used to detect duplicate
+	// entries. Returns an error wrapping ErrNotFound if there is none.
+	FindEventOnDay(ctx context.Context, date time.Time, bookID string, participantIDs []string) (models.Event, error)
 	GetLastEvents(ctx context.Context, limit int) ([]models.Event, error)
 	// GetLastEventsFiltered returns events with optional date range and participant filters.
 	// Zero-value times mean no bound. Empty participant means no filter.
 	GetLastEventsFiltered(ctx context.Context, limit int, since, until time.Time, participant string) ([]models.Event, error)
 
+	// Audit log
+	// AddAuditEntry stores a record of a data change
+	AddAuditEntry(ctx context.Context, entry models.AuditEntry) error
+	// ListAuditEntries returns the most recent audit entries, newest first.
+	// If actorID is not 0, only changes made by that Telegram user are returned.
+	ListAuditEntries(ctx context.Context, limit int, actorID int64) ([]models.AuditEntry, error)
+
+	// API tokens
+	// CreateAPIToken stores a token (ID and CreatedAt are ignored) and returns its generated ID;
+	// fails if the owner already has a token with that name
+	CreateAPIToken(ctx context.Context, token models.APIToken) (string, error)
+	// GetAPITokenByHash looks up a token by the SHA-256 hash of its secret.
+	// Returns an error wrapping ErrNotFound if there is no such token.
+	GetAPITokenByHash(ctx context.Context, hash string) (models.APIToken, error)
+	// ListAPITokens returns the tokens of a Telegram user, ordered by name
+	ListAPITokens(ctx context.Context, ownerID int64) ([]models.APIToken, error)
+	// RevokeAPIToken deletes a token so it can no longer be used.
+	// Returns an error wrapping ErrNotFound if there is no such token.
+	RevokeAPIToken(ctx context.Context, id string) error
+
+	// Chat settings
+	// GetChatSettings returns the settings of a chat, or defaults (with ChatID set) if none were saved
+	GetChatSettings(ctx context.Context, chatID int64) (models.ChatSettings, error)
+	// SaveChatSettings stores the settings of settings.ChatID, replacing previous ones
+	SaveChatSettings(ctx context.Context, settings models.ChatSettings) error
+	// ListReminderChats returns the settings of chats with the daily reminder enabled
+	ListReminderChats(ctx context.Context) ([]models.ChatSettings, error)
+
+	// Scheduled jobs
+	// GetLastScheduledRun returns the occurrence a job last ran for, or zero time if it never ran
+	GetLastScheduledRun(ctx context.Context, job string) (time.Time, error)
+	// ClaimScheduledRun records that a job runs for the given occurrence and reports whether
+	// this caller claimed it. Of several instances claiming the same occurrence, exactly one wins.
+	ClaimScheduledRun(ctx context.Context, job string, scheduledFor time.Time) (bool, error)
+
 	// Statistics operations
 
 	// GetTopBooks returns top N books by read count within the specified time period
@@ -34,13 +157,36 @@ type Storage interface {
 	// If participantName is provided, returns statistics only for that participant
 	GetTopBooks(ctx context.Context, limit int, startDate, endDate time.Time, participantName string) ([]models.BookStat, error)
 
+	// GetSessionStats returns duration and progress totals for sessions in the period.
+	// If participantName is empty, sessions attended by any child are counted.
+	GetSessionStats(ctx context.Context, startDate, endDate time.Time, participantName string) (models.SessionStats, error)
+
+	// GetHourlyStats counts sessions per hour of the day, with hours taken in loc.
+	// Hours without sessions are omitted. Participant filtering works as in GetSessionStats.
+	GetHourlyStats(ctx context.Context, startDate, endDate time.Time, participantName string, loc *time.Location) ([]models.HourStat, error)
+
+	// GetDailyStats counts sessions per calendar day in loc, oldest first.
+	// Days without sessions are omitted. Participant filtering works as in GetSessionStats.
+	GetDailyStats(ctx context.Context, startDate, endDate time.Time, participantName string, loc *time.Location) ([]models.DayStat, error)
+
+	// GetWeeklyReaderStats counts, for every child, the sessions attended per week (weeks start on Monday in loc).
+	// Weeks without sessions are omitted; results are ordered by week, then by participant name.
+	GetWeeklyReaderStats(ctx context.Context, startDate, endDate time.Time, loc *time.Location) ([]models.WeekReaderStat, error)
+
+	// GetReaderActivity returns, for every active participant ordered by name, the number
+	// of events attended since the given time and the date of their last event
+	GetReaderActivity(ctx context.Context, since time.Time) ([]models.ReaderActivity, error)
+
+	// GetStreaks returns consecutive reading day streaks for every active participant, ordered by name.
+	// Days are calendar days in the location of today.
+	GetStreaks(ctx context.Context, today time.Time) ([]models.Streak, error)
+
 	// GetRarelyReadBooks returns books ordered by how long ago they were last read
 	// If childrenOnly is true, only considers reads by children (IsParent=false)
 	// If childrenOnly is false, considers reads by all participants
-	// If label is not empty, only returns books with that label
-	// If excludeLabels is not empty, excludes books that have any of the specified labels
+	// If filter is not nil, only returns books whose labels match it
 	// Books never read are included with DaysSinceLastRead=-1
-	GetRarelyReadBooks(ctx context.Context, limit int, childrenOnly bool, label string, excludeLabels []string) ([]models.RareBookStat, error)
+	GetRarelyReadBooks(ctx context.Context, limit int, childrenOnly bool, filter labels.Expr) ([]models.RareBookStat, error)
 
 	// GetDetailedBookStats returns per-participant reading statistics for books.
 	// For each book × participant combination: read count and last read date.
@@ -54,6 +200,10 @@ type Storage interface {
 	// Results ordered by participant_name ASC, read_count DESC, book_name ASC.
 	GetParticipantStats(ctx context.Context, startDate, endDate time.Time, bookName, participantName string) ([]models.ParticipantBookStat, error)
 
+	// GetNewBooks returns books registered within the period, oldest first.
+	// Books created before registration dates were recorded are never returned.
+	GetNewBooks(ctx context.Context, startDate, endDate time.Time) ([]models.Book, error)
+
 	// Lifecycle
 	Initialize(ctx context.Context) error
 	Close() error
@@ -0,0 +1,37 @@
+package storage
+
+import "time"
+
+// Streaks computes the current and longest runs of consecutive days from distinct
+// reading days sorted ascending. The current streak counts up to today, or up to
+// yesterday if nothing was read today yet, and is zero otherwise.
+func Streaks(days []time.Time, today time.Time) (current, longest int) {
+	run := 0
+	var previous time.Time
+	for i, day := range days {
+		if i > 0 && calendarDay(previous).AddDate(0, 0, 1).Equal(calendarDay(day)) {
+			run++
+		} else {
+			run = 1
+		}
+		if run > longest {
+			longest = run
+		}
+		previous = day
+	}
+
+	if len(days) == 0 {
+		return 0, longest
+	}
+	last := calendarDay(days[len(days)-1])
+	today = calendarDay(today)
+	if last.Equal(today) || last.AddDate(0, 0, 1).Equal(today) {
+		current = run
+	}
+	return current, longest
+}
+
+// calendarDay truncates t to midnight in its own location
+func calendarDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
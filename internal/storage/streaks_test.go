@@ -0,0 +1,30 @@
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStreaks(t *testing.T) {
+	today := time.Date(2026, 10, 16, 20, 0, 0, 0, time.UTC)
+	day := func(d int) time.Time { return time.Date(2026, 10, d, 0, 0, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name             string
+		days             []time.Time
+		current, longest int
+	}{
+		{name: "never read"},
+		{name: "read today", days: []time.Time{day(14), day(15), day(16)}, current: 3, longest: 3},
+		{name: "not yet today", days: []time.Time{day(14), day(15)}, current: 2, longest: 2},
+		{name: "broken streak", days: []time.Time{day(10), day(11), day(12), day(14)}, current: 0, longest: 3},
+		{name: "new streak after gap", days: []time.Time{day(1), day(2), day(3), day(4), day(15), day(16)}, current: 2, longest: 4},
+	}
+
+	for _, tt := range tests {
+		current, longest := Streaks(tt.days, today)
+		if current != tt.current || longest != tt.longest {
+			t.Errorf("%s: got current %d, longest %d, want %d, %d", tt.name, current, longest, tt.current, tt.longest)
+		}
+	}
+}
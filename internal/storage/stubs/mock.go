@@ -2,19 +2,34 @@ package stubs
 
 import (
 	"context"
+	"fmt"
+	"library/internal/labels"
 	"library/internal/models"
+	"library/internal/storage"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/google/uuid"
 )
 
-// MockDB is an in-memory implementation of the Database interface for testing
+// MockDB is an in-memory implementation of the Database interface for testing.
+// Books and participants are keyed by ID; events store IDs and get names resolved on read.
 type MockDB struct {
 	mu           sync.RWMutex
 	books        map[string]models.Book
 	participants map[string]models.Participant
 	events       []models.Event
+	chatSettings map[int64]models.ChatSettings
+	absences     map[string]time.Time          // participant ID -> until
+	runs         map[string]time.Time          // job -> last scheduled occurrence
+	booksAdded   map[string]time.Time          // book ID -> when it was created with CreateBook
+	goals        map[string]models.ReadingGoal // participant ID -> goal
+	badges       []models.Badge
+	audit        []models.AuditEntry
+	tokens       map[string]models.APIToken // token ID -> token
 }
 
 // NewMockDB creates a new mock database
@@ -23,6 +38,12 @@ func NewMockDB() *MockDB {
 		books:        make(map[string]models.Book),
 		participants: make(map[string]models.Participant),
 		events:       make([]models.Event, 0),
+		chatSettings: make(map[int64]models.ChatSettings),
+		absences:     make(map[string]time.Time),
+		runs:         make(map[string]time.Time),
+		booksAdded:   make(map[string]time.Time),
+		goals:        make(map[string]models.ReadingGoal),
+		tokens:       make(map[string]models.APIToken),
 	}
 }
 
@@ -32,22 +53,10 @@ func (m *MockDB) Initialize(ctx context.Context) error {
 	defer m.mu.Unlock()
 
 	// Add default test participants
-	m.participants["Alice"] = models.Participant{
-		Name:     "Alice",
-		IsParent: false,
-	}
-	m.participants["Bob"] = models.Participant{
-		Name:     "Bob",
-		IsParent: false,
-	}
-	m.participants["Mom"] = models.Participant{
-		Name:     "Mom",
-		IsParent: true,
-	}
-	m.participants["Dad"] = models.Participant{
-		Name:     "Dad",
-		IsParent: true,
-	}
+	m.addParticipant("Alice", false)
+	m.addParticipant("Bob", false)
+	m.addParticipant("Mom", true)
+	m.addParticipant("Dad", true)
 
 	// Add default test books
 	testBooks := []string{
@@ -64,27 +73,125 @@ func (m *MockDB) Initialize(ctx context.Context) error {
 	}
 
 	for _, bookName := range testBooks {
-		m.books[bookName] = models.Book{
-			Name:       bookName,
-			IsReadable: true,
-			Labels:     []string{},
-		}
+		m.addBook(bookName)
 	}
 
 	return nil
 }
 
-// CreateBook creates a new book and returns the book name as identifier
+// addBook inserts a readable book and returns its ID; caller must hold the lock
+func (m *MockDB) addBook(name string) string {
+	id := uuid.NewString()
+	m.books[id] = models.Book{
+		ID:         id,
+		Name:       name,
+		IsReadable: true,
+		Labels:     []string{},
+	}
+	return id
+}
+
+// addParticipant inserts an active participant and returns its ID; caller must hold the lock
+func (m *MockDB) addParticipant(name string, isParent bool) string {
+	id := uuid.NewString()
+	m.participants[id] = models.Participant{
+		ID:       id,
+		Name:     name,
+		IsParent: isParent,
+	}
+	return id
+}
+
+// bookByName finds a book by name; caller must hold the lock
+func (m *MockDB) bookByName(name string) (models.Book, bool) {
+	for _, book := range m.books {
+		if book.Name == name {
+			return book, true
+		}
+	}
+	return models.Book{}, false
+}
+
+// participantByName finds a participant by name; caller must hold the lock
+func (m *MockDB) participantByName(name string) (models.Participant, bool) {
+	for _, p := range m.participants {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return models.Participant{}, false
+}
+
+// hasChild reports whether any of the participants is a child; caller must hold the lock
+func (m *MockDB) hasChild(participantIDs []string) bool {
+	for _, id := range participantIDs {
+		if p, exists := m.participants[id]; exists && !p.IsParent {
+			return true
+		}
+	}
+	return false
+}
+
+// containsAny reports whether the two ID lists share an element
+func containsAny(ids, wanted []string) bool {
+	for _, id := range ids {
+		for _, w := range wanted {
+			if id == w {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// containsName reports whether names contains name, ignoring case and surrounding spaces
+func containsName(names []string, name string) bool {
+	for _, n := range names {
+		if strings.EqualFold(strings.TrimSpace(n), strings.TrimSpace(name)) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolvedEvents returns a copy of all events with book and participant names filled in; caller must hold the lock
+func (m *MockDB) resolvedEvents() []models.Event {
+	events := make([]models.Event, len(m.events))
+	for i, event := range m.events {
+		event.BookName = m.books[event.BookID].Name
+		event.ParticipantIDs = append([]string(nil), event.ParticipantIDs...)
+		event.ParticipantNames = make([]string, len(event.ParticipantIDs))
+		for j, id := range event.ParticipantIDs {
+			event.ParticipantNames[j] = m.participants[id].Name
+		}
+		events[i] = event
+	}
+	return events
+}
+
+// CreateBook creates a new book and returns its generated ID
 func (m *MockDB) CreateBook(ctx context.Context, name string) (string, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	m.books[name] = models.Book{
-		Name:       name,
-		IsReadable: true,
-		Labels:     []string{},
+	if _, taken := m.bookByName(name); taken {
+		return "", fmt.Errorf("book %q already exists", name)
+	}
+	id := m.addBook(name)
+	m.booksAdded[id] = time.Now()
+	return id, nil
+}
+
+// GetBookByName returns the book with the given name, readable or retired
+func (m *MockDB) GetBookByName(ctx context.Context, name string) (models.Book, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	book, ok := m.bookByName(name)
+	if !ok {
+		return models.Book{}, fmt.Errorf("book %q %w", name, storage.ErrNotFound)
 	}
-	return name, nil
+	return book, nil
 }
 
 // ListReadableBooks returns all readable books
@@ -108,11 +215,11 @@ func (m *MockDB) ListReadableBooks(ctx context.Context) ([]models.Book, error) {
 }
 
 // AddLabelToBook adds a label to a book's labels array
-func (m *MockDB) AddLabelToBook(ctx context.Context, bookName string, label string) error {
+func (m *MockDB) AddLabelToBook(ctx context.Context, bookID string, label string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	book, exists := m.books[bookName]
+	book, exists := m.books[bookID]
 	if !exists {
 		return nil // Book not found, silently ignore
 	}
@@ -126,135 +233,678 @@ func (m *MockDB) AddLabelToBook(ctx context.Context, bookName string, label stri
 
 	// Add label
 	book.Labels = append(book.Labels, label)
-	m.books[bookName] = book
+	m.books[bookID] = book
+
+	return nil
+}
+
+// AddLabelToBooks adds a label to several books
+func (m *MockDB) AddLabelToBooks(ctx context.Context, bookIDs []string, label string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, id := range bookIDs {
+		book, exists := m.books[id]
+		if !exists || slices.Contains(book.Labels, label) {
+			continue // Book not found or already labeled, silently ignore (same as AddLabelToBook)
+		}
+		book.Labels = append(book.Labels, label)
+		m.books[id] = book
+	}
+	return nil
+}
+
+// RemoveLabelFromBook removes a label from a book
+func (m *MockDB) RemoveLabelFromBook(ctx context.Context, bookID string, label string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	book, exists := m.books[bookID]
+	if !exists {
+		return nil // Book not found, silently ignore (same as AddLabelToBook)
+	}
+
+	var labels []string
+	for _, existing := range book.Labels {
+		if existing != label {
+			labels = append(labels, existing)
+		}
+	}
+	book.Labels = labels
+	m.books[bookID] = book
+	return nil
+}
+
+// RemoveLabelFromBooks removes a label from several books
+func (m *MockDB) RemoveLabelFromBooks(ctx context.Context, bookIDs []string, label string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, id := range bookIDs {
+		book, exists := m.books[id]
+		if !exists {
+			continue // Book not found, silently ignore (same as RemoveLabelFromBook)
+		}
+		book.Labels = slices.DeleteFunc(slices.Clone(book.Labels), func(existing string) bool { return existing == label })
+		m.books[id] = book
+	}
+	return nil
+}
+
+// RenameLabel renames a label on every book
+func (m *MockDB) RenameLabel(ctx context.Context, oldLabel, newLabel string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.labelUsed([]string{newLabel}) {
+		return fmt.Errorf("label %q already exists", newLabel)
+	}
+	return m.replaceLabels([]string{oldLabel}, newLabel)
+}
+
+// MergeLabels replaces every source label with target on every book
+func (m *MockDB) MergeLabels(ctx context.Context, sources []string, target string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.replaceLabels(sources, target)
+}
+
+// replaceLabels replaces the labels in from with to, keeping each label once per book.
+// The caller must hold the lock.
+func (m *MockDB) replaceLabels(from []string, to string) error {
+	if !m.labelUsed(from) {
+		return fmt.Errorf("label %q %w", strings.Join(from, ", "), storage.ErrNotFound)
+	}
+
+	for id, book := range m.books {
+		var labels []string
+		for _, label := range book.Labels {
+			if slices.Contains(from, label) {
+				label = to
+			}
+			if !slices.Contains(labels, label) {
+				labels = append(labels, label)
+			}
+		}
+		book.Labels = labels
+		m.books[id] = book
+	}
+	return nil
+}
+
+// labelUsed reports whether any book has one of the labels. The caller must hold the lock.
+func (m *MockDB) labelUsed(labels []string) bool {
+	for _, book := range m.books {
+		for _, label := range book.Labels {
+			if slices.Contains(labels, label) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// GetBooksWithoutLabel returns books that don't have the specified label
+func (m *MockDB) GetBooksWithoutLabel(ctx context.Context, label string) ([]models.Book, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var books []models.Book
+	for _, book := range m.books {
+		if !book.IsReadable {
+			continue
+		}
+
+		// Check if book has the label
+		hasLabel := false
+		for _, bookLabel := range book.Labels {
+			if bookLabel == label {
+				hasLabel = true
+				break
+			}
+		}
+
+		// Add book if it doesn't have the label
+		if !hasLabel {
+			books = append(books, book)
+		}
+	}
+
+	// Sort by name
+	sort.Slice(books, func(i, j int) bool {
+		return books[i].Name < books[j].Name
+	})
+
+	return books, nil
+}
+
+// GetBooksByLabel returns readable books whose labels match filter
+func (m *MockDB) GetBooksByLabel(ctx context.Context, filter labels.Expr) ([]models.Book, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var books []models.Book
+	for _, book := range m.books {
+		if book.IsReadable && (filter == nil || filter.Match(book.Labels)) {
+			books = append(books, book)
+		}
+	}
+
+	// Sort by name
+	sort.Slice(books, func(i, j int) bool {
+		return books[i].Name < books[j].Name
+	})
+
+	return books, nil
+}
+
+// GetAllLabels returns all unique labels across all books
+func (m *MockDB) GetAllLabels(ctx context.Context) ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	labelSet := make(map[string]bool)
+	for _, book := range m.books {
+		for _, label := range book.Labels {
+			labelSet[label] = true
+		}
+	}
+
+	var labels []string
+	for label := range labelSet {
+		labels = append(labels, label)
+	}
+
+	// Sort alphabetically
+	sort.Strings(labels)
+
+	return labels, nil
+}
+
+// ListRetiredBooks returns all books that are no longer readable
+func (m *MockDB) ListRetiredBooks(ctx context.Context) ([]models.Book, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var books []models.Book
+	for _, book := range m.books {
+		if !book.IsReadable {
+			books = append(books, book)
+		}
+	}
+
+	sort.Slice(books, func(i, j int) bool {
+		return books[i].Name < books[j].Name
+	})
+
+	return books, nil
+}
+
+// setBookReadable updates the IsReadable flag of an existing book
+func (m *MockDB) setBookReadable(id string, readable bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	book, exists := m.books[id]
+	if !exists {
+		return fmt.Errorf("book %s %w", id, storage.ErrNotFound)
+	}
+
+	book.IsReadable = readable
+	m.books[id] = book
+	return nil
+}
+
+// RetireBook marks a book as not readable
+func (m *MockDB) RetireBook(ctx context.Context, id string) error {
+	return m.setBookReadable(id, false)
+}
+
+// RestoreBook marks a retired book as readable again
+func (m *MockDB) RestoreBook(ctx context.Context, id string) error {
+	return m.setBookReadable(id, true)
+}
+
+// RenameBook changes the name of a book; events keep pointing at the same ID
+func (m *MockDB) RenameBook(ctx context.Context, id, newName string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	book, exists := m.books[id]
+	if !exists {
+		return fmt.Errorf("book %s %w", id, storage.ErrNotFound)
+	}
+	if _, taken := m.bookByName(newName); taken {
+		return fmt.Errorf("book %q already exists", newName)
+	}
+
+	book.Name = newName
+	m.books[id] = book
+	return nil
+}
+
+// UpdateBookMetadata replaces the optional details of a book
+func (m *MockDB) UpdateBookMetadata(ctx context.Context, id string, meta models.BookMetadata) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	book, exists := m.books[id]
+	if !exists {
+		return fmt.Errorf("book %s %w", id, storage.ErrNotFound)
+	}
+
+	book.BookMetadata = meta
+	m.books[id] = book
+	return nil
+}
+
+// DeleteBook removes a book without reading history
+func (m *MockDB) DeleteBook(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.books[id]; !exists {
+		return fmt.Errorf("book %s %w", id, storage.ErrNotFound)
+	}
+
+	eventCount := 0
+	for _, event := range m.events {
+		if event.BookID == id {
+			eventCount++
+		}
+	}
+	if eventCount > 0 {
+		return fmt.Errorf("book has %d reading events; retire it instead", eventCount)
+	}
+
+	delete(m.books, id)
+	return nil
+}
+
+// ListParticipants returns all participants
+func (m *MockDB) ListParticipants(ctx context.Context) ([]models.Participant, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var participants []models.Participant
+	for _, p := range m.participants {
+		if p.IsArchived {
+			continue
+		}
+		participants = append(participants, p)
+	}
+
+	// Sort by name
+	sort.Slice(participants, func(i, j int) bool {
+		return participants[i].Name < participants[j].Name
+	})
+
+	return participants, nil
+}
+
+// GetParticipantByName returns the participant with the given name, active or archived
+func (m *MockDB) GetParticipantByName(ctx context.Context, name string) (models.Participant, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	participant, ok := m.participantByName(name)
+	if !ok {
+		return models.Participant{}, fmt.Errorf("participant %q %w", name, storage.ErrNotFound)
+	}
+	return participant, nil
+}
+
+// CreateParticipant adds a new participant and returns its generated ID
+func (m *MockDB) CreateParticipant(ctx context.Context, name string, isParent bool) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, taken := m.participantByName(name); taken {
+		return "", fmt.Errorf("participant %q already exists", name)
+	}
+	return m.addParticipant(name, isParent), nil
+}
+
+// UpdateParticipant renames a participant and/or changes its parent flag
+func (m *MockDB) UpdateParticipant(ctx context.Context, id, newName string, isParent bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	participant, exists := m.participants[id]
+	if !exists {
+		return fmt.Errorf("participant %s %w", id, storage.ErrNotFound)
+	}
+
+	if newName != participant.Name {
+		if _, taken := m.participantByName(newName); taken {
+			return fmt.Errorf("participant %q already exists", newName)
+		}
+	}
+
+	participant.Name = newName
+	participant.IsParent = isParent
+	m.participants[id] = participant
+	return nil
+}
+
+// ArchiveParticipant marks a participant as archived
+func (m *MockDB) ArchiveParticipant(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	participant, exists := m.participants[id]
+	if !exists {
+		return fmt.Errorf("participant %s %w", id, storage.ErrNotFound)
+	}
+
+	participant.IsArchived = true
+	m.participants[id] = participant
+	return nil
+}
+
+// SetAbsence marks a participant as away until the given time
+func (m *MockDB) SetAbsence(ctx context.Context, participantID string, until time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.participants[participantID]; !ok {
+		return fmt.Errorf("participant %s %w", participantID, storage.ErrNotFound)
+	}
+	m.absences[participantID] = until
+	return nil
+}
+
+// ClearAbsence ends a participant's absence
+func (m *MockDB) ClearAbsence(ctx context.Context, participantID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.absences[participantID]; !ok {
+		return fmt.Errorf("absence of %s %w", participantID, storage.ErrNotFound)
+	}
+	delete(m.absences, participantID)
+	return nil
+}
+
+// SetGoal sets a participant's reading goal
+func (m *MockDB) SetGoal(ctx context.Context, participantID string, target int, period string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.participants[participantID]; !ok {
+		return fmt.Errorf("participant %s %w", participantID, storage.ErrNotFound)
+	}
+	m.goals[participantID] = models.ReadingGoal{ParticipantID: participantID, Target: target, Period: period}
+	return nil
+}
+
+// ClearGoal removes a participant's reading goal
+func (m *MockDB) ClearGoal(ctx context.Context, participantID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.goals[participantID]; !ok {
+		return fmt.Errorf("goal of %s %w", participantID, storage.ErrNotFound)
+	}
+	delete(m.goals, participantID)
+	return nil
+}
+
+// ListGoals returns the goals of active participants, ordered by participant name
+func (m *MockDB) ListGoals(ctx context.Context) ([]models.ReadingGoal, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var goals []models.ReadingGoal
+	for _, p := range m.sortedParticipants() {
+		goal, ok := m.goals[p.ID]
+		if !ok || p.IsArchived {
+			continue
+		}
+		goal.ParticipantName = p.Name
+		goals = append(goals, goal)
+	}
+	return goals, nil
+}
+
+// AddBadge stores an unlocked badge
+func (m *MockDB) AddBadge(ctx context.Context, badge models.Badge) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.participants[badge.ParticipantID]; !ok {
+		return fmt.Errorf("participant %s %w", badge.ParticipantID, storage.ErrNotFound)
+	}
+	badge.ParticipantName = ""
+	m.badges = append(m.badges, badge)
+	return nil
+}
+
+// ListBadges returns unlocked badges in unlock order, optionally for one participant
+func (m *MockDB) ListBadges(ctx context.Context, participantID string) ([]models.Badge, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var badges []models.Badge
+	for _, badge := range m.badges {
+		if participantID != "" && badge.ParticipantID != participantID {
+			continue
+		}
+		badge.ParticipantName = m.participants[badge.ParticipantID].Name
+		badges = append(badges, badge)
+	}
+	sort.SliceStable(badges, func(i, j int) bool {
+		return badges[i].UnlockedAt.Before(badges[j].UnlockedAt)
+	})
+	return badges, nil
+}
+
+// CreateAPIToken stores a token and returns its generated ID
+func (m *MockDB) CreateAPIToken(ctx context.Context, token models.APIToken) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, existing := range m.tokens {
+		if existing.OwnerID == token.OwnerID && existing.Name == token.Name {
+			return "", fmt.Errorf("token %q already exists", token.Name)
+		}
+	}
+	token.ID = uuid.NewString()
+	token.CreatedAt = time.Now().UTC()
+	m.tokens[token.ID] = token
+	return token.ID, nil
+}
+
+// GetAPITokenByHash looks up a token by the SHA-256 hash of its secret
+func (m *MockDB) GetAPITokenByHash(ctx context.Context, hash string) (models.APIToken, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, token := range m.tokens {
+		if token.Hash == hash {
+			return token, nil
+		}
+	}
+	return models.APIToken{}, fmt.Errorf("token %w", storage.ErrNotFound)
+}
+
+// ListAPITokens returns the tokens of a Telegram user, ordered by name
+func (m *MockDB) ListAPITokens(ctx context.Context, ownerID int64) ([]models.APIToken, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var tokens []models.APIToken
+	for _, token := range m.tokens {
+		if token.OwnerID == ownerID {
+			tokens = append(tokens, token)
+		}
+	}
+	sort.Slice(tokens, func(i, j int) bool {
+		return tokens[i].Name < tokens[j].Name
+	})
+	return tokens, nil
+}
+
+// RevokeAPIToken deletes a token
+func (m *MockDB) RevokeAPIToken(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.tokens[id]; !ok {
+		return fmt.Errorf("token %s %w", id, storage.ErrNotFound)
+	}
+	delete(m.tokens, id)
+	return nil
+}
+
+// AddAuditEntry stores a record of a data change
+func (m *MockDB) AddAuditEntry(ctx context.Context, entry models.AuditEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
+	m.audit = append(m.audit, entry)
 	return nil
 }
 
-// GetBooksWithoutLabel returns books that don't have the specified label
-func (m *MockDB) GetBooksWithoutLabel(ctx context.Context, label string) ([]models.Book, error) {
+// ListAuditEntries returns the most recent audit entries, newest first, optionally for one actor
+func (m *MockDB) ListAuditEntries(ctx context.Context, limit int, actorID int64) ([]models.AuditEntry, error) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	var books []models.Book
-	for _, book := range m.books {
-		if !book.IsReadable {
+	var entries []models.AuditEntry
+	for i := len(m.audit) - 1; i >= 0 && len(entries) < limit; i-- {
+		if actorID != 0 && m.audit[i].ActorID != actorID {
 			continue
 		}
-
-		// Check if book has the label
-		hasLabel := false
-		for _, bookLabel := range book.Labels {
-			if bookLabel == label {
-				hasLabel = true
-				break
-			}
-		}
-
-		// Add book if it doesn't have the label
-		if !hasLabel {
-			books = append(books, book)
-		}
+		entries = append(entries, m.audit[i])
 	}
-
-	// Sort by name
-	sort.Slice(books, func(i, j int) bool {
-		return books[i].Name < books[j].Name
-	})
-
-	return books, nil
+	return entries, nil
 }
 
-// GetBooksByLabel returns books that have the specified label
-func (m *MockDB) GetBooksByLabel(ctx context.Context, label string) ([]models.Book, error) {
+// ListAbsences returns absences still running at the given time
+func (m *MockDB) ListAbsences(ctx context.Context, at time.Time) ([]models.Absence, error) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	var books []models.Book
-	for _, book := range m.books {
-		if !book.IsReadable {
+	var absences []models.Absence
+	for _, p := range m.sortedParticipants() {
+		until, ok := m.absences[p.ID]
+		if !ok || !until.After(at) {
 			continue
 		}
+		absences = append(absences, models.Absence{ParticipantID: p.ID, ParticipantName: p.Name, Until: until})
+	}
+	return absences, nil
+}
 
-		for _, bookLabel := range book.Labels {
-			if bookLabel == label {
-				books = append(books, book)
-				break
-			}
-		}
+// CreateEvent creates a new reading event and returns its generated ID
+func (m *MockDB) CreateEvent(ctx context.Context, event models.Event) (string, error) {
+	if len(event.ParticipantIDs) == 0 {
+		return "", fmt.Errorf("event needs at least one participant")
 	}
 
-	// Sort by name
-	sort.Slice(books, func(i, j int) bool {
-		return books[i].Name < books[j].Name
-	})
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
-	return books, nil
-}
+	event.ID = uuid.NewString()
+	m.events = append(m.events, storedEvent(event))
 
-// GetAllLabels returns all unique labels across all books
-func (m *MockDB) GetAllLabels(ctx context.Context) ([]string, error) {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
+	return event.ID, nil
+}
 
-	labelSet := make(map[string]bool)
-	for _, book := range m.books {
-		for _, label := range book.Labels {
-			labelSet[label] = true
-		}
+// storedEvent strips resolved names and copies slices so callers can't modify stored events
+func storedEvent(event models.Event) models.Event {
+	return models.Event{
+		ID:              event.ID,
+		Date:            event.Date,
+		BookID:          event.BookID,
+		ParticipantIDs:  append([]string(nil), event.ParticipantIDs...),
+		DurationMinutes: event.DurationMinutes,
+		Progress:        event.Progress,
+		ProgressUnit:    event.ProgressUnit,
 	}
+}
 
-	var labels []string
-	for label := range labelSet {
-		labels = append(labels, label)
+// eventIndex returns the position of the event in m.events or -1; caller must hold the lock
+func (m *MockDB) eventIndex(id string) int {
+	for i, event := range m.events {
+		if event.ID == id {
+			return i
+		}
 	}
-
-	// Sort alphabetically
-	sort.Strings(labels)
-
-	return labels, nil
+	return -1
 }
 
-// ListParticipants returns all participants
-func (m *MockDB) ListParticipants(ctx context.Context) ([]models.Participant, error) {
+// GetEvent returns a single event by ID
+func (m *MockDB) GetEvent(ctx context.Context, id string) (models.Event, error) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	var participants []models.Participant
-	for _, p := range m.participants {
-		participants = append(participants, p)
+	i := m.eventIndex(id)
+	if i < 0 {
+		return models.Event{}, fmt.Errorf("event %s %w", id, storage.ErrNotFound)
 	}
+	return m.resolvedEvents()[i], nil
+}
 
-	// Sort by name
-	sort.Slice(participants, func(i, j int) bool {
-		return participants[i].Name < participants[j].Name
-	})
+// UpdateEvent replaces date, book and participants of an existing event
+func (m *MockDB) UpdateEvent(ctx context.Context, event models.Event) error {
+	if len(event.ParticipantIDs) == 0 {
+		return fmt.Errorf("event needs at least one participant")
+	}
 
-	return participants, nil
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	i := m.eventIndex(event.ID)
+	if i < 0 {
+		return fmt.Errorf("event %s %w", event.ID, storage.ErrNotFound)
+	}
+
+	m.events[i] = storedEvent(event)
+	return nil
 }
 
-// CreateEvent creates a new reading event
-func (m *MockDB) CreateEvent(ctx context.Context, date time.Time, bookName, participantName string) error {
+// DeleteEvent removes a single event
+func (m *MockDB) DeleteEvent(ctx context.Context, id string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	m.events = append(m.events, models.Event{
-		Date:            date,
-		BookName:        bookName,
-		ParticipantName: participantName,
-	})
+	i := m.eventIndex(id)
+	if i < 0 {
+		return fmt.Errorf("event %s %w", id, storage.ErrNotFound)
+	}
 
+	m.events = append(m.events[:i], m.events[i+1:]...)
 	return nil
 }
 
+// FindEventOnDay returns an event for the same book attended by any of the participants on the day of date
+func (m *MockDB) FindEventOnDay(ctx context.Context, date time.Time, bookID string, participantIDs []string) (models.Event, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	year, month, day := date.Date()
+	for _, event := range m.resolvedEvents() {
+		if event.BookID != bookID || !containsAny(event.ParticipantIDs, participantIDs) {
+			continue
+		}
+		y, mo, d := event.Date.In(date.Location()).Date()
+		if y == year && mo == month && d == day {
+			return event, nil
+		}
+	}
+	return models.Event{}, fmt.Errorf("event on %s %w", date.Format("2006-01-02"), storage.ErrNotFound)
+}
+
 // GetLastEvents returns the last N events
 func (m *MockDB) GetLastEvents(ctx context.Context, limit int) ([]models.Event, error) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
 	// Sort events by date descending
-	sortedEvents := make([]models.Event, len(m.events))
-	copy(sortedEvents, m.events)
+	sortedEvents := m.resolvedEvents()
 	sort.Slice(sortedEvents, func(i, j int) bool {
 		return sortedEvents[i].Date.After(sortedEvents[j].Date)
 	})
@@ -270,8 +920,7 @@ func (m *MockDB) GetLastEventsFiltered(ctx context.Context, limit int, since, un
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	sortedEvents := make([]models.Event, len(m.events))
-	copy(sortedEvents, m.events)
+	sortedEvents := m.resolvedEvents()
 	sort.Slice(sortedEvents, func(i, j int) bool {
 		return sortedEvents[i].Date.After(sortedEvents[j].Date)
 	})
@@ -284,7 +933,7 @@ func (m *MockDB) GetLastEventsFiltered(ctx context.Context, limit int, since, un
 		if !until.IsZero() && e.Date.After(until) {
 			continue
 		}
-		if participant != "" && !strings.EqualFold(strings.TrimSpace(e.ParticipantName), strings.TrimSpace(participant)) {
+		if participant != "" && !containsName(e.ParticipantNames, participant) {
 			continue
 		}
 		filtered = append(filtered, e)
@@ -306,24 +955,21 @@ func (m *MockDB) GetTopBooks(ctx context.Context, limit int, startDate, endDate
 	// Count books
 	bookCounts := make(map[string]int)
 
-	for _, event := range m.events {
+	for _, event := range m.resolvedEvents() {
 		// Filter by date range
 		if event.Date.Before(startDate) || event.Date.After(endDate) {
 			continue
 		}
 
-		// Filter by participant
+		// Filter by participant; a shared reading counts once
 		if participantName != "" {
 			// Specific participant
-			if event.ParticipantName != participantName {
+			if !containsAny(event.ParticipantNames, []string{participantName}) {
 				continue
 			}
-		} else {
+		} else if !m.hasChild(event.ParticipantIDs) {
 			// All children (not parents)
-			participant, exists := m.participants[event.ParticipantName]
-			if !exists || participant.IsParent {
-				continue
-			}
+			continue
 		}
 
 		bookCounts[event.BookName]++
@@ -354,12 +1000,228 @@ func (m *MockDB) GetTopBooks(ctx context.Context, limit int, startDate, endDate
 	return stats, nil
 }
 
+// GetSessionStats returns duration and progress totals for sessions in the period
+func (m *MockDB) GetSessionStats(ctx context.Context, startDate, endDate time.Time, participantName string) (models.SessionStats, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var stats models.SessionStats
+	for _, event := range m.sessionEvents(startDate, endDate, participantName) {
+		stats.Sessions++
+		if event.DurationMinutes > 0 {
+			stats.TimedSessions++
+			stats.TotalMinutes += event.DurationMinutes
+		}
+		switch event.ProgressUnit {
+		case models.ProgressPages:
+			stats.PageSessions++
+			stats.TotalPages += event.Progress
+		case models.ProgressChapters:
+			stats.ChapterSessions++
+			stats.TotalChapters += event.Progress
+		}
+	}
+
+	return stats, nil
+}
+
+// GetHourlyStats counts sessions per hour of the day in loc
+func (m *MockDB) GetHourlyStats(ctx context.Context, startDate, endDate time.Time, participantName string, loc *time.Location) ([]models.HourStat, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	counts := make(map[int]int)
+	for _, event := range m.sessionEvents(startDate, endDate, participantName) {
+		counts[event.Date.In(loc).Hour()]++
+	}
+
+	var stats []models.HourStat
+	for hour := 0; hour < 24; hour++ {
+		if counts[hour] > 0 {
+			stats = append(stats, models.HourStat{Hour: hour, ReadCount: counts[hour]})
+		}
+	}
+	return stats, nil
+}
+
+// GetDailyStats counts sessions per calendar day in loc
+func (m *MockDB) GetDailyStats(ctx context.Context, startDate, endDate time.Time, participantName string, loc *time.Location) ([]models.DayStat, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	counts := make(map[time.Time]int)
+	for _, event := range m.sessionEvents(startDate, endDate, participantName) {
+		counts[startOfDay(event.Date, loc)]++
+	}
+
+	var stats []models.DayStat
+	for day, count := range counts {
+		stats = append(stats, models.DayStat{Date: day, ReadCount: count})
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Date.Before(stats[j].Date) })
+	return stats, nil
+}
+
+// GetWeeklyReaderStats counts the sessions every child attended per week
+func (m *MockDB) GetWeeklyReaderStats(ctx context.Context, startDate, endDate time.Time, loc *time.Location) ([]models.WeekReaderStat, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	type key struct {
+		week          time.Time
+		participantID string
+	}
+	counts := make(map[key]int)
+	for _, event := range m.sessionEvents(startDate, endDate, "") {
+		day := startOfDay(event.Date, loc)
+		week := day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+		for _, id := range event.ParticipantIDs {
+			if p, exists := m.participants[id]; exists && !p.IsParent {
+				counts[key{week, id}]++
+			}
+		}
+	}
+
+	var stats []models.WeekReaderStat
+	for k, count := range counts {
+		stats = append(stats, models.WeekReaderStat{
+			WeekStart:       k.week,
+			ParticipantID:   k.participantID,
+			ParticipantName: m.participants[k.participantID].Name,
+			ReadCount:       count,
+		})
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if !stats[i].WeekStart.Equal(stats[j].WeekStart) {
+			return stats[i].WeekStart.Before(stats[j].WeekStart)
+		}
+		return stats[i].ParticipantName < stats[j].ParticipantName
+	})
+	return stats, nil
+}
+
+// startOfDay returns midnight of the calendar day of t in loc
+func startOfDay(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+}
+
+// sessionEvents returns events in the period attended by the participant, or by any child
+// if participantName is empty. Caller must hold the lock.
+func (m *MockDB) sessionEvents(startDate, endDate time.Time, participantName string) []models.Event {
+	var result []models.Event
+	for _, event := range m.resolvedEvents() {
+		if event.Date.Before(startDate) || event.Date.After(endDate) {
+			continue
+		}
+		if participantName != "" {
+			if !containsAny(event.ParticipantNames, []string{participantName}) {
+				continue
+			}
+		} else if !m.hasChild(event.ParticipantIDs) {
+			continue
+		}
+		result = append(result, event)
+	}
+	return result
+}
+
+// GetChatSettings returns saved settings for the chat, or defaults
+func (m *MockDB) GetChatSettings(ctx context.Context, chatID int64) (models.ChatSettings, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	settings, ok := m.chatSettings[chatID]
+	if !ok {
+		return models.ChatSettings{ChatID: chatID}, nil
+	}
+	settings.RotationSkipIDs = append([]string(nil), settings.RotationSkipIDs...)
+	return settings, nil
+}
+
+// SaveChatSettings stores settings for settings.ChatID
+func (m *MockDB) SaveChatSettings(ctx context.Context, settings models.ChatSettings) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	settings.RotationSkipIDs = append([]string(nil), settings.RotationSkipIDs...)
+	m.chatSettings[settings.ChatID] = settings
+	return nil
+}
+
+// ListReminderChats returns the settings of chats with the daily reminder enabled, ordered by chat ID
+func (m *MockDB) ListReminderChats(ctx context.Context) ([]models.ChatSettings, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var result []models.ChatSettings
+	for _, settings := range m.chatSettings {
+		if settings.ReminderEnabled {
+			settings.RotationSkipIDs = append([]string(nil), settings.RotationSkipIDs...)
+			result = append(result, settings)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ChatID < result[j].ChatID
+	})
+	return result, nil
+}
+
+// GetLastScheduledRun returns the occurrence a job last ran for, or zero time
+func (m *MockDB) GetLastScheduledRun(ctx context.Context, job string) (time.Time, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.runs[job], nil
+}
+
+// ClaimScheduledRun records that a job runs for the given occurrence and reports whether
+// this call claimed it; it fails to claim an occurrence that is not newer than the last run
+func (m *MockDB) ClaimScheduledRun(ctx context.Context, job string, scheduledFor time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !scheduledFor.After(m.runs[job]) {
+		return false, nil
+	}
+	m.runs[job] = scheduledFor
+	return true, nil
+}
+
+// GetReaderActivity returns read counts since the given time and last read dates per active participant
+func (m *MockDB) GetReaderActivity(ctx context.Context, since time.Time) ([]models.ReaderActivity, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var activity []models.ReaderActivity
+	for _, p := range m.sortedParticipants() {
+		if p.IsArchived {
+			continue
+		}
+		entry := models.ReaderActivity{ParticipantID: p.ID, ParticipantName: p.Name}
+		for _, event := range m.events {
+			if !containsAny(event.ParticipantIDs, []string{p.ID}) {
+				continue
+			}
+			if !event.Date.Before(since) {
+				entry.ReadsSince++
+			}
+			if entry.LastReadDate == nil || event.Date.After(*entry.LastReadDate) {
+				date := event.Date
+				entry.LastReadDate = &date
+			}
+		}
+		activity = append(activity, entry)
+	}
+	return activity, nil
+}
+
 // GetRarelyReadBooks returns books ordered by how long ago they were last read
 // If childrenOnly is true, only considers reads by children (IsParent=false)
 // If childrenOnly is false, considers reads by all participants
-// If label is not empty, only returns books with that label
+// If filter is not nil, only returns books whose labels match it
 // Books never read are included with DaysSinceLastRead=-1
-func (m *MockDB) GetRarelyReadBooks(ctx context.Context, limit int, childrenOnly bool, label string, excludeLabels []string) ([]models.RareBookStat, error) {
+func (m *MockDB) GetRarelyReadBooks(ctx context.Context, limit int, childrenOnly bool, filter labels.Expr) ([]models.RareBookStat, error) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
@@ -367,13 +1229,10 @@ func (m *MockDB) GetRarelyReadBooks(ctx context.Context, limit int, childrenOnly
 	lastReadDates := make(map[string]time.Time)
 
 	// Find last read date for each book
-	for _, event := range m.events {
+	for _, event := range m.resolvedEvents() {
 		// Filter by participant type if needed
-		if childrenOnly {
-			participant, exists := m.participants[event.ParticipantName]
-			if !exists || participant.IsParent {
-				continue
-			}
+		if childrenOnly && !m.hasChild(event.ParticipantIDs) {
+			continue
 		}
 
 		// Update last read date if this event is more recent
@@ -391,37 +1250,9 @@ func (m *MockDB) GetRarelyReadBooks(ctx context.Context, limit int, childrenOnly
 			continue
 		}
 
-		// Filter by label if specified
-		if label != "" {
-			hasLabel := false
-			for _, bookLabel := range book.Labels {
-				if bookLabel == label {
-					hasLabel = true
-					break
-				}
-			}
-			if !hasLabel {
-				continue
-			}
-		}
-
-		// Exclude books with any of the excluded labels
-		if len(excludeLabels) > 0 {
-			excluded := false
-			for _, bookLabel := range book.Labels {
-				for _, excl := range excludeLabels {
-					if bookLabel == excl {
-						excluded = true
-						break
-					}
-				}
-				if excluded {
-					break
-				}
-			}
-			if excluded {
-				continue
-			}
+		// Filter by labels if specified
+		if filter != nil && !filter.Match(book.Labels) {
+			continue
 		}
 
 		var stat models.RareBookStat
@@ -479,25 +1310,25 @@ func (m *MockDB) GetDetailedBookStats(ctx context.Context, startDate, endDate ti
 	counts := make(map[key]int)
 	lastDates := make(map[key]time.Time)
 
-	for _, event := range m.events {
+	for _, event := range m.resolvedEvents() {
 		if !startDate.IsZero() && event.Date.Before(startDate) {
 			continue
 		}
 		if !endDate.IsZero() && event.Date.After(endDate) {
 			continue
 		}
-		k := key{event.BookName, event.ParticipantName}
-		counts[k]++
-		if d, ok := lastDates[k]; !ok || event.Date.After(d) {
-			lastDates[k] = event.Date
+		// Every attendee is credited with the reading
+		for _, name := range event.ParticipantNames {
+			k := key{event.BookName, name}
+			counts[k]++
+			if d, ok := lastDates[k]; !ok || event.Date.After(d) {
+				lastDates[k] = event.Date
+			}
 		}
 	}
 
 	var stats []models.DetailedBookStat
 	for _, book := range m.books {
-		if !book.IsReadable {
-			continue
-		}
 		if bookName != "" && book.Name != bookName {
 			continue
 		}
@@ -539,15 +1370,16 @@ func (m *MockDB) GetParticipantStats(ctx context.Context, startDate, endDate tim
 	type key struct{ participant, book string }
 	counts := make(map[key]int)
 
-	for _, event := range m.events {
+	for _, event := range m.resolvedEvents() {
 		if !startDate.IsZero() && event.Date.Before(startDate) {
 			continue
 		}
 		if !endDate.IsZero() && event.Date.After(endDate) {
 			continue
 		}
-		k := key{event.ParticipantName, event.BookName}
-		counts[k]++
+		for _, name := range event.ParticipantNames {
+			counts[key{name, event.BookName}]++
+		}
 	}
 
 	var stats []models.ParticipantBookStat
@@ -556,9 +1388,6 @@ func (m *MockDB) GetParticipantStats(ctx context.Context, startDate, endDate tim
 			continue
 		}
 		for _, book := range m.books {
-			if !book.IsReadable {
-				continue
-			}
 			if bookName != "" && book.Name != bookName {
 				continue
 			}
@@ -584,6 +1413,71 @@ func (m *MockDB) GetParticipantStats(ctx context.Context, startDate, endDate tim
 	return stats, nil
 }
 
+// GetStreaks returns consecutive reading day streaks for every active participant
+func (m *MockDB) GetStreaks(ctx context.Context, today time.Time) ([]models.Streak, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	loc := today.Location()
+	daySet := make(map[string]map[time.Time]bool)
+	lastRead := make(map[string]time.Time)
+	for _, event := range m.events {
+		date := event.Date.In(loc)
+		day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, loc)
+		for _, id := range event.ParticipantIDs {
+			if daySet[id] == nil {
+				daySet[id] = make(map[time.Time]bool)
+			}
+			daySet[id][day] = true
+			if event.Date.After(lastRead[id]) {
+				lastRead[id] = event.Date
+			}
+		}
+	}
+
+	var streaks []models.Streak
+	for _, p := range m.sortedParticipants() {
+		if p.IsArchived {
+			continue
+		}
+		var days []time.Time
+		for day := range daySet[p.ID] {
+			days = append(days, day)
+		}
+		sort.Slice(days, func(i, j int) bool {
+			return days[i].Before(days[j])
+		})
+
+		streak := models.Streak{ParticipantID: p.ID, ParticipantName: p.Name}
+		streak.Current, streak.Longest = storage.Streaks(days, today)
+		if last, ok := lastRead[p.ID]; ok {
+			streak.LastReadDate = &last
+		}
+		streaks = append(streaks, streak)
+	}
+	return streaks, nil
+}
+
+// GetNewBooks returns books created within the period, oldest first
+func (m *MockDB) GetNewBooks(ctx context.Context, startDate, endDate time.Time) ([]models.Book, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var result []models.Book
+	for id, added := range m.booksAdded {
+		book, ok := m.books[id]
+		if !ok || added.Before(startDate) || added.After(endDate) {
+			continue
+		}
+		book.Labels = append([]string(nil), book.Labels...)
+		result = append(result, book)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return m.booksAdded[result[i].ID].Before(m.booksAdded[result[j].ID])
+	})
+	return result, nil
+}
+
 // Close does nothing for mock DB
 func (m *MockDB) Close() error {
 	return nil
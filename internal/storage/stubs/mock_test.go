@@ -2,10 +2,35 @@ package stubs
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
+
+	"library/internal/labels"
+	"library/internal/models"
+	"library/internal/storage"
 )
 
+// bookID looks up a book ID by name for test setup
+func bookID(t *testing.T, db *MockDB, name string) string {
+	t.Helper()
+	book, err := db.GetBookByName(context.Background(), name)
+	if err != nil {
+		t.Fatalf("Failed to find book %q: %v", name, err)
+	}
+	return book.ID
+}
+
+// participantID looks up a participant ID by name for test setup
+func participantID(t *testing.T, db *MockDB, name string) string {
+	t.Helper()
+	participant, err := db.GetParticipantByName(context.Background(), name)
+	if err != nil {
+		t.Fatalf("Failed to find participant %q: %v", name, err)
+	}
+	return participant.ID
+}
+
 func TestMockDB_CreateBook(t *testing.T) {
 	db := NewMockDB()
 	ctx := context.Background()
@@ -140,6 +165,141 @@ func TestMockDB_Participants(t *testing.T) {
 	}
 }
 
+func TestMockDB_ParticipantManagement(t *testing.T) {
+	db := NewMockDB()
+	ctx := context.Background()
+
+	if err := db.Initialize(ctx); err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+
+	id, err := db.CreateParticipant(ctx, "Charlie", false)
+	if err != nil {
+		t.Fatalf("Failed to create participant: %v", err)
+	}
+	if _, err := db.CreateParticipant(ctx, "Charlie", false); err == nil {
+		t.Error("Expected error when creating duplicate participant")
+	}
+
+	if _, err := db.CreateEvent(ctx, models.Event{Date: time.Now(), BookID: bookID(t, db, "The Hobbit"), ParticipantIDs: []string{id}}); err != nil {
+		t.Fatalf("Failed to create event: %v", err)
+	}
+
+	// Rename and switch role in one call
+	if err := db.UpdateParticipant(ctx, id, "Charles", true); err != nil {
+		t.Fatalf("Failed to update participant: %v", err)
+	}
+	if err := db.UpdateParticipant(ctx, id, "Alice", true); err == nil {
+		t.Error("Expected error when renaming to an existing name")
+	}
+
+	events, _ := db.GetLastEvents(ctx, 1)
+	if len(events) != 1 || events[0].ParticipantIDs[0] != id || events[0].ParticipantNames[0] != "Charles" {
+		t.Errorf("Expected event to follow renamed participant, got %+v", events)
+	}
+
+	// Archive hides the participant from the list
+	if err := db.ArchiveParticipant(ctx, id); err != nil {
+		t.Fatalf("Failed to archive participant: %v", err)
+	}
+	participants, err := db.ListParticipants(ctx)
+	if err != nil {
+		t.Fatalf("Failed to list participants: %v", err)
+	}
+	for _, p := range participants {
+		if p.Name == "Charles" {
+			t.Error("Expected archived participant to be hidden")
+		}
+	}
+	if p, err := db.GetParticipantByName(ctx, "Charles"); err != nil || !p.IsArchived {
+		t.Errorf("Expected archived participant to be found by name, got %+v, %v", p, err)
+	}
+
+	if err := db.ArchiveParticipant(ctx, "unknown-id"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound when archiving unknown participant, got %v", err)
+	}
+}
+
+func TestMockDB_BookLifecycle(t *testing.T) {
+	db := NewMockDB()
+	ctx := context.Background()
+
+	if err := db.Initialize(ctx); err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+
+	hobbit := bookID(t, db, "The Hobbit")
+	if _, err := db.CreateEvent(ctx, models.Event{Date: time.Now(), BookID: hobbit, ParticipantIDs: []string{participantID(t, db, "Alice")}}); err != nil {
+		t.Fatalf("Failed to create event: %v", err)
+	}
+
+	// Retired books are hidden from reading lists but kept in stats
+	if err := db.RetireBook(ctx, hobbit); err != nil {
+		t.Fatalf("Failed to retire book: %v", err)
+	}
+	books, _ := db.ListReadableBooks(ctx)
+	for _, book := range books {
+		if book.ID == hobbit {
+			t.Error("Expected retired book to be hidden from readable books")
+		}
+	}
+	retired, _ := db.ListRetiredBooks(ctx)
+	if len(retired) != 1 || retired[0].ID != hobbit {
+		t.Errorf("Expected 'The Hobbit' to be retired, got %+v", retired)
+	}
+	stats, _ := db.GetDetailedBookStats(ctx, time.Time{}, time.Time{}, "The Hobbit", "Alice")
+	if len(stats) != 1 || stats[0].ReadCount != 1 {
+		t.Errorf("Expected retired book to stay in stats, got %+v", stats)
+	}
+
+	// Events reference the book by ID, so a rename carries history along
+	if err := db.RenameBook(ctx, hobbit, "The Hobbit, or There and Back Again"); err != nil {
+		t.Fatalf("Failed to rename book: %v", err)
+	}
+	if err := db.RenameBook(ctx, bookID(t, db, "The Cat in the Hat"), "Charlotte's Web"); err == nil {
+		t.Error("Expected error when renaming to an existing book")
+	}
+	events, _ := db.GetLastEvents(ctx, 1)
+	if len(events) != 1 || events[0].BookName != "The Hobbit, or There and Back Again" {
+		t.Errorf("Expected event to follow renamed book, got %+v", events)
+	}
+
+	meta := models.BookMetadata{Author: "J. R. R. Tolkien", Series: "Middle-earth", SeriesVolume: 1, Age: "6+"}
+	if err := db.UpdateBookMetadata(ctx, hobbit, meta); err != nil {
+		t.Fatalf("Failed to update book metadata: %v", err)
+	}
+	book, _ := db.GetBookByName(ctx, "The Hobbit, or There and Back Again")
+	if book.BookMetadata != meta {
+		t.Errorf("Expected metadata %+v, got %+v", meta, book.BookMetadata)
+	}
+	if err := db.UpdateBookMetadata(ctx, "missing", meta); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound when updating unknown book, got %v", err)
+	}
+
+	if err := db.RestoreBook(ctx, hobbit); err != nil {
+		t.Fatalf("Failed to restore book: %v", err)
+	}
+	retired, _ = db.ListRetiredBooks(ctx)
+	if len(retired) != 0 {
+		t.Errorf("Expected no retired books, got %+v", retired)
+	}
+
+	// Only books without history can be deleted
+	if err := db.DeleteBook(ctx, hobbit); err == nil {
+		t.Error("Expected error when deleting a book with events")
+	}
+	greenEggs := bookID(t, db, "Green Eggs and Ham")
+	if err := db.DeleteBook(ctx, greenEggs); err != nil {
+		t.Fatalf("Failed to delete book: %v", err)
+	}
+	if err := db.DeleteBook(ctx, greenEggs); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound when deleting unknown book, got %v", err)
+	}
+	if _, err := db.GetBookByName(ctx, "Green Eggs and Ham"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for deleted book, got %v", err)
+	}
+}
+
 func TestMockDB_Events(t *testing.T) {
 	db := NewMockDB()
 	ctx := context.Background()
@@ -158,11 +318,11 @@ func TestMockDB_Events(t *testing.T) {
 	now := time.Now()
 	yesterday := now.AddDate(0, 0, -1)
 
-	if err := db.CreateEvent(ctx, yesterday, "Test Book", "Alice"); err != nil {
+	if _, err := db.CreateEvent(ctx, models.Event{Date: yesterday, BookID: bookID(t, db, "Test Book"), ParticipantIDs: []string{participantID(t, db, "Alice")}}); err != nil {
 		t.Fatalf("Failed to create event: %v", err)
 	}
 
-	if err := db.CreateEvent(ctx, now, "Test Book", "Bob"); err != nil {
+	if _, err := db.CreateEvent(ctx, models.Event{Date: now, BookID: bookID(t, db, "Test Book"), ParticipantIDs: []string{participantID(t, db, "Bob")}}); err != nil {
 		t.Fatalf("Failed to create event: %v", err)
 	}
 
@@ -177,12 +337,659 @@ func TestMockDB_Events(t *testing.T) {
 	}
 
 	// Events should be in reverse chronological order
-	if events[0].ParticipantName != "Bob" {
-		t.Errorf("Expected first event to be Bob, got %s", events[0].ParticipantName)
+	if events[0].ParticipantNames[0] != "Bob" {
+		t.Errorf("Expected first event to be Bob, got %v", events[0].ParticipantNames)
+	}
+
+	if events[1].ParticipantNames[0] != "Alice" {
+		t.Errorf("Expected second event to be Alice, got %v", events[1].ParticipantNames)
+	}
+}
+
+func TestMockDB_EditEvents(t *testing.T) {
+	db := NewMockDB()
+	ctx := context.Background()
+
+	if err := db.Initialize(ctx); err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+
+	eventID, err := db.CreateEvent(ctx, models.Event{Date: time.Now(), BookID: bookID(t, db, "The Hobbit"), ParticipantIDs: []string{participantID(t, db, "Alice")}})
+	if err != nil {
+		t.Fatalf("Failed to create event: %v", err)
+	}
+
+	event, err := db.GetEvent(ctx, eventID)
+	if err != nil {
+		t.Fatalf("Failed to get event: %v", err)
+	}
+	if event.BookName != "The Hobbit" || event.ParticipantNames[0] != "Alice" {
+		t.Errorf("Unexpected event: %+v", event)
+	}
+
+	event.BookID = bookID(t, db, "Matilda")
+	event.ParticipantIDs = []string{participantID(t, db, "Bob")}
+	if err := db.UpdateEvent(ctx, event); err != nil {
+		t.Fatalf("Failed to update event: %v", err)
+	}
+
+	event, _ = db.GetEvent(ctx, eventID)
+	if event.BookName != "Matilda" || event.ParticipantNames[0] != "Bob" {
+		t.Errorf("Expected updated event, got %+v", event)
+	}
+
+	if err := db.DeleteEvent(ctx, eventID); err != nil {
+		t.Fatalf("Failed to delete event: %v", err)
+	}
+	if _, err := db.GetEvent(ctx, eventID); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for deleted event, got %v", err)
+	}
+	if err := db.DeleteEvent(ctx, eventID); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound when deleting twice, got %v", err)
+	}
+}
+
+func TestMockDB_FindEventOnDay(t *testing.T) {
+	db := NewMockDB()
+	ctx := context.Background()
+
+	if err := db.Initialize(ctx); err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+
+	hobbit := bookID(t, db, "The Hobbit")
+	alice := participantID(t, db, "Alice")
+	evening := time.Date(2024, 5, 10, 20, 0, 0, 0, time.UTC)
+	if _, err := db.CreateEvent(ctx, models.Event{Date: evening, BookID: hobbit, ParticipantIDs: []string{alice}}); err != nil {
+		t.Fatalf("Failed to create event: %v", err)
+	}
+
+	if _, err := db.FindEventOnDay(ctx, time.Date(2024, 5, 10, 0, 0, 0, 0, time.UTC), hobbit, []string{alice}); err != nil {
+		t.Errorf("Expected duplicate on the same day, got %v", err)
+	}
+	if _, err := db.FindEventOnDay(ctx, evening.AddDate(0, 0, 1), hobbit, []string{alice}); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for the next day, got %v", err)
+	}
+	if _, err := db.FindEventOnDay(ctx, evening, hobbit, []string{participantID(t, db, "Bob")}); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for another reader, got %v", err)
+	}
+}
+
+func TestMockDB_SharedEventStats(t *testing.T) {
+	db := NewMockDB()
+	ctx := context.Background()
+
+	if err := db.Initialize(ctx); err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+
+	now := time.Now()
+	hobbit := bookID(t, db, "The Hobbit")
+	alice := participantID(t, db, "Alice")
+	bob := participantID(t, db, "Bob")
+	if _, err := db.CreateEvent(ctx, models.Event{Date: now.AddDate(0, 0, -1), BookID: hobbit, ParticipantIDs: []string{alice, bob}}); err != nil {
+		t.Fatalf("Failed to create event: %v", err)
+	}
+	if _, err := db.CreateEvent(ctx, models.Event{Date: now, BookID: hobbit, ParticipantIDs: nil}); err == nil {
+		t.Error("Expected an event without participants to be rejected")
+	}
+
+	// A shared reading counts once for the children
+	stats, _ := db.GetTopBooks(ctx, 10, now.AddDate(0, 0, -7), now, "")
+	if len(stats) != 1 || stats[0].ReadCount != 1 {
+		t.Errorf("Expected one reading of The Hobbit, got %+v", stats)
+	}
+
+	// ...and once for each attendee
+	for _, name := range []string{"Alice", "Bob"} {
+		stats, _ := db.GetTopBooks(ctx, 10, now.AddDate(0, 0, -7), now, name)
+		if len(stats) != 1 || stats[0].ReadCount != 1 {
+			t.Errorf("Expected one reading for %s, got %+v", name, stats)
+		}
+
+		detailed, _ := db.GetDetailedBookStats(ctx, time.Time{}, time.Time{}, "The Hobbit", name)
+		if len(detailed) != 1 || detailed[0].ReadCount != 1 {
+			t.Errorf("Expected detailed count 1 for %s, got %+v", name, detailed)
+		}
+
+		perParticipant, _ := db.GetParticipantStats(ctx, time.Time{}, time.Time{}, "The Hobbit", name)
+		if len(perParticipant) != 1 || perParticipant[0].ReadCount != 1 {
+			t.Errorf("Expected participant count 1 for %s, got %+v", name, perParticipant)
+		}
+	}
+
+	filtered, _ := db.GetLastEventsFiltered(ctx, 10, time.Time{}, time.Time{}, "bob")
+	if len(filtered) != 1 {
+		t.Errorf("Expected shared event when filtering by Bob, got %d", len(filtered))
+	}
+}
+
+func TestMockDB_GetSessionStats(t *testing.T) {
+	db := NewMockDB()
+	ctx := context.Background()
+
+	if err := db.Initialize(ctx); err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+
+	now := time.Now()
+	hobbit := bookID(t, db, "The Hobbit")
+	alice := participantID(t, db, "Alice")
+	bob := participantID(t, db, "Bob")
+	events := []models.Event{
+		{Date: now.AddDate(0, 0, -2), BookID: hobbit, ParticipantIDs: []string{alice}, DurationMinutes: 20, Progress: 15, ProgressUnit: models.ProgressPages},
+		{Date: now.AddDate(0, 0, -1), BookID: hobbit, ParticipantIDs: []string{alice, bob}, DurationMinutes: 30, Progress: 2, ProgressUnit: models.ProgressChapters},
+		{Date: now, BookID: hobbit, ParticipantIDs: []string{bob}},
+	}
+	for _, event := range events {
+		if _, err := db.CreateEvent(ctx, event); err != nil {
+			t.Fatalf("Failed to create event: %v", err)
+		}
+	}
+
+	stats, err := db.GetSessionStats(ctx, now.AddDate(0, 0, -7), now, "")
+	if err != nil {
+		t.Fatalf("Failed to get session stats: %v", err)
+	}
+	expected := models.SessionStats{Sessions: 3, TimedSessions: 2, TotalMinutes: 50, PageSessions: 1, TotalPages: 15, ChapterSessions: 1, TotalChapters: 2}
+	if stats != expected {
+		t.Errorf("Expected %+v, got %+v", expected, stats)
+	}
+
+	stats, _ = db.GetSessionStats(ctx, now.AddDate(0, 0, -7), now, "Bob")
+	if stats.Sessions != 2 || stats.TotalMinutes != 30 || stats.TotalPages != 0 || stats.TotalChapters != 2 {
+		t.Errorf("Unexpected stats for Bob: %+v", stats)
+	}
+
+	events, _ = db.GetLastEvents(ctx, 1)
+	if len(events) != 1 || events[0].DurationMinutes != 0 {
+		t.Errorf("Expected the latest event without details, got %+v", events)
+	}
+	events, _ = db.GetLastEvents(ctx, 3)
+	if events[2].DurationMinutes != 20 || events[2].ProgressUnit != models.ProgressPages {
+		t.Errorf("Expected session details to be stored, got %+v", events[2])
+	}
+}
+
+func TestMockDB_GetHourlyStats(t *testing.T) {
+	db := NewMockDB()
+	ctx := context.Background()
+
+	if err := db.Initialize(ctx); err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+
+	hobbit := bookID(t, db, "The Hobbit")
+	alice := participantID(t, db, "Alice")
+	mom := participantID(t, db, "Mom")
+	day := time.Date(2024, 5, 10, 0, 0, 0, 0, time.UTC)
+	events := []models.Event{
+		{Date: day.Add(6 * time.Hour), BookID: hobbit, ParticipantIDs: []string{alice}},
+		{Date: day.Add(6*time.Hour + 30*time.Minute), BookID: hobbit, ParticipantIDs: []string{alice}},
+		{Date: day.Add(18 * time.Hour), BookID: hobbit, ParticipantIDs: []string{alice}},
+		{Date: day.Add(20 * time.Hour), BookID: hobbit, ParticipantIDs: []string{mom}},
+	}
+	for _, event := range events {
+		if _, err := db.CreateEvent(ctx, event); err != nil {
+			t.Fatalf("Failed to create event: %v", err)
+		}
+	}
+
+	// Hours are taken in the requested timezone; Mom's reading is not a child's
+	loc := time.FixedZone("UTC+2", 2*60*60)
+	stats, err := db.GetHourlyStats(ctx, day, day.AddDate(0, 0, 1), "", loc)
+	if err != nil {
+		t.Fatalf("Failed to get hourly stats: %v", err)
+	}
+	expected := []models.HourStat{{Hour: 8, ReadCount: 2}, {Hour: 20, ReadCount: 1}}
+	if len(stats) != len(expected) {
+		t.Fatalf("Expected %+v, got %+v", expected, stats)
+	}
+	for i := range expected {
+		if stats[i] != expected[i] {
+			t.Errorf("Expected %+v, got %+v", expected[i], stats[i])
+		}
+	}
+
+	stats, _ = db.GetHourlyStats(ctx, day, day.AddDate(0, 0, 1), "Mom", time.UTC)
+	if len(stats) != 1 || stats[0].Hour != 20 {
+		t.Errorf("Expected Mom's reading at 20:00, got %+v", stats)
+	}
+}
+
+func TestMockDB_GetDailyAndWeeklyStats(t *testing.T) {
+	db := NewMockDB()
+	ctx := context.Background()
+
+	if err := db.Initialize(ctx); err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+
+	hobbit := bookID(t, db, "The Hobbit")
+	alice := participantID(t, db, "Alice")
+	bob := participantID(t, db, "Bob")
+	mom := participantID(t, db, "Mom")
+	sunday := time.Date(2024, 5, 12, 0, 0, 0, 0, time.UTC)
+	events := []models.Event{
+		{Date: sunday.Add(20 * time.Hour), BookID: hobbit, ParticipantIDs: []string{alice}},
+		{Date: sunday.Add(23 * time.Hour), BookID: hobbit, ParticipantIDs: []string{alice, bob}},
+		{Date: sunday.Add(30 * time.Hour), BookID: hobbit, ParticipantIDs: []string{bob}},
+		{Date: sunday.Add(32 * time.Hour), BookID: hobbit, ParticipantIDs: []string{mom}},
+	}
+	for _, event := range events {
+		if _, err := db.CreateEvent(ctx, event); err != nil {
+			t.Fatalf("Failed to create event: %v", err)
+		}
+	}
+
+	// At UTC+2 the 23:00 reading falls on Monday, the start of the next week
+	loc := time.FixedZone("UTC+2", 2*60*60)
+	days, err := db.GetDailyStats(ctx, sunday, sunday.AddDate(0, 0, 2), "", loc)
+	if err != nil {
+		t.Fatalf("Failed to get daily stats: %v", err)
+	}
+	expectedDays := []models.DayStat{
+		{Date: time.Date(2024, 5, 12, 0, 0, 0, 0, loc), ReadCount: 1},
+		{Date: time.Date(2024, 5, 13, 0, 0, 0, 0, loc), ReadCount: 2},
+	}
+	if len(days) != len(expectedDays) {
+		t.Fatalf("Expected %+v, got %+v", expectedDays, days)
+	}
+	for i := range expectedDays {
+		if !days[i].Date.Equal(expectedDays[i].Date) || days[i].ReadCount != expectedDays[i].ReadCount {
+			t.Errorf("Expected %+v, got %+v", expectedDays[i], days[i])
+		}
+	}
+
+	weeks, err := db.GetWeeklyReaderStats(ctx, sunday, sunday.AddDate(0, 0, 2), loc)
+	if err != nil {
+		t.Fatalf("Failed to get weekly reader stats: %v", err)
+	}
+	expectedWeeks := []struct {
+		week  time.Time
+		name  string
+		count int
+	}{
+		{time.Date(2024, 5, 6, 0, 0, 0, 0, loc), "Alice", 1},
+		{time.Date(2024, 5, 13, 0, 0, 0, 0, loc), "Alice", 1},
+		{time.Date(2024, 5, 13, 0, 0, 0, 0, loc), "Bob", 2},
+	}
+	if len(weeks) != len(expectedWeeks) {
+		t.Fatalf("Expected %+v, got %+v", expectedWeeks, weeks)
+	}
+	for i, want := range expectedWeeks {
+		if !weeks[i].WeekStart.Equal(want.week) || weeks[i].ParticipantName != want.name || weeks[i].ReadCount != want.count {
+			t.Errorf("Expected %+v, got %+v", want, weeks[i])
+		}
+	}
+}
+
+func TestMockDB_ChatSettings(t *testing.T) {
+	db := NewMockDB()
+	ctx := context.Background()
+
+	settings, err := db.GetChatSettings(ctx, 42)
+	if err != nil {
+		t.Fatalf("Failed to get chat settings: %v", err)
+	}
+	if settings.ChatID != 42 || settings.RotationStrategy != "" {
+		t.Errorf("Expected default settings, got %+v", settings)
+	}
+
+	skip := []string{"some-id"}
+	if err := db.SaveChatSettings(ctx, models.ChatSettings{ChatID: 42, RotationStrategy: "weekly", RotationSkipIDs: skip}); err != nil {
+		t.Fatalf("Failed to save chat settings: %v", err)
+	}
+	skip[0] = "changed"
+
+	settings, _ = db.GetChatSettings(ctx, 42)
+	if settings.RotationStrategy != "weekly" || len(settings.RotationSkipIDs) != 1 || settings.RotationSkipIDs[0] != "some-id" {
+		t.Errorf("Unexpected saved settings: %+v", settings)
+	}
+}
+
+func TestMockDB_ListReminderChats(t *testing.T) {
+	db := NewMockDB()
+	ctx := context.Background()
+
+	_ = db.SaveChatSettings(ctx, models.ChatSettings{ChatID: 42, ReminderEnabled: true, ReminderThreadID: 5})
+	_ = db.SaveChatSettings(ctx, models.ChatSettings{ChatID: 7, RotationStrategy: "weekly"})
+	_ = db.SaveChatSettings(ctx, models.ChatSettings{ChatID: 1, ReminderEnabled: true})
+
+	chats, err := db.ListReminderChats(ctx)
+	if err != nil {
+		t.Fatalf("Failed to list reminder chats: %v", err)
+	}
+	if len(chats) != 2 || chats[0].ChatID != 1 || chats[1].ChatID != 42 || chats[1].ReminderThreadID != 5 {
+		t.Errorf("Unexpected reminder chats: %+v", chats)
+	}
+}
+
+func TestMockDB_ScheduledRuns(t *testing.T) {
+	db := NewMockDB()
+	ctx := context.Background()
+
+	last, err := db.GetLastScheduledRun(ctx, "reminder")
+	if err != nil || !last.IsZero() {
+		t.Fatalf("Expected no previous run, got %v (%v)", last, err)
 	}
 
-	if events[1].ParticipantName != "Alice" {
-		t.Errorf("Expected second event to be Alice, got %s", events[1].ParticipantName)
+	day := time.Date(2026, 10, 16, 19, 0, 0, 0, time.UTC)
+	if claimed, _ := db.ClaimScheduledRun(ctx, "reminder", day); !claimed {
+		t.Fatal("Expected to claim the first run")
+	}
+	if claimed, _ := db.ClaimScheduledRun(ctx, "reminder", day); claimed {
+		t.Error("Expected an occurrence to be claimed only once")
+	}
+	if claimed, _ := db.ClaimScheduledRun(ctx, "reminder", day.AddDate(0, 0, -1)); claimed {
+		t.Error("Expected an older occurrence not to be claimed")
+	}
+
+	if last, _ := db.GetLastScheduledRun(ctx, "reminder"); !last.Equal(day) {
+		t.Errorf("Expected last run %v, got %v", day, last)
+	}
+	if last, _ := db.GetLastScheduledRun(ctx, "digest"); !last.IsZero() {
+		t.Errorf("Expected no run for another job, got %v", last)
+	}
+}
+
+func TestMockDB_GetNewBooks(t *testing.T) {
+	db := NewMockDB()
+	ctx := context.Background()
+
+	if err := db.Initialize(ctx); err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+
+	start := time.Now()
+	if _, err := db.CreateBook(ctx, "Momo"); err != nil {
+		t.Fatalf("Failed to create book: %v", err)
+	}
+
+	// Default books have no registration date and are never new
+	books, err := db.GetNewBooks(ctx, start.AddDate(0, 0, -1), time.Now())
+	if err != nil {
+		t.Fatalf("Failed to get new books: %v", err)
+	}
+	if len(books) != 1 || books[0].Name != "Momo" {
+		t.Errorf("Expected only Momo, got %+v", books)
+	}
+
+	books, _ = db.GetNewBooks(ctx, start.AddDate(0, 0, -7), start.AddDate(0, 0, -1))
+	if len(books) != 0 {
+		t.Errorf("Expected no books before registration, got %+v", books)
+	}
+}
+
+func TestMockDB_Goals(t *testing.T) {
+	db := NewMockDB()
+	ctx := context.Background()
+
+	if err := db.Initialize(ctx); err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	alice := participantID(t, db, "Alice")
+	bob := participantID(t, db, "Bob")
+
+	_ = db.SetGoal(ctx, bob, 10, models.GoalPeriodMonth)
+	_ = db.SetGoal(ctx, alice, 5, models.GoalPeriodWeek)
+
+	goals, err := db.ListGoals(ctx)
+	if err != nil {
+		t.Fatalf("Failed to list goals: %v", err)
+	}
+	if len(goals) != 2 || goals[0].ParticipantName != "Alice" || goals[0].Target != 5 || goals[1].Period != models.GoalPeriodMonth {
+		t.Errorf("Unexpected goals: %+v", goals)
+	}
+
+	if err := db.ClearGoal(ctx, bob); err != nil {
+		t.Errorf("Failed to clear goal: %v", err)
+	}
+	if err := db.ClearGoal(ctx, bob); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+	if err := db.SetGoal(ctx, "unknown", 1, models.GoalPeriodWeek); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestMockDB_APITokens(t *testing.T) {
+	db := NewMockDB()
+	ctx := context.Background()
+
+	id, err := db.CreateAPIToken(ctx, models.APIToken{Name: "tablet", Scope: models.TokenScopeRead, OwnerID: 1, Hash: "aaa"})
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+	if _, err := db.CreateAPIToken(ctx, models.APIToken{Name: "tablet", Scope: models.TokenScopeRead, OwnerID: 1, Hash: "bbb"}); err == nil {
+		t.Error("Expected duplicate token name to fail")
+	}
+	// Names are unique per owner only
+	if _, err := db.CreateAPIToken(ctx, models.APIToken{Name: "tablet", Scope: models.TokenScopeReadWrite, OwnerID: 2, Hash: "ccc"}); err != nil {
+		t.Errorf("Failed to create token of another owner: %v", err)
+	}
+
+	token, err := db.GetAPITokenByHash(ctx, "aaa")
+	if err != nil {
+		t.Fatalf("Failed to get token: %v", err)
+	}
+	if token.ID != id || token.OwnerID != 1 || token.Scope != models.TokenScopeRead || token.CreatedAt.IsZero() {
+		t.Errorf("Unexpected token: %+v", token)
+	}
+
+	if tokens, _ := db.ListAPITokens(ctx, 1); len(tokens) != 1 || tokens[0].ID != id {
+		t.Errorf("Unexpected tokens: %+v", tokens)
+	}
+
+	if err := db.RevokeAPIToken(ctx, id); err != nil {
+		t.Errorf("Failed to revoke token: %v", err)
+	}
+	if err := db.RevokeAPIToken(ctx, id); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+	if _, err := db.GetAPITokenByHash(ctx, "aaa"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestMockDB_GetStreaks(t *testing.T) {
+	db := NewMockDB()
+	ctx := context.Background()
+
+	if err := db.Initialize(ctx); err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+
+	today := time.Date(2026, 10, 16, 20, 0, 0, 0, time.UTC)
+	hobbit := bookID(t, db, "The Hobbit")
+	alice := participantID(t, db, "Alice")
+	for _, date := range []time.Time{today.AddDate(0, 0, -5), today.AddDate(0, 0, -1), today, today.Add(-time.Hour)} {
+		if _, err := db.CreateEvent(ctx, models.Event{Date: date, BookID: hobbit, ParticipantIDs: []string{alice}}); err != nil {
+			t.Fatalf("Failed to create event: %v", err)
+		}
+	}
+
+	streaks, err := db.GetStreaks(ctx, today)
+	if err != nil {
+		t.Fatalf("Failed to get streaks: %v", err)
+	}
+	if len(streaks) != 4 || streaks[0].ParticipantName != "Alice" || streaks[0].Current != 2 || streaks[0].Longest != 2 {
+		t.Errorf("Unexpected streaks: %+v", streaks)
+	}
+	if streaks[1].ParticipantName != "Bob" || streaks[1].Longest != 0 || streaks[1].LastReadDate != nil {
+		t.Errorf("Expected no streak for Bob, got %+v", streaks[1])
+	}
+}
+
+func TestMockDB_Badges(t *testing.T) {
+	db := NewMockDB()
+	ctx := context.Background()
+
+	if err := db.Initialize(ctx); err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	alice := participantID(t, db, "Alice")
+	bob := participantID(t, db, "Bob")
+
+	now := time.Now()
+	_ = db.AddBadge(ctx, models.Badge{ParticipantID: alice, Key: "streak_7", UnlockedAt: now})
+	_ = db.AddBadge(ctx, models.Badge{ParticipantID: bob, Key: "books_10", UnlockedAt: now.Add(-time.Hour)})
+	if err := db.AddBadge(ctx, models.Badge{ParticipantID: "unknown", Key: "books_10"}); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+
+	badges, err := db.ListBadges(ctx, "")
+	if err != nil {
+		t.Fatalf("Failed to list badges: %v", err)
+	}
+	if len(badges) != 2 || badges[0].ParticipantName != "Bob" || badges[1].Key != "streak_7" {
+		t.Errorf("Unexpected badges: %+v", badges)
+	}
+	if badges, _ := db.ListBadges(ctx, alice); len(badges) != 1 || badges[0].ParticipantName != "Alice" {
+		t.Errorf("Unexpected badges for Alice: %+v", badges)
+	}
+}
+
+func TestMockDB_GetReaderActivity(t *testing.T) {
+	db := NewMockDB()
+	ctx := context.Background()
+
+	if err := db.Initialize(ctx); err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+
+	now := time.Now()
+	hobbit := bookID(t, db, "The Hobbit")
+	alice := participantID(t, db, "Alice")
+	bob := participantID(t, db, "Bob")
+	events := []models.Event{
+		{Date: now.AddDate(0, 0, -10), BookID: hobbit, ParticipantIDs: []string{alice}},
+		{Date: now.AddDate(0, 0, -1), BookID: hobbit, ParticipantIDs: []string{alice, bob}},
+		{Date: now, BookID: hobbit, ParticipantIDs: []string{bob}},
+	}
+	for _, event := range events {
+		if _, err := db.CreateEvent(ctx, event); err != nil {
+			t.Fatalf("Failed to create event: %v", err)
+		}
+	}
+
+	activity, err := db.GetReaderActivity(ctx, now.AddDate(0, 0, -7))
+	if err != nil {
+		t.Fatalf("Failed to get reader activity: %v", err)
+	}
+
+	byName := make(map[string]models.ReaderActivity)
+	for _, a := range activity {
+		byName[a.ParticipantName] = a
+	}
+	if len(activity) != 4 {
+		t.Fatalf("Expected activity for all 4 participants, got %d", len(activity))
+	}
+	if a := byName["Alice"]; a.ReadsSince != 1 || a.LastReadDate == nil || !a.LastReadDate.Equal(now.AddDate(0, 0, -1)) {
+		t.Errorf("Unexpected activity for Alice: %+v", a)
+	}
+	if a := byName["Bob"]; a.ReadsSince != 2 || a.LastReadDate == nil || !a.LastReadDate.Equal(now) {
+		t.Errorf("Unexpected activity for Bob: %+v", a)
+	}
+	if a := byName["Mom"]; a.ReadsSince != 0 || a.LastReadDate != nil {
+		t.Errorf("Expected no activity for Mom, got %+v", a)
+	}
+}
+
+func TestMockDB_Absences(t *testing.T) {
+	db := NewMockDB()
+	ctx := context.Background()
+
+	if err := db.Initialize(ctx); err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+
+	now := time.Now()
+	alice := participantID(t, db, "Alice")
+	bob := participantID(t, db, "Bob")
+	if err := db.SetAbsence(ctx, bob, now.AddDate(0, 0, 3)); err != nil {
+		t.Fatalf("Failed to set absence: %v", err)
+	}
+	if err := db.SetAbsence(ctx, alice, now.AddDate(0, 0, 1)); err != nil {
+		t.Fatalf("Failed to set absence: %v", err)
+	}
+
+	absences, _ := db.ListAbsences(ctx, now)
+	if len(absences) != 2 || absences[0].ParticipantName != "Alice" || absences[1].ParticipantName != "Bob" {
+		t.Errorf("Expected Alice and Bob to be away, got %+v", absences)
+	}
+
+	absences, _ = db.ListAbsences(ctx, now.AddDate(0, 0, 2))
+	if len(absences) != 1 || absences[0].ParticipantID != bob {
+		t.Errorf("Expected only Bob to be away in two days, got %+v", absences)
+	}
+
+	if err := db.ClearAbsence(ctx, bob); err != nil {
+		t.Fatalf("Failed to clear absence: %v", err)
+	}
+	if err := db.ClearAbsence(ctx, bob); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for a participant who is not away, got %v", err)
+	}
+	if err := db.SetAbsence(ctx, "missing", now); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for an unknown participant, got %v", err)
+	}
+}
+
+func TestMockDB_LabelManagement(t *testing.T) {
+	db := NewMockDB()
+	ctx := context.Background()
+
+	if err := db.Initialize(ctx); err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+
+	hobbit := bookID(t, db, "The Hobbit")
+	cat := bookID(t, db, "The Cat in the Hat")
+	_ = db.AddLabelToBook(ctx, hobbit, "bedtime")
+	_ = db.AddLabelToBook(ctx, hobbit, "Bedtime")
+	_ = db.AddLabelToBook(ctx, cat, "Bedtime")
+	_ = db.AddLabelToBook(ctx, cat, "Rhymes")
+
+	if err := db.RenameLabel(ctx, "Rhymes", "bedtime"); err == nil {
+		t.Error("Expected renaming to an existing label to fail")
+	}
+	if err := db.RenameLabel(ctx, "Missing", "x"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound renaming a missing label, got %v", err)
+	}
+	if err := db.RenameLabel(ctx, "Rhymes", "rhymes"); err != nil {
+		t.Fatalf("Failed to rename label: %v", err)
+	}
+
+	if err := db.MergeLabels(ctx, []string{"Bedtime"}, "bedtime"); err != nil {
+		t.Fatalf("Failed to merge labels: %v", err)
+	}
+	labels, _ := db.GetAllLabels(ctx)
+	if len(labels) != 2 || labels[0] != "bedtime" || labels[1] != "rhymes" {
+		t.Errorf("Expected [bedtime rhymes], got %v", labels)
+	}
+	if book, _ := db.GetBookByName(ctx, "The Hobbit"); len(book.Labels) != 1 {
+		t.Errorf("Expected merged labels to be kept once, got %v", book.Labels)
+	}
+
+	if err := db.AddLabelToBooks(ctx, []string{hobbit, cat, "missing"}, "rhymes"); err != nil {
+		t.Fatalf("Failed to add label to books: %v", err)
+	}
+	if book, _ := db.GetBookByName(ctx, "The Hobbit"); len(book.Labels) != 2 || book.Labels[1] != "rhymes" {
+		t.Errorf("Expected [bedtime rhymes] after bulk add, got %v", book.Labels)
+	}
+
+	if err := db.RemoveLabelFromBook(ctx, cat, "bedtime"); err != nil {
+		t.Fatalf("Failed to remove label: %v", err)
+	}
+	if book, _ := db.GetBookByName(ctx, "The Cat in the Hat"); len(book.Labels) != 1 || book.Labels[0] != "rhymes" {
+		t.Errorf("Expected [rhymes] after removal, got %v", book.Labels)
+	}
+
+	if err := db.RemoveLabelFromBooks(ctx, []string{hobbit, cat, "missing"}, "rhymes"); err != nil {
+		t.Fatalf("Failed to remove label from books: %v", err)
+	}
+	if labels, _ := db.GetAllLabels(ctx); len(labels) != 1 || labels[0] != "bedtime" {
+		t.Errorf("Expected [bedtime] after bulk removal, got %v", labels)
 	}
 }
 
@@ -195,12 +1002,12 @@ func TestMockDB_GetBooksByLabel(t *testing.T) {
 	}
 
 	// Add labels to some books
-	_ = db.AddLabelToBook(ctx, "The Hobbit", "Fantasy")
-	_ = db.AddLabelToBook(ctx, "Harry Potter and the Philosopher's Stone", "Fantasy")
-	_ = db.AddLabelToBook(ctx, "The Cat in the Hat", "Rhymes")
+	_ = db.AddLabelToBook(ctx, bookID(t, db, "The Hobbit"), "Fantasy")
+	_ = db.AddLabelToBook(ctx, bookID(t, db, "Harry Potter and the Philosopher's Stone"), "Fantasy")
+	_ = db.AddLabelToBook(ctx, bookID(t, db, "The Cat in the Hat"), "Rhymes")
 
 	// Get books by label "Fantasy"
-	books, err := db.GetBooksByLabel(ctx, "Fantasy")
+	books, err := db.GetBooksByLabel(ctx, labels.Label("Fantasy"))
 	if err != nil {
 		t.Fatalf("Failed to get books by label: %v", err)
 	}
@@ -220,7 +1027,7 @@ func TestMockDB_GetBooksByLabel(t *testing.T) {
 	}
 
 	// Get books by label that doesn't exist
-	books, err = db.GetBooksByLabel(ctx, "NonExistent")
+	books, err = db.GetBooksByLabel(ctx, labels.Label("NonExistent"))
 	if err != nil {
 		t.Fatalf("Failed to get books by label: %v", err)
 	}
@@ -228,6 +1035,28 @@ func TestMockDB_GetBooksByLabel(t *testing.T) {
 	if len(books) != 0 {
 		t.Errorf("Expected 0 books with label 'NonExistent', got %d", len(books))
 	}
+
+	// Filter expressions
+	_ = db.AddLabelToBook(ctx, bookID(t, db, "The Hobbit"), "lang:en")
+	filter, err := labels.Parse("Fantasy AND NOT lang=en OR Rhymes")
+	if err != nil {
+		t.Fatalf("Failed to parse filter: %v", err)
+	}
+	books, err = db.GetBooksByLabel(ctx, filter)
+	if err != nil {
+		t.Fatalf("Failed to get books by label: %v", err)
+	}
+	if len(books) != 2 || books[0].Name != "Harry Potter and the Philosopher's Stone" || books[1].Name != "The Cat in the Hat" {
+		t.Errorf("Expected Harry Potter and The Cat in the Hat, got %v", books)
+	}
+
+	stats, err := db.GetRarelyReadBooks(ctx, 10, false, labels.Prefix("lang"))
+	if err != nil {
+		t.Fatalf("Failed to get rarely read books: %v", err)
+	}
+	if len(stats) != 1 || stats[0].BookName != "The Hobbit" {
+		t.Errorf("Expected only The Hobbit in lang:*, got %v", stats)
+	}
 }
 
 func TestMockDB_GetDetailedBookStats(t *testing.T) {
@@ -238,9 +1067,9 @@ func TestMockDB_GetDetailedBookStats(t *testing.T) {
 	}
 
 	now := time.Now()
-	_ = db.CreateEvent(ctx, now.AddDate(0, 0, -5), "The Hobbit", "Alice")
-	_ = db.CreateEvent(ctx, now.AddDate(0, 0, -2), "The Hobbit", "Alice")
-	_ = db.CreateEvent(ctx, now.AddDate(0, 0, -1), "The Hobbit", "Bob")
+	_, _ = db.CreateEvent(ctx, models.Event{Date: now.AddDate(0, 0, -5), BookID: bookID(t, db, "The Hobbit"), ParticipantIDs: []string{participantID(t, db, "Alice")}})
+	_, _ = db.CreateEvent(ctx, models.Event{Date: now.AddDate(0, 0, -2), BookID: bookID(t, db, "The Hobbit"), ParticipantIDs: []string{participantID(t, db, "Alice")}})
+	_, _ = db.CreateEvent(ctx, models.Event{Date: now.AddDate(0, 0, -1), BookID: bookID(t, db, "The Hobbit"), ParticipantIDs: []string{participantID(t, db, "Bob")}})
 
 	// All stats (no filters) — should have rows for every book × participant
 	stats, err := db.GetDetailedBookStats(ctx, time.Time{}, time.Time{}, "", "")
@@ -326,9 +1155,9 @@ func TestMockDB_GetParticipantStats(t *testing.T) {
 	}
 
 	now := time.Now()
-	_ = db.CreateEvent(ctx, now.AddDate(0, 0, -5), "The Hobbit", "Alice")
-	_ = db.CreateEvent(ctx, now.AddDate(0, 0, -2), "The Hobbit", "Alice")
-	_ = db.CreateEvent(ctx, now.AddDate(0, 0, -1), "Goodnight Moon", "Alice")
+	_, _ = db.CreateEvent(ctx, models.Event{Date: now.AddDate(0, 0, -5), BookID: bookID(t, db, "The Hobbit"), ParticipantIDs: []string{participantID(t, db, "Alice")}})
+	_, _ = db.CreateEvent(ctx, models.Event{Date: now.AddDate(0, 0, -2), BookID: bookID(t, db, "The Hobbit"), ParticipantIDs: []string{participantID(t, db, "Alice")}})
+	_, _ = db.CreateEvent(ctx, models.Event{Date: now.AddDate(0, 0, -1), BookID: bookID(t, db, "Goodnight Moon"), ParticipantIDs: []string{participantID(t, db, "Alice")}})
 
 	// All stats
 	stats, err := db.GetParticipantStats(ctx, time.Time{}, time.Time{}, "", "")
@@ -405,7 +1234,7 @@ func TestMockDB_GetLastEvents_Limit(t *testing.T) {
 	// Create 5 events
 	for i := 0; i < 5; i++ {
 		date := time.Now().AddDate(0, 0, -i)
-		if err := db.CreateEvent(ctx, date, "Test Book", "Alice"); err != nil {
+		if _, err := db.CreateEvent(ctx, models.Event{Date: date, BookID: bookID(t, db, "Test Book"), ParticipantIDs: []string{participantID(t, db, "Alice")}}); err != nil {
 			t.Fatalf("Failed to create event: %v", err)
 		}
 	}
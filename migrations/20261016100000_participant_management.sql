@@ -0,0 +1,23 @@
+-- +goose Up
+-- Allow participants to be archived instead of deleted
+
+-- +goose StatementBegin
+ALTER TABLE participants ADD COLUMN is_archived Bool DEFAULT false;
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE participants MODIFY SETTING enable_block_number_column = 1, enable_block_offset_column = 1;
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE events MODIFY SETTING enable_block_number_column = 1, enable_block_offset_column = 1;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE events MODIFY SETTING enable_block_number_column = 0, enable_block_offset_column = 0;
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE participants MODIFY SETTING enable_block_number_column = 0, enable_block_offset_column = 0;
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE participants DROP COLUMN is_archived;
+-- +goose StatementEnd
@@ -0,0 +1,176 @@
+-- +goose Up
+-- Give books and participants UUID keys and make events reference them by ID.
+-- Tables are rebuilt because the sorting key changes from name to id.
+
+-- +goose StatementBegin
+CREATE TABLE books_v2 (
+    id UUID,
+    name String,
+    is_readable Bool,
+    labels Array(String)
+) ENGINE = MergeTree()
+ORDER BY id
+SETTINGS enable_block_number_column = 1, enable_block_offset_column = 1;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+INSERT INTO books_v2 (id, name, is_readable, labels)
+SELECT generateUUIDv4(), name, is_readable, labels
+FROM (
+    SELECT name, max(is_readable) AS is_readable, groupUniqArrayArray(labels) AS labels
+    FROM books
+    GROUP BY name
+);
+-- +goose StatementEnd
+
+-- Events that point to unregistered books keep their history as retired books
+-- +goose StatementBegin
+INSERT INTO books_v2 (id, name, is_readable, labels)
+SELECT generateUUIDv4(), book_name, false, []
+FROM (
+    SELECT DISTINCT book_name
+    FROM events
+    WHERE book_name NOT IN (SELECT name FROM books)
+);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TABLE participants_v2 (
+    id UUID,
+    name String,
+    is_parent Bool,
+    is_archived Bool DEFAULT false
+) ENGINE = MergeTree()
+ORDER BY id
+SETTINGS enable_block_number_column = 1, enable_block_offset_column = 1;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+INSERT INTO participants_v2 (id, name, is_parent, is_archived)
+SELECT generateUUIDv4(), name, is_parent, is_archived
+FROM (
+    SELECT name, max(is_parent) AS is_parent, min(is_archived) AS is_archived
+    FROM participants
+    GROUP BY name
+);
+-- +goose StatementEnd
+
+-- Events that point to unknown participants keep their history as archived participants
+-- +goose StatementBegin
+INSERT INTO participants_v2 (id, name, is_parent, is_archived)
+SELECT generateUUIDv4(), participant_name, false, true
+FROM (
+    SELECT DISTINCT participant_name
+    FROM events
+    WHERE participant_name NOT IN (SELECT name FROM participants)
+);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TABLE events_v2 (
+    date DateTime,
+    book_id UUID,
+    participant_id UUID
+) ENGINE = MergeTree()
+ORDER BY date
+SETTINGS enable_block_number_column = 1, enable_block_offset_column = 1;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+INSERT INTO events_v2 (date, book_id, participant_id)
+SELECT e.date, b.id, p.id
+FROM events e
+INNER JOIN books_v2 b ON b.name = e.book_name
+INNER JOIN participants_v2 p ON p.name = e.participant_name;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+RENAME TABLE
+    books TO books_by_name,
+    participants TO participants_by_name,
+    events TO events_by_name,
+    books_v2 TO books,
+    participants_v2 TO participants,
+    events_v2 TO events;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DROP TABLE books_by_name;
+-- +goose StatementEnd
+-- +goose StatementBegin
+DROP TABLE participants_by_name;
+-- +goose StatementEnd
+-- +goose StatementBegin
+DROP TABLE events_by_name;
+-- +goose StatementEnd
+
+-- +goose Down
+-- Restore name-keyed tables; events are rewritten back to names
+
+-- +goose StatementBegin
+CREATE TABLE books_by_name (
+    name String,
+    is_readable Bool,
+    labels Array(String)
+) ENGINE = MergeTree()
+ORDER BY name
+SETTINGS enable_block_number_column = 1, enable_block_offset_column = 1;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+INSERT INTO books_by_name (name, is_readable, labels)
+SELECT name, is_readable, labels FROM books;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TABLE participants_by_name (
+    name String,
+    is_parent Bool,
+    is_archived Bool DEFAULT false
+) ENGINE = MergeTree()
+ORDER BY name
+SETTINGS enable_block_number_column = 1, enable_block_offset_column = 1;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+INSERT INTO participants_by_name (name, is_parent, is_archived)
+SELECT name, is_parent, is_archived FROM participants;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TABLE events_by_name (
+    date DateTime,
+    book_name String,
+    participant_name String
+) ENGINE = MergeTree()
+ORDER BY date
+SETTINGS enable_block_number_column = 1, enable_block_offset_column = 1;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+INSERT INTO events_by_name (date, book_name, participant_name)
+SELECT e.date, b.name, p.name
+FROM events e
+INNER JOIN books b ON b.id = e.book_id
+INNER JOIN participants p ON p.id = e.participant_id;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+RENAME TABLE
+    books TO books_v2,
+    participants TO participants_v2,
+    events TO events_v2,
+    books_by_name TO books,
+    participants_by_name TO participants,
+    events_by_name TO events;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DROP TABLE books_v2;
+-- +goose StatementEnd
+-- +goose StatementBegin
+DROP TABLE participants_v2;
+-- +goose StatementEnd
+-- +goose StatementBegin
+DROP TABLE events_v2;
+-- +goose StatementEnd
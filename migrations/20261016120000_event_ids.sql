@@ -0,0 +1,15 @@
+-- +goose Up
+-- Give every reading event its own ID so single events can be edited or undone
+
+-- +goose StatementBegin
+ALTER TABLE events ADD COLUMN id UUID DEFAULT generateUUIDv4() FIRST;
+-- +goose StatementEnd
+-- Persist generated IDs for existing rows
+-- +goose StatementBegin
+ALTER TABLE events MATERIALIZE COLUMN id SETTINGS mutations_sync = 2;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE events DROP COLUMN id;
+-- +goose StatementEnd
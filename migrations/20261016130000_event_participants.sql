@@ -0,0 +1,31 @@
+-- +goose Up
+-- Allow several participants to attend one reading event
+
+-- +goose StatementBegin
+ALTER TABLE events ADD COLUMN participant_ids Array(UUID) AFTER book_id;
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE events UPDATE participant_ids = [participant_id] WHERE 1 SETTINGS mutations_sync = 2;
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE events DROP COLUMN participant_id;
+-- +goose StatementEnd
+
+-- +goose Down
+-- Shared readings are split back into one event per participant
+
+-- +goose StatementBegin
+ALTER TABLE events ADD COLUMN participant_id UUID AFTER book_id;
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE events UPDATE participant_id = participant_ids[1] WHERE 1 SETTINGS mutations_sync = 2;
+-- +goose StatementEnd
+-- +goose StatementBegin
+INSERT INTO events (id, date, book_id, participant_id)
+SELECT generateUUIDv4(), date, book_id, extra_id
+FROM events
+ARRAY JOIN arraySlice(participant_ids, 2) AS extra_id;
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE events DROP COLUMN participant_ids;
+-- +goose StatementEnd
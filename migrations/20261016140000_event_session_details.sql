@@ -0,0 +1,17 @@
+-- +goose Up
+-- Optional session length and progress (pages or chapters) for reading events
+
+-- +goose StatementBegin
+ALTER TABLE events
+    ADD COLUMN duration_minutes UInt32 DEFAULT 0 AFTER participant_ids,
+    ADD COLUMN progress UInt32 DEFAULT 0 AFTER duration_minutes,
+    ADD COLUMN progress_unit LowCardinality(String) DEFAULT '' AFTER progress;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE events
+    DROP COLUMN progress_unit,
+    DROP COLUMN progress,
+    DROP COLUMN duration_minutes;
+-- +goose StatementEnd
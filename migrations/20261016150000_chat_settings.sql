@@ -0,0 +1,17 @@
+-- +goose Up
+-- Per-chat preferences such as the /who_is_next rotation policy
+
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS chat_settings (
+    chat_id Int64,
+    rotation_strategy LowCardinality(String) DEFAULT '',
+    rotation_skip_ids Array(UUID) DEFAULT []
+) ENGINE = MergeTree()
+ORDER BY chat_id
+SETTINGS enable_block_number_column = 1, enable_block_offset_column = 1;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS chat_settings;
+-- +goose StatementEnd
@@ -0,0 +1,17 @@
+-- +goose Up
+-- Participants who are away are skipped by /who_is_next until they are back
+
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS absences (
+    participant_id UUID,
+    until DateTime,
+    created_at DateTime DEFAULT now()
+) ENGINE = MergeTree()
+ORDER BY participant_id
+SETTINGS enable_block_number_column = 1, enable_block_offset_column = 1;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS absences;
+-- +goose StatementEnd
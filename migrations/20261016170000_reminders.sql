@@ -0,0 +1,33 @@
+-- +goose Up
+-- Chats registered with /remind get the daily "who reads tonight" reminder.
+-- scheduled_runs remembers which occurrences of scheduled jobs already ran, so restarts neither skip nor repeat them.
+-- Every instance inserts a claim before running a job; the claim inserted first wins, so a job runs once even when
+-- several instances are up.
+
+-- +goose StatementBegin
+ALTER TABLE chat_settings
+    ADD COLUMN reminder_enabled Bool DEFAULT false AFTER rotation_skip_ids,
+    ADD COLUMN reminder_thread_id Int32 DEFAULT 0 AFTER reminder_enabled;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS scheduled_runs (
+    job LowCardinality(String),
+    scheduled_for DateTime,
+    ran_at DateTime DEFAULT now(),
+    claim_id UUID
+) ENGINE = MergeTree()
+ORDER BY (job, scheduled_for)
+SETTINGS enable_block_number_column = 1, enable_block_offset_column = 1;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS scheduled_runs;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE chat_settings
+    DROP COLUMN reminder_thread_id,
+    DROP COLUMN reminder_enabled;
+-- +goose StatementEnd
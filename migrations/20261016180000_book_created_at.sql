@@ -0,0 +1,14 @@
+-- +goose Up
+-- When a book was registered, for the "new books" section of digests.
+-- Existing books keep the epoch default since their registration date is unknown.
+
+-- +goose StatementBegin
+ALTER TABLE books
+    ADD COLUMN created_at DateTime DEFAULT toDateTime(0) AFTER labels;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE books
+    DROP COLUMN created_at;
+-- +goose StatementEnd
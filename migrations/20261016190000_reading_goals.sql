@@ -0,0 +1,18 @@
+-- +goose Up
+-- Per-participant reading goals such as "5 reads per week", set with /goals
+
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS reading_goals (
+    participant_id UUID,
+    target UInt32,
+    period LowCardinality(String),
+    created_at DateTime DEFAULT now()
+) ENGINE = MergeTree()
+ORDER BY participant_id
+SETTINGS enable_block_number_column = 1, enable_block_offset_column = 1;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS reading_goals;
+-- +goose StatementEnd
@@ -0,0 +1,18 @@
+-- +goose Up
+-- Achievements unlocked by participants, announced in chat and listed with /badges
+
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS badges (
+    participant_id UUID,
+    badge LowCardinality(String),
+    event_id UUID,
+    unlocked_at DateTime DEFAULT now()
+) ENGINE = MergeTree()
+ORDER BY (participant_id, badge)
+SETTINGS enable_block_number_column = 1, enable_block_offset_column = 1;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS badges;
+-- +goose StatementEnd
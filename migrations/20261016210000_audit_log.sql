@@ -0,0 +1,20 @@
+-- +goose Up
+-- Record of every data change: who made it, from where (bot, Mini App, /ask) and its arguments
+
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS audit_log (
+    created_at DateTime64(3),
+    actor_id Int64,
+    actor_name String,
+    source LowCardinality(String),
+    action LowCardinality(String),
+    payload String
+) ENGINE = MergeTree()
+ORDER BY created_at
+SETTINGS enable_block_number_column = 1, enable_block_offset_column = 1;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS audit_log;
+-- +goose StatementEnd
@@ -0,0 +1,26 @@
+-- +goose Up
+-- Optional book details: author (dropped in 20251129225126), ISBN, series, cover, recommended age and notes.
+-- cover is an image URL or a Telegram file_id.
+
+-- +goose StatementBegin
+ALTER TABLE books
+    ADD COLUMN author String DEFAULT '' AFTER name,
+    ADD COLUMN isbn String DEFAULT '' AFTER created_at,
+    ADD COLUMN series String DEFAULT '' AFTER isbn,
+    ADD COLUMN series_volume UInt16 DEFAULT 0 AFTER series,
+    ADD COLUMN cover String DEFAULT '' AFTER series_volume,
+    ADD COLUMN age LowCardinality(String) DEFAULT '' AFTER cover,
+    ADD COLUMN notes String DEFAULT '' AFTER age;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE books
+    DROP COLUMN notes,
+    DROP COLUMN age,
+    DROP COLUMN cover,
+    DROP COLUMN series_volume,
+    DROP COLUMN series,
+    DROP COLUMN isbn,
+    DROP COLUMN author;
+-- +goose StatementEnd
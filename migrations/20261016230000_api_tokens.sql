@@ -0,0 +1,20 @@
+-- +goose Up
+-- Personal bearer tokens for the Mini App API, created with /token. Only the SHA-256 hash of a token is stored.
+
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS api_tokens (
+    id UUID,
+    name String,
+    scope LowCardinality(String),
+    owner_id Int64,
+    hash String,
+    created_at DateTime DEFAULT now()
+) ENGINE = MergeTree()
+ORDER BY (owner_id, name)
+SETTINGS enable_block_number_column = 1, enable_block_offset_column = 1;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS api_tokens;
+-- +goose StatementEnd